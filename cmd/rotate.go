@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"unterlagen/platform/configuration"
+	"unterlagen/platform/database/sqlite"
+	"unterlagen/platform/storage"
+	"unterlagen/platform/storage/encrypted"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// rotateKey encrypts all stored documents, previews and mail passwords with the current encryption key. It is run
+// while the server is stopped, after the new key was configured and the old one moved to the previous keys.
+func rotateKey(configuration configuration.Configuration, db *sqlx.DB) {
+	rotated, err := storage.RotateKey(configuration)
+	if err != nil {
+		slog.Error("failed to rotate encryption key", "rotated", rotated, "error", err)
+		os.Exit(1)
+	}
+
+	// Saving a mail account encrypts its password with the current key
+	mailAccountRepository := sqlite.NewMailAccountRepository(db, encrypted.GetKeyring(configuration))
+	accounts, err := mailAccountRepository.FindAll()
+	if err != nil {
+		slog.Error("failed to rotate encryption key of mail passwords", "error", err)
+		os.Exit(1)
+	}
+	for _, account := range accounts {
+		err := mailAccountRepository.Save(account)
+		if err != nil {
+			slog.Error("failed to rotate encryption key of mail password", "account", account.ID, "error", err)
+			os.Exit(1)
+		}
+	}
+
+	slog.Info("rotated encryption key", "rotated", rotated, "mailAccounts", len(accounts))
+}
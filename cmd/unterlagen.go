@@ -14,8 +14,11 @@ import (
 	"unterlagen/platform/database/memory"
 	"unterlagen/platform/database/sqlite"
 	"unterlagen/platform/llm"
+	"unterlagen/platform/mail"
 	"unterlagen/platform/messaging/synchronous"
-	"unterlagen/platform/storage/filesystem"
+	"unterlagen/platform/ocr"
+	"unterlagen/platform/storage"
+	"unterlagen/platform/storage/encrypted"
 	"unterlagen/platform/web"
 )
 
@@ -30,29 +33,97 @@ func main() {
 
 	// Database
 	db := sqlite.Initialize(shutdown, jobScheduler, configuration)
+
+	if len(os.Args) > 1 && os.Args[1] == "rotate-key" {
+		rotateKey(configuration, db)
+		shutdown.Execute()
+		return
+	}
+
 	userRepository := sqlite.NewUserRepository(db)
+	groupRepository := sqlite.NewGroupRepository(db)
 	documentRepository := sqlite.NewDocumentRepository(db)
+	documentVersionRepository := sqlite.NewDocumentVersionRepository(db)
 	folderRepository := sqlite.NewFolderRepository(db)
+	preferencesRepository := sqlite.NewPreferencesRepository(db)
+	tagRepository := sqlite.NewTagRepository(db)
+	customFieldRepository := sqlite.NewCustomFieldRepository(db)
+	correspondentRepository := sqlite.NewCorrespondentRepository(db)
+	documentTypeRepository := sqlite.NewDocumentTypeRepository(db)
+	classificationRuleRepository := sqlite.NewClassificationRuleRepository(db)
+	mailAccountRepository := sqlite.NewMailAccountRepository(db, encrypted.GetKeyring(configuration))
+	bulkOperationRepository := sqlite.NewBulkOperationRepository(db)
+	noteRepository := sqlite.NewNoteRepository(db)
+	grantRepository := sqlite.NewGrantRepository(db)
+	shareLinkRepository := sqlite.NewShareLinkRepository(db)
+	retentionPolicyRepository := sqlite.NewRetentionPolicyRepository(db)
+	legalHoldRepository := sqlite.NewLegalHoldRepository(db)
 	taskRepository := sqlite.NewTaskRepository(db)
 	settingsRepository := memory.NewSettingsRepository()
 	searchRepository := sqlite.NewSearchRepository(db)
 
 	// Messaging
 	userMessages := synchronous.NewUserMessages()
+	groupMessages := synchronous.NewGroupMessages()
 	documentMessages := synchronous.NewDocumentMessages()
 
 	// Storage
-	documentStorage := filesystem.NewDocumentStorage(configuration)
-	documentPreviewStorage := filesystem.NewDocumentPreviewStorage(configuration)
+	documentStorage := storage.GetDocumentStorage(configuration)
+	documentPreviewStorage := storage.GetDocumentPreviewStorage(configuration)
 
 	// LLM
 	documentSummarizer := llm.GetSummarizer(configuration)
 
+	// OCR
+	ocrEngine := ocr.GetEngine(configuration)
+	mailClient := mail.NewIMAPClient()
+
 	// Features
 	taskScheduler := common.NewTaskScheduler(shutdown, taskRepository, common.TaskSchedulerModeSynchronous)
-	administration := administration.New(settingsRepository, userRepository, userMessages, taskRepository)
-	archive := archive.New(documentRepository, documentStorage, documentPreviewStorage, documentMessages, documentSummarizer, folderRepository, userMessages, jobScheduler, taskScheduler, shutdown)
-	search := search.New(searchRepository, documentMessages, taskScheduler)
+	administration := administration.New(settingsRepository, userRepository, userMessages, groupRepository, groupMessages, taskRepository)
+	archive := archive.New(
+		archive.Repositories{
+			Documents:           documentRepository,
+			DocumentVersions:    documentVersionRepository,
+			Folders:             folderRepository,
+			Preferences:         preferencesRepository,
+			Tags:                tagRepository,
+			CustomFields:        customFieldRepository,
+			Correspondents:      correspondentRepository,
+			DocumentTypes:       documentTypeRepository,
+			ClassificationRules: classificationRuleRepository,
+			MailAccounts:        mailAccountRepository,
+			BulkOperations:      bulkOperationRepository,
+			Notes:               noteRepository,
+			Grants:              grantRepository,
+			ShareLinks:          shareLinkRepository,
+			RetentionPolicies:   retentionPolicyRepository,
+			LegalHolds:          legalHoldRepository,
+			Users:               userRepository,
+			Groups:              groupRepository,
+		},
+		archive.Dependencies{
+			DocumentStorage:        documentStorage,
+			DocumentPreviewStorage: documentPreviewStorage,
+			DocumentMessages:       documentMessages,
+			DocumentSummarizer:     documentSummarizer,
+			OCREngine:              ocrEngine,
+			MailClient:             mailClient,
+			UserMessages:           userMessages,
+			GroupMessages:          groupMessages,
+			JobScheduler:           jobScheduler,
+			TaskScheduler:          taskScheduler,
+			Shutdown:               shutdown,
+		},
+		archive.Settings{
+			OCRMinCharactersPerPage: configuration.OCR.MinCharactersPerPage,
+			DataDirectory:           configuration.Data.Directory,
+			ConsumeStableDuration:   configuration.Consume.StableDuration,
+			MailPollInterval:        configuration.Mail.PollInterval,
+			TrashRetentionDays:      configuration.Trash.RetentionDays,
+		},
+	)
+	search := search.New(searchRepository, archive, documentMessages, taskScheduler)
 
 	// Web
 	server := web.NewServer(administration, archive, search, shutdown, configuration)
@@ -12,6 +12,7 @@ var Version string
 type Administration struct {
 	*settingsManager
 	*users
+	*groups
 	taskRepository common.TaskRepository
 	startTime      time.Time
 }
@@ -110,10 +111,11 @@ func formatUptime(uptime time.Duration) string {
 	}
 }
 
-func New(settingsRepository SettingsRepository, userRepository UserRepository, userMessages UserMessages, taskRepository common.TaskRepository) *Administration {
+func New(settingsRepository SettingsRepository, userRepository UserRepository, userMessages UserMessages, groupRepository GroupRepository, groupMessages GroupMessages, taskRepository common.TaskRepository) *Administration {
 	return &Administration{
 		settingsManager: newSettingsManager(settingsRepository),
 		users:           newUsers(userRepository, userMessages),
+		groups:          newGroups(groupRepository, userRepository, groupMessages),
 		taskRepository:  taskRepository,
 		startTime:       time.Now(),
 	}
@@ -0,0 +1,122 @@
+package administration
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidGroupName = errors.New("invalid group name")
+	ErrGroupExists      = errors.New("a group or user with this name already exists")
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrMemberNotFound   = errors.New("member not found")
+)
+
+// UserRoleGroup is the role of the account of a group. Groups own the folders and documents their members
+// work on together just like users own theirs, their accounts have no password and cannot sign in.
+const UserRoleGroup UserRole = "group"
+
+type GroupMessages interface {
+	PublishGroupCreated(group Group) error
+	SubscribeGroupCreated(subscriber func(group Group) error) error
+}
+
+type Group struct {
+	Name      string
+	Members   []string
+	CreatedAt time.Time
+}
+
+func (group Group) HasMember(username string) bool {
+	return slices.Contains(group.Members, username)
+}
+
+type GroupRepository interface {
+	Save(group Group) error
+	FindByName(name string) (Group, error)
+	FindAll() ([]Group, error)
+	FindAllByMember(username string) ([]Group, error)
+}
+
+type groups struct {
+	repository     GroupRepository
+	userRepository UserRepository
+	messages       GroupMessages
+}
+
+// CreateGroup creates a group along with its account, the name is shared with the usernames.
+func (g *groups) CreateGroup(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrInvalidGroupName
+	}
+
+	_, err := g.userRepository.FindByUsername(name)
+	if err == nil {
+		return ErrGroupExists
+	}
+
+	err = g.userRepository.Save(User{Username: name, Role: UserRoleGroup})
+	if err != nil {
+		return err
+	}
+
+	group := Group{Name: name, CreatedAt: time.Now()}
+	err = g.repository.Save(group)
+	if err != nil {
+		return err
+	}
+
+	return g.messages.PublishGroupCreated(group)
+}
+
+func (g *groups) AddGroupMember(name string, username string) error {
+	group, err := g.repository.FindByName(name)
+	if err != nil {
+		return err
+	}
+
+	user, err := g.userRepository.FindByUsername(username)
+	if err != nil || user.Role == UserRoleGroup {
+		return ErrMemberNotFound
+	}
+	if group.HasMember(user.Username) {
+		return nil
+	}
+
+	group.Members = append(group.Members, user.Username)
+	return g.repository.Save(group)
+}
+
+func (g *groups) RemoveGroupMember(name string, username string) error {
+	group, err := g.repository.FindByName(name)
+	if err != nil {
+		return err
+	}
+
+	group.Members = slices.DeleteFunc(group.Members, func(member string) bool { return member == username })
+	return g.repository.Save(group)
+}
+
+func (g *groups) GetGroup(name string) (Group, error) {
+	return g.repository.FindByName(name)
+}
+
+func (g *groups) GetGroups() ([]Group, error) {
+	return g.repository.FindAll()
+}
+
+// GetGroupsOfUser returns the groups the user is a member of.
+func (g *groups) GetGroupsOfUser(username string) ([]Group, error) {
+	return g.repository.FindAllByMember(username)
+}
+
+func newGroups(repository GroupRepository, userRepository UserRepository, messages GroupMessages) *groups {
+	return &groups{
+		repository:     repository,
+		userRepository: userRepository,
+		messages:       messages,
+	}
+}
@@ -1,6 +1,7 @@
 package administration
 
 import (
+	"slices"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -45,6 +46,12 @@ type users struct {
 }
 
 func (users *users) CreateUser(username string, password string, role UserRole) error {
+	// Saving would turn the account of a group into a user
+	existing, err := users.repository.FindByUsername(username)
+	if err == nil && existing.Role == UserRoleGroup {
+		return ErrGroupExists
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
@@ -73,8 +80,14 @@ func (users *users) GetUser(username string) (User, error) {
 	return users.repository.FindByUsername(username)
 }
 
+// GetAllUsers returns all users that can sign in, the accounts of groups are left out.
 func (users *users) GetAllUsers() ([]User, error) {
-	return users.repository.FindAll()
+	all, err := users.repository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(all, func(user User) bool { return user.Role == UserRoleGroup }), nil
 }
 
 func (users *users) GetAllUsersByRole(role UserRole) ([]User, error) {
@@ -1,6 +1,7 @@
 package archive
 
 import (
+	"time"
 	"unterlagen/features/administration"
 	"unterlagen/features/common"
 )
@@ -8,38 +9,115 @@ import (
 type Archive struct {
 	*documents
 	*folders
+	*preferences
+	*tags
+	*customFields
+	*correspondents
+	*documentTypes
+	*classificationRules
+	*consumer
+	*mailAccounts
+	*bulkOperations
+	*notes
+	*permissions
+	*shareLinks
+	*retentionPolicies
+	*legalHolds
+	*trash
 }
 
 func (a *Archive) Synchronize(owner string) error {
 	return a.rescheduleAllDocumentTasks(owner)
 }
 
-func New(
-	documentRepository DocumentRepository,
-	documentStorage DocumentStorage,
-	documentPreviewStorage DocumentPreviewStorage,
-	documentMessages DocumentMessages,
-	documentSummarizer DocumentSummarizer,
-	folderRepository FolderRepository,
-	userMessages administration.UserMessages,
-	jobScheduler *common.JobScheduler,
-	taskScheduler *common.TaskScheduler,
-	shutdown *common.Shutdown,
-) *Archive {
+// Repositories store the entities of the archive and the users and groups it shares with.
+type Repositories struct {
+	Documents           DocumentRepository
+	DocumentVersions    DocumentVersionRepository
+	Folders             FolderRepository
+	Preferences         PreferencesRepository
+	Tags                TagRepository
+	CustomFields        CustomFieldRepository
+	Correspondents      CorrespondentRepository
+	DocumentTypes       DocumentTypeRepository
+	ClassificationRules ClassificationRuleRepository
+	MailAccounts        MailAccountRepository
+	BulkOperations      BulkOperationRepository
+	Notes               NoteRepository
+	Grants              GrantRepository
+	ShareLinks          ShareLinkRepository
+	RetentionPolicies   RetentionPolicyRepository
+	LegalHolds          LegalHoldRepository
+	Users               administration.UserRepository
+	Groups              administration.GroupRepository
+}
+
+// Dependencies are the storages, messaging, external services and schedulers the archive works with.
+type Dependencies struct {
+	DocumentStorage        DocumentStorage
+	DocumentPreviewStorage DocumentPreviewStorage
+	DocumentMessages       DocumentMessages
+	DocumentSummarizer     DocumentSummarizer
+	OCREngine              OCREngine
+	MailClient             MailClient
+	UserMessages           administration.UserMessages
+	GroupMessages          administration.GroupMessages
+	JobScheduler           *common.JobScheduler
+	TaskScheduler          *common.TaskScheduler
+	Shutdown               *common.Shutdown
+}
+
+// Settings are the configured values the archive works with.
+type Settings struct {
+	OCRMinCharactersPerPage int
+	DataDirectory           string
+	ConsumeStableDuration   time.Duration
+	MailPollInterval        time.Duration
+	TrashRetentionDays      int
+}
+
+func New(repositories Repositories, dependencies Dependencies, settings Settings) *Archive {
+	preferences := newPreferences(repositories.Preferences, settings.TrashRetentionDays)
+	permissions := newPermissions(repositories.Grants, repositories.Documents, repositories.Folders, repositories.Users, repositories.Groups)
+	legalHolds := newLegalHolds(repositories.LegalHolds, repositories.Documents, repositories.Folders, permissions)
+	folders := newFolders(repositories.Folders, repositories.Documents, permissions, legalHolds, dependencies.UserMessages, dependencies.GroupMessages)
+	documents := newDocuments(
+		repositories.Documents,
+		repositories.DocumentVersions,
+		dependencies.DocumentStorage,
+		dependencies.DocumentPreviewStorage,
+		dependencies.DocumentMessages,
+		dependencies.DocumentSummarizer,
+		dependencies.OCREngine,
+		settings.OCRMinCharactersPerPage,
+		preferences,
+		folders,
+		permissions,
+		legalHolds,
+		repositories.Tags,
+		dependencies.TaskScheduler,
+		dependencies.Shutdown,
+	)
+	tags := newTags(repositories.Tags, documents)
+	customFields := newCustomFields(repositories.CustomFields, documents)
+	documentTypes := newDocumentTypes(repositories.DocumentTypes, documents)
 	return &Archive{
-		documents: newDocuments(
-			documentRepository,
-			documentStorage,
-			documentPreviewStorage,
-			documentMessages,
-			documentSummarizer,
-			jobScheduler,
-			taskScheduler,
-			shutdown,
-		),
-		folders: newFolders(
-			folderRepository,
-			userMessages,
-		),
+		documents:           documents,
+		folders:             folders,
+		preferences:         preferences,
+		tags:                tags,
+		customFields:        customFields,
+		correspondents:      newCorrespondents(repositories.Correspondents, documents),
+		documentTypes:       documentTypes,
+		classificationRules: newClassificationRules(repositories.ClassificationRules, documents, folders, tags, customFields, dependencies.TaskScheduler),
+		consumer:            newConsumer(documents, folders, preferences, settings.DataDirectory, settings.ConsumeStableDuration, dependencies.JobScheduler),
+		mailAccounts:        newMailAccounts(repositories.MailAccounts, dependencies.MailClient, documents, folders, settings.MailPollInterval, dependencies.JobScheduler),
+		bulkOperations:      newBulkOperations(repositories.BulkOperations, documents, folders, tags, customFields, dependencies.TaskScheduler),
+		notes:               newNotes(repositories.Notes, documents),
+		permissions:         permissions,
+		shareLinks:          newShareLinks(repositories.ShareLinks, documents, folders, permissions),
+		legalHolds:          legalHolds,
+		trash:               newTrash(documents, folders, preferences, permissions, legalHolds, dependencies.JobScheduler),
+		retentionPolicies:   newRetentionPolicies(repositories.RetentionPolicies, documents, tags, documentTypes, customFields, dependencies.JobScheduler),
 	}
 }
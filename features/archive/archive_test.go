@@ -0,0 +1,232 @@
+package archive_test
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"slices"
+	"testing"
+	"time"
+	"unterlagen/features/administration"
+	"unterlagen/features/archive"
+	"unterlagen/features/common"
+	"unterlagen/platform/configuration"
+	"unterlagen/platform/database/memory"
+	"unterlagen/platform/llm"
+	"unterlagen/platform/mail"
+	"unterlagen/platform/messaging/synchronous"
+	"unterlagen/platform/storage/filesystem"
+)
+
+func TestMain(m *testing.M) {
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+	os.Exit(m.Run())
+}
+
+// testArchive is an archive wired like the application, with its repositories in memory and files stored in memory.
+type testArchive struct {
+	*archive.Archive
+	administration *administration.Administration
+	dataDirectory  string
+	documents      *memory.DocumentRepository
+	folders        *memory.FolderRepository
+	grants         *memory.GrantRepository
+	shareLinks     *memory.ShareLinkRepository
+	tasks          *memory.TaskRepository
+}
+
+// newTestArchive creates an archive, configure replaces dependencies such as the OCR engine or settings before it is created.
+func newTestArchive(t *testing.T, configure ...func(dependencies *archive.Dependencies, settings *archive.Settings)) *testArchive {
+	shutdown := common.NewShutdown()
+	t.Cleanup(shutdown.Execute)
+
+	tags := memory.NewTagRepository()
+	notes := memory.NewNoteRepository()
+	a := &testArchive{
+		dataDirectory: t.TempDir(),
+		documents:     memory.NewDocumentRepository(tags, notes),
+		folders:       memory.NewFolderRepository(),
+		grants:        memory.NewGrantRepository(),
+		shareLinks:    memory.NewShareLinkRepository(),
+		tasks:         memory.NewTaskRepository(),
+	}
+	users := memory.NewUserRepository()
+	groups := memory.NewGroupRepository()
+	userMessages := synchronous.NewUserMessages()
+	groupMessages := synchronous.NewGroupMessages()
+	taskScheduler := common.NewTaskScheduler(shutdown, a.tasks, common.TaskSchedulerModeSynchronous)
+
+	dependencies := archive.Dependencies{
+		DocumentStorage:        filesystem.NewDocumentStorage(configuration.Configuration{}),
+		DocumentPreviewStorage: filesystem.NewDocumentPreviewStorage(configuration.Configuration{}),
+		DocumentMessages:       synchronous.NewDocumentMessages(),
+		DocumentSummarizer:     llm.NewDumbAI(),
+		MailClient:             mail.NewIMAPClient(),
+		UserMessages:           userMessages,
+		GroupMessages:          groupMessages,
+		JobScheduler:           common.NewJobScheduler(shutdown),
+		TaskScheduler:          taskScheduler,
+		Shutdown:               shutdown,
+	}
+	settings := archive.Settings{
+		OCRMinCharactersPerPage: 50,
+		DataDirectory:           a.dataDirectory,
+		ConsumeStableDuration:   100 * time.Millisecond,
+		MailPollInterval:        time.Minute,
+		TrashRetentionDays:      30,
+	}
+	for _, configure := range configure {
+		configure(&dependencies, &settings)
+	}
+
+	a.administration = administration.New(memory.NewSettingsRepository(), users, userMessages, groups, groupMessages, a.tasks)
+	a.Archive = archive.New(
+		archive.Repositories{
+			Documents:           a.documents,
+			DocumentVersions:    memory.NewDocumentVersionRepository(),
+			Folders:             a.folders,
+			Preferences:         memory.NewPreferencesRepository(),
+			Tags:                tags,
+			CustomFields:        memory.NewCustomFieldRepository(),
+			Correspondents:      memory.NewCorrespondentRepository(),
+			DocumentTypes:       memory.NewDocumentTypeRepository(),
+			ClassificationRules: memory.NewClassificationRuleRepository(),
+			MailAccounts:        memory.NewMailAccountRepository(),
+			BulkOperations:      memory.NewBulkOperationRepository(),
+			Notes:               notes,
+			Grants:              a.grants,
+			ShareLinks:          a.shareLinks,
+			RetentionPolicies:   memory.NewRetentionPolicyRepository(),
+			LegalHolds:          memory.NewLegalHoldRepository(),
+			Users:               users,
+			Groups:              groups,
+		},
+		dependencies,
+		settings,
+	)
+	return a
+}
+
+func (a *testArchive) createUsers(t *testing.T, usernames ...string) {
+	t.Helper()
+	for _, username := range usernames {
+		if err := a.administration.CreateUser(username, "password", administration.UserRoleUser); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// createGroup creates a group with the members and returns the folder of the group.
+func (a *testArchive) createGroup(t *testing.T, name string, members ...string) archive.Folder {
+	t.Helper()
+	if err := a.administration.CreateGroup(name); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		if err := a.administration.AddGroupMember(name, member); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	folders, err := a.folders.FindAllByOwner(name)
+	if err != nil || len(folders) != 1 {
+		t.Fatalf("expected the folder of group %s, got %v: %v", name, folders, err)
+	}
+	return folders[0]
+}
+
+// createFolder creates a folder below the parent and returns it.
+func (a *testArchive) createFolder(t *testing.T, name string, parentID string, user string) archive.Folder {
+	t.Helper()
+	if err := a.CreateFolder(name, parentID, user); err != nil {
+		t.Fatal(err)
+	}
+
+	children, err := a.folders.FindAllByParentID(parentID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := slices.IndexFunc(children, func(folder archive.Folder) bool { return folder.Name == name && !folder.IsTrashed() })
+	if index < 0 {
+		t.Fatalf("expected folder %s to be created", name)
+	}
+	return children[index]
+}
+
+// upload uploads a file into a folder, waits until it is processed and returns the new document.
+func (a *testArchive) upload(t *testing.T, filename string, content []byte, folderID string, user string) archive.Document {
+	t.Helper()
+	before, err := a.documents.FindAllByFolderID(folderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.UploadDocument(filename, uint64(len(content)), folderID, user, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("failed to upload %s: %v", filename, err)
+	}
+	a.waitForTasks(t)
+
+	after, err := a.documents.FindAllByFolderID(folderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, document := range after {
+		if document.Filename == filename && !slices.ContainsFunc(before, func(existing archive.Document) bool { return existing.ID == document.ID }) {
+			return document
+		}
+	}
+	t.Fatalf("expected %s to be uploaded", filename)
+	return archive.Document{}
+}
+
+// document returns the current state of a document.
+func (a *testArchive) document(t *testing.T, id string) archive.Document {
+	t.Helper()
+	document, err := a.documents.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return document
+}
+
+// waitForTasks waits until all scheduled tasks are done, tasks that are retried are waited for as well.
+func (a *testArchive) waitForTasks(t *testing.T) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		tasks, err := a.tasks.FindAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.ContainsFunc(tasks, func(task common.Task) bool {
+			return task.Status == common.TaskStatusPending || task.Status == common.TaskStatusRunning
+		}) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("tasks did not finish in time")
+}
+
+// waitFor waits until the condition holds, like for files in consume directories to be picked up.
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition did not hold in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// testFile returns a file of the test data.
+func testFile(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile("../../testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
@@ -0,0 +1,346 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrBulkOperationNotFound = errors.New("bulk operation not found")
+	ErrInvalidBulkOperation  = errors.New("invalid bulk operation")
+)
+
+// bulkTaskThreshold is the number of documents above which a bulk operation runs as a task.
+const bulkTaskThreshold = 50
+
+// bulkProgressInterval is the number of documents after which a running bulk operation saves its progress.
+const bulkProgressInterval = 10
+
+type BulkAction string
+
+const (
+	BulkActionTrash          BulkAction = "trash"
+	BulkActionRestore        BulkAction = "restore"
+	BulkActionMove           BulkAction = "move"
+	BulkActionTag            BulkAction = "tag"
+	BulkActionSetCustomField BulkAction = "set_custom_field"
+	BulkActionReprocess      BulkAction = "reprocess"
+)
+
+var BulkActions = []BulkAction{
+	BulkActionTrash,
+	BulkActionRestore,
+	BulkActionMove,
+	BulkActionTag,
+	BulkActionSetCustomField,
+	BulkActionReprocess,
+}
+
+func (action BulkAction) IsValid() bool {
+	return slices.Contains(BulkActions, action)
+}
+
+func (action BulkAction) Label() string {
+	switch action {
+	case BulkActionTrash:
+		return "Move to trash"
+	case BulkActionRestore:
+		return "Restore"
+	case BulkActionMove:
+		return "Move to folder"
+	case BulkActionTag:
+		return "Assign tag"
+	case BulkActionSetCustomField:
+		return "Set custom field"
+	case BulkActionReprocess:
+		return "Reprocess"
+	default:
+		return string(action)
+	}
+}
+
+type BulkOperationStatus string
+
+const (
+	BulkOperationStatusRunning   BulkOperationStatus = "running"
+	BulkOperationStatusCompleted BulkOperationStatus = "completed"
+)
+
+// BulkOperation applies an action to a list of documents. FolderID, TagID, CustomFieldID and
+// CustomFieldValue are the arguments of the action, only the ones the action needs are set.
+type BulkOperation struct {
+	ID               string
+	Action           BulkAction
+	DocumentIDs      []string
+	FolderID         string
+	TagID            string
+	CustomFieldID    string
+	CustomFieldValue string
+	Status           BulkOperationStatus
+	Processed        int
+	Failed           int
+	LastError        string
+	Owner            string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+func (operation BulkOperation) Total() int {
+	return len(operation.DocumentIDs)
+}
+
+func (operation BulkOperation) IsRunning() bool {
+	return operation.Status == BulkOperationStatusRunning
+}
+
+type BulkOperationRepository interface {
+	Save(operation BulkOperation) error
+	FindByID(id string) (BulkOperation, error)
+	FindAllByOwner(owner string) ([]BulkOperation, error)
+	DeleteByID(id string) error
+}
+
+type BulkOperationPayload struct {
+	OperationID string `json:"operation_id"`
+}
+
+type bulkOperations struct {
+	repository    BulkOperationRepository
+	documents     *documents
+	folders       *folders
+	tags          *tags
+	customFields  *customFields
+	taskScheduler *common.TaskScheduler
+}
+
+// RunBulkOperation applies the operation to its documents, which the owner must all be able to change.
+// Small operations are applied right away, larger ones are scheduled as a task and report their progress.
+func (b *bulkOperations) RunBulkOperation(operation BulkOperation, owner string) (BulkOperation, error) {
+	err := b.validate(&operation, owner)
+	if err != nil {
+		return BulkOperation{}, err
+	}
+
+	operation.ID = common.GenerateID()
+	operation.Status = BulkOperationStatusRunning
+	operation.Owner = owner
+	operation.CreatedAt = time.Now()
+	operation.UpdatedAt = time.Now()
+
+	if operation.Total() <= bulkTaskThreshold {
+		b.apply(&operation)
+		return operation, nil
+	}
+
+	err = b.repository.Save(operation)
+	if err != nil {
+		return BulkOperation{}, err
+	}
+
+	err = b.taskScheduler.ScheduleTask(common.TaskTypeBulkOperation, BulkOperationPayload{OperationID: operation.ID}, 3)
+	if err != nil {
+		return BulkOperation{}, err
+	}
+	return operation, nil
+}
+
+func (b *bulkOperations) GetBulkOperation(id string, owner string) (BulkOperation, error) {
+	operation, err := b.repository.FindByID(id)
+	if err != nil {
+		return BulkOperation{}, err
+	}
+
+	if operation.Owner != owner {
+		return BulkOperation{}, ErrNotAllowed
+	}
+
+	return operation, nil
+}
+
+// GetBulkOperations returns the bulk operations of the owner that ran as a task, newest first.
+func (b *bulkOperations) GetBulkOperations(owner string) ([]BulkOperation, error) {
+	operations, err := b.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(operations, func(o1, o2 BulkOperation) int {
+		return o2.CreatedAt.Compare(o1.CreatedAt)
+	})
+	return operations, nil
+}
+
+// DismissBulkOperation removes a finished bulk operation from the list of the owner.
+func (b *bulkOperations) DismissBulkOperation(id string, owner string) error {
+	operation, err := b.GetBulkOperation(id, owner)
+	if err != nil {
+		return err
+	}
+
+	if operation.IsRunning() {
+		return ErrNotAllowed
+	}
+
+	return b.repository.DeleteByID(operation.ID)
+}
+
+// DownloadDocuments writes the files of the documents as a zip archive.
+func (b *bulkOperations) DownloadDocuments(ids []string, owner string, writer io.Writer) error {
+	documents, err := b.documents.GetDocuments(ids, owner)
+	if err != nil {
+		return err
+	}
+
+	return b.documents.writeZip(documents, writer)
+}
+
+// validate checks the action with its arguments and that the owner can change all documents, document ids are deduplicated.
+func (b *bulkOperations) validate(operation *BulkOperation, owner string) error {
+	if !operation.Action.IsValid() {
+		return ErrInvalidBulkOperation
+	}
+
+	operation.DocumentIDs = slices.Compact(slices.Sorted(slices.Values(operation.DocumentIDs)))
+	operation.DocumentIDs = slices.DeleteFunc(operation.DocumentIDs, func(id string) bool { return id == "" })
+	if len(operation.DocumentIDs) == 0 {
+		return ErrInvalidBulkOperation
+	}
+
+	documents, err := b.documents.getDocuments(operation.DocumentIDs, owner, PermissionWrite)
+	if err != nil {
+		return err
+	}
+	if len(documents) != len(operation.DocumentIDs) {
+		return ErrNotAllowed
+	}
+
+	switch operation.Action {
+	case BulkActionMove:
+		folder, err := b.folders.getFolder(operation.FolderID, owner, PermissionWrite)
+		if err != nil {
+			return err
+		}
+		if folder.IsTrashed() {
+			return ErrFolderTrashed
+		}
+	case BulkActionTag:
+		_, err := b.tags.GetTag(operation.TagID, owner)
+		if err != nil {
+			return err
+		}
+	case BulkActionSetCustomField:
+		field, err := b.customFields.GetCustomField(operation.CustomFieldID, owner)
+		if err != nil {
+			return err
+		}
+		_, err = field.Normalize(operation.CustomFieldValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply runs the operation from the first document not yet processed, so a retried task picks up where it stopped.
+// Documents failing the action are counted and skipped.
+func (b *bulkOperations) apply(operation *BulkOperation) {
+	for operation.Processed < operation.Total() {
+		documentID := operation.DocumentIDs[operation.Processed]
+		err := b.applyTo(*operation, documentID)
+		if err != nil {
+			slog.Warn("failed to apply bulk operation to document", "operationID", operation.ID, "documentID", documentID, "error", err.Error())
+			operation.Failed++
+			operation.LastError = err.Error()
+		}
+		operation.Processed++
+
+		if operation.Processed%bulkProgressInterval == 0 {
+			b.saveProgress(*operation)
+		}
+	}
+
+	operation.Status = BulkOperationStatusCompleted
+	operation.UpdatedAt = time.Now()
+}
+
+func (b *bulkOperations) applyTo(operation BulkOperation, documentID string) error {
+	switch operation.Action {
+	case BulkActionTrash:
+		return b.documents.TrashDocument(documentID, operation.Owner)
+	case BulkActionRestore:
+		return b.documents.RestoreDocument(documentID, operation.Owner)
+	case BulkActionMove:
+		return b.documents.MoveDocument(documentID, operation.FolderID, operation.Owner)
+	case BulkActionTag:
+		return b.tags.AssignTag(documentID, operation.TagID, operation.Owner)
+	case BulkActionSetCustomField:
+		values := map[string]string{operation.CustomFieldID: operation.CustomFieldValue}
+		return b.customFields.UpdateDocumentCustomFields(documentID, operation.Owner, values)
+	case BulkActionReprocess:
+		document, err := b.documents.getWritableDocument(documentID, operation.Owner)
+		if err != nil {
+			return err
+		}
+		return b.documents.scheduleDocumentProcessing(document)
+	default:
+		return ErrInvalidBulkOperation
+	}
+}
+
+// saveProgress stores the progress of operations running as a task, operations applied right away are not stored.
+func (b *bulkOperations) saveProgress(operation BulkOperation) {
+	if operation.Total() <= bulkTaskThreshold {
+		return
+	}
+
+	operation.UpdatedAt = time.Now()
+	err := b.repository.Save(operation)
+	if err != nil {
+		slog.Error("failed to save bulk operation progress", "operationID", operation.ID, "error", err.Error())
+	}
+}
+
+// runBulkOperation applies a bulk operation scheduled as a task.
+func (b *bulkOperations) runBulkOperation(operationID string) error {
+	operation, err := b.repository.FindByID(operationID)
+	if errors.Is(err, ErrBulkOperationNotFound) {
+		// The operation was deleted along with its owner
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !operation.IsRunning() {
+		return nil
+	}
+
+	b.apply(&operation)
+	return b.repository.Save(operation)
+}
+
+func newBulkOperations(
+	repository BulkOperationRepository,
+	documents *documents,
+	folders *folders,
+	tags *tags,
+	customFields *customFields,
+	taskScheduler *common.TaskScheduler,
+) *bulkOperations {
+	bulkOperations := &bulkOperations{
+		repository:    repository,
+		documents:     documents,
+		folders:       folders,
+		tags:          tags,
+		customFields:  customFields,
+		taskScheduler: taskScheduler,
+	}
+
+	taskScheduler.Register(newBulkTaskProcessor(bulkOperations))
+	return bulkOperations
+}
@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"encoding/json"
+	"unterlagen/features/common"
+)
+
+type BulkTaskProcessor struct {
+	bulkOperations *bulkOperations
+}
+
+func (p *BulkTaskProcessor) Name() string {
+	return "BulkTaskProcessor"
+}
+
+func (p *BulkTaskProcessor) ProcessTask(task common.Task) error {
+	switch task.Type {
+	case common.TaskTypeBulkOperation:
+		var payload BulkOperationPayload
+		if err := json.Unmarshal(task.Payload, &payload); err != nil {
+			return err
+		}
+		return p.bulkOperations.runBulkOperation(payload.OperationID)
+	default:
+		return nil
+	}
+}
+
+func (p *BulkTaskProcessor) ResponsibleFor() []common.TaskType {
+	return []common.TaskType{common.TaskTypeBulkOperation}
+}
+
+func newBulkTaskProcessor(bulkOperations *bulkOperations) *BulkTaskProcessor {
+	return &BulkTaskProcessor{
+		bulkOperations: bulkOperations,
+	}
+}
@@ -0,0 +1,137 @@
+package archive_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"unterlagen/features/archive"
+	"unterlagen/features/common"
+)
+
+func TestRunBulkOperation(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	invoice := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	receipt := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	held := a.upload(t, "contract.pdf", testFile(t, "mock_pdfs/contract_SA_0001.pdf"), archive.FolderRootID, "alice")
+	if _, err := a.PlaceDocumentLegalHold(held.ID, "Audit", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Repeated and empty ids are dropped, documents failing the action are skipped
+	operation, err := a.RunBulkOperation(archive.BulkOperation{
+		Action:      archive.BulkActionTrash,
+		DocumentIDs: []string{invoice.ID, receipt.ID, invoice.ID, "", held.ID},
+	}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operation.IsRunning() || operation.Total() != 3 || operation.Processed != 3 || operation.Failed != 1 {
+		t.Errorf("expected three documents processed right away with one failure, got %+v", operation)
+	}
+	for _, document := range []archive.Document{invoice, receipt} {
+		if !a.document(t, document.ID).IsTrashed() {
+			t.Errorf("expected document %s to be trashed", document.Filename)
+		}
+	}
+	if a.document(t, held.ID).IsTrashed() {
+		t.Error("expected the held document to stay")
+	}
+
+	// Operations applied right away are not listed
+	if operations, err := a.GetBulkOperations("alice"); err != nil || len(operations) != 0 {
+		t.Errorf("expected no listed operations, got %v: %v", operations, err)
+	}
+}
+
+func TestRunBulkOperationValidation(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	foreign := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "bob")
+	old := a.createFolder(t, "Old", archive.FolderRootID, "alice")
+	if err := a.TrashFolder(old.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	amount, err := a.CreateCustomField("Amount", archive.CustomFieldTypeMonetary, nil, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := map[string]struct {
+		operation archive.BulkOperation
+		expected  error
+	}{
+		"with an unknown action": {archive.BulkOperation{Action: "shred", DocumentIDs: []string{document.ID}}, archive.ErrInvalidBulkOperation},
+		"without documents":      {archive.BulkOperation{Action: archive.BulkActionTrash, DocumentIDs: []string{""}}, archive.ErrInvalidBulkOperation},
+		"with documents of other users": {
+			archive.BulkOperation{Action: archive.BulkActionTrash, DocumentIDs: []string{document.ID, foreign.ID}},
+			archive.ErrNotAllowed,
+		},
+		"into a trashed folder": {
+			archive.BulkOperation{Action: archive.BulkActionMove, DocumentIDs: []string{document.ID}, FolderID: old.ID},
+			archive.ErrFolderTrashed,
+		},
+		"with an invalid value": {
+			archive.BulkOperation{Action: archive.BulkActionSetCustomField, DocumentIDs: []string{document.ID}, CustomFieldID: amount.ID, CustomFieldValue: "a lot"},
+			archive.ErrInvalidCustomFieldValue,
+		},
+	}
+	for name, test := range operations {
+		if _, err := a.RunBulkOperation(test.operation, "alice"); !errors.Is(err, test.expected) {
+			t.Errorf("expected an operation %s to be rejected with %v, got %v", name, test.expected, err)
+		}
+	}
+	if a.document(t, document.ID).IsTrashed() {
+		t.Error("expected rejected operations to leave the documents as they were")
+	}
+}
+
+func TestRunBulkOperationAsTask(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	ids := []string{document.ID}
+	for i := range 50 {
+		copied := document
+		copied.ID = common.GenerateID()
+		copied.Filename = fmt.Sprintf("invoice_%d.pdf", i)
+		if err := a.documents.Save(copied); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, copied.ID)
+	}
+	tag, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operation, err := a.RunBulkOperation(archive.BulkOperation{Action: archive.BulkActionTag, DocumentIDs: ids, TagID: tag.ID}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	operation, err = a.GetBulkOperation(operation.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operation.IsRunning() || operation.Processed != len(ids) || operation.Failed != 0 {
+		t.Errorf("expected all documents to be processed, got %+v", operation)
+	}
+	for _, id := range ids {
+		if tags := a.document(t, id).Tags; len(tags) != 1 || tags[0].ID != tag.ID {
+			t.Fatalf("expected document %s to be tagged, got %v", id, tags)
+		}
+	}
+
+	if _, err := a.GetBulkOperation(operation.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected operations of other users to be hidden, got %v", err)
+	}
+	if err := a.DismissBulkOperation(operation.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if operations, err := a.GetBulkOperations("alice"); err != nil || len(operations) != 0 {
+		t.Errorf("expected the dismissed operation to be removed, got %v: %v", operations, err)
+	}
+}
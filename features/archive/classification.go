@@ -0,0 +1,452 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrClassificationRuleNotFound = errors.New("classification rule not found")
+	ErrInvalidClassificationRule  = errors.New("invalid classification rule")
+)
+
+type ClassificationMatch string
+
+const (
+	ClassificationMatchAnyKeyword  ClassificationMatch = "any"
+	ClassificationMatchAllKeywords ClassificationMatch = "all"
+	ClassificationMatchPhrase      ClassificationMatch = "phrase"
+	ClassificationMatchRegex       ClassificationMatch = "regex"
+	ClassificationMatchFilename    ClassificationMatch = "filename"
+)
+
+var ClassificationMatches = []ClassificationMatch{
+	ClassificationMatchAnyKeyword,
+	ClassificationMatchAllKeywords,
+	ClassificationMatchPhrase,
+	ClassificationMatchRegex,
+	ClassificationMatchFilename,
+}
+
+func (match ClassificationMatch) IsValid() bool {
+	return slices.Contains(ClassificationMatches, match)
+}
+
+func (match ClassificationMatch) Label() string {
+	switch match {
+	case ClassificationMatchAnyKeyword:
+		return "Any keyword"
+	case ClassificationMatchAllKeywords:
+		return "All keywords"
+	case ClassificationMatchPhrase:
+		return "Exact phrase"
+	case ClassificationMatchRegex:
+		return "Regular expression"
+	case ClassificationMatchFilename:
+		return "Filename pattern"
+	default:
+		return string(match)
+	}
+}
+
+// ClassificationRule files new documents automatically once their text is extracted.
+// Rules of a user are evaluated by Position, for folder, title and each custom field
+// the first matching rule wins while the tags of all matching rules are assigned.
+type ClassificationRule struct {
+	ID       string
+	Name     string
+	Position int
+	Match    ClassificationMatch
+	// Pattern holds space separated keywords, a phrase, a regular expression or a filename glob depending on Match
+	Pattern  string
+	FolderID string
+	TagIDs   []string
+	// TitleTemplate may contain the placeholders {title}, {filename}, {date}, {year}, {month} and {match}
+	TitleTemplate string
+	CustomFields  map[string]string
+	Owner         string
+}
+
+// Matches evaluates the rule against the text or filename of a document. Keywords and phrases
+// are compared case-insensitively. The matched text is what {match} is replaced with in the title template.
+func (rule ClassificationRule) Matches(document Document) (string, bool) {
+	text := strings.ToLower(document.Text)
+	switch rule.Match {
+	case ClassificationMatchAnyKeyword:
+		for _, keyword := range strings.Fields(strings.ToLower(rule.Pattern)) {
+			if strings.Contains(text, keyword) {
+				return keyword, true
+			}
+		}
+		return "", false
+	case ClassificationMatchAllKeywords:
+		keywords := strings.Fields(strings.ToLower(rule.Pattern))
+		for _, keyword := range keywords {
+			if !strings.Contains(text, keyword) {
+				return "", false
+			}
+		}
+		return strings.Join(keywords, " "), len(keywords) > 0
+	case ClassificationMatchPhrase:
+		// Extracted text breaks lines anywhere, so all whitespace counts as a single space
+		phrase := strings.Join(strings.Fields(strings.ToLower(rule.Pattern)), " ")
+		if phrase == "" {
+			return "", false
+		}
+		return phrase, strings.Contains(strings.Join(strings.Fields(text), " "), phrase)
+	case ClassificationMatchRegex:
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return "", false
+		}
+		submatches := pattern.FindStringSubmatch(document.Text)
+		if submatches == nil {
+			return "", false
+		}
+		// The first group is the interesting part of the match, if the pattern has one
+		if len(submatches) > 1 {
+			return submatches[1], true
+		}
+		return submatches[0], true
+	case ClassificationMatchFilename:
+		matched, err := path.Match(strings.ToLower(rule.Pattern), strings.ToLower(document.Filename))
+		if err != nil || !matched {
+			return "", false
+		}
+		return document.Filename, true
+	default:
+		return "", false
+	}
+}
+
+// Title renders the title template for a matched document.
+func (rule ClassificationRule) Title(document Document, match string) string {
+	date := document.CreatedAt
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	replacer := strings.NewReplacer(
+		"{title}", document.Title,
+		"{filename}", document.Filename,
+		"{date}", date.Format(CustomFieldDateLayout),
+		"{year}", date.Format("2006"),
+		"{month}", date.Format("01"),
+		"{match}", strings.TrimSpace(match),
+	)
+	return strings.TrimSpace(replacer.Replace(rule.TitleTemplate))
+}
+
+func (rule ClassificationRule) validate() error {
+	if strings.TrimSpace(rule.Name) == "" || strings.TrimSpace(rule.Pattern) == "" || !rule.Match.IsValid() {
+		return ErrInvalidClassificationRule
+	}
+
+	switch rule.Match {
+	case ClassificationMatchRegex:
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidClassificationRule, err.Error())
+		}
+	case ClassificationMatchFilename:
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidClassificationRule, err.Error())
+		}
+	}
+
+	if rule.FolderID == "" && len(rule.TagIDs) == 0 && rule.TitleTemplate == "" && len(rule.CustomFields) == 0 {
+		return fmt.Errorf("%w: a rule needs at least one action", ErrInvalidClassificationRule)
+	}
+
+	return nil
+}
+
+type ClassificationRuleRepository interface {
+	Save(rule ClassificationRule) error
+	FindByID(id string) (ClassificationRule, error)
+	FindAllByOwner(owner string) ([]ClassificationRule, error)
+	DeleteByID(id string) error
+}
+
+type classificationRules struct {
+	repository   ClassificationRuleRepository
+	documents    *documents
+	folders      *folders
+	tags         *tags
+	customFields *customFields
+}
+
+// CreateClassificationRule validates the rule and appends it to the rules of the owner.
+func (c *classificationRules) CreateClassificationRule(rule ClassificationRule, owner string) (ClassificationRule, error) {
+	rule.Name = strings.TrimSpace(rule.Name)
+	rule.Pattern = strings.TrimSpace(rule.Pattern)
+	rule.TitleTemplate = strings.TrimSpace(rule.TitleTemplate)
+	rule.Owner = owner
+	err := rule.validate()
+	if err != nil {
+		return ClassificationRule{}, err
+	}
+
+	if rule.FolderID != "" {
+		if _, err := c.folders.GetFolder(rule.FolderID, owner); err != nil {
+			return ClassificationRule{}, err
+		}
+	}
+
+	for _, tagID := range rule.TagIDs {
+		if _, err := c.tags.GetTag(tagID, owner); err != nil {
+			return ClassificationRule{}, err
+		}
+	}
+
+	customFields := make(map[string]string)
+	for fieldID, value := range rule.CustomFields {
+		field, err := c.customFields.GetCustomField(fieldID, owner)
+		if err != nil {
+			return ClassificationRule{}, err
+		}
+
+		normalized, err := field.Normalize(value)
+		if err != nil {
+			return ClassificationRule{}, err
+		}
+		if normalized != "" {
+			customFields[field.ID] = normalized
+		}
+	}
+	rule.CustomFields = customFields
+
+	existing, err := c.repository.FindAllByOwner(owner)
+	if err != nil {
+		return ClassificationRule{}, err
+	}
+
+	rule.ID = common.GenerateID()
+	rule.Position = len(existing)
+	return rule, c.repository.Save(rule)
+}
+
+func (c *classificationRules) GetClassificationRule(id string, owner string) (ClassificationRule, error) {
+	rule, err := c.repository.FindByID(id)
+	if err != nil {
+		return ClassificationRule{}, err
+	}
+
+	if rule.Owner != owner {
+		return ClassificationRule{}, ErrNotAllowed
+	}
+
+	return rule, nil
+}
+
+// GetClassificationRules returns the rules of the owner in the order they are evaluated in.
+func (c *classificationRules) GetClassificationRules(owner string) ([]ClassificationRule, error) {
+	rules, err := c.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(rules, func(r1, r2 ClassificationRule) int {
+		return r1.Position - r2.Position
+	})
+	return rules, nil
+}
+
+func (c *classificationRules) DeleteClassificationRule(id string, owner string) error {
+	rule, err := c.GetClassificationRule(id, owner)
+	if err != nil {
+		return err
+	}
+
+	err = c.repository.DeleteByID(rule.ID)
+	if err != nil {
+		return err
+	}
+
+	rules, err := c.GetClassificationRules(owner)
+	if err != nil {
+		return err
+	}
+	return c.savePositions(rules)
+}
+
+// MoveClassificationRule moves a rule up (negative offset) or down (positive offset) in the evaluation order.
+func (c *classificationRules) MoveClassificationRule(id string, owner string, offset int) error {
+	rules, err := c.GetClassificationRules(owner)
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(rules, func(rule ClassificationRule) bool { return rule.ID == id })
+	if index < 0 {
+		return ErrClassificationRuleNotFound
+	}
+
+	target := min(max(index+offset, 0), len(rules)-1)
+	rule := rules[index]
+	rules = slices.Delete(rules, index, index+1)
+	rules = slices.Insert(rules, target, rule)
+	return c.savePositions(rules)
+}
+
+// savePositions numbers the rules gaplessly in their current order.
+func (c *classificationRules) savePositions(rules []ClassificationRule) error {
+	for position, rule := range rules {
+		if rule.Position == position {
+			continue
+		}
+
+		rule.Position = position
+		err := c.repository.Save(rule)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DryRunClassificationRule returns the existing documents the rule would match, without changing them.
+func (c *classificationRules) DryRunClassificationRule(id string, owner string) ([]Document, error) {
+	rule, err := c.GetClassificationRule(id, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := c.documents.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Document
+	for _, document := range documents {
+		if document.IsTrashed() || document.Filetype == MBOX {
+			continue
+		}
+		if _, ok := rule.Matches(document); ok {
+			matches = append(matches, document)
+		}
+	}
+	return matches, nil
+}
+
+// classifyDocument applies the matching rules of the owner to a freshly extracted document.
+func (c *classificationRules) classifyDocument(documentID string) error {
+	document, err := c.documents.repository.FindByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	// Only new documents are classified, a new version must not undo manual filing
+	if document.Version > 1 {
+		return nil
+	}
+
+	rules, err := c.GetClassificationRules(document.Owner)
+	if err != nil {
+		return err
+	}
+
+	var matched []string
+	var folderID, title string
+	var tagIDs []string
+	customFields := make(map[string]string)
+	for _, rule := range rules {
+		match, ok := rule.Matches(document)
+		if !ok {
+			continue
+		}
+		matched = append(matched, rule.Name)
+
+		if folderID == "" {
+			folderID = rule.FolderID
+		}
+		if title == "" && rule.TitleTemplate != "" {
+			title = rule.Title(document, match)
+		}
+		for _, tagID := range rule.TagIDs {
+			if !slices.Contains(tagIDs, tagID) {
+				tagIDs = append(tagIDs, tagID)
+			}
+		}
+		for fieldID, value := range rule.CustomFields {
+			if _, ok := customFields[fieldID]; !ok {
+				customFields[fieldID] = value
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil
+	}
+
+	// Folders, tags and fields may have been deleted since the rule was created, those actions are skipped
+	if folderID != "" {
+		if _, err := c.folders.GetFolder(folderID, document.Owner); err == nil {
+			document.FolderID = folderID
+		} else {
+			slog.Warn("skipping folder of classification rule", "document_id", document.ID, "folder_id", folderID, "error", err.Error())
+		}
+	}
+
+	if title != "" {
+		document.Title = title
+	}
+
+	if document.CustomFields == nil {
+		document.CustomFields = make(map[string]string)
+	}
+	for fieldID, value := range customFields {
+		if _, err := c.customFields.GetCustomField(fieldID, document.Owner); err != nil {
+			slog.Warn("skipping custom field of classification rule", "document_id", document.ID, "field_id", fieldID, "error", err.Error())
+			continue
+		}
+		document.CustomFields[fieldID] = value
+	}
+
+	document.UpdatedAt = time.Now()
+	err = c.documents.repository.Save(document)
+	if err != nil {
+		return err
+	}
+
+	for _, tagID := range tagIDs {
+		if _, err := c.tags.GetTag(tagID, document.Owner); err != nil {
+			slog.Warn("skipping tag of classification rule", "document_id", document.ID, "tag_id", tagID, "error", err.Error())
+			continue
+		}
+
+		err := c.tags.repository.AssignToDocument(tagID, document.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.Info("document classified", "document_id", document.ID, "rules", matched)
+	return c.tags.publishTagsChanged(document.ID)
+}
+
+func newClassificationRules(
+	repository ClassificationRuleRepository,
+	documents *documents,
+	folders *folders,
+	tags *tags,
+	customFields *customFields,
+	taskScheduler *common.TaskScheduler,
+) *classificationRules {
+	rules := &classificationRules{
+		repository:   repository,
+		documents:    documents,
+		folders:      folders,
+		tags:         tags,
+		customFields: customFields,
+	}
+
+	taskScheduler.Register(newClassificationTaskProcessor(rules))
+	return rules
+}
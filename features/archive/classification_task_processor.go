@@ -0,0 +1,37 @@
+package archive
+
+import (
+	"encoding/json"
+	"unterlagen/features/common"
+)
+
+type ClassificationTaskProcessor struct {
+	rules *classificationRules
+}
+
+func (p *ClassificationTaskProcessor) Name() string {
+	return "ClassificationTaskProcessor"
+}
+
+func (p *ClassificationTaskProcessor) ProcessTask(task common.Task) error {
+	switch task.Type {
+	case common.TaskTypeClassifyDocument:
+		var payload DocumentProcessingPayload
+		if err := json.Unmarshal(task.Payload, &payload); err != nil {
+			return err
+		}
+		return p.rules.classifyDocument(payload.DocumentID)
+	default:
+		return nil
+	}
+}
+
+func (p *ClassificationTaskProcessor) ResponsibleFor() []common.TaskType {
+	return []common.TaskType{common.TaskTypeClassifyDocument}
+}
+
+func newClassificationTaskProcessor(rules *classificationRules) *ClassificationTaskProcessor {
+	return &ClassificationTaskProcessor{
+		rules: rules,
+	}
+}
@@ -0,0 +1,163 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+func TestClassificationRuleMatches(t *testing.T) {
+	document := archive.Document{
+		Filename: "Scan_2024-03.PDF",
+		Text:     "INVOICE #0001\nAcme Corporation\nInvoice Date: 2025-03-16\nDue\n  Date: 2025-04-15",
+	}
+	tests := []struct {
+		match   archive.ClassificationMatch
+		pattern string
+		matches bool
+		matched string
+	}{
+		{archive.ClassificationMatchAnyKeyword, "receipt acme", true, "acme"},
+		{archive.ClassificationMatchAnyKeyword, "receipt contract", false, ""},
+		{archive.ClassificationMatchAllKeywords, "Invoice ACME", true, "invoice acme"},
+		{archive.ClassificationMatchAllKeywords, "invoice contract", false, ""},
+		// Line breaks in the text count as spaces
+		{archive.ClassificationMatchPhrase, "due date", true, "due date"},
+		{archive.ClassificationMatchPhrase, "date due", false, ""},
+		{archive.ClassificationMatchRegex, `INVOICE #(\d+)`, true, "0001"},
+		{archive.ClassificationMatchRegex, `Due Date: \d+`, false, ""},
+		{archive.ClassificationMatchRegex, `(`, false, ""},
+		{archive.ClassificationMatchFilename, "scan_*.pdf", true, "Scan_2024-03.PDF"},
+		{archive.ClassificationMatchFilename, "*.docx", false, ""},
+	}
+	for _, test := range tests {
+		rule := archive.ClassificationRule{Match: test.match, Pattern: test.pattern}
+		matched, ok := rule.Matches(document)
+		if ok != test.matches || (ok && matched != test.matched) {
+			t.Errorf("expected %s %q to match %v with %q, got %v with %q", test.match, test.pattern, test.matches, test.matched, ok, matched)
+		}
+	}
+}
+
+func TestClassificationRuleTitle(t *testing.T) {
+	document := archive.Document{Title: "scan", Filename: "scan.pdf", CreatedAt: time.Date(2025, 3, 16, 12, 0, 0, 0, time.UTC)}
+	rule := archive.ClassificationRule{TitleTemplate: "Invoice {match} {year}-{month} ({title}, {date})"}
+
+	if title := rule.Title(document, " 0001 "); title != "Invoice 0001 2025-03 (scan, 2025-03-16)" {
+		t.Errorf("expected the placeholders to be replaced, got %q", title)
+	}
+}
+
+func TestCreateClassificationRule(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	foreign := a.createFolder(t, "Private", archive.FolderRootID, "bob")
+
+	rules := map[string]archive.ClassificationRule{
+		"without a name":        {Match: archive.ClassificationMatchAnyKeyword, Pattern: "invoice", TitleTemplate: "Invoice"},
+		"with an unknown match": {Name: "Rule", Match: "fuzzy", Pattern: "invoice", TitleTemplate: "Invoice"},
+		"with a broken regex":   {Name: "Rule", Match: archive.ClassificationMatchRegex, Pattern: "(", TitleTemplate: "Invoice"},
+		"without an action":     {Name: "Rule", Match: archive.ClassificationMatchAnyKeyword, Pattern: "invoice"},
+	}
+	for name, rule := range rules {
+		if _, err := a.CreateClassificationRule(rule, "alice"); !errors.Is(err, archive.ErrInvalidClassificationRule) {
+			t.Errorf("expected a rule %s to be rejected, got %v", name, err)
+		}
+	}
+
+	rule := archive.ClassificationRule{Name: "Rule", Match: archive.ClassificationMatchAnyKeyword, Pattern: "invoice", FolderID: foreign.ID}
+	if _, err := a.CreateClassificationRule(rule, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected rules not to file into folders of other users, got %v", err)
+	}
+}
+
+func TestClassifyUploadedDocument(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	invoices := a.createFolder(t, "Invoices", archive.FolderRootID, "alice")
+	other := a.createFolder(t, "Other", archive.FolderRootID, "alice")
+	finance, err := a.CreateTag("Finance", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scanned, err := a.CreateTag("Scanned", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Folder and title of the first matching rule win, the tags of all matching rules are assigned
+	rules := []archive.ClassificationRule{
+		{Name: "Contracts", Match: archive.ClassificationMatchPhrase, Pattern: "service agreement", FolderID: other.ID},
+		{Name: "Invoices", Match: archive.ClassificationMatchRegex, Pattern: `INVOICE #(\d+)`, FolderID: invoices.ID, TagIDs: []string{finance.ID}, TitleTemplate: "Invoice {match}"},
+		{Name: "Scans", Match: archive.ClassificationMatchFilename, Pattern: "*.pdf", FolderID: other.ID, TagIDs: []string{scanned.ID}, TitleTemplate: "Scan"},
+	}
+	for _, rule := range rules {
+		if _, err := a.CreateClassificationRule(rule, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content := testFile(t, "mock_pdfs/invoice_0001.pdf")
+	if err := a.UploadDocument("invoice.pdf", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	documents, err := a.documents.FindAllByOwner("alice")
+	if err != nil || len(documents) != 1 {
+		t.Fatalf("expected the uploaded document, got %v: %v", documents, err)
+	}
+	document := documents[0]
+	if document.FolderID != invoices.ID || document.Title != "Invoice 0001" {
+		t.Errorf("expected the invoice rule to file the document, got folder %s and title %q", document.FolderID, document.Title)
+	}
+	var tagIDs []string
+	for _, tag := range document.Tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+	slices.Sort(tagIDs)
+	expected := []string{finance.ID, scanned.ID}
+	slices.Sort(expected)
+	if !slices.Equal(tagIDs, expected) {
+		t.Errorf("expected the tags of both matching rules, got %v", document.Tags)
+	}
+}
+
+func TestMoveClassificationRule(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	var ids []string
+	for _, name := range []string{"First", "Second", "Third"} {
+		rule, err := a.CreateClassificationRule(archive.ClassificationRule{Name: name, Match: archive.ClassificationMatchAnyKeyword, Pattern: "invoice", TitleTemplate: name}, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rule.ID)
+	}
+
+	if err := a.MoveClassificationRule(ids[2], "alice", -5); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.DeleteClassificationRule(ids[0], "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := a.GetClassificationRules("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for position, rule := range rules {
+		if rule.Position != position {
+			t.Errorf("expected positions without gaps, got %d for %s", rule.Position, rule.Name)
+		}
+		names = append(names, rule.Name)
+	}
+	if !slices.Equal(names, []string{"Third", "Second"}) {
+		t.Errorf("expected the moved rule first, got %v", names)
+	}
+}
@@ -0,0 +1,367 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	ErrInvalidConsumeDirectory = errors.New("invalid consume directory")
+	ErrInvalidConsumeAction    = errors.New("invalid consume action")
+)
+
+const (
+	// consumeScanInterval is how often consume directories are rescanned, this picks up changed
+	// settings and files whose events were missed, like on network shares
+	consumeScanInterval = 10 * time.Second
+	consumedDirectory   = ".consumed"
+	failedDirectory     = ".failed"
+	// consumeRoot is the directory below the data directory relative consume directories of each owner are in
+	consumeRoot = "consume"
+)
+
+// consumer ingests files dropped into the consume directories of users, like by a network scanner.
+type consumer struct {
+	documents      *documents
+	folders        *folders
+	preferences    *preferences
+	dataDirectory  string
+	stableDuration time.Duration
+}
+
+// UpdateConsumeSettings sets the consume directory of the owner, an empty directory turns consumption off.
+// Relative directories are below the owner's own consume directory, absolute ones must stay out of the data directory
+// except for its consume directory. Only administrators may be allowed to use absolute directories.
+func (c *consumer) UpdateConsumeSettings(owner string, directory string, folderID string, action ConsumeAction) error {
+	directory = strings.TrimSpace(directory)
+	if directory != "" && !c.isAllowedConsumeDirectory(owner, directory) {
+		return ErrInvalidConsumeDirectory
+	}
+	if !action.IsValid() {
+		return ErrInvalidConsumeAction
+	}
+
+	// Consumed files are uploaded into the folder, so it has to be writable and not just readable
+	folder, err := c.folders.getFolder(folderID, owner, PermissionWrite)
+	if err != nil {
+		return err
+	}
+
+	preferences, err := c.preferences.GetPreferences(owner)
+	if err != nil {
+		return err
+	}
+
+	preferences.ConsumeDirectory = directory
+	preferences.ConsumeFolderID = folder.ID
+	preferences.ConsumeAction = action
+	err = c.preferences.repository.Save(preferences)
+	if err != nil {
+		return err
+	}
+
+	if directory != "" {
+		return os.MkdirAll(c.ConsumeDirectoryPath(preferences), 0755)
+	}
+	return nil
+}
+
+// ConsumeDirectoryPath resolves the consume directory of the preferences, relative paths are below the owner's directory
+// in the consume directory of the data directory.
+func (c *consumer) ConsumeDirectoryPath(preferences Preferences) string {
+	if preferences.ConsumeDirectory == "" || filepath.IsAbs(preferences.ConsumeDirectory) {
+		return filepath.Clean(preferences.ConsumeDirectory)
+	}
+	return filepath.Join(c.dataDirectory, consumeRoot, preferences.Owner, preferences.ConsumeDirectory)
+}
+
+// isAllowedConsumeDirectory keeps consume directories away from the stored documents and the database in the data directory,
+// the consumer would import, move and delete their files otherwise.
+func (c *consumer) isAllowedConsumeDirectory(owner string, directory string) bool {
+	if owner == "" || !filepath.IsLocal(owner) || strings.ContainsRune(owner, filepath.Separator) {
+		return false
+	}
+	if !filepath.IsAbs(directory) {
+		return filepath.IsLocal(directory)
+	}
+
+	dataDirectory, err := filepath.Abs(c.dataDirectory)
+	if err != nil {
+		return false
+	}
+	if isWithin(filepath.Join(dataDirectory, consumeRoot), directory) && !isWithin(directory, filepath.Join(dataDirectory, consumeRoot)) {
+		return true
+	}
+	return !isWithin(dataDirectory, directory) && !isWithin(directory, dataDirectory)
+}
+
+// isWithin tells whether path is the directory or below it.
+func isWithin(directory string, path string) bool {
+	relative, err := filepath.Rel(directory, path)
+	return err == nil && filepath.IsLocal(relative)
+}
+
+type pendingFile struct {
+	size    int64
+	modTime time.Time
+	since   time.Time
+}
+
+// consumeWatch is the state of a running watch, it is only used by the watching goroutine.
+type consumeWatch struct {
+	watcher *fsnotify.Watcher
+	// roots are the preferences of each watched consume directory
+	roots   map[string]Preferences
+	pending map[string]pendingFile
+}
+
+func (c *consumer) watch(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		slog.Error("failed to start consume directory watcher", "error", err.Error())
+		return
+	}
+	defer watcher.Close()
+
+	watch := &consumeWatch{
+		watcher: watcher,
+		roots:   make(map[string]Preferences),
+		pending: make(map[string]pendingFile),
+	}
+	c.scan(watch)
+
+	scanTicker := time.NewTicker(consumeScanInterval)
+	defer scanTicker.Stop()
+	stableTicker := time.NewTicker(time.Second)
+	defer stableTicker.Stop()
+
+	for {
+		select {
+		case event := <-watcher.Events:
+			if event.Has(fsnotify.Create) || event.Has(fsnotify.Write) {
+				c.track(watch, event.Name)
+			}
+		case err := <-watcher.Errors:
+			slog.Warn("consume directory watcher failed", "error", err.Error())
+		case <-scanTicker.C:
+			c.scan(watch)
+		case <-stableTicker.C:
+			c.consumeStableFiles(watch)
+		case <-ctx.Done():
+			slog.Info("consume directory watcher stopped")
+			return
+		}
+	}
+}
+
+// scan watches the consume directories of all users and tracks the files already in them.
+func (c *consumer) scan(watch *consumeWatch) {
+	allPreferences, err := c.preferences.repository.FindAll()
+	if err != nil {
+		slog.Error("failed to load consume directories", "error", err.Error())
+		return
+	}
+
+	roots := make(map[string]Preferences)
+	for _, preferences := range allPreferences {
+		if preferences.ConsumeDirectory == "" {
+			continue
+		}
+		if !c.isAllowedConsumeDirectory(preferences.Owner, preferences.ConsumeDirectory) {
+			slog.Warn("ignoring consume directory outside of the allowed directories", "owner", preferences.Owner, "directory", preferences.ConsumeDirectory)
+			continue
+		}
+		roots[c.ConsumeDirectoryPath(preferences)] = preferences
+	}
+	watch.roots = roots
+
+	for _, watched := range watch.watcher.WatchList() {
+		if _, _, ok := watch.rootOf(watched); !ok {
+			watch.watcher.Remove(watched)
+		}
+	}
+
+	for root := range roots {
+		c.track(watch, root)
+	}
+}
+
+// track starts watching a directory with all of its subdirectories or remembers a file until it is stable.
+func (c *consumer) track(watch *consumeWatch, path string) {
+	root, _, ok := watch.rootOf(path)
+	if !ok {
+		return
+	}
+
+	err := filepath.WalkDir(path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && isIgnoredConsumeFile(entry.Name()) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if entry.IsDir() {
+			return watch.watcher.Add(path)
+		}
+		// Links could lead the consumer to files outside of the consume directory
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if _, ok := watch.pending[path]; !ok {
+			watch.pending[path] = pendingFile{size: info.Size(), modTime: info.ModTime(), since: time.Now()}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("failed to scan consume directory", "path", path, "error", err.Error())
+	}
+}
+
+// consumeStableFiles uploads the files that did not change for the stable duration,
+// scanners write files in chunks and must not be consumed halfway.
+func (c *consumer) consumeStableFiles(watch *consumeWatch) {
+	for path, pending := range watch.pending {
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			delete(watch.pending, path)
+			continue
+		}
+
+		if info.Size() != pending.size || !info.ModTime().Equal(pending.modTime) {
+			watch.pending[path] = pendingFile{size: info.Size(), modTime: info.ModTime(), since: time.Now()}
+			continue
+		}
+
+		if time.Since(pending.since) < c.stableDuration {
+			continue
+		}
+
+		delete(watch.pending, path)
+		root, preferences, ok := watch.rootOf(path)
+		if !ok {
+			continue
+		}
+		c.consumeFile(root, preferences, path, info.Size())
+	}
+}
+
+func (c *consumer) consumeFile(root string, preferences Preferences, path string, size int64) {
+	relative, err := filepath.Rel(root, path)
+	if err != nil {
+		slog.Error("failed to consume file", "path", path, "error", err.Error())
+		return
+	}
+
+	err = c.uploadFile(preferences, path, relative, size)
+	if linked, ok := LinkedDuplicate(err); ok {
+		slog.Info("consumed file is already in the archive", "path", path, "owner", preferences.Owner, "documentID", linked.ID)
+		err = nil
+	}
+	if err != nil {
+		slog.Error("failed to consume file", "path", path, "owner", preferences.Owner, "error", err.Error())
+		c.moveFile(root, failedDirectory, relative)
+		return
+	}
+
+	slog.Info("consumed file", "path", path, "owner", preferences.Owner)
+	if preferences.ConsumeAction == ConsumeActionDelete {
+		if err := os.Remove(path); err != nil {
+			slog.Error("failed to delete consumed file", "path", path, "error", err.Error())
+		}
+		return
+	}
+	c.moveFile(root, consumedDirectory, relative)
+}
+
+// uploadFile uploads the file into the target folder, subdirectories of the consume directory become folders.
+func (c *consumer) uploadFile(preferences Preferences, path string, relative string, size int64) error {
+	folderID := preferences.ConsumeFolderID
+	if directory := filepath.Dir(relative); directory != "." {
+		for _, name := range strings.Split(directory, string(filepath.Separator)) {
+			folder, err := c.folders.ensureFolder(name, folderID, preferences.Owner)
+			if err != nil {
+				return err
+			}
+			folderID = folder.ID
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return c.documents.UploadDocument(filepath.Base(path), uint64(size), folderID, preferences.Owner, file)
+}
+
+// moveFile moves a file of the consume directory below one of its hidden directories, keeping its relative path.
+func (c *consumer) moveFile(root string, directory string, relative string) {
+	destination := filepath.Join(root, directory, relative)
+	if _, err := os.Stat(destination); err == nil {
+		extension := filepath.Ext(destination)
+		destination = strings.TrimSuffix(destination, extension) + "-" + time.Now().Format("20060102150405") + extension
+	}
+
+	err := os.MkdirAll(filepath.Dir(destination), 0755)
+	if err == nil {
+		err = os.Rename(filepath.Join(root, relative), destination)
+	}
+	if err != nil {
+		slog.Error("failed to move consumed file", "path", filepath.Join(root, relative), "destination", destination, "error", err.Error())
+	}
+}
+
+// rootOf returns the consume directory a path belongs to.
+func (watch *consumeWatch) rootOf(path string) (string, Preferences, bool) {
+	for root, preferences := range watch.roots {
+		relative, err := filepath.Rel(root, path)
+		if err == nil && filepath.IsLocal(relative) {
+			return root, preferences, true
+		}
+	}
+	return "", Preferences{}, false
+}
+
+// isIgnoredConsumeFile skips hidden files, like .consumed, and temporary files of editors and scanners.
+func isIgnoredConsumeFile(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "~") || strings.HasSuffix(name, ".tmp") || strings.HasSuffix(name, ".part")
+}
+
+func newConsumer(
+	documents *documents,
+	folders *folders,
+	preferences *preferences,
+	dataDirectory string,
+	stableDuration time.Duration,
+	jobScheduler *common.JobScheduler,
+) *consumer {
+	consumer := &consumer{
+		documents:      documents,
+		folders:        folders,
+		preferences:    preferences,
+		dataDirectory:  dataDirectory,
+		stableDuration: stableDuration,
+	}
+
+	jobScheduler.Schedule(consumer.watch)
+	return consumer
+}
@@ -0,0 +1,162 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+// consume writes a file into the consume directory of the user and waits until it is consumed.
+func (a *testArchive) consume(t *testing.T, user string, relative string, content []byte) {
+	t.Helper()
+	preferences, err := a.GetPreferences(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directory := a.ConsumeDirectoryPath(preferences)
+
+	path := filepath.Join(directory, relative)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	})
+	a.waitForTasks(t)
+}
+
+func TestConsumeIntoGroupFolder(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	family := a.createGroup(t, "family", "alice", "bob")
+
+	if err := a.UpdateConsumeSettings("bob", "scanner", family.ID, archive.ConsumeActionDelete); err != nil {
+		t.Fatal(err)
+	}
+	a.consume(t, "bob", filepath.Join("Bills", "2025", "invoice.pdf"), testFile(t, "mock_pdfs/invoice_0001.pdf"))
+
+	// Folders created for subdirectories belong to the group like every other folder in its tree
+	bills, err := a.GetFolderChildren(family.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bills) != 1 || bills[0].Name != "Bills" || bills[0].Owner != "family" {
+		t.Fatalf("expected alice to see the Bills folder of the group, got %v", bills)
+	}
+	year, err := a.GetFolderChildren(bills[0].ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(year) != 1 || year[0].Name != "2025" || year[0].Owner != "family" {
+		t.Fatalf("expected alice to see the 2025 folder of the group, got %v", year)
+	}
+
+	documents, err := a.GetDocumentsInFolder(year[0].ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 || documents[0].Owner != "family" {
+		t.Errorf("expected the consumed invoice to belong to the group, got %v", documents)
+	}
+	if err := a.RenameFolder(year[0].ID, "2026", "alice"); err != nil {
+		t.Errorf("expected alice to manage the folder bob's scanner created, got %v", err)
+	}
+}
+
+func TestConsumeIntoSharedFolder(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob", "carol")
+	inbox := a.createFolder(t, "Inbox", archive.FolderRootID, "alice")
+	if _, err := a.ShareFolder(inbox.ID, "bob", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ShareFolder(inbox.ID, "carol", archive.PermissionWrite, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every consumed file would fail to upload into a folder that is only readable
+	if err := a.UpdateConsumeSettings("bob", "scanner", inbox.ID, archive.ConsumeActionMove); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the read only folder to be rejected, got %v", err)
+	}
+	if err := a.UpdateConsumeSettings("carol", "scanner", inbox.ID, archive.ConsumeActionMove); err != nil {
+		t.Errorf("expected the writable folder to be accepted, got %v", err)
+	}
+}
+
+func TestConsumeDirectoryConfinement(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	outside := t.TempDir()
+
+	directories := map[string]bool{
+		"scanner":         true,
+		"scanner/office":  true,
+		"../bob":          false,
+		"scanner/../../x": false,
+		outside:           true,
+		a.dataDirectory:   false,
+		filepath.Join(a.dataDirectory, "archive"):                  false,
+		filepath.Join(a.dataDirectory, "consume"):                  false,
+		filepath.Join(a.dataDirectory, "consume", "alice", "scan"): true,
+		filepath.Dir(a.dataDirectory):                              false,
+	}
+	for directory, allowed := range directories {
+		err := a.UpdateConsumeSettings("alice", directory, archive.FolderRootID, archive.ConsumeActionMove)
+		if allowed && err != nil {
+			t.Errorf("expected %s to be allowed, got %v", directory, err)
+		}
+		if !allowed && !errors.Is(err, archive.ErrInvalidConsumeDirectory) {
+			t.Errorf("expected %s to be rejected, got %v", directory, err)
+		}
+	}
+
+	if err := a.UpdateConsumeSettings("alice", "scanner", archive.FolderRootID, "copy"); !errors.Is(err, archive.ErrInvalidConsumeAction) {
+		t.Errorf("expected an unknown action to be rejected, got %v", err)
+	}
+	if err := a.UpdateConsumeSettings("../alice", "scanner", archive.FolderRootID, archive.ConsumeActionMove); !errors.Is(err, archive.ErrInvalidConsumeDirectory) {
+		t.Errorf("expected owners not to escape the consume directory, got %v", err)
+	}
+}
+
+func TestConsumeMovesFile(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	if err := a.UpdateConsumeSettings("alice", "scanner", archive.FolderRootID, archive.ConsumeActionMove); err != nil {
+		t.Fatal(err)
+	}
+	preferences, err := a.GetPreferences("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	directory := a.ConsumeDirectoryPath(preferences)
+	if directory != filepath.Join(a.dataDirectory, "consume", "alice", "scanner") {
+		t.Errorf("expected the directory below the consume directory of alice, got %s", directory)
+	}
+
+	// Files still being written are left alone
+	partial := filepath.Join(directory, "scan.pdf.part")
+	if err := os.WriteFile(partial, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content := testFile(t, "mock_pdfs/invoice_0001.pdf")
+	a.consume(t, "alice", "invoice.pdf", content)
+
+	consumed, err := os.ReadFile(filepath.Join(directory, ".consumed", "invoice.pdf"))
+	if err != nil || !bytes.Equal(consumed, content) {
+		t.Errorf("expected the file to be moved below .consumed, got %v", err)
+	}
+	if _, err := os.Stat(partial); err != nil {
+		t.Errorf("expected the partial file to be left alone, got %v", err)
+	}
+	documents, err := a.documents.FindAllByOwner("alice")
+	if err != nil || len(documents) != 1 || documents[0].Filename != "invoice.pdf" {
+		t.Errorf("expected only the invoice to be uploaded, got %v: %v", documents, err)
+	}
+}
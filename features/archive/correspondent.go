@@ -0,0 +1,58 @@
+package archive
+
+import "errors"
+
+var (
+	ErrCorrespondentNotFound    = errors.New("correspondent not found")
+	ErrCorrespondentExists      = errors.New("correspondent already exists")
+	ErrInvalidCorrespondentName = errors.New("invalid correspondent name")
+)
+
+// Correspondent is who a document was sent by or is about, like an insurance or the tax office.
+type Correspondent = Label
+
+type CorrespondentRepository = LabelRepository
+
+type correspondents struct {
+	labels *labels
+}
+
+func (c *correspondents) CreateCorrespondent(name string, owner string) (Correspondent, error) {
+	return c.labels.create(name, owner)
+}
+
+func (c *correspondents) GetCorrespondent(id string, owner string) (Correspondent, error) {
+	return c.labels.get(id, owner)
+}
+
+// GetCorrespondents returns all correspondents of the owner with their document counts.
+func (c *correspondents) GetCorrespondents(owner string) ([]Correspondent, error) {
+	return c.labels.list(owner)
+}
+
+// DeleteCorrespondent deletes the correspondent, its documents are kept without a correspondent.
+func (c *correspondents) DeleteCorrespondent(id string, owner string) error {
+	return c.labels.delete(id, owner)
+}
+
+// AssignCorrespondent sets the correspondent of a document, an empty id removes it.
+// The correspondent must belong to the owner of the document.
+func (c *correspondents) AssignCorrespondent(documentID string, correspondentID string, user string) error {
+	return c.labels.assign(documentID, correspondentID, user)
+}
+
+func newCorrespondents(repository CorrespondentRepository, documents *documents) *correspondents {
+	kind := labelKind{
+		errExists:      ErrCorrespondentExists,
+		errInvalidName: ErrInvalidCorrespondentName,
+		count: func(repository DocumentRepository, owner string) (map[string]int, error) {
+			return repository.CountAllByCorrespondent(owner)
+		},
+		assign: func(document *Document, correspondentID string) {
+			document.CorrespondentID = correspondentID
+		},
+	}
+	return &correspondents{
+		labels: newLabels(kind, repository, documents),
+	}
+}
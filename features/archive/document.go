@@ -3,13 +3,17 @@ package archive
 import (
 	"archive/zip"
 	"bytes"
-	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"image"
 	"io"
 	"log/slog"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 	"unterlagen/features/common"
@@ -20,12 +24,40 @@ import (
 var (
 	ErrUnsupportedFiletype = errors.New("unsupported filetype")
 	ErrNotAllowed          = errors.New("not allowed")
+	ErrDuplicateDocument   = errors.New("document already exists")
 )
 
+// DuplicateLinkedError is returned for uploads the owner already has in the archive when the duplicate policy links them.
+// The upload is not stored, Document is the document it was linked to.
+type DuplicateLinkedError struct {
+	Document Document
+}
+
+func (err *DuplicateLinkedError) Error() string {
+	return "linked to existing document " + err.Document.Title
+}
+
+// LinkedDuplicate returns the document an upload was linked to if the error tells that it was.
+func LinkedDuplicate(err error) (Document, bool) {
+	var linked *DuplicateLinkedError
+	if errors.As(err, &linked) {
+		return linked.Document, true
+	}
+	return Document{}, false
+}
+
 const (
-	PDF        Filetype = "pdf"
-	Unknown    Filetype = "unknown"
-	ThirtyDays          = 30 * 24 * time.Hour
+	PDF     Filetype = "pdf"
+	PNG     Filetype = "png"
+	JPEG    Filetype = "jpeg"
+	TIFF    Filetype = "tiff"
+	DOCX    Filetype = "docx"
+	XLSX    Filetype = "xlsx"
+	ODT     Filetype = "odt"
+	ODS     Filetype = "ods"
+	EML     Filetype = "eml"
+	MBOX    Filetype = "mbox"
+	Unknown Filetype = "unknown"
 )
 
 type Filetype string
@@ -36,9 +68,19 @@ type Document struct {
 	Filename         string
 	Filetype         Filetype
 	Filesize         uint64
+	ContentHash      string
+	Version          int
 	Text             string
+	OCRPages         []string
 	Summary          DocumentSummary
 	PreviewFilepaths []string
+	Metadata         map[string]string
+	Tags             []Tag
+	Notes            []Note
+	CustomFields     map[string]string
+	CorrespondentID  string
+	DocumentTypeID   string
+	ParentID         string
 	Owner            string
 	FolderID         string
 	TrashedAt        sql.NullTime
@@ -54,6 +96,7 @@ func newDocument(filename string, filetype Filetype, filesize uint64, owner stri
 		Filename: filename,
 		Filetype: filetype,
 		Filesize: filesize,
+		Version:  1,
 		Summary: DocumentSummary{
 			IsGenerating: true,
 		},
@@ -62,6 +105,8 @@ func newDocument(filename string, filetype Filetype, filesize uint64, owner stri
 		TrashedAt: sql.NullTime{
 			Valid: false,
 		},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
 	}
 }
 
@@ -77,14 +122,6 @@ func (document Document) PreviewPrefix() string {
 	return path.Join(document.Owner, document.ID, "previews")
 }
 
-func (document Document) ShouldBeDeleted() bool {
-	if !document.TrashedAt.Valid {
-		return false
-	}
-
-	return time.Since(document.TrashedAt.Time) >= ThirtyDays
-}
-
 func (document Document) IsTrashed() bool {
 	return document.TrashedAt.Valid
 }
@@ -101,10 +138,30 @@ type DocumentRepository interface {
 	FindAllByIDIn(ids []string) ([]Document, error)
 	FindAllByOwner(owner string) ([]Document, error)
 	FindAllByFolderID(folderID string) ([]Document, error)
+	FindAllByParentID(parentID string) ([]Document, error)
+	FindAllByTagID(tagID string) ([]Document, error)
+	FindAllByCustomFieldID(fieldID string) ([]Document, error)
+	FindAllByOwnerAndFilter(owner string, filter DocumentFilter) ([]Document, error)
+	CountAllByCorrespondent(owner string) (map[string]int, error)
+	CountAllByDocumentType(owner string) (map[string]int, error)
+	FindAllByOwnerAndContentHash(owner string, contentHash string) ([]Document, error)
+	FindAllDuplicatesByOwner(owner string) ([]Document, error)
+	FindAllWithoutContentHash() ([]Document, error)
 	FindAllTrashed() ([]Document, error)
 	DeleteByID(id string) error
 }
 
+// DocumentFilter selects documents of an owner across all folders. Empty criteria match every document.
+type DocumentFilter struct {
+	TagID           string
+	CorrespondentID string
+	DocumentTypeID  string
+}
+
+func (filter DocumentFilter) IsEmpty() bool {
+	return filter.TagID == "" && filter.CorrespondentID == "" && filter.DocumentTypeID == ""
+}
+
 type DocumentConsumer func(r io.Reader) error
 type DocumentStorage interface {
 	Store(filepath string, r io.Reader) error
@@ -128,6 +185,22 @@ type DocumentSummarizer interface {
 	SummarizeText(text string) (DocumentSummary, error)
 }
 
+// PageRenderer is implemented by analyzers whose documents consist of pages
+// that can be rendered to images, which makes them candidates for OCR.
+type PageRenderer interface {
+	PageCount(document Document) (int, error)
+	RenderPages(document Document, dpi int, consumer func(page int, img image.Image) error) error
+}
+
+type OCREngine interface {
+	RecognizeText(image io.Reader) (string, error)
+}
+
+// IsOCRed reports whether the text of the document was recognized by OCR.
+func (document Document) IsOCRed() bool {
+	return len(document.OCRPages) > 0
+}
+
 type DocumentMessages interface {
 	PublishDocumentUpserted(document Document) error
 	SubscribeDocumentUpserted(subscriber func(document Document) error) error
@@ -135,22 +208,52 @@ type DocumentMessages interface {
 	SubscribeDocumentTextExtracted(subscriber func(document Document) error) error
 	PublishDocumentDeleted(document Document) error
 	SubscribeDocumentDeleted(subscriber func(document Document) error) error
+	PublishDocumentMoved(document Document, previousFolderID string) error
+	SubscribeDocumentMoved(subscriber func(document Document, previousFolderID string) error) error
 }
 
 type documents struct {
-	repository     DocumentRepository
-	storage        DocumentStorage
-	previewStorage DocumentPreviewStorage
-	messages       DocumentMessages
-	taskScheduler  *common.TaskScheduler
+	repository        DocumentRepository
+	versionRepository DocumentVersionRepository
+	storage           DocumentStorage
+	previewStorage    DocumentPreviewStorage
+	messages          DocumentMessages
+	preferences       *preferences
+	folders           *folders
+	permissions       *permissions
+	legalHolds        *legalHolds
+	tagRepository     TagRepository
+	pageEditor        PageEditor
+	taskScheduler     *common.TaskScheduler
 }
 
-func (d *documents) UploadDocument(filename string, filesize uint64, folderID string, owner string, r io.Reader) error {
+// UploadDocument stores a new document in a folder the user can write to, it belongs to the owner of the folder.
+// Uploads of content the owner already has are handled according to the duplicate policy in the user's preferences.
+func (d *documents) UploadDocument(filename string, filesize uint64, folderID string, user string, r io.Reader) error {
+	folder, err := d.folders.getFolder(folderID, user, PermissionWrite)
+	if err != nil {
+		return err
+	}
+
+	preferences, err := d.preferences.GetPreferences(user)
+	if err != nil {
+		return err
+	}
+
+	return d.uploadDocument(filename, filesize, folder.ID, folder.Owner, "", preferences.DuplicatePolicy, r)
+}
+
+func (d *documents) uploadDocument(filename string, filesize uint64, folderID string, owner string, parentID string, policy DuplicatePolicy, r io.Reader) error {
 	document := newDocument(filename, Unknown, filesize, owner, folderID)
-	err := d.storage.Store(document.Filepath(), r)
+	document.ParentID = parentID
+
+	// The hash is computed while storing, so the content is only read once
+	hash := sha256.New()
+	err := d.storage.Store(document.Filepath(), io.TeeReader(r, hash))
 	if err != nil {
 		return err
 	}
+	document.ContentHash = hex.EncodeToString(hash.Sum(nil))
 
 	err = d.storage.Retrieve(document.Filepath(), func(r io.Reader) error {
 		filetype, err := d.determineFiletype(r)
@@ -161,9 +264,58 @@ func (d *documents) UploadDocument(filename string, filesize uint64, folderID st
 		return nil
 	})
 	if err != nil {
+		if deleteErr := d.storage.Delete(document.Filepath()); deleteErr != nil {
+			slog.Error("failed to delete rejected document file", "error", deleteErr.Error(), "documentID", document.ID)
+		}
 		return err
 	}
 
+	if document.Filetype != MBOX {
+		duplicates, err := d.findDuplicates(document)
+		if err != nil {
+			return err
+		}
+
+		if len(duplicates) > 0 && policy != DuplicatePolicyWarn {
+			if err := d.storage.Delete(document.Filepath()); err != nil {
+				slog.Error("failed to delete duplicate document file", "error", err.Error(), "documentID", document.ID)
+			}
+
+			slog.Info("discarded duplicate upload", "filename", filename, "duplicateOf", duplicates[0].ID, "policy", policy)
+			if policy == DuplicatePolicyReject {
+				return fmt.Errorf("%w: %s", ErrDuplicateDocument, duplicates[0].Title)
+			}
+			return &DuplicateLinkedError{Document: duplicates[0]}
+		}
+
+		if len(duplicates) > 0 {
+			slog.Warn("uploaded duplicate document", "filename", filename, "duplicateOf", duplicates[0].ID)
+		}
+	}
+
+	var attachments []emailAttachment
+	switch document.Filetype {
+	case MBOX:
+		return d.uploadMailbox(document, policy)
+	case EML:
+		err = d.storage.Retrieve(document.Filepath(), func(r io.Reader) error {
+			message, err := parseEmail(r)
+			if err != nil {
+				return err
+			}
+
+			document.Metadata = message.Headers
+			if subject := message.Headers["Subject"]; subject != "" {
+				document.Title = subject
+			}
+			attachments = message.Attachments
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	err = d.repository.Save(document)
 	if err != nil {
 		return err
@@ -174,63 +326,232 @@ func (d *documents) UploadDocument(filename string, filesize uint64, folderID st
 		return err
 	}
 
-	return d.scheduleDocumentProcessing(document)
+	err = d.scheduleDocumentProcessing(document)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range attachments {
+		err := d.uploadDocument(attachment.Filename, uint64(len(attachment.Data)), folderID, owner, document.ID, policy, bytes.NewReader(attachment.Data))
+		if linked, ok := LinkedDuplicate(err); ok {
+			slog.Info("linked email attachment to existing document", "documentID", document.ID, "filename", attachment.Filename, "existingID", linked.ID)
+			continue
+		}
+		if err != nil {
+			slog.Warn("failed to store email attachment", "error", err.Error(), "documentID", document.ID, "filename", attachment.Filename)
+		}
+	}
+
+	return nil
+}
+
+// uploadMailbox stores every message of an mbox archive as its own email document.
+// The archive itself is not kept.
+func (d *documents) uploadMailbox(mailbox Document, policy DuplicatePolicy) error {
+	var messages [][]byte
+	err := d.storage.Retrieve(mailbox.Filepath(), func(r io.Reader) error {
+		var err error
+		messages, err = splitMailbox(r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, message := range messages {
+		filename := fmt.Sprintf("%s-%d.eml", mailbox.Name(), i+1)
+		err := d.uploadDocument(filename, uint64(len(message)), mailbox.FolderID, mailbox.Owner, "", policy, bytes.NewReader(message))
+		if linked, ok := LinkedDuplicate(err); ok {
+			slog.Info("linked mailbox message to existing document", "filename", filename, "existingID", linked.ID)
+			continue
+		}
+		if err != nil {
+			slog.Warn("failed to store mailbox message", "error", err.Error(), "filename", filename)
+		}
+	}
+
+	return d.storage.Delete(mailbox.Filepath())
 }
 
 func (d *documents) determineFiletype(r io.Reader) (Filetype, error) {
-	var buf bytes.Buffer
-	tee := io.TeeReader(r, &buf)
 	header := make([]byte, 261)
-	if _, err := io.ReadFull(tee, header); err != nil {
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
 		return "", err
 	}
+	header = header[:n]
 
 	kind, _ := filetype.Match(header)
-	if kind.Extension == "pdf" {
+	switch kind.Extension {
+	case "pdf":
 		return PDF, nil
+	case "png":
+		return PNG, nil
+	case "jpg":
+		return JPEG, nil
+	case "tif":
+		return TIFF, nil
+	case "zip", "docx", "xlsx":
+		// Office documents are zip archives, their type is determined by the contained parts
+		data, err := readAtMost(io.MultiReader(bytes.NewReader(header), r), maxZipSize)
+		if err != nil {
+			return "", err
+		}
+		return detectZipFiletype(data)
+	default:
+		// Emails are plain text and only recognizable by their headers, the body does not need to be read
+		rest, err := io.ReadAll(io.LimitReader(r, maxEmailHeaderSize))
+		if err != nil {
+			return "", err
+		}
+		return detectEmailFiletype(append(header, rest...))
+	}
+}
+
+// findDuplicates returns the other documents of the owner with the same content.
+// Trashed documents do not count, they are about to disappear.
+func (d *documents) findDuplicates(document Document) ([]Document, error) {
+	if document.ContentHash == "" {
+		return nil, nil
 	}
 
-	return "", ErrUnsupportedFiletype
+	documents, err := d.repository.FindAllByOwnerAndContentHash(document.Owner, document.ContentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []Document
+	for _, candidate := range documents {
+		if candidate.ID != document.ID && !candidate.IsTrashed() {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	return duplicates, nil
 }
 
-func (d *documents) GetDocumentsInFolder(folderID string, owner string) ([]Document, error) {
-	documents, err := d.repository.FindAllByFolderID(folderID)
+// GetDocumentDuplicates returns the other documents with the same content as the given document.
+// Duplicates are only looked up for the owner, they may not be shared with other users.
+func (d *documents) GetDocumentDuplicates(documentID string, user string) ([]Document, error) {
+	document, err := d.GetDocument(documentID, user)
+	if err != nil {
+		return nil, err
+	}
+	if document.Owner != user {
+		return nil, nil
+	}
+
+	return d.findDuplicates(document)
+}
+
+// GetDuplicateDocuments groups all documents of the owner that share their content.
+func (d *documents) GetDuplicateDocuments(owner string) ([][]Document, error) {
+	documents, err := d.repository.FindAllDuplicatesByOwner(owner)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter documents to only those owned by the user
-	var userDocuments []Document
+	var groups [][]Document
+	indexes := make(map[string]int)
 	for _, document := range documents {
-		if document.Owner == owner {
-			userDocuments = append(userDocuments, document)
+		if document.IsTrashed() {
+			continue
+		}
+
+		index, ok := indexes[document.ContentHash]
+		if !ok {
+			index = len(groups)
+			indexes[document.ContentHash] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], document)
+	}
+
+	// Trashed documents may have left groups with a single document
+	return slices.DeleteFunc(groups, func(group []Document) bool {
+		return len(group) < 2
+	}), nil
+}
+
+// GetDocumentAttachments returns the documents that were split off an email.
+func (d *documents) GetDocumentAttachments(documentID string, owner string) ([]Document, error) {
+	document, err := d.GetDocument(documentID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.repository.FindAllByParentID(document.ID)
+}
+
+// GetDocumentsInFolder returns the documents of a folder the user can read. The root folder is shared
+// by all users, only the user's own documents are listed in it.
+func (d *documents) GetDocumentsInFolder(folderID string, user string) ([]Document, error) {
+	if folderID != FolderRootID {
+		_, err := d.folders.GetFolder(folderID, user)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	return userDocuments, nil
+	documents, err := d.repository.FindAllByFolderID(folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if folderID == FolderRootID {
+		documents = slices.DeleteFunc(documents, func(document Document) bool { return document.Owner != user })
+	}
+	return documents, nil
 }
 
-func (d *documents) GetDocument(id string, owner string) (Document, error) {
+// FilterDocuments returns the documents of the owner matching all criteria of the filter.
+func (d *documents) FilterDocuments(filter DocumentFilter, owner string) ([]Document, error) {
+	return d.repository.FindAllByOwnerAndFilter(owner, filter)
+}
+
+// GetDocument returns a document the user can read.
+func (d *documents) GetDocument(id string, user string) (Document, error) {
+	return d.getDocument(id, user, PermissionRead)
+}
+
+// getWritableDocument returns a document the user can change.
+func (d *documents) getWritableDocument(id string, user string) (Document, error) {
+	return d.getDocument(id, user, PermissionWrite)
+}
+
+func (d *documents) getDocument(id string, user string, required Permission) (Document, error) {
 	document, err := d.repository.FindByID(id)
 	if err != nil {
 		return Document{}, err
 	}
 
-	if document.Owner != owner {
+	permission, err := d.permissions.documentPermission(document, user)
+	if err != nil {
+		return Document{}, err
+	}
+	if !permission.Allows(required) {
 		return Document{}, ErrNotAllowed
 	}
 
 	return document, nil
 }
 
-func (d *documents) GetDocuments(ids []string, owner string) ([]Document, error) {
+// GetDocuments returns documents the user can read, it fails if the user cannot read one of them.
+func (d *documents) GetDocuments(ids []string, user string) ([]Document, error) {
+	return d.getDocuments(ids, user, PermissionRead)
+}
+
+func (d *documents) getDocuments(ids []string, user string, required Permission) ([]Document, error) {
 	documents, err := d.repository.FindAllByIDIn(ids)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, document := range documents {
-		if document.Owner != owner {
+		permission, err := d.permissions.documentPermission(document, user)
+		if err != nil {
+			return nil, err
+		}
+		if !permission.Allows(required) {
 			return nil, ErrNotAllowed
 		}
 	}
@@ -238,47 +559,54 @@ func (d *documents) GetDocuments(ids []string, owner string) ([]Document, error)
 	return documents, nil
 }
 
-func (d *documents) GetDocumentPreview(id string, owner string, pageNumber int, consumer func(r io.Reader) error) error {
-	document, err := d.repository.FindByID(id)
+func (d *documents) GetDocumentPreview(id string, user string, pageNumber int, consumer func(r io.Reader) error) error {
+	document, err := d.GetDocument(id, user)
 	if err != nil {
 		return err
 	}
 
-	if document.Owner != owner {
-		return ErrNotAllowed
-	}
-
 	previewFilepath := document.PreviewFilepaths[pageNumber]
 	return d.previewStorage.Retrieve(previewFilepath, consumer)
 }
 
-func (d *documents) DownloadDocument(documentID string, owner string, consumer DocumentConsumer) error {
-	document, err := d.repository.FindByID(documentID)
+func (d *documents) DownloadDocument(documentID string, user string, consumer DocumentConsumer) error {
+	document, err := d.GetDocument(documentID, user)
 	if err != nil {
 		return err
 	}
 
-	if document.Owner != owner {
-		return errors.New("unauthorized")
-	}
-
 	return d.storage.Retrieve(document.Filepath(), consumer)
 }
 
-func (d *documents) ExportAllDocuments(owner string, writer io.Writer) error {
-	documents, err := d.repository.FindAllByOwner(owner)
+// ExportAllDocuments writes the documents of the user and the documents shared with the user as a zip archive.
+func (d *documents) ExportAllDocuments(user string, writer io.Writer) error {
+	documents, err := d.repository.FindAllByOwner(user)
+	if err != nil {
+		return err
+	}
+
+	sharedIDs, err := d.permissions.GetSharedDocumentIDs(user)
 	if err != nil {
 		return err
 	}
+	if len(sharedIDs) > 0 {
+		shared, err := d.repository.FindAllByIDIn(sharedIDs)
+		if err != nil {
+			return err
+		}
+		documents = append(documents, shared...)
+	}
 
+	documents = slices.DeleteFunc(documents, func(document Document) bool { return document.IsTrashed() })
+	return d.writeZip(documents, writer)
+}
+
+// writeZip writes the files of the documents as a zip archive, files that cannot be read are left out.
+func (d *documents) writeZip(documents []Document, writer io.Writer) error {
 	zipWriter := zip.NewWriter(writer)
 	defer zipWriter.Close()
 
 	for _, document := range documents {
-		if document.IsTrashed() {
-			continue
-		}
-
 		err := d.storage.Retrieve(document.Filepath(), func(r io.Reader) error {
 			fileWriter, err := zipWriter.Create(document.Filename)
 			if err != nil {
@@ -300,14 +628,15 @@ func (d *documents) ExportAllDocuments(owner string, writer io.Writer) error {
 	return nil
 }
 
-func (d *documents) TrashDocument(documentID string, owner string) error {
-	document, err := d.repository.FindByID(documentID)
+func (d *documents) TrashDocument(documentID string, user string) error {
+	document, err := d.getWritableDocument(documentID, user)
 	if err != nil {
 		return err
 	}
 
-	if document.Owner != owner {
-		return errors.New("unauthorized")
+	err = d.legalHolds.checkDocument(document)
+	if err != nil {
+		return err
 	}
 
 	document.TrashedAt.Valid = true
@@ -315,30 +644,28 @@ func (d *documents) TrashDocument(documentID string, owner string) error {
 	return d.repository.Save(document)
 }
 
-func (d *documents) RestoreDocument(documentID string, owner string) error {
-	document, err := d.repository.FindByID(documentID)
+func (d *documents) RestoreDocument(documentID string, user string) error {
+	document, err := d.getWritableDocument(documentID, user)
 	if err != nil {
 		return err
 	}
 
-	if document.Owner != owner {
-		return errors.New("unauthorized")
+	// Documents of a folder that is still trashed are restored into the root folder
+	folder, err := d.folders.GetFolder(document.FolderID, document.Owner)
+	if err != nil || folder.IsTrashed() {
+		document.FolderID = FolderRootID
 	}
 
 	document.TrashedAt.Valid = false
 	return d.repository.Save(document)
 }
 
-func (d *documents) UpdateDocumentTitle(documentID string, owner string, newTitle string) error {
-	document, err := d.repository.FindByID(documentID)
+func (d *documents) UpdateDocumentTitle(documentID string, user string, newTitle string) error {
+	document, err := d.getWritableDocument(documentID, user)
 	if err != nil {
 		return err
 	}
 
-	if document.Owner != owner {
-		return errors.New("unauthorized")
-	}
-
 	document.Title = newTitle
 	document.UpdatedAt = time.Now()
 
@@ -350,6 +677,27 @@ func (d *documents) UpdateDocumentTitle(documentID string, owner string, newTitl
 	return d.messages.PublishDocumentUpserted(document)
 }
 
+// scheduleContentHashBackfill computes the content hash of documents uploaded before deduplication existed.
+func (d *documents) scheduleContentHashBackfill() error {
+	documents, err := d.repository.FindAllWithoutContentHash()
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		payload := DocumentProcessingPayload{DocumentID: document.ID}
+		err := d.taskScheduler.ScheduleTask(common.TaskTypeComputeContentHash, payload, 3)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(documents) > 0 {
+		slog.Info("scheduled content hash backfill", "count", len(documents))
+	}
+	return nil
+}
+
 func (d *documents) rescheduleAllDocumentTasks(owner string) error {
 	documents, err := d.repository.FindAllByOwner(owner)
 	if err != nil {
@@ -365,51 +713,39 @@ func (d *documents) rescheduleAllDocumentTasks(owner string) error {
 	return nil
 }
 
-func (d *documents) emptyTrash(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Hour)
-	for {
-		select {
-		case <-ticker.C:
-			documents, err := d.repository.FindAllTrashed()
-			if err != nil {
-				slog.Error("failed to find all trashed documents", "error", err)
-				continue
-			}
+// purgeDocument deletes a trashed document with its files, previews and versions for good.
+func (d *documents) purgeDocument(document Document) error {
+	err := d.storage.Delete(document.Filepath())
+	if err != nil {
+		return err
+	}
 
-			for _, document := range documents {
-				if document.ShouldBeDeleted() {
-					err = d.storage.Delete(document.Filepath())
-					if err != nil {
-						slog.Error("failed to delete document file", "error", err)
-						continue
-					}
-
-					for _, path := range document.PreviewFilepaths {
-						err := d.previewStorage.Delete(path)
-						if err != nil {
-							slog.Error("failed to delete preview file", "error", err)
-							continue
-						}
-					}
-
-					err := d.repository.DeleteByID(document.ID)
-					if err != nil {
-						slog.Error("failed to delete document", "error", err)
-						continue
-					}
-
-					err = d.messages.PublishDocumentDeleted(document)
-					if err != nil {
-						slog.Error("failed to publish document deleted event", "error", err)
-						continue
-					}
-				}
-			}
-		case <-ctx.Done():
-			slog.Info("documents trash emptying stopped")
-			return
+	for _, path := range document.PreviewFilepaths {
+		err := d.previewStorage.Delete(path)
+		if err != nil {
+			slog.Error("failed to delete preview file", "error", err)
+			continue
 		}
 	}
+
+	versions, err := d.versionRepository.FindAllByDocumentID(document.ID)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		err := d.storage.Delete(version.Filepath())
+		if err != nil {
+			slog.Error("failed to delete document version file", "error", err)
+			continue
+		}
+	}
+
+	err = d.repository.DeleteByID(document.ID)
+	if err != nil {
+		return err
+	}
+
+	return d.messages.PublishDocumentDeleted(document)
 }
 
 func (d *documents) scheduleDocumentProcessing(document Document) error {
@@ -435,24 +771,38 @@ type DocumentProcessingPayload struct {
 
 func newDocuments(
 	repository DocumentRepository,
+	versionRepository DocumentVersionRepository,
 	storage DocumentStorage,
 	previewStorage DocumentPreviewStorage,
 	messages DocumentMessages,
 	summarizer DocumentSummarizer,
-	jobScheduler *common.JobScheduler,
+	ocrEngine OCREngine,
+	ocrMinCharactersPerPage int,
+	preferences *preferences,
+	folders *folders,
+	permissions *permissions,
+	legalHolds *legalHolds,
+	tagRepository TagRepository,
 	taskScheduler *common.TaskScheduler,
 	shutdown *common.Shutdown) *documents {
 
+	pdfAnalyzer := NewPDFAnalyzer(storage, previewStorage, shutdown)
 	documents := &documents{
-		repository:     repository,
-		storage:        storage,
-		previewStorage: previewStorage,
-		messages:       messages,
-		taskScheduler:  taskScheduler,
-	}
-
-	documentProcessor := newDocumentProcessor(repository, storage, previewStorage, messages, summarizer, shutdown)
-	jobScheduler.Schedule(documents.emptyTrash)
+		repository:        repository,
+		versionRepository: versionRepository,
+		storage:           storage,
+		previewStorage:    previewStorage,
+		messages:          messages,
+		preferences:       preferences,
+		folders:           folders,
+		permissions:       permissions,
+		legalHolds:        legalHolds,
+		tagRepository:     tagRepository,
+		pageEditor:        pdfAnalyzer,
+		taskScheduler:     taskScheduler,
+	}
+
+	documentProcessor := newDocumentProcessor(repository, storage, previewStorage, messages, summarizer, ocrEngine, ocrMinCharactersPerPage, pdfAnalyzer, taskScheduler)
 	taskScheduler.Register(documentProcessor)
 
 	// Schedule summarization after text extraction completes
@@ -464,5 +814,10 @@ func newDocuments(
 		panic(err)
 	}
 
+	err = documents.scheduleContentHashBackfill()
+	if err != nil {
+		slog.Error("failed to schedule content hash backfill", "error", err.Error())
+	}
+
 	return documents
 }
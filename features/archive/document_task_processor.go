@@ -2,20 +2,29 @@ package archive
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"image"
 	"image/jpeg"
+	"image/png"
 	"io"
 	"log/slog"
 	"path"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 	"unterlagen/features/common"
 
 	"github.com/klippa-app/go-pdfium"
+	"github.com/klippa-app/go-pdfium/enums"
+	"github.com/klippa-app/go-pdfium/references"
 	"github.com/klippa-app/go-pdfium/requests"
 	"github.com/klippa-app/go-pdfium/responses"
 	"github.com/klippa-app/go-pdfium/webassembly"
+	"github.com/tetratelabs/wazero"
 )
 
 type DocumentTaskProcessor struct {
@@ -24,9 +33,16 @@ type DocumentTaskProcessor struct {
 	previewStorage DocumentPreviewStorage
 	messages       DocumentMessages
 	summarizer     DocumentSummarizer
+	ocrEngine      OCREngine
+	taskScheduler  *common.TaskScheduler
 	analyzers      map[Filetype]DocumentAnalyzer
+	// Documents with less extracted characters per page are sent to OCR
+	ocrMinCharactersPerPage int
 }
 
+// ocrDPI is the resolution pages are rendered in for OCR, lower resolutions hurt recognition
+const ocrDPI = 300
+
 func (p *DocumentTaskProcessor) Name() string {
 	return "DocumentTaskProcessor"
 }
@@ -39,6 +55,10 @@ func (p *DocumentTaskProcessor) ProcessTask(task common.Task) error {
 		return p.processPreviewGeneration(task)
 	case common.TaskTypeSummarizeDocument:
 		return p.processSummarization(task)
+	case common.TaskTypeOCR:
+		return p.processOCR(task)
+	case common.TaskTypeComputeContentHash:
+		return p.processContentHash(task)
 	default:
 		return nil
 	}
@@ -49,6 +69,8 @@ func (p *DocumentTaskProcessor) ResponsibleFor() []common.TaskType {
 		common.TaskTypeExtractText,
 		common.TaskTypeGeneratePreviews,
 		common.TaskTypeSummarizeDocument,
+		common.TaskTypeOCR,
+		common.TaskTypeComputeContentHash,
 	}
 }
 
@@ -79,7 +101,91 @@ func (p *DocumentTaskProcessor) processTextExtraction(task common.Task) error {
 	}
 
 	slog.Info("text extracted for document", "document_id", document.ID)
-	return nil
+
+	// Classification waits for OCR, rules need the recognized text
+	if p.needsOCR(document, analyzer) {
+		return p.taskScheduler.ScheduleTask(common.TaskTypeOCR, payload, 3)
+	}
+	return p.taskScheduler.ScheduleTask(common.TaskTypeClassifyDocument, payload, 3)
+}
+
+// needsOCR decides whether the extracted text is too sparse to be a real text layer.
+func (p *DocumentTaskProcessor) needsOCR(document Document, analyzer DocumentAnalyzer) bool {
+	if p.ocrEngine == nil {
+		return false
+	}
+
+	renderer, ok := analyzer.(PageRenderer)
+	if !ok {
+		return false
+	}
+
+	pageCount, err := renderer.PageCount(document)
+	if err != nil || pageCount == 0 {
+		return false
+	}
+
+	characters := utf8.RuneCountInString(strings.TrimSpace(document.Text))
+	return characters < pageCount*p.ocrMinCharactersPerPage
+}
+
+func (p *DocumentTaskProcessor) processOCR(task common.Task) error {
+	var payload DocumentProcessingPayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return err
+	}
+
+	document, err := p.repository.FindByID(payload.DocumentID)
+	if err != nil {
+		return err
+	}
+
+	renderer, ok := p.analyzers[document.Filetype].(PageRenderer)
+	if !ok || p.ocrEngine == nil {
+		return nil
+	}
+
+	var pages []string
+	err = renderer.RenderPages(document, ocrDPI, func(page int, img image.Image) error {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return err
+		}
+
+		text, err := p.ocrEngine.RecognizeText(&buf)
+		if err != nil {
+			return err
+		}
+		pages = append(pages, text)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Keep the text layer if OCR did not recognize more than was already there
+	ocrText := strings.TrimSpace(strings.Join(pages, "\n\n"))
+	if utf8.RuneCountInString(ocrText) <= utf8.RuneCountInString(strings.TrimSpace(document.Text)) {
+		slog.Info("ocr did not improve document text", "document_id", document.ID)
+		return p.taskScheduler.ScheduleTask(common.TaskTypeClassifyDocument, payload, 3)
+	}
+
+	document, err = p.update(document.ID, func(document *Document) {
+		document.OCRPages = pages
+		document.Text = ocrText
+		document.Summary.IsGenerating = true
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("ocr finished for document", "document_id", document.ID, "pages", len(pages))
+
+	err = p.messages.PublishDocumentTextExtracted(document)
+	if err != nil {
+		return err
+	}
+	return p.taskScheduler.ScheduleTask(common.TaskTypeClassifyDocument, payload, 3)
 }
 
 func (p *DocumentTaskProcessor) processPreviewGeneration(task common.Task) error {
@@ -103,8 +209,10 @@ func (p *DocumentTaskProcessor) processPreviewGeneration(task common.Task) error
 		return err
 	}
 
-	document.PreviewFilepaths = previewFilepaths
-	if err := p.repository.Save(document); err != nil {
+	document, err = p.update(document.ID, func(document *Document) {
+		document.PreviewFilepaths = previewFilepaths
+	})
+	if err != nil {
 		return err
 	}
 
@@ -137,16 +245,20 @@ func (p *DocumentTaskProcessor) processSummarization(task common.Task) error {
 	summary, err := p.summarizer.SummarizeText(document.Text)
 	if err != nil {
 		// Set IsGenerating to false even on error
-		document.Summary.IsGenerating = false
-		if saveErr := p.repository.Save(document); saveErr != nil {
+		_, saveErr := p.update(document.ID, func(document *Document) {
+			document.Summary.IsGenerating = false
+		})
+		if saveErr != nil {
 			slog.Error("failed to save document after summarization error", "document_id", document.ID, "save_error", saveErr.Error())
 		}
 		return err
 	}
 
 	summary.IsGenerating = false
-	document.Summary = summary
-	if err := p.repository.Save(document); err != nil {
+	document, err = p.update(document.ID, func(document *Document) {
+		document.Summary = summary
+	})
+	if err != nil {
 		return err
 	}
 
@@ -156,17 +268,71 @@ func (p *DocumentTaskProcessor) processSummarization(task common.Task) error {
 	return p.messages.PublishDocumentUpserted(document)
 }
 
+// update reloads the document right before saving it. Previews and summaries take a while,
+// changes made to the document in the meantime, like by classification, must not be overwritten.
+func (p *DocumentTaskProcessor) update(documentID string, change func(document *Document)) (Document, error) {
+	document, err := p.repository.FindByID(documentID)
+	if err != nil {
+		return Document{}, err
+	}
+
+	change(&document)
+	return document, p.repository.Save(document)
+}
+
+func (p *DocumentTaskProcessor) processContentHash(task common.Task) error {
+	var payload DocumentProcessingPayload
+	if err := json.Unmarshal(task.Payload, &payload); err != nil {
+		return err
+	}
+
+	document, err := p.repository.FindByID(payload.DocumentID)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	err = p.storage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		_, err := io.Copy(hash, r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	document.ContentHash = hex.EncodeToString(hash.Sum(nil))
+	if err := p.repository.Save(document); err != nil {
+		return err
+	}
+
+	slog.Info("content hash computed for document", "document_id", document.ID)
+	return nil
+}
+
 func newDocumentProcessor(
 	repository DocumentRepository,
 	storage DocumentStorage,
 	previewStorage DocumentPreviewStorage,
 	messages DocumentMessages,
 	summarizer DocumentSummarizer,
-	shutdown *common.Shutdown,
+	ocrEngine OCREngine,
+	ocrMinCharactersPerPage int,
+	pdfAnalyzer *PDFAnalyzer,
+	taskScheduler *common.TaskScheduler,
 ) *DocumentTaskProcessor {
-	pdfAnalyzer := NewPDFAnalyzer(storage, previewStorage, shutdown)
+	imageAnalyzer := NewImageAnalyzer(storage, previewStorage)
+	officeAnalyzer := NewOfficeAnalyzer(storage, previewStorage)
+	emailAnalyzer := NewEmailAnalyzer(storage, previewStorage)
 	analyzers := make(map[Filetype]DocumentAnalyzer)
 	analyzers[PDF] = pdfAnalyzer
+	analyzers[PNG] = imageAnalyzer
+	analyzers[JPEG] = imageAnalyzer
+	analyzers[TIFF] = imageAnalyzer
+	analyzers[DOCX] = officeAnalyzer
+	analyzers[XLSX] = officeAnalyzer
+	analyzers[ODT] = officeAnalyzer
+	analyzers[ODS] = officeAnalyzer
+	analyzers[EML] = emailAnalyzer
 
 	return &DocumentTaskProcessor{
 		repository:     repository,
@@ -174,11 +340,19 @@ func newDocumentProcessor(
 		previewStorage: previewStorage,
 		messages:       messages,
 		summarizer:     summarizer,
+		ocrEngine:      ocrEngine,
+		taskScheduler:  taskScheduler,
 		analyzers:      analyzers,
+
+		ocrMinCharactersPerPage: ocrMinCharactersPerPage,
 	}
 }
 
-var _ DocumentAnalyzer = &PDFAnalyzer{}
+var (
+	_ DocumentAnalyzer = &PDFAnalyzer{}
+	_ PageRenderer     = &PDFAnalyzer{}
+	_ PageEditor       = &PDFAnalyzer{}
+)
 
 type PDFAnalyzer struct {
 	documentStorage DocumentStorage
@@ -193,8 +367,20 @@ func (p *PDFAnalyzer) withInstance(document Document, block func(instance pdfium
 	}
 	defer instance.Close()
 
+	pdfDocument, err := p.openDocument(instance, document)
+	if err != nil {
+		return err
+	}
+	defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+		Document: pdfDocument.Document,
+	})
+
+	return block(instance, pdfDocument)
+}
+
+func (p *PDFAnalyzer) openDocument(instance pdfium.Pdfium, document Document) (*responses.OpenDocument, error) {
 	var pdfDocument *responses.OpenDocument
-	err = p.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+	err := p.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
 		data, err := io.ReadAll(r)
 		if err != nil {
 			return err
@@ -205,14 +391,7 @@ func (p *PDFAnalyzer) withInstance(document Document, block func(instance pdfium
 		})
 		return err
 	})
-	if err != nil {
-		return err
-	}
-	defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
-		Document: pdfDocument.Document,
-	})
-
-	return block(instance, pdfDocument)
+	return pdfDocument, err
 }
 
 // ExtractText implements DocumentAnalyzer.
@@ -278,6 +457,203 @@ func (p *PDFAnalyzer) GeneratePreviews(document Document) ([]string, error) {
 	return filepaths, err
 }
 
+// PageCount implements PageRenderer.
+func (p *PDFAnalyzer) PageCount(document Document) (int, error) {
+	var count int
+	err := p.withInstance(document, func(instance pdfium.Pdfium, pdfDocument *responses.OpenDocument) error {
+		pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: pdfDocument.Document,
+		})
+		if err != nil {
+			return err
+		}
+		count = pageCount.PageCount
+		return nil
+	})
+
+	return count, err
+}
+
+// RenderPages implements PageRenderer.
+func (p *PDFAnalyzer) RenderPages(document Document, dpi int, consumer func(page int, img image.Image) error) error {
+	return p.withInstance(document, func(instance pdfium.Pdfium, pdfDocument *responses.OpenDocument) error {
+		pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+			Document: pdfDocument.Document,
+		})
+		if err != nil {
+			return err
+		}
+
+		for page := range pageCount.PageCount {
+			pageRender, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
+				DPI: dpi,
+				Page: requests.Page{
+					ByIndex: &requests.PageByIndex{
+						Document: pdfDocument.Document,
+						Index:    page,
+					},
+				},
+			})
+			if err != nil {
+				return err
+			}
+
+			err = consumer(page, pageRender.Result.Image)
+			pageRender.Cleanup()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// RotatePages implements PageEditor.
+func (p *PDFAnalyzer) RotatePages(document Document, pages []int, quarterTurns int) ([]byte, error) {
+	var data []byte
+	err := p.withInstance(document, func(instance pdfium.Pdfium, pdfDocument *responses.OpenDocument) error {
+		for _, page := range pages {
+			pageReference := requests.Page{ByIndex: &requests.PageByIndex{Document: pdfDocument.Document, Index: page}}
+			rotation, err := instance.FPDFPage_GetRotation(&requests.FPDFPage_GetRotation{Page: pageReference})
+			if err != nil {
+				return err
+			}
+
+			// Rotations are clockwise quarter turns from 0 to 3, counterclockwise turns are negative
+			turns := ((int(rotation.PageRotation)+quarterTurns)%4 + 4) % 4
+			_, err = instance.FPDFPage_SetRotation(&requests.FPDFPage_SetRotation{
+				Page:   pageReference,
+				Rotate: enums.FPDF_PAGE_ROTATION(turns),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		data, err = p.save(instance, pdfDocument.Document)
+		return err
+	})
+
+	return data, err
+}
+
+// DeletePages implements PageEditor.
+func (p *PDFAnalyzer) DeletePages(document Document, pages []int) ([]byte, error) {
+	var data []byte
+	err := p.withInstance(document, func(instance pdfium.Pdfium, pdfDocument *responses.OpenDocument) error {
+		// Deleting from the back keeps the indexes of the remaining pages valid
+		sorted := slices.Sorted(slices.Values(pages))
+		slices.Reverse(sorted)
+		for _, page := range sorted {
+			_, err := instance.FPDFPage_Delete(&requests.FPDFPage_Delete{
+				Document:  pdfDocument.Document,
+				PageIndex: page,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		data, err = p.save(instance, pdfDocument.Document)
+		return err
+	})
+
+	return data, err
+}
+
+// ExtractPages implements PageEditor.
+func (p *PDFAnalyzer) ExtractPages(document Document, pages []int) ([]byte, error) {
+	var data []byte
+	err := p.withInstance(document, func(instance pdfium.Pdfium, pdfDocument *responses.OpenDocument) error {
+		extract, err := instance.FPDF_CreateNewDocument(&requests.FPDF_CreateNewDocument{})
+		if err != nil {
+			return err
+		}
+		defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+			Document: extract.Document,
+		})
+
+		_, err = instance.FPDF_ImportPagesByIndex(&requests.FPDF_ImportPagesByIndex{
+			Source:      pdfDocument.Document,
+			Destination: extract.Document,
+			PageIndices: pages,
+		})
+		if err != nil {
+			return err
+		}
+
+		data, err = p.save(instance, extract.Document)
+		return err
+	})
+
+	return data, err
+}
+
+// MergeDocuments implements PageEditor.
+func (p *PDFAnalyzer) MergeDocuments(documents []Document) ([]byte, error) {
+	instance, err := p.pool.GetInstance(time.Second * 30)
+	if err != nil {
+		return nil, err
+	}
+	defer instance.Close()
+
+	merged, err := instance.FPDF_CreateNewDocument(&requests.FPDF_CreateNewDocument{})
+	if err != nil {
+		return nil, err
+	}
+	defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+		Document: merged.Document,
+	})
+
+	for _, document := range documents {
+		err := p.appendDocument(instance, merged.Document, document)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p.save(instance, merged.Document)
+}
+
+// appendDocument imports all pages of the document behind the pages of the destination.
+func (p *PDFAnalyzer) appendDocument(instance pdfium.Pdfium, destination references.FPDF_DOCUMENT, document Document) error {
+	pdfDocument, err := p.openDocument(instance, document)
+	if err != nil {
+		return err
+	}
+	defer instance.FPDF_CloseDocument(&requests.FPDF_CloseDocument{
+		Document: pdfDocument.Document,
+	})
+
+	pageCount, err := instance.FPDF_GetPageCount(&requests.FPDF_GetPageCount{
+		Document: destination,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = instance.FPDF_ImportPagesByIndex(&requests.FPDF_ImportPagesByIndex{
+		Source:      pdfDocument.Document,
+		Destination: destination,
+		Index:       pageCount.PageCount,
+	})
+	return err
+}
+
+func (p *PDFAnalyzer) save(instance pdfium.Pdfium, document references.FPDF_DOCUMENT) ([]byte, error) {
+	saved, err := instance.FPDF_SaveAsCopy(&requests.FPDF_SaveAsCopy{
+		Document: document,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return *saved.FileBytes, nil
+}
+
 func (p *PDFAnalyzer) generatePreview(instance pdfium.Pdfium, pdfDocument *responses.OpenDocument, filepath string, page int) error {
 	pageRender, err := instance.RenderPageInDPI(&requests.RenderPageInDPI{
 		DPI: 80, // The DPI to render the page in.
@@ -310,11 +686,15 @@ func (p *PDFAnalyzer) generatePreview(instance pdfium.Pdfium, pdfDocument *respo
 	return err
 }
 
+// pdfiumCompilationCache keeps the compiled pdfium module, so every further pool starts without compiling it again.
+var pdfiumCompilationCache = wazero.NewCompilationCache()
+
 func NewPDFAnalyzer(documentStorage DocumentStorage, previewStorage DocumentPreviewStorage, shutdown *common.Shutdown) *PDFAnalyzer {
 	pool, err := webassembly.Init(webassembly.Config{
-		MinIdle:  1, // Makes sure that at least x workers are always available
-		MaxIdle:  1, // Makes sure that at most x workers are ever available
-		MaxTotal: 4, // Maxium amount of workers in total, allows the amount of workers to grow when needed, items between total max and idle max are automatically cleaned up, while idle workers are kept alive so they can be used directly.
+		MinIdle:       1, // Makes sure that at least x workers are always available
+		MaxIdle:       1, // Makes sure that at most x workers are ever available
+		MaxTotal:      4, // Maxium amount of workers in total, allows the amount of workers to grow when needed, items between total max and idle max are automatically cleaned up, while idle workers are kept alive so they can be used directly.
+		RuntimeConfig: wazero.NewRuntimeConfig().WithCompilationCache(pdfiumCompilationCache),
 	})
 	if err != nil {
 		panic(err)
@@ -0,0 +1,58 @@
+package archive
+
+import "errors"
+
+var (
+	ErrDocumentTypeNotFound    = errors.New("document type not found")
+	ErrDocumentTypeExists      = errors.New("document type already exists")
+	ErrInvalidDocumentTypeName = errors.New("invalid document type name")
+)
+
+// DocumentType is the kind of paper a document is, like an invoice, a contract or a payslip.
+type DocumentType = Label
+
+type DocumentTypeRepository = LabelRepository
+
+type documentTypes struct {
+	labels *labels
+}
+
+func (t *documentTypes) CreateDocumentType(name string, owner string) (DocumentType, error) {
+	return t.labels.create(name, owner)
+}
+
+func (t *documentTypes) GetDocumentType(id string, owner string) (DocumentType, error) {
+	return t.labels.get(id, owner)
+}
+
+// GetDocumentTypes returns all document types of the owner with their document counts.
+func (t *documentTypes) GetDocumentTypes(owner string) ([]DocumentType, error) {
+	return t.labels.list(owner)
+}
+
+// DeleteDocumentType deletes the document type, its documents are kept without a type.
+func (t *documentTypes) DeleteDocumentType(id string, owner string) error {
+	return t.labels.delete(id, owner)
+}
+
+// AssignDocumentType sets the type of a document, an empty id removes it.
+// The type must belong to the owner of the document.
+func (t *documentTypes) AssignDocumentType(documentID string, documentTypeID string, user string) error {
+	return t.labels.assign(documentID, documentTypeID, user)
+}
+
+func newDocumentTypes(repository DocumentTypeRepository, documents *documents) *documentTypes {
+	kind := labelKind{
+		errExists:      ErrDocumentTypeExists,
+		errInvalidName: ErrInvalidDocumentTypeName,
+		count: func(repository DocumentRepository, owner string) (map[string]int, error) {
+			return repository.CountAllByDocumentType(owner)
+		},
+		assign: func(document *Document, documentTypeID string) {
+			document.DocumentTypeID = documentTypeID
+		},
+	}
+	return &documentTypes{
+		labels: newLabels(kind, repository, documents),
+	}
+}
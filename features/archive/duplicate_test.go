@@ -0,0 +1,100 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestDuplicatePolicies(t *testing.T) {
+	content := testFile(t, "mock_pdfs/invoice_0001.pdf")
+
+	t.Run("warn", func(t *testing.T) {
+		a := newTestArchive(t)
+		a.createUsers(t, "alice")
+		original := a.upload(t, "invoice.pdf", content, archive.FolderRootID, "alice")
+
+		duplicate := a.upload(t, "copy.pdf", content, archive.FolderRootID, "alice")
+		duplicates, err := a.GetDocumentDuplicates(duplicate.ID, "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(duplicates) != 1 || duplicates[0].ID != original.ID {
+			t.Errorf("expected the duplicate to be stored and reported, got %v", duplicates)
+		}
+		groups, err := a.GetDuplicateDocuments("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != 1 || len(groups[0]) != 2 {
+			t.Errorf("expected one group of duplicates, got %v", groups)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		a := newTestArchive(t)
+		a.createUsers(t, "alice")
+		if err := a.UpdateDuplicatePolicy("alice", archive.DuplicatePolicyReject); err != nil {
+			t.Fatal(err)
+		}
+		a.upload(t, "invoice.pdf", content, archive.FolderRootID, "alice")
+
+		err := a.UploadDocument("copy.pdf", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content))
+		if !errors.Is(err, archive.ErrDuplicateDocument) {
+			t.Errorf("expected the duplicate to be rejected, got %v", err)
+		}
+		if documents, _ := a.documents.FindAllByFolderID(archive.FolderRootID); len(documents) != 1 {
+			t.Errorf("expected the duplicate not to be stored, got %d documents", len(documents))
+		}
+	})
+
+	t.Run("link", func(t *testing.T) {
+		a := newTestArchive(t)
+		a.createUsers(t, "alice")
+		if err := a.UpdateDuplicatePolicy("alice", archive.DuplicatePolicyLink); err != nil {
+			t.Fatal(err)
+		}
+		original := a.upload(t, "invoice.pdf", content, archive.FolderRootID, "alice")
+
+		err := a.UploadDocument("copy.pdf", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content))
+		linked, ok := archive.LinkedDuplicate(err)
+		if !ok || linked.ID != original.ID {
+			t.Errorf("expected the upload to link to the original, got %v", err)
+		}
+		if documents, _ := a.documents.FindAllByFolderID(archive.FolderRootID); len(documents) != 1 {
+			t.Errorf("expected the duplicate not to be stored, got %d documents", len(documents))
+		}
+	})
+}
+
+func TestDuplicatesOfOtherOwnersOrTrashed(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	if err := a.UpdateDuplicatePolicy("alice", archive.DuplicatePolicyReject); err != nil {
+		t.Fatal(err)
+	}
+	content := testFile(t, "mock_pdfs/invoice_0001.pdf")
+
+	// The same file of another user is not a duplicate
+	a.upload(t, "invoice.pdf", content, archive.FolderRootID, "bob")
+	trashed := a.upload(t, "invoice.pdf", content, archive.FolderRootID, "alice")
+
+	// Neither is a document in the trash
+	if err := a.TrashDocument(trashed.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	err := a.UploadDocument("invoice.pdf", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content))
+	if err != nil {
+		t.Errorf("expected the upload to be accepted, got %v", err)
+	}
+}
+
+func TestInvalidDuplicatePolicy(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	if err := a.UpdateDuplicatePolicy("alice", "ignore"); !errors.Is(err, archive.ErrInvalidDuplicatePolicy) {
+		t.Errorf("expected an unknown policy to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,347 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+var _ DocumentAnalyzer = &EmailAnalyzer{}
+
+// EmailHeaders are the headers that are kept as document metadata.
+var EmailHeaders = []string{"From", "To", "Cc", "Subject", "Date"}
+
+// EmailAnalyzer extracts the body of an email as text.
+// Attachments are split into their own documents on upload.
+type EmailAnalyzer struct {
+	documentStorage DocumentStorage
+	previewStorage  DocumentPreviewStorage
+}
+
+// ExtractText implements DocumentAnalyzer.
+func (a *EmailAnalyzer) ExtractText(document Document) (string, error) {
+	var message email
+	err := a.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		var err error
+		message, err = parseEmail(r)
+		return err
+	})
+
+	return message.Text, err
+}
+
+// GeneratePreviews implements DocumentAnalyzer.
+func (a *EmailAnalyzer) GeneratePreviews(document Document) ([]string, error) {
+	var message email
+	err := a.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		var err error
+		message, err = parseEmail(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, header := range EmailHeaders {
+		if value, ok := message.Headers[header]; ok {
+			fmt.Fprintf(&text, "%s: %s\n", header, value)
+		}
+	}
+	text.WriteString("\n")
+	text.WriteString(message.Text)
+
+	filepath := path.Join(document.PreviewPrefix(), "page0.jpeg")
+	err = storePreview(a.previewStorage, renderTextPreview(text.String()), filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filepath}, nil
+}
+
+type email struct {
+	Headers     map[string]string
+	Text        string
+	Attachments []emailAttachment
+}
+
+type emailAttachment struct {
+	Filename string
+	Data     []byte
+}
+
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		encoding, err := htmlindex.Get(charset)
+		if err != nil {
+			return nil, err
+		}
+		return encoding.NewDecoder().Reader(input), nil
+	},
+}
+
+func parseEmail(r io.Reader) (email, error) {
+	message, err := mail.ReadMessage(r)
+	if err != nil {
+		return email{}, err
+	}
+
+	parsed := email{Headers: make(map[string]string)}
+	for _, header := range EmailHeaders {
+		value := message.Header.Get(header)
+		if value == "" {
+			continue
+		}
+		if decoded, err := wordDecoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+		parsed.Headers[header] = value
+	}
+
+	var plain, htmlText strings.Builder
+	err = parseEmailPart(textproto.MIMEHeader(message.Header), message.Body, &parsed, &plain, &htmlText)
+	if err != nil {
+		return email{}, err
+	}
+
+	parsed.Text = strings.TrimSpace(plain.String())
+	if parsed.Text == "" {
+		parsed.Text = strings.TrimSpace(htmlText.String())
+	}
+	return parsed, nil
+}
+
+func parseEmailPart(header textproto.MIMEHeader, body io.Reader, parsed *email, plain *strings.Builder, htmlText *strings.Builder) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = parseEmailPart(part.Header, part, parsed, plain, htmlText)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if disposition == "attachment" || mediaType == "message/rfc822" || (filename != "" && !isText) {
+		if filename == "" && mediaType == "message/rfc822" {
+			filename = "message.eml"
+		}
+		if filename == "" {
+			filename = fmt.Sprintf("attachment-%d", len(parsed.Attachments)+1)
+		}
+		parsed.Attachments = append(parsed.Attachments, emailAttachment{
+			Filename: path.Base(filename),
+			Data:     data,
+		})
+		return nil
+	}
+
+	switch mediaType {
+	case "text/plain":
+		plain.WriteString(decodeCharset(data, params["charset"]))
+		plain.WriteString("\n")
+	case "text/html":
+		htmlText.WriteString(htmlToText(decodeCharset(data, params["charset"])))
+		htmlText.WriteString("\n")
+	}
+
+	return nil
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: body})
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// base64Cleaner drops the line breaks that mails insert into base64 content.
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	cleaned := 0
+	for _, b := range p[:n] {
+		if b != '\r' && b != '\n' && b != ' ' && b != '\t' {
+			p[cleaned] = b
+			cleaned++
+		}
+	}
+	return cleaned, err
+}
+
+func decodeCharset(data []byte, charset string) string {
+	charset = strings.ToLower(charset)
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return string(data)
+	}
+
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return string(data)
+	}
+
+	decoded, err := encoding.NewDecoder().Bytes(data)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+func htmlToText(content string) string {
+	var text strings.Builder
+	skip := false
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return text.String()
+		case html.TextToken:
+			if !skip {
+				text.WriteString(strings.Join(strings.Fields(string(tokenizer.Text())), " "))
+				text.WriteString(" ")
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "head":
+				skip = true
+			case "br", "p", "div", "tr", "li", "h1", "h2", "h3", "h4", "h5", "h6":
+				text.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "head":
+				skip = false
+			}
+		}
+	}
+}
+
+// splitMailbox splits an mbox archive into its messages.
+func splitMailbox(r io.Reader) ([][]byte, error) {
+	var messages [][]byte
+	var current bytes.Buffer
+	inMessage := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if bytes.HasPrefix(line, []byte("From ")) {
+			if inMessage && current.Len() > 0 {
+				messages = append(messages, bytes.Clone(current.Bytes()))
+			}
+			current.Reset()
+			inMessage = true
+			continue
+		}
+
+		if !inMessage {
+			continue
+		}
+
+		// Undo mboxrd quoting of lines starting with "From "
+		if unquoted := bytes.TrimLeft(line, ">"); len(unquoted) < len(line) && bytes.HasPrefix(unquoted, []byte("From ")) {
+			line = line[1:]
+		}
+		current.Write(line)
+		current.WriteString("\r\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if inMessage && current.Len() > 0 {
+		messages = append(messages, bytes.Clone(current.Bytes()))
+	}
+	return messages, nil
+}
+
+// maxEmailHeaderSize is how much of a file is read to find email headers, they are at its beginning.
+const maxEmailHeaderSize = 256 << 10
+
+// detectEmailFiletype recognizes single emails and mbox archives by their headers.
+func detectEmailFiletype(data []byte) (Filetype, error) {
+	content := data
+	filetype := EML
+	if bytes.HasPrefix(data, []byte("From ")) {
+		_, rest, found := bytes.Cut(data, []byte("\n"))
+		if !found {
+			return "", ErrUnsupportedFiletype
+		}
+		content = rest
+		filetype = MBOX
+	}
+
+	message, err := mail.ReadMessage(bytes.NewReader(content))
+	if err != nil {
+		return "", ErrUnsupportedFiletype
+	}
+
+	header := message.Header
+	if header.Get("From") == "" {
+		return "", ErrUnsupportedFiletype
+	}
+	if header.Get("Date") == "" && header.Get("Subject") == "" && header.Get("Message-Id") == "" {
+		return "", ErrUnsupportedFiletype
+	}
+
+	return filetype, nil
+}
+
+func NewEmailAnalyzer(documentStorage DocumentStorage, previewStorage DocumentPreviewStorage) *EmailAnalyzer {
+	return &EmailAnalyzer{
+		documentStorage: documentStorage,
+		previewStorage:  previewStorage,
+	}
+}
@@ -0,0 +1,114 @@
+package archive_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+// emailWithAttachment creates an email with a text body and the file attached.
+func emailWithAttachment(subject string, body string, filename string, attachment []byte) []byte {
+	var message strings.Builder
+	message.WriteString("From: Bob <bob@example.com>\r\n")
+	message.WriteString("To: Alice <alice@example.com>\r\n")
+	message.WriteString("Subject: " + subject + "\r\n")
+	message.WriteString("Date: Mon, 02 Jan 2006 15:04:05 +0000\r\n")
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: multipart/mixed; boundary=boundary\r\n\r\n")
+	message.WriteString("--boundary\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(body + "\r\n")
+	message.WriteString("--boundary\r\nContent-Type: application/pdf\r\n")
+	message.WriteString("Content-Disposition: attachment; filename=\"" + filename + "\"\r\n")
+	message.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	message.WriteString(base64.StdEncoding.EncodeToString(attachment) + "\r\n")
+	message.WriteString("--boundary--\r\n")
+	return []byte(message.String())
+}
+
+func TestEmailUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	invoice := testFile(t, "mock_pdfs/invoice_0001.pdf")
+
+	document := a.upload(t, "invoice.eml", emailWithAttachment("Your invoice", "Please find the invoice attached.", "invoice.pdf", invoice), archive.FolderRootID, "alice")
+	if document.Filetype != archive.EML {
+		t.Fatalf("expected an email, got %s", document.Filetype)
+	}
+	if document.Title != "Your invoice" || !strings.Contains(document.Metadata["From"], "bob@example.com") {
+		t.Errorf("expected the headers of the email, got title %q and %v", document.Title, document.Metadata)
+	}
+	if !strings.Contains(document.Text, "Please find the invoice attached.") {
+		t.Errorf("expected the body as text, got %q", document.Text)
+	}
+
+	attachments, err := a.GetDocumentAttachments(document.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 1 || attachments[0].Filename != "invoice.pdf" || attachments[0].Filetype != archive.PDF {
+		t.Errorf("expected the attachment as its own document, got %v", attachments)
+	}
+}
+
+func TestEmailWithLongBodyUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	// Only the beginning is read to find the headers
+	body := strings.Repeat("A very long letter. ", 50000)
+	document := a.upload(t, "letter.eml", emailWithAttachment("Letter", body, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf")), archive.FolderRootID, "alice")
+	if document.Filetype != archive.EML {
+		t.Errorf("expected an email, got %s", document.Filetype)
+	}
+}
+
+func TestMailboxUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	var mailbox bytes.Buffer
+	for _, subject := range []string{"First", "Second"} {
+		mailbox.WriteString("From bob@example.com Mon Jan  2 15:04:05 2006\n")
+		mailbox.Write(emailWithAttachment(subject, "Message "+subject, subject+".pdf", testFile(t, "mock_pdfs/invoice_0003.pdf")))
+		mailbox.WriteString("\n")
+	}
+
+	err := a.UploadDocument("archive.mbox", uint64(mailbox.Len()), archive.FolderRootID, "alice", bytes.NewReader(mailbox.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	documents, err := a.documents.FindAllByFolderID(archive.FolderRootID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, document := range documents {
+		if document.Filetype == archive.MBOX {
+			t.Error("expected the mailbox itself not to be kept")
+		}
+		if document.Filetype == archive.EML {
+			titles = append(titles, document.Title)
+		}
+	}
+	slices.Sort(titles)
+	if !slices.Equal(titles, []string{"First", "Second"}) {
+		t.Errorf("expected every message as its own email, got %v", titles)
+	}
+}
+
+func TestPlainTextUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	content := []byte("Just some notes\nwithout any headers\n")
+	err := a.UploadDocument("notes.txt", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content))
+	if !errors.Is(err, archive.ErrUnsupportedFiletype) {
+		t.Errorf("expected text without email headers to be rejected, got %v", err)
+	}
+}
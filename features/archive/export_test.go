@@ -0,0 +1,19 @@
+package archive
+
+// DocumentPermission returns the access of the user to a document.
+func (a *Archive) DocumentPermission(documentID string, user string) (Permission, error) {
+	document, err := a.documents.repository.FindByID(documentID)
+	if err != nil {
+		return PermissionNone, err
+	}
+	return a.permissions.documentPermission(document, user)
+}
+
+// FolderPermission returns the access of the user to a folder.
+func (a *Archive) FolderPermission(folderID string, user string) (Permission, error) {
+	hierarchy, err := a.folders.repository.GetHierarchy(folderID)
+	if err != nil {
+		return PermissionNone, err
+	}
+	return a.permissions.folderPermission(hierarchy, user)
+}
@@ -0,0 +1,264 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrCustomFieldNotFound     = errors.New("custom field not found")
+	ErrCustomFieldExists       = errors.New("custom field already exists")
+	ErrInvalidCustomField      = errors.New("invalid custom field")
+	ErrInvalidCustomFieldValue = errors.New("invalid custom field value")
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldTypeString   CustomFieldType = "string"
+	CustomFieldTypeNumber   CustomFieldType = "number"
+	CustomFieldTypeDate     CustomFieldType = "date"
+	CustomFieldTypeMonetary CustomFieldType = "monetary"
+	CustomFieldTypeBoolean  CustomFieldType = "boolean"
+	CustomFieldTypeSelect   CustomFieldType = "select"
+)
+
+var CustomFieldTypes = []CustomFieldType{
+	CustomFieldTypeString,
+	CustomFieldTypeNumber,
+	CustomFieldTypeDate,
+	CustomFieldTypeMonetary,
+	CustomFieldTypeBoolean,
+	CustomFieldTypeSelect,
+}
+
+const CustomFieldDateLayout = "2006-01-02"
+
+func (fieldType CustomFieldType) IsValid() bool {
+	return slices.Contains(CustomFieldTypes, fieldType)
+}
+
+// IsNumeric reports whether values of the type compare as numbers instead of text.
+func (fieldType CustomFieldType) IsNumeric() bool {
+	return fieldType == CustomFieldTypeNumber || fieldType == CustomFieldTypeMonetary
+}
+
+// IsRange reports whether values of the type can be filtered by a lower and upper bound.
+// Dates are stored as ISO dates, so they compare correctly as text.
+func (fieldType CustomFieldType) IsRange() bool {
+	return fieldType.IsNumeric() || fieldType == CustomFieldTypeDate
+}
+
+// CustomField is a user defined metadata field. Its values are stored per document in Document.CustomFields.
+type CustomField struct {
+	ID      string
+	Name    string
+	Type    CustomFieldType
+	Options []string
+	Owner   string
+}
+
+// Normalize validates a raw value against the field type and returns its canonical form.
+// An empty value stays empty, it means the field is not set.
+func (field CustomField) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+
+	invalid := fmt.Errorf("%w for %s: %q", ErrInvalidCustomFieldValue, field.Name, value)
+	switch field.Type {
+	case CustomFieldTypeString:
+		return value, nil
+	case CustomFieldTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", invalid
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case CustomFieldTypeMonetary:
+		// Amounts are often typed with a decimal comma
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+		if err != nil {
+			return "", invalid
+		}
+		return strconv.FormatFloat(amount, 'f', 2, 64), nil
+	case CustomFieldTypeDate:
+		date, err := time.Parse(CustomFieldDateLayout, value)
+		if err != nil {
+			return "", invalid
+		}
+		return date.Format(CustomFieldDateLayout), nil
+	case CustomFieldTypeBoolean:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", invalid
+		}
+		return strconv.FormatBool(boolean), nil
+	case CustomFieldTypeSelect:
+		if !slices.Contains(field.Options, value) {
+			return "", invalid
+		}
+		return value, nil
+	default:
+		return "", invalid
+	}
+}
+
+type CustomFieldRepository interface {
+	Save(field CustomField) error
+	FindByID(id string) (CustomField, error)
+	FindAllByOwner(owner string) ([]CustomField, error)
+	DeleteByID(id string) error
+}
+
+type customFields struct {
+	repository CustomFieldRepository
+	documents  *documents
+}
+
+func (c *customFields) CreateCustomField(name string, fieldType CustomFieldType, options []string, owner string) (CustomField, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || !fieldType.IsValid() {
+		return CustomField{}, ErrInvalidCustomField
+	}
+
+	var cleanedOptions []string
+	if fieldType == CustomFieldTypeSelect {
+		for _, option := range options {
+			option = strings.TrimSpace(option)
+			if option != "" && !slices.Contains(cleanedOptions, option) {
+				cleanedOptions = append(cleanedOptions, option)
+			}
+		}
+		if len(cleanedOptions) == 0 {
+			return CustomField{}, fmt.Errorf("%w: select fields need at least one option", ErrInvalidCustomField)
+		}
+	}
+
+	existing, err := c.repository.FindAllByOwner(owner)
+	if err != nil {
+		return CustomField{}, err
+	}
+	if slices.ContainsFunc(existing, func(field CustomField) bool { return strings.EqualFold(field.Name, name) }) {
+		return CustomField{}, ErrCustomFieldExists
+	}
+
+	field := CustomField{
+		ID:      common.GenerateID(),
+		Name:    name,
+		Type:    fieldType,
+		Options: cleanedOptions,
+		Owner:   owner,
+	}
+	return field, c.repository.Save(field)
+}
+
+func (c *customFields) GetCustomField(id string, owner string) (CustomField, error) {
+	field, err := c.repository.FindByID(id)
+	if err != nil {
+		return CustomField{}, err
+	}
+
+	if field.Owner != owner {
+		return CustomField{}, ErrNotAllowed
+	}
+
+	return field, nil
+}
+
+func (c *customFields) GetCustomFields(owner string) ([]CustomField, error) {
+	fields, err := c.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(fields, func(f1, f2 CustomField) int {
+		return strings.Compare(strings.ToLower(f1.Name), strings.ToLower(f2.Name))
+	})
+	return fields, nil
+}
+
+// DeleteCustomField removes the field from the schema, its values on documents are removed with it.
+// The documents that had a value are republished, so the value is no longer found by the search.
+func (c *customFields) DeleteCustomField(id string, owner string) error {
+	field, err := c.GetCustomField(id, owner)
+	if err != nil {
+		return err
+	}
+
+	documents, err := c.documents.repository.FindAllByCustomFieldID(field.ID)
+	if err != nil {
+		return err
+	}
+
+	err = c.repository.DeleteByID(field.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		document, err := c.documents.repository.FindByID(document.ID)
+		if err != nil {
+			return err
+		}
+
+		err = c.documents.messages.PublishDocumentUpserted(document)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateDocumentCustomFields sets the custom field values of a document, keyed by field id.
+// Fields missing from values are left untouched, empty values unset the field. The fields must belong to the owner of the document.
+func (c *customFields) UpdateDocumentCustomFields(documentID string, user string, values map[string]string) error {
+	document, err := c.documents.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+
+	if document.CustomFields == nil {
+		document.CustomFields = make(map[string]string)
+	}
+
+	for fieldID, value := range values {
+		field, err := c.GetCustomField(fieldID, document.Owner)
+		if err != nil {
+			return err
+		}
+
+		normalized, err := field.Normalize(value)
+		if err != nil {
+			return err
+		}
+
+		if normalized == "" {
+			delete(document.CustomFields, field.ID)
+		} else {
+			document.CustomFields[field.ID] = normalized
+		}
+	}
+
+	document.UpdatedAt = time.Now()
+	err = c.documents.repository.Save(document)
+	if err != nil {
+		return err
+	}
+
+	return c.documents.messages.PublishDocumentUpserted(document)
+}
+
+func newCustomFields(repository CustomFieldRepository, documents *documents) *customFields {
+	return &customFields{
+		repository: repository,
+		documents:  documents,
+	}
+}
@@ -0,0 +1,116 @@
+package archive_test
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestCustomFieldNormalize(t *testing.T) {
+	tests := []struct {
+		fieldType archive.CustomFieldType
+		value     string
+		expected  string
+		valid     bool
+	}{
+		{archive.CustomFieldTypeString, " Contract 42 ", "Contract 42", true},
+		{archive.CustomFieldTypeNumber, "1.50", "1.5", true},
+		{archive.CustomFieldTypeNumber, "one", "", false},
+		{archive.CustomFieldTypeMonetary, "12,5", "12.50", true},
+		{archive.CustomFieldTypeMonetary, "12 EUR", "", false},
+		{archive.CustomFieldTypeDate, "2024-02-29", "2024-02-29", true},
+		{archive.CustomFieldTypeDate, "2023-02-29", "", false},
+		{archive.CustomFieldTypeDate, "29.02.2024", "", false},
+		{archive.CustomFieldTypeBoolean, "1", "true", true},
+		{archive.CustomFieldTypeBoolean, "yes", "", false},
+		{archive.CustomFieldTypeSelect, "Paid", "Paid", true},
+		{archive.CustomFieldTypeSelect, "paid", "", false},
+		{archive.CustomFieldTypeNumber, " ", "", true},
+	}
+	for _, test := range tests {
+		field := archive.CustomField{Name: "Field", Type: test.fieldType, Options: []string{"Open", "Paid"}}
+		normalized, err := field.Normalize(test.value)
+		if test.valid && (err != nil || normalized != test.expected) {
+			t.Errorf("expected %s %q to become %q, got %q, %v", test.fieldType, test.value, test.expected, normalized, err)
+		}
+		if !test.valid && !errors.Is(err, archive.ErrInvalidCustomFieldValue) {
+			t.Errorf("expected %s %q to be invalid, got %q", test.fieldType, test.value, normalized)
+		}
+	}
+}
+
+func TestCreateCustomField(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	status, err := a.CreateCustomField("Status", archive.CustomFieldTypeSelect, []string{" Open", "Paid", "", "Open"}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(status.Options, []string{"Open", "Paid"}) {
+		t.Errorf("expected trimmed options without duplicates, got %v", status.Options)
+	}
+
+	if _, err := a.CreateCustomField("status", archive.CustomFieldTypeString, nil, "alice"); !errors.Is(err, archive.ErrCustomFieldExists) {
+		t.Errorf("expected names to be unique regardless of case, got %v", err)
+	}
+	if _, err := a.CreateCustomField("Priority", archive.CustomFieldTypeSelect, []string{" "}, "alice"); !errors.Is(err, archive.ErrInvalidCustomField) {
+		t.Errorf("expected select fields without options to be rejected, got %v", err)
+	}
+	if _, err := a.CreateCustomField("Color", "color", nil, "alice"); !errors.Is(err, archive.ErrInvalidCustomField) {
+		t.Errorf("expected unknown types to be rejected, got %v", err)
+	}
+}
+
+func TestUpdateDocumentCustomFields(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	amount, err := a.CreateCustomField("Amount", archive.CustomFieldTypeMonetary, nil, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	due, err := a.CreateCustomField("Due", archive.CustomFieldTypeDate, nil, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := a.CreateCustomField("Amount", archive.CustomFieldTypeNumber, nil, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = a.UpdateDocumentCustomFields(document.ID, "alice", map[string]string{amount.ID: "99,9", due.ID: "2025-01-31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{amount.ID: "99.90", due.ID: "2025-01-31"}
+	if values := a.document(t, document.ID).CustomFields; !maps.Equal(values, expected) {
+		t.Errorf("expected normalized values, got %v", values)
+	}
+
+	err = a.UpdateDocumentCustomFields(document.ID, "alice", map[string]string{amount.ID: "5", due.ID: "tomorrow"})
+	if !errors.Is(err, archive.ErrInvalidCustomFieldValue) {
+		t.Errorf("expected an invalid date to be rejected, got %v", err)
+	}
+	err = a.UpdateDocumentCustomFields(document.ID, "alice", map[string]string{foreign.ID: "1"})
+	if !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected fields of other users to be rejected, got %v", err)
+	}
+	err = a.UpdateDocumentCustomFields(document.ID, "bob", map[string]string{amount.ID: "1"})
+	if !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected other users not to change the document, got %v", err)
+	}
+	if values := a.document(t, document.ID).CustomFields; !maps.Equal(values, expected) {
+		t.Errorf("expected rejected values to leave the document as it was, got %v", values)
+	}
+
+	// Empty values unset the field, fields left out stay as they are
+	if err := a.UpdateDocumentCustomFields(document.ID, "alice", map[string]string{amount.ID: ""}); err != nil {
+		t.Fatal(err)
+	}
+	if values := a.document(t, document.ID).CustomFields; !maps.Equal(values, map[string]string{due.ID: "2025-01-31"}) {
+		t.Errorf("expected only the amount to be unset, got %v", values)
+	}
+}
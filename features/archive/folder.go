@@ -1,92 +1,196 @@
 package archive
 
 import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
 	"unterlagen/features/administration"
 	"unterlagen/features/common"
 )
 
 var FolderRootID = "root"
 
+var (
+	ErrInvalidFolderName = errors.New("invalid folder name")
+	ErrFolderCycle       = errors.New("folder cannot be moved into itself")
+	ErrFolderTrashed     = errors.New("folder is in the trash")
+)
+
 type Folder struct {
-	ID       string
-	Name     string
-	ParentID string
-	Owner    string
+	ID        string
+	Name      string
+	ParentID  string
+	Owner     string
+	TrashedAt sql.NullTime
+}
+
+func (folder Folder) IsTrashed() bool {
+	return folder.TrashedAt.Valid
 }
 
 type FolderRepository interface {
 	Save(folder Folder) error
 	FindAllByParentID(parentID string) ([]Folder, error)
+	FindAllByOwner(owner string) ([]Folder, error)
 	GetHierarchy(folderID string) ([]Folder, error)
+	FindAllTrashed() ([]Folder, error)
+	DeleteByID(id string) error
 }
 
 type folders struct {
-	repository FolderRepository
+	repository         FolderRepository
+	documentRepository DocumentRepository
+	permissions        *permissions
+	legalHolds         *legalHolds
 }
 
-func (f *folders) CreateFolder(name string, parentID string, owner string) error {
-	return f.create(common.GenerateID(), name, parentID, owner)
+// CreateFolder creates a folder below a folder the user can write to, it belongs to the owner of the parent folder.
+func (f *folders) CreateFolder(name string, parentID string, user string) error {
+	parent, err := f.getFolder(parentID, user, PermissionWrite)
+	if err != nil {
+		return err
+	}
+	if parent.IsTrashed() {
+		return ErrFolderTrashed
+	}
+
+	return f.create(common.GenerateID(), name, parent.ID, parent.Owner)
 }
 
 func (f *folders) CreateRootFolderFor(user administration.User) error {
 	return f.create(FolderRootID, "Root", "", user.Username)
 }
 
+// CreateGroupFolderFor creates the folder of a group below the root folder, all members of the group share it.
+func (f *folders) CreateGroupFolderFor(group administration.Group) error {
+	return f.create(common.GenerateID(), group.Name, FolderRootID, group.Name)
+}
+
 func (f *folders) create(id string, name string, parentID string, owner string) error {
 	folder := Folder{ID: id, Name: name, ParentID: parentID, Owner: owner}
 	return f.repository.Save(folder)
 }
 
-func (f *folders) GetFolderChildren(parentID string, owner string) ([]Folder, error) {
+// ensureFolder returns the child folder with the given name, it is created if it does not exist yet.
+// Like CreateFolder, the user needs to be able to write to the parent folder, whose owner owns the child.
+func (f *folders) ensureFolder(name string, parentID string, user string) (Folder, error) {
+	parent, err := f.getFolder(parentID, user, PermissionWrite)
+	if err != nil {
+		return Folder{}, err
+	}
+	if parent.IsTrashed() {
+		return Folder{}, ErrFolderTrashed
+	}
+
+	children, err := f.GetFolderChildren(parent.ID, user)
+	if err != nil {
+		return Folder{}, err
+	}
+
+	for _, child := range children {
+		if child.Name == name && !child.IsTrashed() {
+			return child, nil
+		}
+	}
+
+	folder := Folder{ID: common.GenerateID(), Name: name, ParentID: parent.ID, Owner: parent.Owner}
+	return folder, f.repository.Save(folder)
+}
+
+// GetFolderChildren returns the subfolders of a folder the user can read. The root folder is shared
+// by all users, only the user's own folders and the folders of the user's groups are listed in it.
+func (f *folders) GetFolderChildren(parentID string, user string) ([]Folder, error) {
+	if parentID != FolderRootID {
+		_, err := f.GetFolder(parentID, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	folders, err := f.repository.FindAllByParentID(parentID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter folders to only those owned by the user
-	var userFolders []Folder
-	for _, folder := range folders {
-		if folder.Owner == owner {
-			userFolders = append(userFolders, folder)
+	if parentID == FolderRootID {
+		groupNames, err := f.permissions.getGroupNames(user)
+		if err != nil {
+			return nil, err
 		}
+		folders = slices.DeleteFunc(folders, func(folder Folder) bool {
+			return folder.Owner != user && !slices.Contains(groupNames, folder.Owner)
+		})
+	}
+	return folders, nil
+}
+
+// GetFolders returns all folders of the owner below the root folder that are not trashed, sorted by name.
+func (f *folders) GetFolders(owner string) ([]Folder, error) {
+	folders, err := f.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
 	}
 
-	return userFolders, nil
+	folders = slices.DeleteFunc(folders, func(folder Folder) bool { return folder.ID == FolderRootID || folder.IsTrashed() })
+	slices.SortFunc(folders, func(f1, f2 Folder) int {
+		return strings.Compare(strings.ToLower(f1.Name), strings.ToLower(f2.Name))
+	})
+	return folders, nil
 }
 
-func (f *folders) GetFolderHierarchy(folderID string, owner string) ([]Folder, error) {
+// GetFolderHierarchy returns the path from the root folder to the folder. Folders of other users
+// the user cannot read are left out, so a shared folder starts right below the root folder.
+func (f *folders) GetFolderHierarchy(folderID string, user string) ([]Folder, error) {
+	root, err := f.GetFolder(FolderRootID, user)
+	if err != nil {
+		return nil, err
+	}
+	if folderID == FolderRootID {
+		return []Folder{root}, nil
+	}
+
 	hierarchy, err := f.repository.GetHierarchy(folderID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Verify that the user owns the target folder (last in hierarchy)
-	if len(hierarchy) > 0 {
-		targetFolder := hierarchy[len(hierarchy)-1]
-		if targetFolder.Owner != owner {
-			return nil, ErrNotAllowed
+	userHierarchy := []Folder{root}
+	for i, folder := range hierarchy {
+		if folder.ID == FolderRootID {
+			continue
 		}
-	}
 
-	// Filter hierarchy to only folders owned by the user
-	var userHierarchy []Folder
-	for _, folder := range hierarchy {
-		if folder.Owner == owner {
+		permission, err := f.permissions.folderPermission(hierarchy[:i+1], user)
+		if err != nil {
+			return nil, err
+		}
+		if permission.Allows(PermissionRead) {
 			userHierarchy = append(userHierarchy, folder)
 		}
 	}
 
+	if len(userHierarchy) == 1 {
+		return nil, ErrNotAllowed
+	}
 	return userHierarchy, nil
 }
 
-func (f *folders) GetFolder(folderID string, owner string) (Folder, error) {
+// GetFolder returns a folder the user can read.
+func (f *folders) GetFolder(folderID string, user string) (Folder, error) {
+	return f.getFolder(folderID, user, PermissionRead)
+}
+
+// getFolder returns a folder the user has the required permission for.
+func (f *folders) getFolder(folderID string, user string, required Permission) (Folder, error) {
 	// Special case for root folder - construct it dynamically
 	if folderID == FolderRootID {
 		return Folder{
 			ID:       FolderRootID,
 			Name:     "Root",
 			ParentID: "",
-			Owner:    owner,
+			Owner:    user,
 		}, nil
 	}
 
@@ -101,19 +205,211 @@ func (f *folders) GetFolder(folderID string, owner string) (Folder, error) {
 		return Folder{}, ErrNotAllowed
 	}
 
-	targetFolder := hierarchy[len(hierarchy)-1]
-	if targetFolder.Owner != owner {
+	permission, err := f.permissions.folderPermission(hierarchy, user)
+	if err != nil {
+		return Folder{}, err
+	}
+	if !permission.Allows(required) {
 		return Folder{}, ErrNotAllowed
 	}
 
-	return targetFolder, nil
+	return hierarchy[len(hierarchy)-1], nil
+}
+
+func (f *folders) RenameFolder(folderID string, name string, user string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrInvalidFolderName
+	}
+
+	folder, err := f.getMutableFolder(folderID, user)
+	if err != nil {
+		return err
+	}
+
+	folder.Name = name
+	return f.repository.Save(folder)
+}
+
+// MoveFolder moves a folder with its subfolders and documents below another folder of its owner.
+// Only the owner can move a folder into the root folder.
+func (f *folders) MoveFolder(folderID string, parentID string, user string) error {
+	folder, err := f.getMutableFolder(folderID, user)
+	if err != nil {
+		return err
+	}
+
+	parent, err := f.getFolder(parentID, user, PermissionWrite)
+	if err != nil {
+		return err
+	}
+	if parent.Owner != folder.Owner {
+		return ErrNotAllowed
+	}
+	if parent.IsTrashed() {
+		return ErrFolderTrashed
+	}
+
+	// The new parent must not be the folder itself or one of its subfolders
+	hierarchy, err := f.repository.GetHierarchy(parent.ID)
+	if err != nil {
+		return err
+	}
+	if slices.ContainsFunc(hierarchy, func(ancestor Folder) bool { return ancestor.ID == folder.ID }) {
+		return ErrFolderCycle
+	}
+
+	folder.ParentID = parent.ID
+	return f.repository.Save(folder)
+}
+
+// TrashFolder trashes a folder with all of its subfolders and documents. Everything is trashed at the
+// same time, so restoring the folder leaves subfolders and documents alone that were trashed before.
+func (f *folders) TrashFolder(folderID string, user string) error {
+	folder, err := f.getMutableFolder(folderID, user)
+	if err != nil {
+		return err
+	}
+	if folder.IsTrashed() {
+		return nil
+	}
+
+	err = f.legalHolds.checkFolder(folder)
+	if err != nil {
+		return err
+	}
+
+	trashedAt := sql.NullTime{Time: time.Now().Truncate(time.Second), Valid: true}
+	return f.walkSubtree(folder, func(folder Folder, documents []Document) error {
+		for _, document := range documents {
+			if !document.IsTrashed() {
+				document.TrashedAt = trashedAt
+				err := f.documentRepository.Save(document)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if !folder.IsTrashed() {
+			folder.TrashedAt = trashedAt
+			return f.repository.Save(folder)
+		}
+		return nil
+	})
+}
+
+// RestoreFolder restores a folder with the subfolders and documents that were trashed along with it.
+// Folders whose parent is still trashed are restored into the root folder.
+func (f *folders) RestoreFolder(folderID string, user string) error {
+	folder, err := f.getMutableFolder(folderID, user)
+	if err != nil {
+		return err
+	}
+	if !folder.IsTrashed() {
+		return nil
+	}
+
+	parent, err := f.GetFolder(folder.ParentID, folder.Owner)
+	if err != nil || parent.IsTrashed() {
+		folder.ParentID = FolderRootID
+	}
+
+	trashedAt := folder.TrashedAt.Time
+	return f.walkSubtree(folder, func(subfolder Folder, documents []Document) error {
+		if subfolder.ID != folder.ID && !subfolder.TrashedAt.Time.Equal(trashedAt) {
+			return nil
+		}
+
+		for _, document := range documents {
+			if document.TrashedAt.Valid && document.TrashedAt.Time.Equal(trashedAt) {
+				document.TrashedAt.Valid = false
+				err := f.documentRepository.Save(document)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if subfolder.ID == folder.ID {
+			subfolder.ParentID = folder.ParentID
+		}
+		subfolder.TrashedAt.Valid = false
+		return f.repository.Save(subfolder)
+	})
+}
+
+// getMutableFolder returns a folder the user can write to that may be changed, the root folder may not.
+func (f *folders) getMutableFolder(folderID string, user string) (Folder, error) {
+	if folderID == FolderRootID {
+		return Folder{}, ErrNotAllowed
+	}
+
+	return f.getFolder(folderID, user, PermissionWrite)
+}
+
+// walkSubtree visits the folder and all of its subfolders with their documents, parents before their children.
+func (f *folders) walkSubtree(folder Folder, visit func(folder Folder, documents []Document) error) error {
+	documents, err := f.documentRepository.FindAllByFolderID(folder.ID)
+	if err != nil {
+		return err
+	}
+
+	err = visit(folder, documents)
+	if err != nil {
+		return err
+	}
+
+	// Subfolders always belong to the owner of their parent
+	children, err := f.repository.FindAllByParentID(folder.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		err := f.walkSubtree(child, visit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteIfEmpty deletes a trashed folder once its documents and subfolders were deleted by the trash emptying,
+// it reports whether the folder was deleted. Held folders are kept.
+func (f *folders) deleteIfEmpty(folder Folder) (bool, error) {
+	err := f.legalHolds.checkFolder(folder)
+	if errors.Is(err, ErrLegalHold) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	documents, err := f.documentRepository.FindAllByFolderID(folder.ID)
+	if err != nil {
+		return false, err
+	}
+	children, err := f.repository.FindAllByParentID(folder.ID)
+	if err != nil {
+		return false, err
+	}
+	if len(documents) > 0 || len(children) > 0 {
+		return false, nil
+	}
+
+	return true, f.repository.DeleteByID(folder.ID)
 }
 
-func newFolders(repository FolderRepository, userMessages administration.UserMessages) *folders {
+func newFolders(repository FolderRepository, documentRepository DocumentRepository, permissions *permissions, legalHolds *legalHolds, userMessages administration.UserMessages, groupMessages administration.GroupMessages) *folders {
 	folders := &folders{
-		repository: repository,
+		repository:         repository,
+		documentRepository: documentRepository,
+		permissions:        permissions,
+		legalHolds:         legalHolds,
 	}
 
 	userMessages.SubscribeUserCreated(folders.CreateRootFolderFor)
+	groupMessages.SubscribeGroupCreated(folders.CreateGroupFolderFor)
 	return folders
 }
@@ -0,0 +1,132 @@
+package archive_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+// folder returns the current state of a folder.
+func (a *testArchive) folder(t *testing.T, id string) archive.Folder {
+	t.Helper()
+	hierarchy, err := a.folders.GetHierarchy(id)
+	if err != nil || len(hierarchy) == 0 || hierarchy[len(hierarchy)-1].ID != id {
+		t.Fatalf("expected folder %s, got %v: %v", id, hierarchy, err)
+	}
+	return hierarchy[len(hierarchy)-1]
+}
+
+func TestMoveFolderCycle(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	year := a.createFolder(t, "2025", taxes.ID, "alice")
+	receipts := a.createFolder(t, "Receipts", year.ID, "alice")
+
+	for _, parent := range []archive.Folder{taxes, year, receipts} {
+		if err := a.MoveFolder(taxes.ID, parent.ID, "alice"); !errors.Is(err, archive.ErrFolderCycle) {
+			t.Errorf("expected moving Taxes below %s to be rejected, got %v", parent.Name, err)
+		}
+	}
+
+	if err := a.MoveFolder(receipts.ID, archive.FolderRootID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.MoveFolder(taxes.ID, receipts.ID, "alice"); err != nil {
+		t.Errorf("expected Taxes to move below the former subfolder, got %v", err)
+	}
+	if moved := a.folder(t, taxes.ID); moved.ParentID != receipts.ID {
+		t.Errorf("expected Taxes below Receipts, got %s", moved.ParentID)
+	}
+}
+
+func TestMoveFolderOfOtherUser(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	inbox := a.createFolder(t, "Inbox", archive.FolderRootID, "bob")
+	if _, err := a.ShareFolder(inbox.ID, "alice", archive.PermissionWrite, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.MoveFolder(taxes.ID, inbox.ID, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected folders not to move to other owners, got %v", err)
+	}
+	if err := a.MoveFolder(taxes.ID, archive.FolderRootID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected folders of other users not to be moved, got %v", err)
+	}
+}
+
+func TestTrashAndRestoreFolderTree(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	year := a.createFolder(t, "2025", taxes.ID, "alice")
+	invoice := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), taxes.ID, "alice")
+	receipt := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), year.ID, "alice")
+	discarded := a.upload(t, "draft.pdf", testFile(t, "mock_pdfs/invoice_0003.pdf"), year.ID, "alice")
+
+	// The draft was trashed on its own before
+	if err := a.TrashDocument(discarded.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	draft := a.document(t, discarded.ID)
+	draft.TrashedAt.Time = draft.TrashedAt.Time.Add(-time.Hour)
+	if err := a.documents.Save(draft); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.TrashFolder(taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []archive.Folder{taxes, year} {
+		if !a.folder(t, folder.ID).IsTrashed() {
+			t.Errorf("expected folder %s to be trashed", folder.Name)
+		}
+	}
+	for _, document := range []archive.Document{invoice, receipt} {
+		if !a.document(t, document.ID).IsTrashed() {
+			t.Errorf("expected document %s to be trashed", document.Filename)
+		}
+	}
+
+	if err := a.RestoreFolder(taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, folder := range []archive.Folder{taxes, year} {
+		if a.folder(t, folder.ID).IsTrashed() {
+			t.Errorf("expected folder %s to be restored", folder.Name)
+		}
+	}
+	for _, document := range []archive.Document{invoice, receipt} {
+		if a.document(t, document.ID).IsTrashed() {
+			t.Errorf("expected document %s to be restored", document.Filename)
+		}
+	}
+	if !a.document(t, discarded.ID).IsTrashed() {
+		t.Error("expected the draft trashed before to stay in the trash")
+	}
+}
+
+func TestRestoreFolderOfTrashedParent(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	year := a.createFolder(t, "2025", taxes.ID, "alice")
+
+	if err := a.TrashFolder(taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.RestoreFolder(year.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := a.folder(t, year.ID)
+	if restored.IsTrashed() || restored.ParentID != archive.FolderRootID {
+		t.Errorf("expected the folder to be restored into the root folder, got parent %s", restored.ParentID)
+	}
+	if !a.folder(t, taxes.ID).IsTrashed() {
+		t.Error("expected the parent to stay in the trash")
+	}
+}
@@ -0,0 +1,71 @@
+package archive_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"unterlagen/features/administration"
+	"unterlagen/features/archive"
+)
+
+func TestCreateGroup(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	a.createGroup(t, "family", "alice")
+
+	for _, name := range []string{"alice", "family"} {
+		if err := a.administration.CreateGroup(name); !errors.Is(err, administration.ErrGroupExists) {
+			t.Errorf("expected the name %s to be taken, got %v", name, err)
+		}
+	}
+	if err := a.administration.CreateGroup(" "); !errors.Is(err, administration.ErrInvalidGroupName) {
+		t.Errorf("expected an empty name to be rejected, got %v", err)
+	}
+
+	// Groups are not members of groups
+	for _, member := range []string{"family", "unknown"} {
+		if err := a.administration.AddGroupMember("family", member); !errors.Is(err, administration.ErrMemberNotFound) {
+			t.Errorf("expected %s not to become a member, got %v", member, err)
+		}
+	}
+}
+
+func TestGroupFolder(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	family := a.createGroup(t, "family", "alice", "bob")
+
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), family.ID, "alice")
+	if document.Owner != "family" {
+		t.Errorf("expected documents in the group folder to be owned by the group, got %s", document.Owner)
+	}
+	if _, err := a.GetDocument(document.ID, "bob"); err != nil {
+		t.Errorf("expected members to see the documents of the group, got %v", err)
+	}
+
+	children, err := a.GetFolderChildren(archive.FolderRootID, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(children, func(folder archive.Folder) bool { return folder.ID == family.ID }) {
+		t.Errorf("expected the group folder in the root folder of members, got %v", children)
+	}
+
+	// Removed members lose access right away
+	if err := a.administration.RemoveGroupMember("family", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	children, err = a.GetFolderChildren(archive.FolderRootID, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.ContainsFunc(children, func(folder archive.Folder) bool { return folder.ID == family.ID }) {
+		t.Error("expected the group folder to be hidden from former members")
+	}
+	if _, err := a.GetDocument(document.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected former members not to see the documents of the group, got %v", err)
+	}
+	if _, err := a.GetDocument(document.ID, "alice"); err != nil {
+		t.Errorf("expected remaining members to keep access, got %v", err)
+	}
+}
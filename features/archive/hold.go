@@ -0,0 +1,315 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrLegalHold         = errors.New("under legal hold")
+	ErrLegalHoldNotFound = errors.New("legal hold not found")
+	ErrInvalidLegalHold  = errors.New("invalid legal hold")
+)
+
+// LegalHold freezes a document or a folder with all of its subfolders and documents, they cannot be
+// trashed, purged or get a new version until the hold is released. Exactly one of DocumentID and FolderID is set.
+type LegalHold struct {
+	ID         string
+	DocumentID string
+	FolderID   string
+	Reason     string
+	// Actor is the user who placed the hold
+	Actor     string
+	CreatedAt time.Time
+	// Subject is the title of the held document or the name of the held folder and Owner the owner of it,
+	// both are only filled in by GetLegalHolds
+	Subject string
+	Owner   string
+}
+
+type LegalHoldRepository interface {
+	Save(hold LegalHold) error
+	FindByID(id string) (LegalHold, error)
+	FindAll() ([]LegalHold, error)
+	FindAllByDocumentID(documentID string) ([]LegalHold, error)
+	FindAllByFolderIDIn(folderIDs []string) ([]LegalHold, error)
+	DeleteByID(id string) error
+}
+
+type legalHolds struct {
+	repository         LegalHoldRepository
+	documentRepository DocumentRepository
+	folderRepository   FolderRepository
+	permissions        *permissions
+}
+
+// PlaceDocumentLegalHold freezes a document the actor can change.
+func (l *legalHolds) PlaceDocumentLegalHold(documentID string, reason string, actor string) (LegalHold, error) {
+	document, err := l.documentRepository.FindByID(documentID)
+	if err != nil {
+		return LegalHold{}, err
+	}
+
+	permission, err := l.permissions.documentPermission(document, actor)
+	if err != nil {
+		return LegalHold{}, err
+	}
+	if !permission.Allows(PermissionWrite) {
+		return LegalHold{}, ErrNotAllowed
+	}
+
+	return l.place(LegalHold{DocumentID: document.ID}, reason, actor)
+}
+
+// PlaceFolderLegalHold freezes a folder the actor can change with all of its subfolders and documents.
+func (l *legalHolds) PlaceFolderLegalHold(folderID string, reason string, actor string) (LegalHold, error) {
+	if folderID == FolderRootID {
+		return LegalHold{}, ErrNotAllowed
+	}
+
+	hierarchy, err := l.folderRepository.GetHierarchy(folderID)
+	if err != nil {
+		return LegalHold{}, err
+	}
+
+	permission, err := l.permissions.folderPermission(hierarchy, actor)
+	if err != nil {
+		return LegalHold{}, err
+	}
+	if !permission.Allows(PermissionWrite) {
+		return LegalHold{}, ErrNotAllowed
+	}
+
+	return l.place(LegalHold{FolderID: hierarchy[len(hierarchy)-1].ID}, reason, actor)
+}
+
+func (l *legalHolds) place(hold LegalHold, reason string, actor string) (LegalHold, error) {
+	hold.Reason = strings.TrimSpace(reason)
+	if hold.Reason == "" {
+		return LegalHold{}, fmt.Errorf("%w: a legal hold needs a reason", ErrInvalidLegalHold)
+	}
+
+	hold.ID = common.GenerateID()
+	hold.Actor = actor
+	hold.CreatedAt = time.Now()
+	return hold, l.repository.Save(hold)
+}
+
+// ReleaseLegalHold lifts a hold, only the user who placed it can do so.
+func (l *legalHolds) ReleaseLegalHold(holdID string, actor string) error {
+	hold, err := l.repository.FindByID(holdID)
+	if err != nil {
+		return err
+	}
+	if hold.Actor != actor {
+		return ErrNotAllowed
+	}
+
+	return l.repository.DeleteByID(hold.ID)
+}
+
+// ReleaseAnyLegalHold lifts a hold regardless of who placed it. It is meant for administrators,
+// callers have to check the role of the user.
+func (l *legalHolds) ReleaseAnyLegalHold(holdID string) error {
+	hold, err := l.repository.FindByID(holdID)
+	if err != nil {
+		return err
+	}
+
+	return l.repository.DeleteByID(hold.ID)
+}
+
+// GetLegalHolds returns all holds of all users, newest first. It is meant for administrators.
+func (l *legalHolds) GetLegalHolds() ([]LegalHold, error) {
+	holds, err := l.repository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, hold := range holds {
+		if hold.DocumentID != "" {
+			document, err := l.documentRepository.FindByID(hold.DocumentID)
+			if err != nil {
+				return nil, err
+			}
+			holds[i].Subject = document.Title
+			holds[i].Owner = document.Owner
+			continue
+		}
+
+		hierarchy, err := l.folderRepository.GetHierarchy(hold.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		holds[i].Subject = hierarchy[len(hierarchy)-1].Name
+		holds[i].Owner = hierarchy[len(hierarchy)-1].Owner
+	}
+
+	slices.SortFunc(holds, func(h1, h2 LegalHold) int {
+		return h2.CreatedAt.Compare(h1.CreatedAt)
+	})
+	return holds, nil
+}
+
+// GetDocumentLegalHolds returns the holds on a document the user can read and on the folders it is in.
+func (l *legalHolds) GetDocumentLegalHolds(documentID string, user string) ([]LegalHold, error) {
+	document, err := l.documentRepository.FindByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	permission, err := l.permissions.documentPermission(document, user)
+	if err != nil {
+		return nil, err
+	}
+	if !permission.Allows(PermissionRead) {
+		return nil, ErrNotAllowed
+	}
+
+	return l.documentHolds(document)
+}
+
+// GetFolderLegalHolds returns the holds on a folder the user can read and on the folders above it.
+func (l *legalHolds) GetFolderLegalHolds(folderID string, user string) ([]LegalHold, error) {
+	if folderID == FolderRootID {
+		return nil, nil
+	}
+
+	hierarchy, err := l.folderRepository.GetHierarchy(folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	permission, err := l.permissions.folderPermission(hierarchy, user)
+	if err != nil {
+		return nil, err
+	}
+	if !permission.Allows(PermissionRead) {
+		return nil, ErrNotAllowed
+	}
+
+	return l.repository.FindAllByFolderIDIn(folderIDs(hierarchy))
+}
+
+// checkDocument fails with ErrLegalHold if the document or one of its folders is held.
+func (l *legalHolds) checkDocument(document Document) error {
+	holds, err := l.documentHolds(document)
+	if err != nil {
+		return err
+	}
+
+	return holdError(holds)
+}
+
+// checkFolder fails with ErrLegalHold if the folder, a folder above it or anything below it is held.
+func (l *legalHolds) checkFolder(folder Folder) error {
+	hierarchy, err := l.folderRepository.GetHierarchy(folder.ID)
+	if err != nil {
+		return err
+	}
+
+	holds, err := l.repository.FindAllByFolderIDIn(folderIDs(hierarchy))
+	if err != nil {
+		return err
+	}
+	err = holdError(holds)
+	if err != nil {
+		return err
+	}
+
+	return l.checkSubtree(folder.ID)
+}
+
+func (l *legalHolds) checkSubtree(folderID string) error {
+	documents, err := l.documentRepository.FindAllByFolderID(folderID)
+	if err != nil {
+		return err
+	}
+	for _, document := range documents {
+		holds, err := l.repository.FindAllByDocumentID(document.ID)
+		if err != nil {
+			return err
+		}
+		err = holdError(holds)
+		if err != nil {
+			return err
+		}
+	}
+
+	children, err := l.folderRepository.FindAllByParentID(folderID)
+	if err != nil {
+		return err
+	}
+	childIDs := folderIDs(children)
+	if len(childIDs) == 0 {
+		return nil
+	}
+
+	holds, err := l.repository.FindAllByFolderIDIn(childIDs)
+	if err != nil {
+		return err
+	}
+	err = holdError(holds)
+	if err != nil {
+		return err
+	}
+
+	for _, childID := range childIDs {
+		err := l.checkSubtree(childID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *legalHolds) documentHolds(document Document) ([]LegalHold, error) {
+	holds, err := l.repository.FindAllByDocumentID(document.ID)
+	if err != nil {
+		return nil, err
+	}
+	if document.FolderID == FolderRootID {
+		return holds, nil
+	}
+
+	hierarchy, err := l.folderRepository.GetHierarchy(document.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	folderHolds, err := l.repository.FindAllByFolderIDIn(folderIDs(hierarchy))
+	if err != nil {
+		return nil, err
+	}
+	return append(holds, folderHolds...), nil
+}
+
+func holdError(holds []LegalHold) error {
+	if len(holds) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrLegalHold, holds[0].Reason)
+}
+
+func folderIDs(folders []Folder) []string {
+	ids := make([]string, 0, len(folders))
+	for _, folder := range folders {
+		if folder.ID != FolderRootID {
+			ids = append(ids, folder.ID)
+		}
+	}
+	return ids
+}
+
+func newLegalHolds(repository LegalHoldRepository, documentRepository DocumentRepository, folderRepository FolderRepository, permissions *permissions) *legalHolds {
+	return &legalHolds{
+		repository:         repository,
+		documentRepository: documentRepository,
+		folderRepository:   folderRepository,
+		permissions:        permissions,
+	}
+}
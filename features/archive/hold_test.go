@@ -0,0 +1,131 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestPlaceLegalHold(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	if _, err := a.ShareFolder(taxes.ID, "bob", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), taxes.ID, "alice")
+
+	if _, err := a.PlaceDocumentLegalHold(document.ID, " ", "alice"); !errors.Is(err, archive.ErrInvalidLegalHold) {
+		t.Errorf("expected a hold without a reason to be rejected, got %v", err)
+	}
+	if _, err := a.PlaceFolderLegalHold(archive.FolderRootID, "Audit", "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the root folder not to be held, got %v", err)
+	}
+	if _, err := a.PlaceDocumentLegalHold(document.ID, "Audit", "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected readers not to place holds, got %v", err)
+	}
+
+	hold, err := a.PlaceFolderLegalHold(taxes.ID, "Audit", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holds, err := a.GetDocumentLegalHolds(document.ID, "bob"); err != nil || len(holds) != 1 || holds[0].ID != hold.ID {
+		t.Errorf("expected the hold of the folder to apply to its documents, got %v: %v", holds, err)
+	}
+}
+
+func TestLegalHoldRefusesTrash(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	year := a.createFolder(t, "2025", taxes.ID, "alice")
+	receipts := a.createFolder(t, "Receipts", year.ID, "alice")
+	invoice := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), year.ID, "alice")
+	receipt := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), receipts.ID, "alice")
+
+	documentHold, err := a.PlaceDocumentLegalHold(invoice.ID, "Audit", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	folderHold, err := a.PlaceFolderLegalHold(receipts.ID, "Audit", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Holds reach the documents of held folders and the folders above anything held
+	if err := a.TrashDocument(invoice.ID, "alice"); !errors.Is(err, archive.ErrLegalHold) {
+		t.Errorf("expected the held document not to be trashed, got %v", err)
+	}
+	if err := a.TrashDocument(receipt.ID, "alice"); !errors.Is(err, archive.ErrLegalHold) {
+		t.Errorf("expected documents of held folders not to be trashed, got %v", err)
+	}
+	if err := a.TrashFolder(taxes.ID, "alice"); !errors.Is(err, archive.ErrLegalHold) {
+		t.Errorf("expected folders above held folders not to be trashed, got %v", err)
+	}
+	content := testFile(t, "mock_pdfs/invoice_0003.pdf")
+	err = a.ReplaceDocumentFile(invoice.ID, "alice", "invoice.pdf", uint64(len(content)), bytes.NewReader(content))
+	if !errors.Is(err, archive.ErrLegalHold) {
+		t.Errorf("expected the file of the held document not to be replaced, got %v", err)
+	}
+
+	if err := a.ReleaseLegalHold(documentHold.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected holds to be released by the user who placed them, got %v", err)
+	}
+	for _, hold := range []archive.LegalHold{documentHold, folderHold} {
+		if err := a.ReleaseLegalHold(hold.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.TrashFolder(taxes.ID, "alice"); err != nil {
+		t.Errorf("expected released folders to be trashed, got %v", err)
+	}
+}
+
+func TestLegalHoldRefusesPurge(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	old := a.createFolder(t, "Old", archive.FolderRootID, "alice")
+	held := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	discarded := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	for _, document := range []archive.Document{held, discarded} {
+		if err := a.TrashDocument(document.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.TrashFolder(old.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Items can be held while they are in the trash
+	if _, err := a.PlaceDocumentLegalHold(held.ID, "Audit", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PlaceFolderLegalHold(old.ID, "Audit", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.DeleteDocumentPermanently(held.ID, "alice"); !errors.Is(err, archive.ErrLegalHold) {
+		t.Errorf("expected the held document not to be deleted, got %v", err)
+	}
+	if err := a.DeleteFolderPermanently(old.ID, "alice"); !errors.Is(err, archive.ErrLegalHold) {
+		t.Errorf("expected the held folder not to be deleted, got %v", err)
+	}
+
+	kept, err := a.EmptyTrash("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept != 1 {
+		t.Errorf("expected the held document to be kept, got %d", kept)
+	}
+	if !a.document(t, held.ID).IsTrashed() {
+		t.Error("expected the held document to stay in the trash")
+	}
+	if _, err := a.documents.FindByID(discarded.ID); err == nil {
+		t.Error("expected the other document to be deleted")
+	}
+	if !a.folder(t, old.ID).IsTrashed() {
+		t.Error("expected the held folder to stay in the trash")
+	}
+}
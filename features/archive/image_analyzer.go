@@ -0,0 +1,262 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"path"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/tiff"
+)
+
+var (
+	_ DocumentAnalyzer = &ImageAnalyzer{}
+	_ PageRenderer     = &ImageAnalyzer{}
+)
+
+var ErrInvalidTIFF = errors.New("invalid tiff")
+
+// previewMaxWidth keeps image previews in the same ballpark as rendered PDF pages.
+const previewMaxWidth = 1000
+
+type ImageAnalyzer struct {
+	documentStorage DocumentStorage
+	previewStorage  DocumentPreviewStorage
+}
+
+// ExtractText implements DocumentAnalyzer.
+// Images do not carry a text layer, so there is nothing to extract.
+func (a *ImageAnalyzer) ExtractText(document Document) (string, error) {
+	return "", nil
+}
+
+// GeneratePreviews implements DocumentAnalyzer.
+func (a *ImageAnalyzer) GeneratePreviews(document Document) ([]string, error) {
+	var pages []image.Image
+	err := a.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		var err error
+		pages, err = a.decode(document.Filetype, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var filepaths []string
+	for page, img := range pages {
+		filepath := path.Join(document.PreviewPrefix(), fmt.Sprintf("page%d.jpeg", page))
+		err := storePreview(a.previewStorage, scaleToWidth(img, previewMaxWidth), filepath)
+		if err != nil {
+			continue
+		}
+		filepaths = append(filepaths, filepath)
+	}
+
+	return filepaths, nil
+}
+
+// PageCount implements PageRenderer.
+func (a *ImageAnalyzer) PageCount(document Document) (int, error) {
+	if document.Filetype != TIFF {
+		return 1, nil
+	}
+
+	var count int
+	err := a.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+
+		offsets, err := tiffDirectoryOffsets(data)
+		count = len(offsets)
+		return err
+	})
+
+	return count, err
+}
+
+// RenderPages implements PageRenderer.
+// Images are passed on in their original resolution, the dpi is ignored.
+func (a *ImageAnalyzer) RenderPages(document Document, dpi int, consumer func(page int, img image.Image) error) error {
+	var pages []image.Image
+	err := a.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		var err error
+		pages, err = a.decode(document.Filetype, r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for page, img := range pages {
+		if err := consumer(page, img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *ImageAnalyzer) decode(filetype Filetype, r io.Reader) ([]image.Image, error) {
+	switch filetype {
+	case PNG:
+		img, err := png.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return []image.Image{img}, nil
+	case JPEG:
+		img, err := jpeg.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return []image.Image{img}, nil
+	case TIFF:
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return decodeTIFFPages(data)
+	default:
+		return nil, ErrUnsupportedFiletype
+	}
+}
+
+func storePreview(previewStorage DocumentPreviewStorage, img image.Image, filepath string) error {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{
+		Quality: 90,
+	})
+	if err != nil {
+		slog.Warn("failed to encode image to jpeg", "err", err.Error())
+		return err
+	}
+
+	err = previewStorage.Store(filepath, &buf)
+	if err != nil {
+		slog.Warn("failed to store preview", "err", err.Error())
+	}
+	return err
+}
+
+func scaleToWidth(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+// decodeTIFFPages decodes every image file directory (page) of a TIFF.
+// The tiff package only decodes the first directory, so each page is decoded
+// from a view of the data whose header points to that page's directory.
+func decodeTIFFPages(data []byte) ([]image.Image, error) {
+	offsets, err := tiffDirectoryOffsets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []image.Image
+	for page, offset := range offsets {
+		header := make([]byte, 8)
+		copy(header, data[:8])
+		byteOrder(data).PutUint32(header[4:], offset)
+
+		view := tiffPageView{data: data, header: header}
+		img, err := tiff.Decode(io.NewSectionReader(view, 0, int64(len(data))))
+		if err != nil {
+			slog.Warn("failed to decode tiff page", "page", page, "err", err.Error())
+			continue
+		}
+		pages = append(pages, img)
+	}
+
+	if len(pages) == 0 {
+		return nil, ErrInvalidTIFF
+	}
+	return pages, nil
+}
+
+func tiffDirectoryOffsets(data []byte) ([]uint32, error) {
+	if len(data) < 8 {
+		return nil, ErrInvalidTIFF
+	}
+
+	order := byteOrder(data)
+	if order == nil || order.Uint16(data[2:4]) != 42 {
+		return nil, ErrInvalidTIFF
+	}
+
+	var offsets []uint32
+	seen := make(map[uint32]bool)
+	offset := order.Uint32(data[4:8])
+	for offset != 0 && !seen[offset] {
+		if int(offset)+2 > len(data) {
+			break
+		}
+		seen[offset] = true
+		offsets = append(offsets, offset)
+
+		entries := int(order.Uint16(data[offset : offset+2]))
+		next := int(offset) + 2 + entries*12
+		if next+4 > len(data) {
+			break
+		}
+		offset = order.Uint32(data[next : next+4])
+	}
+
+	if len(offsets) == 0 {
+		return nil, ErrInvalidTIFF
+	}
+	return offsets, nil
+}
+
+func byteOrder(data []byte) binary.ByteOrder {
+	switch string(data[:2]) {
+	case "II":
+		return binary.LittleEndian
+	case "MM":
+		return binary.BigEndian
+	default:
+		return nil
+	}
+}
+
+// tiffPageView exposes the TIFF data with a replaced header.
+type tiffPageView struct {
+	data   []byte
+	header []byte
+}
+
+func (v tiffPageView) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(v.data)) {
+		return 0, io.EOF
+	}
+
+	n := copy(p, v.data[off:])
+	if off < int64(len(v.header)) {
+		copy(p, v.header[off:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func NewImageAnalyzer(documentStorage DocumentStorage, previewStorage DocumentPreviewStorage) *ImageAnalyzer {
+	return &ImageAnalyzer{
+		documentStorage: documentStorage,
+		previewStorage:  previewStorage,
+	}
+}
@@ -0,0 +1,68 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+	"unterlagen/features/archive"
+	"unterlagen/platform/ocr"
+
+	"golang.org/x/image/tiff"
+)
+
+// encodeImage encodes a small white image in the given format.
+func encodeImage(t *testing.T, encode func(buf *bytes.Buffer, img image.Image) error) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	for x := range 40 {
+		for y := range 20 {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageUpload(t *testing.T) {
+	a := newTestArchive(t, withOCR(ocr.NewStaticEngine("Scanned receipt"), 50))
+	a.createUsers(t, "alice")
+
+	images := map[archive.Filetype][]byte{
+		archive.PNG:  encodeImage(t, func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }),
+		archive.JPEG: encodeImage(t, func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }),
+		archive.TIFF: encodeImage(t, func(buf *bytes.Buffer, img image.Image) error { return tiff.Encode(buf, img, nil) }),
+	}
+	for filetype, content := range images {
+		document := a.upload(t, "scan."+string(filetype), content, archive.FolderRootID, "alice")
+		if document.Filetype != filetype {
+			t.Errorf("expected %s to be detected, got %s", filetype, document.Filetype)
+		}
+		if len(document.PreviewFilepaths) != 1 {
+			t.Errorf("expected a preview of the %s, got %v", filetype, document.PreviewFilepaths)
+		}
+		// Images have no text layer, their text can only be recognized
+		if !document.IsOCRed() || !strings.Contains(document.Text, "Scanned receipt") {
+			t.Errorf("expected the text of the %s to be recognized, got %q", filetype, document.Text)
+		}
+	}
+}
+
+func TestUnknownFiletypeUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	content := []byte("\x00\x01\x02 not a document")
+	err := a.UploadDocument("data.bin", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content))
+	if !errors.Is(err, archive.ErrUnsupportedFiletype) {
+		t.Errorf("expected unknown files to be rejected, got %v", err)
+	}
+}
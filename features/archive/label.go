@@ -0,0 +1,137 @@
+package archive
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+)
+
+// Label is a name an owner files documents under, a document has at most one label of each kind.
+// Correspondents and document types are labels.
+type Label struct {
+	ID    string
+	Name  string
+	Owner string
+	// DocumentCount is the number of documents with the label that are not trashed
+	DocumentCount int
+}
+
+type LabelRepository interface {
+	Save(label Label) error
+	FindByID(id string) (Label, error)
+	FindAllByOwner(owner string) ([]Label, error)
+	DeleteByID(id string) error
+}
+
+// labelKind holds what kinds of labels differ in.
+type labelKind struct {
+	errExists      error
+	errInvalidName error
+	// count returns the number of documents of the owner per label
+	count func(repository DocumentRepository, owner string) (map[string]int, error)
+	// assign sets the label of the document
+	assign func(document *Document, labelID string)
+}
+
+type labels struct {
+	kind       labelKind
+	repository LabelRepository
+	documents  *documents
+}
+
+func (l *labels) create(name string, owner string) (Label, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Label{}, l.kind.errInvalidName
+	}
+
+	existing, err := l.repository.FindAllByOwner(owner)
+	if err != nil {
+		return Label{}, err
+	}
+	if slices.ContainsFunc(existing, func(label Label) bool { return strings.EqualFold(label.Name, name) }) {
+		return Label{}, l.kind.errExists
+	}
+
+	label := Label{ID: common.GenerateID(), Name: name, Owner: owner}
+	return label, l.repository.Save(label)
+}
+
+func (l *labels) get(id string, owner string) (Label, error) {
+	label, err := l.repository.FindByID(id)
+	if err != nil {
+		return Label{}, err
+	}
+
+	if label.Owner != owner {
+		return Label{}, ErrNotAllowed
+	}
+
+	return label, nil
+}
+
+// list returns all labels of the owner with their document counts, sorted by name.
+func (l *labels) list(owner string) ([]Label, error) {
+	labels, err := l.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := l.kind.count(l.documents.repository, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range labels {
+		labels[i].DocumentCount = counts[labels[i].ID]
+	}
+
+	slices.SortFunc(labels, func(l1, l2 Label) int {
+		return strings.Compare(strings.ToLower(l1.Name), strings.ToLower(l2.Name))
+	})
+	return labels, nil
+}
+
+// delete deletes the label, its documents are kept without one.
+func (l *labels) delete(id string, owner string) error {
+	label, err := l.get(id, owner)
+	if err != nil {
+		return err
+	}
+
+	return l.repository.DeleteByID(label.ID)
+}
+
+// assign sets the label of a document, an empty id removes it. The label must belong to the owner of the document.
+func (l *labels) assign(documentID string, labelID string, user string) error {
+	document, err := l.documents.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+
+	if labelID != "" {
+		label, err := l.get(labelID, document.Owner)
+		if err != nil {
+			return err
+		}
+		labelID = label.ID
+	}
+
+	l.kind.assign(&document, labelID)
+	document.UpdatedAt = time.Now()
+	err = l.documents.repository.Save(document)
+	if err != nil {
+		return err
+	}
+
+	return l.documents.messages.PublishDocumentUpserted(document)
+}
+
+func newLabels(kind labelKind, repository LabelRepository, documents *documents) *labels {
+	return &labels{
+		kind:       kind,
+		repository: repository,
+		documents:  documents,
+	}
+}
@@ -0,0 +1,110 @@
+package archive_test
+
+import (
+	"errors"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestCreateLabels(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	if _, err := a.CreateCorrespondent(" Tax Office ", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CreateCorrespondent("tax office", "alice"); !errors.Is(err, archive.ErrCorrespondentExists) {
+		t.Errorf("expected correspondents to be unique regardless of case, got %v", err)
+	}
+	if _, err := a.CreateCorrespondent(" ", "alice"); !errors.Is(err, archive.ErrInvalidCorrespondentName) {
+		t.Errorf("expected an empty correspondent to be rejected, got %v", err)
+	}
+
+	// Kinds of labels do not share their names
+	if _, err := a.CreateDocumentType("Tax Office", "alice"); err != nil {
+		t.Errorf("expected a document type named like a correspondent, got %v", err)
+	}
+	if _, err := a.CreateDocumentType("", "alice"); !errors.Is(err, archive.ErrInvalidDocumentTypeName) {
+		t.Errorf("expected an empty document type to be rejected, got %v", err)
+	}
+}
+
+func TestAssignLabels(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	invoice := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	receipt := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	trashed := a.upload(t, "old.pdf", testFile(t, "mock_pdfs/invoice_0003.pdf"), archive.FolderRootID, "alice")
+	utility, err := a.CreateCorrespondent("Utility", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoices, err := a.CreateDocumentType("Invoice", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, document := range []archive.Document{invoice, receipt, trashed} {
+		if err := a.AssignCorrespondent(document.ID, utility.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.AssignDocumentType(invoice.ID, invoices.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.TrashDocument(trashed.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Trashed documents are not counted
+	correspondents, err := a.GetCorrespondents("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(correspondents) != 1 || correspondents[0].DocumentCount != 2 {
+		t.Errorf("expected two documents of the correspondent, got %v", correspondents)
+	}
+
+	documents, err := a.FilterDocuments(archive.DocumentFilter{CorrespondentID: utility.ID, DocumentTypeID: invoices.ID}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 || documents[0].ID != invoice.ID {
+		t.Errorf("expected the documents matching both labels, got %v", documents)
+	}
+
+	// An empty id removes the label
+	if err := a.AssignCorrespondent(receipt.ID, "", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if document := a.document(t, receipt.ID); document.CorrespondentID != "" {
+		t.Errorf("expected the correspondent to be removed, got %s", document.CorrespondentID)
+	}
+}
+
+func TestAssignLabelsOfOtherUser(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	foreign, err := a.CreateDocumentType("Invoice", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	own, err := a.CreateDocumentType("Invoice", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.AssignDocumentType(document.ID, foreign.ID, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected document types of other users not to be assigned, got %v", err)
+	}
+	if err := a.AssignDocumentType(document.ID, own.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected documents of other users not to be labeled, got %v", err)
+	}
+	if err := a.DeleteDocumentType(own.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected document types of other users not to be deleted, got %v", err)
+	}
+	if err := a.AssignDocumentType(document.ID, "unknown", "alice"); !errors.Is(err, archive.ErrDocumentTypeNotFound) {
+		t.Errorf("expected unknown document types not to be found, got %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package archive
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"time"
+	"unterlagen/features/common"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrShareLinkNotFound  = errors.New("share link not found")
+	ErrInvalidShareLink   = errors.New("invalid share link")
+	ErrShareLinkExpired   = errors.New("share link expired")
+	ErrShareLinkRevoked   = errors.New("share link revoked")
+	ErrShareLinkExhausted = errors.New("share link download limit reached")
+	ErrShareLinkPassword  = errors.New("wrong share link password")
+	ErrShareLinkLocked    = errors.New("share link requires a password")
+)
+
+// ShareLinkAction is what a visitor did with a share link.
+type ShareLinkAction string
+
+const (
+	ShareLinkActionView     ShareLinkAction = "view"
+	ShareLinkActionDownload ShareLinkAction = "download"
+	ShareLinkActionDenied   ShareLinkAction = "denied"
+)
+
+// ShareLink gives anybody knowing its token access to a document or a folder of the owner without logging in.
+// Exactly one of DocumentID and FolderID is set. A MaxDownloads of zero allows any number of downloads.
+type ShareLink struct {
+	ID           string
+	Token        string
+	DocumentID   string
+	FolderID     string
+	Owner        string
+	PasswordHash string
+	ExpiresAt    sql.NullTime
+	MaxDownloads int
+	Downloads    int
+	RevokedAt    sql.NullTime
+	CreatedAt    time.Time
+}
+
+func (link ShareLink) HasPassword() bool {
+	return link.PasswordHash != ""
+}
+
+func (link ShareLink) IsExpired() bool {
+	return link.ExpiresAt.Valid && time.Now().After(link.ExpiresAt.Time)
+}
+
+func (link ShareLink) IsRevoked() bool {
+	return link.RevokedAt.Valid
+}
+
+func (link ShareLink) IsExhausted() bool {
+	return link.MaxDownloads > 0 && link.Downloads >= link.MaxDownloads
+}
+
+// IsActive reports whether the link can still be opened.
+func (link ShareLink) IsActive() bool {
+	return !link.IsRevoked() && !link.IsExpired() && !link.IsExhausted()
+}
+
+// validate checks whether the link can be used, unlocked tells whether the visitor entered the password.
+func (link ShareLink) validate(unlocked bool) error {
+	switch {
+	case link.IsRevoked():
+		return ErrShareLinkRevoked
+	case link.IsExpired():
+		return ErrShareLinkExpired
+	case link.IsExhausted():
+		return ErrShareLinkExhausted
+	case link.HasPassword() && !unlocked:
+		return ErrShareLinkLocked
+	default:
+		return nil
+	}
+}
+
+// ShareLinkOptions restrict a new share link. A zero ExpiresIn never expires, an empty Password opens the link without one.
+type ShareLinkOptions struct {
+	ExpiresIn    time.Duration
+	Password     string
+	MaxDownloads int
+}
+
+// ShareLinkVisitor identifies who opened a share link in the access log.
+type ShareLinkVisitor struct {
+	Address   string
+	UserAgent string
+}
+
+// ShareLinkAccess is an entry of the access log of a share link.
+type ShareLinkAccess struct {
+	ID         string
+	LinkID     string
+	Action     ShareLinkAction
+	DocumentID string
+	Address    string
+	UserAgent  string
+	AccessedAt time.Time
+}
+
+type ShareLinkRepository interface {
+	Save(link ShareLink) error
+	FindByID(id string) (ShareLink, error)
+	FindByToken(token string) (ShareLink, error)
+	FindAllByOwner(owner string) ([]ShareLink, error)
+	SaveAccess(access ShareLinkAccess) error
+	FindAllAccessesByLinkID(linkID string) ([]ShareLinkAccess, error)
+}
+
+type shareLinks struct {
+	repository  ShareLinkRepository
+	documents   *documents
+	folders     *folders
+	permissions *permissions
+	// downloads serializes counting downloads, so a link cannot be used more often than allowed
+	downloads sync.Mutex
+}
+
+// CreateDocumentShareLink creates a share link to a document of the owner.
+func (s *shareLinks) CreateDocumentShareLink(documentID string, owner string, options ShareLinkOptions) (ShareLink, error) {
+	document, err := s.documents.GetDocument(documentID, owner)
+	if err != nil {
+		return ShareLink{}, err
+	}
+	if document.Owner != owner {
+		return ShareLink{}, ErrNotAllowed
+	}
+	if document.IsTrashed() {
+		return ShareLink{}, ErrInvalidShareLink
+	}
+
+	return s.create(ShareLink{DocumentID: document.ID}, owner, options)
+}
+
+// CreateFolderShareLink creates a share link to a folder of the owner with all of its subfolders and documents.
+func (s *shareLinks) CreateFolderShareLink(folderID string, owner string, options ShareLinkOptions) (ShareLink, error) {
+	folder, err := s.permissions.getOwnedFolder(folderID, owner)
+	if err != nil {
+		return ShareLink{}, err
+	}
+	if folder.IsTrashed() {
+		return ShareLink{}, ErrFolderTrashed
+	}
+
+	return s.create(ShareLink{FolderID: folder.ID}, owner, options)
+}
+
+func (s *shareLinks) create(link ShareLink, owner string, options ShareLinkOptions) (ShareLink, error) {
+	if options.ExpiresIn < 0 || options.MaxDownloads < 0 {
+		return ShareLink{}, ErrInvalidShareLink
+	}
+
+	token, err := generateShareLinkToken()
+	if err != nil {
+		return ShareLink{}, err
+	}
+
+	link.ID = common.GenerateID()
+	link.Token = token
+	link.Owner = owner
+	link.MaxDownloads = options.MaxDownloads
+	link.CreatedAt = time.Now()
+	if options.ExpiresIn > 0 {
+		link.ExpiresAt = sql.NullTime{Time: link.CreatedAt.Add(options.ExpiresIn), Valid: true}
+	}
+	if options.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(options.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return ShareLink{}, err
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	return link, s.repository.Save(link)
+}
+
+// generateShareLinkToken returns a random token that cannot be guessed, unlike generated ids.
+func generateShareLinkToken() (string, error) {
+	token := make([]byte, 24)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// GetShareLinks returns the share links of the owner, newest first.
+func (s *shareLinks) GetShareLinks(owner string) ([]ShareLink, error) {
+	links, err := s.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(links, func(l1, l2 ShareLink) int {
+		return l2.CreatedAt.Compare(l1.CreatedAt)
+	})
+	return links, nil
+}
+
+// GetShareLinkAccesses returns the access log of a share link of the owner, newest first.
+func (s *shareLinks) GetShareLinkAccesses(linkID string, owner string) ([]ShareLinkAccess, error) {
+	link, err := s.getOwnedShareLink(linkID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	accesses, err := s.repository.FindAllAccessesByLinkID(link.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(accesses, func(a1, a2 ShareLinkAccess) int {
+		return a2.AccessedAt.Compare(a1.AccessedAt)
+	})
+	return accesses, nil
+}
+
+// RevokeShareLink stops a share link from working, it stays listed with its access log.
+func (s *shareLinks) RevokeShareLink(linkID string, owner string) error {
+	link, err := s.getOwnedShareLink(linkID, owner)
+	if err != nil {
+		return err
+	}
+	if link.IsRevoked() {
+		return nil
+	}
+
+	link.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	return s.repository.Save(link)
+}
+
+func (s *shareLinks) getOwnedShareLink(linkID string, owner string) (ShareLink, error) {
+	link, err := s.repository.FindByID(linkID)
+	if err != nil {
+		return ShareLink{}, err
+	}
+	if link.Owner != owner {
+		return ShareLink{}, ErrNotAllowed
+	}
+	return link, nil
+}
+
+// OpenShareLink returns the share link of the token if it can still be used, unlocked tells whether the visitor
+// entered the password of the link before. Failed attempts are logged as well.
+func (s *shareLinks) OpenShareLink(token string, unlocked bool, visitor ShareLinkVisitor) (ShareLink, error) {
+	link, err := s.repository.FindByToken(token)
+	if err != nil {
+		return ShareLink{}, err
+	}
+
+	err = link.validate(unlocked)
+	if errors.Is(err, ErrShareLinkLocked) {
+		return link, err
+	}
+	if err != nil {
+		return ShareLink{}, s.logAccess(link, ShareLinkActionDenied, "", visitor, err)
+	}
+
+	return link, s.logAccess(link, ShareLinkActionView, "", visitor, nil)
+}
+
+// UnlockShareLink checks the password of a share link, links without a password are always unlocked.
+func (s *shareLinks) UnlockShareLink(token string, password string, visitor ShareLinkVisitor) (ShareLink, error) {
+	link, err := s.repository.FindByToken(token)
+	if err != nil {
+		return ShareLink{}, err
+	}
+
+	err = link.validate(true)
+	if err == nil && link.HasPassword() {
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+			err = ErrShareLinkPassword
+		}
+	}
+	if err != nil {
+		return ShareLink{}, s.logAccess(link, ShareLinkActionDenied, "", visitor, err)
+	}
+
+	return link, nil
+}
+
+// GetShareLinkFolder returns a folder of a folder share link with its subfolders and documents.
+// An empty folder id stands for the shared folder itself, trashed items are left out.
+func (s *shareLinks) GetShareLinkFolder(link ShareLink, folderID string) (Folder, []Folder, []Document, error) {
+	if folderID == "" {
+		folderID = link.FolderID
+	}
+
+	folder, err := s.getShareLinkFolder(link, folderID)
+	if err != nil {
+		return Folder{}, nil, nil, err
+	}
+
+	folders, err := s.folders.repository.FindAllByParentID(folder.ID)
+	if err != nil {
+		return Folder{}, nil, nil, err
+	}
+	folders = slices.DeleteFunc(folders, func(folder Folder) bool { return folder.IsTrashed() })
+
+	documents, err := s.documents.repository.FindAllByFolderID(folder.ID)
+	if err != nil {
+		return Folder{}, nil, nil, err
+	}
+	documents = slices.DeleteFunc(documents, func(document Document) bool { return document.IsTrashed() })
+
+	return folder, folders, documents, nil
+}
+
+// GetShareLinkDocument returns a document the share link points to or that lies below its folder.
+func (s *shareLinks) GetShareLinkDocument(link ShareLink, documentID string) (Document, error) {
+	document, err := s.documents.repository.FindByID(documentID)
+	if err != nil {
+		return Document{}, err
+	}
+	if document.IsTrashed() {
+		return Document{}, ErrNotAllowed
+	}
+
+	if link.DocumentID != "" {
+		if document.ID != link.DocumentID {
+			return Document{}, ErrNotAllowed
+		}
+		return document, nil
+	}
+
+	_, err = s.getShareLinkFolder(link, document.FolderID)
+	if err != nil {
+		return Document{}, err
+	}
+	return document, nil
+}
+
+// getShareLinkFolder returns a folder if it is the shared folder or one of its subfolders and none of them is trashed.
+func (s *shareLinks) getShareLinkFolder(link ShareLink, folderID string) (Folder, error) {
+	if link.FolderID == "" || folderID == FolderRootID {
+		return Folder{}, ErrNotAllowed
+	}
+
+	hierarchy, err := s.folders.repository.GetHierarchy(folderID)
+	if err != nil {
+		return Folder{}, err
+	}
+
+	shared := slices.IndexFunc(hierarchy, func(folder Folder) bool { return folder.ID == link.FolderID })
+	if shared < 0 {
+		return Folder{}, ErrNotAllowed
+	}
+	if slices.ContainsFunc(hierarchy[shared:], func(folder Folder) bool { return folder.IsTrashed() }) {
+		return Folder{}, ErrNotAllowed
+	}
+
+	return hierarchy[len(hierarchy)-1], nil
+}
+
+// DownloadShareLinkDocument streams a document of the share link and counts the download against its limit.
+func (s *shareLinks) DownloadShareLinkDocument(token string, documentID string, unlocked bool, visitor ShareLinkVisitor, consumer func(document Document, r io.Reader) error) error {
+	link, err := s.repository.FindByToken(token)
+	if err != nil {
+		return err
+	}
+
+	document, err := s.GetShareLinkDocument(link, documentID)
+	if err == nil {
+		err = s.countDownload(link.ID, unlocked)
+	}
+	if err != nil {
+		return s.logAccess(link, ShareLinkActionDenied, documentID, visitor, err)
+	}
+
+	err = s.logAccess(link, ShareLinkActionDownload, document.ID, visitor, nil)
+	if err != nil {
+		return err
+	}
+	return s.documents.storage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		return consumer(document, r)
+	})
+}
+
+// countDownload counts a download of the link, it fails once the link cannot be used anymore.
+func (s *shareLinks) countDownload(linkID string, unlocked bool) error {
+	s.downloads.Lock()
+	defer s.downloads.Unlock()
+
+	link, err := s.repository.FindByID(linkID)
+	if err != nil {
+		return err
+	}
+	err = link.validate(unlocked)
+	if err != nil {
+		return err
+	}
+
+	link.Downloads++
+	return s.repository.Save(link)
+}
+
+// logAccess adds an entry to the access log of the link and returns the given error, unless logging fails.
+func (s *shareLinks) logAccess(link ShareLink, action ShareLinkAction, documentID string, visitor ShareLinkVisitor, err error) error {
+	logErr := s.repository.SaveAccess(ShareLinkAccess{
+		ID:         common.GenerateID(),
+		LinkID:     link.ID,
+		Action:     action,
+		DocumentID: documentID,
+		Address:    visitor.Address,
+		UserAgent:  visitor.UserAgent,
+		AccessedAt: time.Now(),
+	})
+	if logErr != nil {
+		return logErr
+	}
+	return err
+}
+
+func newShareLinks(repository ShareLinkRepository, documents *documents, folders *folders, permissions *permissions) *shareLinks {
+	return &shareLinks{
+		repository:  repository,
+		documents:   documents,
+		folders:     folders,
+		permissions: permissions,
+	}
+}
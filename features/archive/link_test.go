@@ -0,0 +1,252 @@
+package archive_test
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+var visitor = archive.ShareLinkVisitor{Address: "192.0.2.1", UserAgent: "test"}
+
+func (s *sharing) download(token string, documentID string, unlocked bool) (string, error) {
+	var content []byte
+	err := s.DownloadShareLinkDocument(token, documentID, unlocked, visitor, func(document archive.Document, r io.Reader) error {
+		var err error
+		content, err = io.ReadAll(r)
+		return err
+	})
+	return string(content), err
+}
+
+func TestShareLinkCreation(t *testing.T) {
+	s := newSharing(t)
+
+	if _, err := s.CreateDocumentShareLink(s.receipt.ID, "carol", archive.ShareLinkOptions{}); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected carol not to share the receipt, got %v", err)
+	}
+	if _, err := s.CreateFolderShareLink(s.taxes.ID, "carol", archive.ShareLinkOptions{}); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected carol not to share the folder, got %v", err)
+	}
+	if _, err := s.CreateFolderShareLink(archive.FolderRootID, "alice", archive.ShareLinkOptions{}); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the root folder not to be shared, got %v", err)
+	}
+	if _, err := s.CreateDocumentShareLink(s.receipt.ID, "alice", archive.ShareLinkOptions{MaxDownloads: -1}); !errors.Is(err, archive.ErrInvalidShareLink) {
+		t.Errorf("expected a negative download limit to be invalid, got %v", err)
+	}
+
+	// Readers of a shared document cannot hand it out to anybody
+	if _, err := s.ShareDocument(s.receipt.ID, "carol", archive.PermissionWrite, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateDocumentShareLink(s.receipt.ID, "carol", archive.ShareLinkOptions{}); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected only the owner to share the receipt, got %v", err)
+	}
+}
+
+func TestShareLinkExpiry(t *testing.T) {
+	s := newSharing(t)
+
+	link, err := s.CreateDocumentShareLink(s.receipt.ID, "alice", archive.ShareLinkOptions{ExpiresIn: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OpenShareLink(link.Token, false, visitor); err != nil {
+		t.Fatalf("expected the link to open before it expires, got %v", err)
+	}
+
+	link.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+	if err := s.shareLinks.Save(link); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OpenShareLink(link.Token, false, visitor); !errors.Is(err, archive.ErrShareLinkExpired) {
+		t.Errorf("expected the link to be expired, got %v", err)
+	}
+	if _, err := s.download(link.Token, s.receipt.ID, false); !errors.Is(err, archive.ErrShareLinkExpired) {
+		t.Errorf("expected no download of an expired link, got %v", err)
+	}
+
+	accesses, err := s.GetShareLinkAccesses(link.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := 0
+	for _, access := range accesses {
+		if access.Action == archive.ShareLinkActionDenied {
+			denied++
+		}
+	}
+	if len(accesses) != 3 || denied != 2 {
+		t.Errorf("expected a view and two denied accesses to be logged, got %v", accesses)
+	}
+}
+
+func TestShareLinkPassword(t *testing.T) {
+	s := newSharing(t)
+
+	link, err := s.CreateDocumentShareLink(s.receipt.ID, "alice", archive.ShareLinkOptions{Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link.PasswordHash == "secret" {
+		t.Error("expected the password to be hashed")
+	}
+
+	if _, err := s.OpenShareLink(link.Token, false, visitor); !errors.Is(err, archive.ErrShareLinkLocked) {
+		t.Errorf("expected the link to be locked, got %v", err)
+	}
+	if _, err := s.download(link.Token, s.receipt.ID, false); !errors.Is(err, archive.ErrShareLinkLocked) {
+		t.Errorf("expected no download without the password, got %v", err)
+	}
+	if _, err := s.UnlockShareLink(link.Token, "wrong", visitor); !errors.Is(err, archive.ErrShareLinkPassword) {
+		t.Errorf("expected the wrong password to be rejected, got %v", err)
+	}
+
+	if _, err := s.UnlockShareLink(link.Token, "secret", visitor); err != nil {
+		t.Fatalf("expected the password to unlock the link, got %v", err)
+	}
+	if _, err := s.OpenShareLink(link.Token, true, visitor); err != nil {
+		t.Errorf("expected the unlocked link to open, got %v", err)
+	}
+	content, err := s.download(link.Token, s.receipt.ID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != string(testFile(t, "mock_pdfs/invoice_0001.pdf")) {
+		t.Errorf("expected the receipt to be downloaded, got %q", content)
+	}
+}
+
+func TestShareLinkDownloadLimit(t *testing.T) {
+	s := newSharing(t)
+
+	link, err := s.CreateDocumentShareLink(s.receipt.ID, "alice", archive.ShareLinkOptions{MaxDownloads: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		if _, err := s.download(link.Token, s.receipt.ID, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.download(link.Token, s.receipt.ID, false); !errors.Is(err, archive.ErrShareLinkExhausted) {
+		t.Errorf("expected the third download to exceed the limit, got %v", err)
+	}
+	if _, err := s.OpenShareLink(link.Token, false, visitor); !errors.Is(err, archive.ErrShareLinkExhausted) {
+		t.Errorf("expected the exhausted link not to open, got %v", err)
+	}
+
+	links, err := s.GetShareLinks("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].Downloads != 2 || links[0].IsActive() {
+		t.Errorf("expected the link to count two downloads and be inactive, got %v", links)
+	}
+}
+
+func TestDocumentShareLinkScope(t *testing.T) {
+	s := newSharing(t)
+
+	link, err := s.CreateDocumentShareLink(s.receipt.ID, "alice", archive.ShareLinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetShareLinkDocument(link, s.receipt.ID); err != nil {
+		t.Errorf("expected the receipt to be shared, got %v", err)
+	}
+	if _, err := s.GetShareLinkDocument(link, s.invoice.ID); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the invoice not to be shared, got %v", err)
+	}
+	if _, err := s.download(link.Token, s.invoice.ID, false); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected no download of the invoice, got %v", err)
+	}
+	if _, _, _, err := s.GetShareLinkFolder(link, s.taxes2025.ID); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the folder of the receipt not to be shared, got %v", err)
+	}
+}
+
+func TestFolderShareLinkScope(t *testing.T) {
+	s := newSharing(t)
+
+	link, err := s.CreateFolderShareLink(s.taxes.ID, "alice", archive.ShareLinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	folder, folders, documents, err := s.GetShareLinkFolder(link, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if folder.ID != s.taxes.ID || len(folders) != 1 || folders[0].ID != s.taxes2025.ID || len(documents) != 0 {
+		t.Errorf("expected the shared folder with its subfolder, got %v, %v and %v", folder, folders, documents)
+	}
+	_, _, documents, err = s.GetShareLinkFolder(link, s.taxes2025.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 || documents[0].ID != s.receipt.ID {
+		t.Errorf("expected the receipt in the subfolder, got %v", documents)
+	}
+	if _, err := s.download(link.Token, s.receipt.ID, false); err != nil {
+		t.Errorf("expected the receipt in the subfolder to be downloaded, got %v", err)
+	}
+
+	// Nothing above or beside the shared folder is reachable
+	for _, folderID := range []string{archive.FolderRootID, s.familyFiles.ID} {
+		if _, _, _, err := s.GetShareLinkFolder(link, folderID); !errors.Is(err, archive.ErrNotAllowed) {
+			t.Errorf("expected folder %s not to be shared, got %v", folderID, err)
+		}
+	}
+	for _, documentID := range []string{s.invoice.ID, s.photo.ID} {
+		if _, err := s.download(link.Token, documentID, false); !errors.Is(err, archive.ErrNotAllowed) {
+			t.Errorf("expected no download of document %s, got %v", documentID, err)
+		}
+	}
+
+	// Trashing a subfolder hides it and everything in it
+	s.taxes2025.TrashedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := s.folders.Save(s.taxes2025); err != nil {
+		t.Fatal(err)
+	}
+	_, folders, _, err = s.GetShareLinkFolder(link, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 0 {
+		t.Errorf("expected the trashed subfolder to be left out, got %v", folders)
+	}
+	if _, err := s.GetShareLinkDocument(link, s.receipt.ID); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the receipt in the trashed subfolder not to be shared, got %v", err)
+	}
+}
+
+func TestRevokeShareLink(t *testing.T) {
+	s := newSharing(t)
+
+	link, err := s.CreateFolderShareLink(s.taxes.ID, "alice", archive.ShareLinkOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RevokeShareLink(link.ID, "carol"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected carol not to revoke the link, got %v", err)
+	}
+	if err := s.RevokeShareLink(link.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RevokeShareLink(link.ID, "alice"); err != nil {
+		t.Errorf("expected revoking twice to succeed, got %v", err)
+	}
+
+	if _, err := s.OpenShareLink(link.Token, false, visitor); !errors.Is(err, archive.ErrShareLinkRevoked) {
+		t.Errorf("expected the link to be revoked, got %v", err)
+	}
+	if _, err := s.download(link.Token, s.receipt.ID, false); !errors.Is(err, archive.ErrShareLinkRevoked) {
+		t.Errorf("expected no download of a revoked link, got %v", err)
+	}
+}
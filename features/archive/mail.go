@@ -0,0 +1,347 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unterlagen/features/common"
+)
+
+// defaultMailPollInterval applies if the configured interval is not positive, tickers cannot tick that often.
+const defaultMailPollInterval = 5 * time.Minute
+
+var (
+	ErrMailAccountNotFound = errors.New("mail account not found")
+	ErrMailAccountExists   = errors.New("mail account already exists")
+	ErrInvalidMailAccount  = errors.New("invalid mail account")
+)
+
+type MailProcessedAction string
+
+const (
+	// MailProcessedActionSeen flags processed messages as seen and leaves them in the mailbox
+	MailProcessedActionSeen MailProcessedAction = "seen"
+	// MailProcessedActionMove moves processed messages into MailAccount.ProcessedMailbox
+	MailProcessedActionMove MailProcessedAction = "move"
+)
+
+func (action MailProcessedAction) IsValid() bool {
+	return action == MailProcessedActionSeen || action == MailProcessedActionMove
+}
+
+// MailAccount is an IMAP mailbox whose attachments are imported periodically.
+// Messages are only imported if their sender and subject contain the filters, empty filters match every message.
+type MailAccount struct {
+	ID       string
+	Name     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// TLS connects with implicit TLS, otherwise STARTTLS is required
+	TLS bool
+	// Insecure allows logging in without TLS if the server does not offer STARTTLS, the password is sent in clear text
+	Insecure         bool
+	Mailbox          string
+	SenderFilter     string
+	SubjectFilter    string
+	FolderID         string
+	ProcessedAction  MailProcessedAction
+	ProcessedMailbox string
+	Owner            string
+	LastPolledAt     time.Time
+	LastError        string
+}
+
+// Matches reports whether a message with the given sender and subject passes the filters of the account.
+func (account MailAccount) Matches(sender string, subject string) bool {
+	return strings.Contains(strings.ToLower(sender), strings.ToLower(account.SenderFilter)) &&
+		strings.Contains(strings.ToLower(subject), strings.ToLower(account.SubjectFilter))
+}
+
+// MailClient connects to the mail server of an account.
+type MailClient interface {
+	Open(account MailAccount) (MailSession, error)
+}
+
+// MailSession is a connection with the mailbox of an account selected.
+type MailSession interface {
+	// UIDValidity changes when the UIDs of the mailbox were reassigned, seen UIDs of another validity are meaningless
+	UIDValidity() uint32
+	UIDs() ([]uint32, error)
+	Fetch(uid uint32) ([]byte, error)
+	MarkSeen(uid uint32) error
+	Move(uid uint32, mailbox string) error
+	Close() error
+}
+
+type MailAccountRepository interface {
+	Save(account MailAccount) error
+	FindByID(id string) (MailAccount, error)
+	FindAll() ([]MailAccount, error)
+	FindAllByOwner(owner string) ([]MailAccount, error)
+	DeleteByID(id string) error
+	FindSeenUIDs(accountID string, uidValidity uint32) ([]uint32, error)
+	SaveSeenUID(accountID string, uidValidity uint32, uid uint32) error
+}
+
+type mailAccounts struct {
+	repository   MailAccountRepository
+	client       MailClient
+	documents    *documents
+	folders      *folders
+	pollInterval time.Duration
+	// polling is serialized, so a manual poll cannot import the same message as the scheduled one
+	mutex sync.Mutex
+}
+
+func (m *mailAccounts) CreateMailAccount(account MailAccount, owner string) (MailAccount, error) {
+	account.Name = strings.TrimSpace(account.Name)
+	account.Host = strings.TrimSpace(account.Host)
+	account.Username = strings.TrimSpace(account.Username)
+	account.Mailbox = strings.TrimSpace(account.Mailbox)
+	account.ProcessedMailbox = strings.TrimSpace(account.ProcessedMailbox)
+	if account.Name == "" || account.Host == "" || account.Username == "" || !account.ProcessedAction.IsValid() {
+		return MailAccount{}, ErrInvalidMailAccount
+	}
+	if account.ProcessedAction == MailProcessedActionMove && account.ProcessedMailbox == "" {
+		return MailAccount{}, fmt.Errorf("%w: moving processed messages needs a mailbox", ErrInvalidMailAccount)
+	}
+	if account.Mailbox == "" {
+		account.Mailbox = "INBOX"
+	}
+	if account.Port == 0 {
+		account.Port = 143
+		if account.TLS {
+			account.Port = 993
+		}
+	}
+
+	if account.FolderID == "" {
+		account.FolderID = FolderRootID
+	}
+	// Attachments are uploaded into the folder, so it has to be writable and not just readable
+	if _, err := m.folders.getFolder(account.FolderID, owner, PermissionWrite); err != nil {
+		return MailAccount{}, err
+	}
+
+	existing, err := m.repository.FindAllByOwner(owner)
+	if err != nil {
+		return MailAccount{}, err
+	}
+	if slices.ContainsFunc(existing, func(existing MailAccount) bool { return strings.EqualFold(existing.Name, account.Name) }) {
+		return MailAccount{}, ErrMailAccountExists
+	}
+
+	account.ID = common.GenerateID()
+	account.Owner = owner
+	return account, m.repository.Save(account)
+}
+
+func (m *mailAccounts) GetMailAccount(id string, owner string) (MailAccount, error) {
+	account, err := m.repository.FindByID(id)
+	if err != nil {
+		return MailAccount{}, err
+	}
+
+	if account.Owner != owner {
+		return MailAccount{}, ErrNotAllowed
+	}
+
+	return account, nil
+}
+
+func (m *mailAccounts) GetMailAccounts(owner string) ([]MailAccount, error) {
+	accounts, err := m.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(accounts, func(a1, a2 MailAccount) int {
+		return strings.Compare(strings.ToLower(a1.Name), strings.ToLower(a2.Name))
+	})
+	return accounts, nil
+}
+
+// DeleteMailAccount removes the account with its seen messages, imported documents stay in the archive.
+func (m *mailAccounts) DeleteMailAccount(id string, owner string) error {
+	account, err := m.GetMailAccount(id, owner)
+	if err != nil {
+		return err
+	}
+
+	return m.repository.DeleteByID(account.ID)
+}
+
+// PollMailAccount imports the new messages of the account right away instead of waiting for the next poll.
+func (m *mailAccounts) PollMailAccount(id string, owner string) error {
+	account, err := m.GetMailAccount(id, owner)
+	if err != nil {
+		return err
+	}
+
+	return m.poll(account)
+}
+
+func (m *mailAccounts) pollAll(ctx context.Context) {
+	ticker := time.NewTicker(m.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			accounts, err := m.repository.FindAll()
+			if err != nil {
+				slog.Error("failed to find mail accounts", "error", err.Error())
+				continue
+			}
+
+			for _, account := range accounts {
+				err := m.poll(account)
+				if err != nil {
+					slog.Error("failed to poll mail account", "account", account.ID, "error", err.Error())
+				}
+			}
+		case <-ctx.Done():
+			slog.Info("mail polling stopped")
+			return
+		}
+	}
+}
+
+// poll imports the attachments of all messages not seen before and records the outcome on the account.
+func (m *mailAccounts) poll(account MailAccount) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	imported, err := m.importMessages(account)
+	account.LastPolledAt = time.Now()
+	account.LastError = ""
+	if err != nil {
+		account.LastError = err.Error()
+	}
+
+	if saveErr := m.repository.Save(account); saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return err
+	}
+
+	slog.Info("polled mail account", "account", account.ID, "imported", imported)
+	return nil
+}
+
+func (m *mailAccounts) importMessages(account MailAccount) (int, error) {
+	session, err := m.client.Open(account)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := session.Close(); err != nil {
+			slog.Warn("failed to close mail session", "account", account.ID, "error", err.Error())
+		}
+	}()
+
+	seenUIDs, err := m.repository.FindSeenUIDs(account.ID, session.UIDValidity())
+	if err != nil {
+		return 0, err
+	}
+
+	uids, err := session.UIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, uid := range uids {
+		if slices.Contains(seenUIDs, uid) {
+			continue
+		}
+
+		data, err := session.Fetch(uid)
+		if err != nil {
+			return imported, err
+		}
+
+		message, err := parseEmail(bytes.NewReader(data))
+		if err != nil {
+			slog.Warn("skipping unreadable mail", "account", account.ID, "uid", uid, "error", err.Error())
+		} else if account.Matches(message.Headers["From"], message.Headers["Subject"]) {
+			err := m.importAttachments(account, message)
+			if err != nil {
+				return imported, err
+			}
+			imported++
+
+			err = m.markProcessed(account, session, uid)
+			if err != nil {
+				return imported, err
+			}
+		}
+
+		// Messages are remembered even if they did not match, so they are not downloaded again
+		err = m.repository.SaveSeenUID(account.ID, session.UIDValidity(), uid)
+		if err != nil {
+			return imported, err
+		}
+	}
+
+	return imported, nil
+}
+
+func (m *mailAccounts) importAttachments(account MailAccount, message email) error {
+	for _, attachment := range message.Attachments {
+		err := m.documents.UploadDocument(attachment.Filename, uint64(len(attachment.Data)), account.FolderID, account.Owner, bytes.NewReader(attachment.Data))
+		if linked, ok := LinkedDuplicate(err); ok {
+			slog.Info("linked mail attachment to existing document", "account", account.ID, "filename", attachment.Filename, "documentID", linked.ID)
+			continue
+		}
+		if errors.Is(err, ErrUnsupportedFiletype) || errors.Is(err, ErrDuplicateDocument) {
+			slog.Warn("skipping mail attachment", "account", account.ID, "filename", attachment.Filename, "error", err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mailAccounts) markProcessed(account MailAccount, session MailSession, uid uint32) error {
+	if account.ProcessedAction == MailProcessedActionMove {
+		return session.Move(uid, account.ProcessedMailbox)
+	}
+	return session.MarkSeen(uid)
+}
+
+func newMailAccounts(
+	repository MailAccountRepository,
+	client MailClient,
+	documents *documents,
+	folders *folders,
+	pollInterval time.Duration,
+	jobScheduler *common.JobScheduler,
+) *mailAccounts {
+	if pollInterval <= 0 {
+		slog.Warn("invalid mail poll interval, using the default", "interval", pollInterval, "default", defaultMailPollInterval)
+		pollInterval = defaultMailPollInterval
+	}
+
+	mailAccounts := &mailAccounts{
+		repository:   repository,
+		client:       client,
+		documents:    documents,
+		folders:      folders,
+		pollInterval: pollInterval,
+	}
+
+	jobScheduler.Schedule(mailAccounts.pollAll)
+	return mailAccounts
+}
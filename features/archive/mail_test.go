@@ -0,0 +1,42 @@
+package archive_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+func TestMailAccountIntoSharedFolder(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob", "carol")
+	inbox := a.createFolder(t, "Inbox", archive.FolderRootID, "alice")
+	if _, err := a.ShareFolder(inbox.ID, "bob", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ShareFolder(inbox.ID, "carol", archive.PermissionWrite, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	account := archive.MailAccount{Name: "Invoices", Host: "localhost", Username: "invoices", ProcessedAction: archive.MailProcessedActionSeen, FolderID: inbox.ID}
+
+	// Every poll would fail to upload attachments into a folder that is only readable
+	if _, err := a.CreateMailAccount(account, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected the read only folder to be rejected, got %v", err)
+	}
+	if _, err := a.CreateMailAccount(account, "carol"); err != nil {
+		t.Errorf("expected the writable folder to be accepted, got %v", err)
+	}
+}
+
+func TestMailPollIntervalNotPositive(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		a := newTestArchive(t, func(dependencies *archive.Dependencies, settings *archive.Settings) {
+			settings.MailPollInterval = interval
+		})
+
+		// Polling starts right away, a ticker panics with an interval that is not positive
+		a.createUsers(t, "alice")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"io"
+	"log/slog"
+	"path"
+	"time"
+	"unterlagen/features/common"
+)
+
+// MoveDocument moves a document into another folder of its owner. Only the owner can move a document into the root folder.
+func (d *documents) MoveDocument(documentID string, folderID string, user string) error {
+	document, target, err := d.getDocumentAndTargetFolder(documentID, folderID, user, PermissionWrite)
+	if err != nil {
+		return err
+	}
+	if target.Owner != document.Owner {
+		return ErrNotAllowed
+	}
+
+	if document.FolderID == target.ID {
+		return nil
+	}
+
+	previousFolderID := document.FolderID
+	document.FolderID = target.ID
+	document.UpdatedAt = time.Now()
+	err = d.repository.Save(document)
+	if err != nil {
+		return err
+	}
+
+	return d.messages.PublishDocumentMoved(document, previousFolderID)
+}
+
+// CopyDocument duplicates a document with its file, previews, text and metadata into a folder the user can write to.
+// The copy belongs to the owner of the folder, starts a new version history and is indexed on its own.
+// Tags, custom fields, correspondent and type belong to the owner, so they are only copied within the documents of the owner.
+func (d *documents) CopyDocument(documentID string, folderID string, user string) (Document, error) {
+	document, target, err := d.getDocumentAndTargetFolder(documentID, folderID, user, PermissionRead)
+	if err != nil {
+		return Document{}, err
+	}
+
+	duplicate := document
+	duplicate.ID = common.GenerateID()
+	duplicate.Owner = target.Owner
+	duplicate.FolderID = target.ID
+	duplicate.ParentID = ""
+	duplicate.Notes = nil
+	duplicate.Version = 1
+	duplicate.TrashedAt.Valid = false
+	duplicate.CreatedAt = time.Now()
+	duplicate.UpdatedAt = time.Now()
+	if duplicate.FolderID == document.FolderID {
+		duplicate.Title = document.Title + " (Copy)"
+	}
+	if duplicate.Owner != document.Owner {
+		duplicate.CustomFields = nil
+		duplicate.CorrespondentID = ""
+		duplicate.DocumentTypeID = ""
+		duplicate.Tags = nil
+	}
+
+	err = d.copyFile(document.Filepath(), duplicate.Filepath())
+	if err != nil {
+		return Document{}, err
+	}
+
+	duplicate.PreviewFilepaths = nil
+	for _, preview := range document.PreviewFilepaths {
+		destination := path.Join(duplicate.PreviewPrefix(), path.Base(preview))
+		err := d.previewStorage.Retrieve(preview, func(r io.Reader) error {
+			return d.previewStorage.Store(destination, r)
+		})
+		if err != nil {
+			d.deleteFiles(duplicate)
+			return Document{}, err
+		}
+		duplicate.PreviewFilepaths = append(duplicate.PreviewFilepaths, destination)
+	}
+
+	err = d.repository.Save(duplicate)
+	if err != nil {
+		d.deleteFiles(duplicate)
+		return Document{}, err
+	}
+
+	for _, tag := range duplicate.Tags {
+		err := d.tagRepository.AssignToDocument(tag.ID, duplicate.ID)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+
+	// Copies of documents still being processed are processed on their own
+	if duplicate.Text == "" || len(duplicate.PreviewFilepaths) == 0 {
+		err = d.scheduleDocumentProcessing(duplicate)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+
+	// The text is already extracted, so only the search index needs the copy
+	return duplicate, d.messages.PublishDocumentUpserted(duplicate)
+}
+
+// getDocumentAndTargetFolder loads a document for moving or copying, the user needs the required permission
+// for the document and must be able to write to the target folder.
+func (d *documents) getDocumentAndTargetFolder(documentID string, folderID string, user string, required Permission) (Document, Folder, error) {
+	document, err := d.getDocument(documentID, user, required)
+	if err != nil {
+		return Document{}, Folder{}, err
+	}
+
+	target, err := d.folders.getFolder(folderID, user, PermissionWrite)
+	if err != nil {
+		return Document{}, Folder{}, err
+	}
+	if target.IsTrashed() {
+		return Document{}, Folder{}, ErrFolderTrashed
+	}
+
+	return document, target, nil
+}
+
+// deleteFiles removes the stored file and previews of a document that could not be saved.
+func (d *documents) deleteFiles(document Document) {
+	err := d.storage.Delete(document.Filepath())
+	if err != nil {
+		slog.Error("failed to delete document file", "documentID", document.ID, "error", err.Error())
+	}
+
+	for _, preview := range document.PreviewFilepaths {
+		err := d.previewStorage.Delete(preview)
+		if err != nil {
+			slog.Error("failed to delete preview file", "documentID", document.ID, "error", err.Error())
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestMoveDocument(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	old := a.createFolder(t, "Old", archive.FolderRootID, "alice")
+	foreign := a.createFolder(t, "Private", archive.FolderRootID, "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+
+	if err := a.MoveDocument(document.ID, taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if moved := a.document(t, document.ID); moved.FolderID != taxes.ID {
+		t.Errorf("expected the document in the Taxes folder, got %s", moved.FolderID)
+	}
+
+	if err := a.MoveDocument(document.ID, foreign.ID, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected folders of other users to be rejected, got %v", err)
+	}
+	if err := a.MoveDocument(document.ID, taxes.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected documents of other users not to be moved, got %v", err)
+	}
+	if err := a.TrashFolder(old.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.MoveDocument(document.ID, old.ID, "alice"); !errors.Is(err, archive.ErrFolderTrashed) {
+		t.Errorf("expected trashed folders to be rejected, got %v", err)
+	}
+	if moved := a.document(t, document.ID); moved.FolderID != taxes.ID {
+		t.Errorf("expected rejected moves to leave the document in place, got %s", moved.FolderID)
+	}
+}
+
+func TestCopyDocument(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	content := testFile(t, "mock_pdfs/invoice_0001.pdf")
+	document := a.upload(t, "invoice.pdf", content, archive.FolderRootID, "alice")
+	taxes, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AssignTag(document.ID, taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	a.replace(t, document.ID, "invoice.pdf", content, "alice")
+
+	duplicate, err := a.CopyDocument(document.ID, archive.FolderRootID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	copied := a.document(t, duplicate.ID)
+	if copied.Title != document.Title+" (Copy)" || copied.Version != 1 || copied.Owner != "alice" {
+		t.Errorf("expected a new document titled as a copy, got %q in version %d", copied.Title, copied.Version)
+	}
+	if len(copied.Tags) != 1 || copied.Tags[0].ID != taxes.ID {
+		t.Errorf("expected the tags to be copied, got %v", copied.Tags)
+	}
+	if copied.Text == "" || len(copied.PreviewFilepaths) == 0 {
+		t.Error("expected the text and previews to be copied")
+	}
+	if !bytes.Equal(a.download(t, copied.ID, "alice"), content) {
+		t.Error("expected the file to be copied")
+	}
+	if versions, err := a.GetDocumentVersions(copied.ID, "alice"); err != nil || len(versions) != 0 {
+		t.Errorf("expected the copy to start without versions, got %v: %v", versions, err)
+	}
+}
+
+func TestCopyDocumentIntoSharedFolder(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	inbox := a.createFolder(t, "Inbox", archive.FolderRootID, "bob")
+	if _, err := a.ShareFolder(inbox.ID, "alice", archive.PermissionWrite, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	taxes, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AssignTag(document.ID, taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	duplicate, err := a.CopyDocument(document.ID, inbox.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tags of alice mean nothing to bob
+	copied := a.document(t, duplicate.ID)
+	if copied.Owner != "bob" || copied.FolderID != inbox.ID || len(copied.Tags) != 0 || copied.Title != document.Title {
+		t.Errorf("expected a copy of bob without the tags of alice, got %+v", copied)
+	}
+
+	// Moving hands documents over to other users, copying does not
+	if err := a.MoveDocument(document.ID, inbox.ID, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected documents not to be moved to other owners, got %v", err)
+	}
+}
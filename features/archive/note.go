@@ -0,0 +1,204 @@
+package archive
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrNoteNotFound      = errors.New("note not found")
+	ErrInvalidNote       = errors.New("invalid note")
+	ErrInvalidNoteAnchor = errors.New("invalid note anchor")
+)
+
+// NoteAnchor places a note on a page of a document. Page is one-based, zero means the note belongs to the whole document.
+// The area is given in fractions of the page size, so it matches the page in any resolution. An area without width
+// or height covers the whole page.
+type NoteAnchor struct {
+	Page   int
+	X      float64
+	Y      float64
+	Width  float64
+	Height float64
+}
+
+func (anchor NoteAnchor) IsAnchored() bool {
+	return anchor.Page > 0
+}
+
+func (anchor NoteAnchor) HasArea() bool {
+	return anchor.Width > 0 && anchor.Height > 0
+}
+
+func (anchor NoteAnchor) validate(pageCount int) error {
+	if anchor.Page < 0 || (pageCount > 0 && anchor.Page > pageCount) {
+		return ErrInvalidNoteAnchor
+	}
+
+	if anchor.X == 0 && anchor.Y == 0 && !anchor.HasArea() {
+		return nil
+	}
+	if !anchor.IsAnchored() || !anchor.HasArea() {
+		return ErrInvalidNoteAnchor
+	}
+	if anchor.X < 0 || anchor.Y < 0 || anchor.X+anchor.Width > 1 || anchor.Y+anchor.Height > 1 {
+		return ErrInvalidNoteAnchor
+	}
+	return nil
+}
+
+// Note is a remark on a document, the body is written in markdown.
+type Note struct {
+	ID         string
+	DocumentID string
+	Body       string
+	Author     string
+	Anchor     NoteAnchor
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type NoteRepository interface {
+	Save(note Note) error
+	FindByID(id string) (Note, error)
+	FindAllByDocumentID(documentID string) ([]Note, error)
+	DeleteByID(id string) error
+}
+
+type notes struct {
+	repository NoteRepository
+	documents  *documents
+}
+
+// CreateNote adds a note to a document the author can change.
+func (n *notes) CreateNote(documentID string, author string, body string, anchor NoteAnchor) (Note, error) {
+	document, err := n.documents.getWritableDocument(documentID, author)
+	if err != nil {
+		return Note{}, err
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return Note{}, ErrInvalidNote
+	}
+
+	err = anchor.validate(len(document.PreviewFilepaths))
+	if err != nil {
+		return Note{}, err
+	}
+
+	note := Note{
+		ID:         common.GenerateID(),
+		DocumentID: document.ID,
+		Body:       body,
+		Author:     author,
+		Anchor:     anchor,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	err = n.repository.Save(note)
+	if err != nil {
+		return Note{}, err
+	}
+
+	return note, n.publishNotesChanged(document.ID)
+}
+
+// GetNotes returns the notes of a document, oldest first.
+func (n *notes) GetNotes(documentID string, user string) ([]Note, error) {
+	document, err := n.documents.GetDocument(documentID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := n.repository.FindAllByDocumentID(document.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(notes, func(n1, n2 Note) int {
+		return n1.CreatedAt.Compare(n2.CreatedAt)
+	})
+	return notes, nil
+}
+
+// UpdateNote changes the body of a note, only its author can do so.
+func (n *notes) UpdateNote(documentID string, noteID string, user string, body string) error {
+	note, _, err := n.getNote(documentID, noteID, user)
+	if err != nil {
+		return err
+	}
+	if note.Author != user {
+		return ErrNotAllowed
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return ErrInvalidNote
+	}
+
+	note.Body = body
+	note.UpdatedAt = time.Now()
+	err = n.repository.Save(note)
+	if err != nil {
+		return err
+	}
+
+	return n.publishNotesChanged(note.DocumentID)
+}
+
+// DeleteNote removes a note, the author and the owner of the document can do so.
+func (n *notes) DeleteNote(documentID string, noteID string, user string) error {
+	note, document, err := n.getNote(documentID, noteID, user)
+	if err != nil {
+		return err
+	}
+	if note.Author != user && document.Owner != user {
+		return ErrNotAllowed
+	}
+
+	err = n.repository.DeleteByID(note.ID)
+	if err != nil {
+		return err
+	}
+
+	return n.publishNotesChanged(note.DocumentID)
+}
+
+// getNote returns a note along with its document, the user needs access to the document.
+func (n *notes) getNote(documentID string, noteID string, user string) (Note, Document, error) {
+	document, err := n.documents.GetDocument(documentID, user)
+	if err != nil {
+		return Note{}, Document{}, err
+	}
+
+	note, err := n.repository.FindByID(noteID)
+	if err != nil {
+		return Note{}, Document{}, err
+	}
+	if note.DocumentID != document.ID {
+		return Note{}, Document{}, ErrNoteNotFound
+	}
+
+	return note, document, nil
+}
+
+// publishNotesChanged reloads the document, so subscribers like the search index see its current notes.
+func (n *notes) publishNotesChanged(documentID string) error {
+	document, err := n.documents.repository.FindByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	return n.documents.messages.PublishDocumentUpserted(document)
+}
+
+func newNotes(repository NoteRepository, documents *documents) *notes {
+	return &notes{
+		repository: repository,
+		documents:  documents,
+	}
+}
@@ -0,0 +1,102 @@
+package archive_test
+
+import (
+	"errors"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestCreateNoteAnchors(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	pageCount := len(document.PreviewFilepaths)
+
+	valid := map[string]archive.NoteAnchor{
+		"on the whole document": {},
+		"on a page":             {Page: 1},
+		"on an area of a page":  {Page: 1, X: 0.5, Y: 0.25, Width: 0.5, Height: 0.75},
+	}
+	for name, anchor := range valid {
+		if _, err := a.CreateNote(document.ID, "alice", "Paid", anchor); err != nil {
+			t.Errorf("expected a note %s, got %v", name, err)
+		}
+	}
+
+	invalid := map[string]archive.NoteAnchor{
+		"after the last page":        {Page: pageCount + 1},
+		"before the first page":      {Page: -1},
+		"on an area without a page":  {X: 0.1, Y: 0.1, Width: 0.1, Height: 0.1},
+		"on an area without a size":  {Page: 1, X: 0.1, Y: 0.1},
+		"on an area beyond the page": {Page: 1, X: 0.5, Y: 0.5, Width: 0.6, Height: 0.1},
+		"on an area before the page": {Page: 1, X: -0.1, Y: 0, Width: 0.5, Height: 0.5},
+	}
+	for name, anchor := range invalid {
+		if _, err := a.CreateNote(document.ID, "alice", "Paid", anchor); !errors.Is(err, archive.ErrInvalidNoteAnchor) {
+			t.Errorf("expected a note %s to be rejected, got %v", name, err)
+		}
+	}
+
+	if _, err := a.CreateNote(document.ID, "alice", " \n ", archive.NoteAnchor{}); !errors.Is(err, archive.ErrInvalidNote) {
+		t.Errorf("expected an empty note to be rejected, got %v", err)
+	}
+	if notes := a.document(t, document.ID).Notes; len(notes) != len(valid) {
+		t.Errorf("expected the document to carry %d notes, got %d", len(valid), len(notes))
+	}
+}
+
+func TestNotesOfSharedDocument(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob", "carol")
+	shared := a.createFolder(t, "Shared", archive.FolderRootID, "alice")
+	if _, err := a.ShareFolder(shared.ID, "bob", archive.PermissionWrite, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ShareFolder(shared.ID, "carol", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), shared.ID, "alice")
+
+	own, err := a.CreateNote(document.ID, "alice", "Check the amount", archive.NoteAnchor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := a.CreateNote(document.ID, "bob", "Amount is correct", archive.NoteAnchor{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CreateNote(document.ID, "carol", "Looks fine", archive.NoteAnchor{}); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected readers not to add notes, got %v", err)
+	}
+
+	notes, err := a.GetNotes(document.ID, "carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 2 || notes[0].ID != own.ID || notes[1].ID != foreign.ID {
+		t.Errorf("expected readers to see the notes oldest first, got %v", notes)
+	}
+
+	// Only the author changes a note, the owner of the document may remove it as well
+	if err := a.UpdateNote(document.ID, foreign.ID, "alice", "Amount is wrong"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected notes of others not to be changed, got %v", err)
+	}
+	if err := a.DeleteNote(document.ID, own.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected notes of the owner not to be removed by others, got %v", err)
+	}
+	if err := a.UpdateNote(document.ID, foreign.ID, "bob", " "); !errors.Is(err, archive.ErrInvalidNote) {
+		t.Errorf("expected a note not to be emptied, got %v", err)
+	}
+	if err := a.DeleteNote(document.ID, foreign.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	other := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), shared.ID, "alice")
+	if err := a.UpdateNote(other.ID, own.ID, "alice", "Moved"); !errors.Is(err, archive.ErrNoteNotFound) {
+		t.Errorf("expected notes to belong to their document, got %v", err)
+	}
+
+	if notes := a.document(t, document.ID).Notes; len(notes) != 1 || notes[0].ID != own.ID {
+		t.Errorf("expected only the note of alice to remain, got %v", notes)
+	}
+}
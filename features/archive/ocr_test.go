@@ -0,0 +1,61 @@
+package archive_test
+
+import (
+	"strings"
+	"testing"
+	"unterlagen/features/archive"
+	"unterlagen/platform/ocr"
+)
+
+// recognized is longer than the text layer of the test invoices, OCR only replaces text it improves on
+var recognized = strings.Repeat("Recognized text of a scanned page. ", 100)
+
+func withOCR(engine archive.OCREngine, minCharactersPerPage int) func(*archive.Dependencies, *archive.Settings) {
+	return func(dependencies *archive.Dependencies, settings *archive.Settings) {
+		dependencies.OCREngine = engine
+		settings.OCRMinCharactersPerPage = minCharactersPerPage
+	}
+}
+
+func TestOCRSparseText(t *testing.T) {
+	a := newTestArchive(t, withOCR(ocr.NewStaticEngine(recognized), 100000))
+	a.createUsers(t, "alice")
+
+	document := a.upload(t, "scan.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	if !document.IsOCRed() {
+		t.Fatal("expected a document with sparse text to be sent to OCR")
+	}
+	if !strings.Contains(document.Text, "Recognized text of a scanned page.") {
+		t.Errorf("expected the recognized text to replace the text layer, got %q", document.Text)
+	}
+}
+
+func TestOCRTextLayer(t *testing.T) {
+	a := newTestArchive(t, withOCR(ocr.NewStaticEngine(recognized), 1))
+	a.createUsers(t, "alice")
+
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	if document.IsOCRed() || document.Text == "" || strings.Contains(document.Text, "Recognized") {
+		t.Errorf("expected a document with a text layer to keep it, got %q", document.Text)
+	}
+}
+
+func TestOCRWithoutEngine(t *testing.T) {
+	a := newTestArchive(t, withOCR(nil, 100000))
+	a.createUsers(t, "alice")
+
+	document := a.upload(t, "scan.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	if document.IsOCRed() || document.Text == "" {
+		t.Errorf("expected the text layer without an OCR engine, got %q", document.Text)
+	}
+}
+
+func TestOCRNotImproving(t *testing.T) {
+	a := newTestArchive(t, withOCR(ocr.NewStaticEngine("x"), 100000))
+	a.createUsers(t, "alice")
+
+	document := a.upload(t, "scan.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	if document.IsOCRed() || document.Text == "" {
+		t.Errorf("expected the text layer to be kept if OCR recognized less, got %q", document.Text)
+	}
+}
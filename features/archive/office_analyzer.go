@@ -0,0 +1,479 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var _ DocumentAnalyzer = &OfficeAnalyzer{}
+
+var ErrMissingDocumentPart = errors.New("missing document part")
+
+const (
+	mimetypeODT = "application/vnd.oasis.opendocument.text"
+	mimetypeODS = "application/vnd.oasis.opendocument.spreadsheet"
+	// maxZipSize bounds the office documents read into memory, the directory of a zip archive is at its end
+	maxZipSize = 100 << 20
+	// maxZipFileSize bounds each decompressed part, a small archive can expand to gigabytes
+	maxZipFileSize = 100 << 20
+)
+
+// OfficeAnalyzer extracts text from OOXML (DOCX, XLSX) and ODF (ODT, ODS) documents.
+// Both formats are zip archives containing XML, so no external tooling is required.
+type OfficeAnalyzer struct {
+	documentStorage DocumentStorage
+	previewStorage  DocumentPreviewStorage
+}
+
+// ExtractText implements DocumentAnalyzer.
+func (a *OfficeAnalyzer) ExtractText(document Document) (string, error) {
+	var text string
+	err := a.documentStorage.Retrieve(document.Filepath(), func(r io.Reader) error {
+		archive, err := openZip(r)
+		if err != nil {
+			return err
+		}
+
+		switch document.Filetype {
+		case DOCX:
+			text, err = extractDOCXText(archive)
+		case XLSX:
+			text, err = extractXLSXText(archive)
+		case ODT, ODS:
+			text, err = extractODFText(archive)
+		default:
+			err = ErrUnsupportedFiletype
+		}
+		return err
+	})
+
+	return strings.TrimSpace(text), err
+}
+
+// GeneratePreviews implements DocumentAnalyzer.
+// Office documents have no fixed layout without a rendering engine, so the
+// preview is the first page of the extracted text.
+func (a *OfficeAnalyzer) GeneratePreviews(document Document) ([]string, error) {
+	text, err := a.ExtractText(document)
+	if err != nil {
+		return nil, err
+	}
+
+	filepath := path.Join(document.PreviewPrefix(), "page0.jpeg")
+	err = storePreview(a.previewStorage, renderTextPreview(text), filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{filepath}, nil
+}
+
+func openZip(r io.Reader) (*zip.Reader, error) {
+	data, err := readAtMost(r, maxZipSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// readAtMost reads everything unless there are more than limit bytes.
+func readAtMost(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: larger than %d MB", ErrUnsupportedFiletype, limit>>20)
+	}
+	return data, nil
+}
+
+// detectZipFiletype distinguishes the office formats sharing the zip container.
+func detectZipFiletype(data []byte) (Filetype, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", ErrUnsupportedFiletype
+	}
+
+	mimetype, err := readZipFile(archive, "mimetype")
+	if err == nil {
+		switch strings.TrimSpace(string(mimetype)) {
+		case mimetypeODT:
+			return ODT, nil
+		case mimetypeODS:
+			return ODS, nil
+		}
+	}
+
+	for _, file := range archive.File {
+		switch file.Name {
+		case "word/document.xml":
+			return DOCX, nil
+		case "xl/workbook.xml":
+			return XLSX, nil
+		}
+	}
+
+	return "", ErrUnsupportedFiletype
+}
+
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrMissingDocumentPart, name)
+	}
+	defer file.Close()
+
+	data, err := readAtMost(file, maxZipFileSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return data, nil
+}
+
+func extractDOCXText(archive *zip.Reader) (string, error) {
+	data, err := readZipFile(archive, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	inText := false
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(element)
+			}
+		}
+	}
+
+	return text.String(), nil
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+
+	var text strings.Builder
+	for _, run := range t.Runs {
+		text.WriteString(run.Text)
+	}
+	return text.String()
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Type   string       `xml:"t,attr"`
+			Value  string       `xml:"v"`
+			Inline xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func extractXLSXText(archive *zip.Reader) (string, error) {
+	var workbook xlsxWorkbook
+	if err := unmarshalZipFile(archive, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+
+	var relationships xlsxRelationships
+	if err := unmarshalZipFile(archive, "xl/_rels/workbook.xml.rels", &relationships); err != nil {
+		return "", err
+	}
+
+	// Shared strings are optional, workbooks with numbers only do not have them
+	var sharedStrings xlsxSharedStrings
+	err := unmarshalZipFile(archive, "xl/sharedStrings.xml", &sharedStrings)
+	if err != nil && !errors.Is(err, ErrMissingDocumentPart) {
+		return "", err
+	}
+
+	targets := make(map[string]string)
+	for _, relationship := range relationships.Relationships {
+		target := strings.TrimPrefix(relationship.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[relationship.ID] = target
+	}
+
+	var text strings.Builder
+	for _, sheet := range workbook.Sheets {
+		var worksheet xlsxWorksheet
+		if err := unmarshalZipFile(archive, targets[sheet.ID], &worksheet); err != nil {
+			return "", err
+		}
+
+		text.WriteString(sheet.Name)
+		text.WriteString("\n")
+		for _, row := range worksheet.Rows {
+			var values []string
+			for _, cell := range row.Cells {
+				value := cell.Value
+				switch cell.Type {
+				case "s":
+					var index int
+					if _, err := fmt.Sscanf(cell.Value, "%d", &index); err == nil && index < len(sharedStrings.Items) {
+						value = sharedStrings.Items[index].String()
+					}
+				case "inlineStr":
+					value = cell.Inline.String()
+				}
+				values = append(values, value)
+			}
+			text.WriteString(strings.Join(values, "\t"))
+			text.WriteString("\n")
+		}
+		text.WriteString("\n")
+	}
+
+	return text.String(), nil
+}
+
+func unmarshalZipFile(archive *zip.Reader, name string, v any) error {
+	data, err := readZipFile(archive, name)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// extractODFText walks content.xml of text documents and spreadsheets alike.
+// Paragraphs become lines, spreadsheet cells are separated by tabs.
+func extractODFText(archive *zip.Reader) (string, error) {
+	data, err := readZipFile(archive, "content.xml")
+	if err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	var row []string
+	var cell strings.Builder
+	paragraphDepth := 0
+	inCell := false
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		current := &text
+		if inCell {
+			current = &cell
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "p", "h":
+				if paragraphDepth == 0 && inCell && cell.Len() > 0 {
+					cell.WriteString(" ")
+				}
+				paragraphDepth++
+			case "s":
+				spaces := 1
+				for _, attr := range element.Attr {
+					if attr.Name.Local == "c" {
+						fmt.Sscanf(attr.Value, "%d", &spaces)
+					}
+				}
+				current.WriteString(strings.Repeat(" ", max(spaces, 1)))
+			case "tab":
+				current.WriteString("\t")
+			case "line-break":
+				current.WriteString("\n")
+			case "table-cell":
+				inCell = true
+				cell.Reset()
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "p", "h":
+				paragraphDepth--
+				if paragraphDepth == 0 && !inCell {
+					text.WriteString("\n")
+				}
+			case "table-cell":
+				inCell = false
+				row = append(row, cell.String())
+			case "table-row":
+				// Trailing empty cells are stored as repeated columns, drop them
+				for len(row) > 0 && row[len(row)-1] == "" {
+					row = row[:len(row)-1]
+				}
+				if len(row) > 0 {
+					text.WriteString(strings.Join(row, "\t"))
+					text.WriteString("\n")
+				}
+				row = nil
+			}
+		case xml.CharData:
+			if paragraphDepth > 0 {
+				current.Write(element)
+			}
+		}
+	}
+
+	return text.String(), nil
+}
+
+const (
+	textPreviewWidth      = 661 // A4 at 80 DPI, matching rendered PDF pages
+	textPreviewHeight     = 935
+	textPreviewMargin     = 48
+	textPreviewFontSize   = 11
+	textPreviewLineHeight = 16
+)
+
+// renderTextPreview draws as much of the text as fits onto a single page.
+func renderTextPreview(text string) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, textPreviewWidth, textPreviewHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	face, err := newPreviewFontFace()
+	if err != nil {
+		return img
+	}
+	defer face.Close()
+
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.Black),
+		Face: face,
+	}
+
+	maxWidth := fixed.I(textPreviewWidth - 2*textPreviewMargin)
+	y := textPreviewMargin + textPreviewLineHeight
+	for _, line := range wrapLines(drawer, text, maxWidth) {
+		if y > textPreviewHeight-textPreviewMargin {
+			break
+		}
+		drawer.Dot = fixed.P(textPreviewMargin, y)
+		drawer.DrawString(line)
+		y += textPreviewLineHeight
+	}
+
+	return img
+}
+
+func newPreviewFontFace() (font.Face, error) {
+	parsed, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		return nil, err
+	}
+
+	return opentype.NewFace(parsed, &opentype.FaceOptions{
+		Size:    textPreviewFontSize,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+func wrapLines(drawer *font.Drawer, text string, maxWidth fixed.Int26_6) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		paragraph = strings.ReplaceAll(paragraph, "\t", "    ")
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line := words[0]
+		for _, word := range words[1:] {
+			candidate := line + " " + word
+			if drawer.MeasureString(candidate) > maxWidth {
+				lines = append(lines, line)
+				line = word
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+
+	// Collapse runs of empty lines so the preview is not mostly whitespace
+	return slices.CompactFunc(lines, func(a, b string) bool {
+		return a == "" && b == ""
+	})
+}
+
+func NewOfficeAnalyzer(documentStorage DocumentStorage, previewStorage DocumentPreviewStorage) *OfficeAnalyzer {
+	return &OfficeAnalyzer{
+		documentStorage: documentStorage,
+		previewStorage:  previewStorage,
+	}
+}
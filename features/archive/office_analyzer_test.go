@@ -0,0 +1,126 @@
+package archive_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"unterlagen/features/archive"
+	"unterlagen/platform/configuration"
+	"unterlagen/platform/storage/filesystem"
+)
+
+// zipFile creates a zip archive like an office document, files are given as pairs of name and content in order.
+func zipFile(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		file, err := writer.Create(files[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Write([]byte(files[i+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOfficeUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	tests := []struct {
+		filename string
+		content  []byte
+		filetype archive.Filetype
+		text     []string
+	}{
+		{
+			filename: "letter.docx",
+			content: zipFile(t,
+				"[Content_Types].xml", "<Types/>",
+				"word/document.xml", `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Dear Alice</w:t></w:r></w:p><w:p><w:r><w:t>Your invoice</w:t></w:r></w:p></w:body></w:document>`,
+			),
+			filetype: archive.DOCX,
+			text:     []string{"Dear Alice\nYour invoice"},
+		},
+		{
+			filename: "budget.xlsx",
+			content: zipFile(t,
+				"[Content_Types].xml", "<Types/>",
+				"xl/workbook.xml", `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Budget" r:id="rId1"/></sheets></workbook>`,
+				"xl/_rels/workbook.xml.rels", `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+				"xl/sharedStrings.xml", `<sst><si><t>Rent</t></si></sst>`,
+				"xl/worksheets/sheet1.xml", `<worksheet><sheetData><row><c t="s"><v>0</v></c><c><v>950</v></c></row></sheetData></worksheet>`,
+			),
+			filetype: archive.XLSX,
+			text:     []string{"Budget", "Rent\t950"},
+		},
+		{
+			filename: "minutes.odt",
+			content: zipFile(t,
+				"mimetype", "application/vnd.oasis.opendocument.text",
+				"content.xml", `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><office:text><text:p>Minutes of the meeting</text:p></office:text></office:body></office:document-content>`,
+			),
+			filetype: archive.ODT,
+			text:     []string{"Minutes of the meeting"},
+		},
+		{
+			filename: "expenses.ods",
+			content: zipFile(t,
+				"mimetype", "application/vnd.oasis.opendocument.spreadsheet",
+				"content.xml", `<office:document-content xmlns:office="o" xmlns:table="t" xmlns:text="x"><office:body><office:spreadsheet><table:table><table:table-row><table:table-cell><text:p>Travel</text:p></table:table-cell><table:table-cell><text:p>120</text:p></table:table-cell></table:table-row></table:table></office:spreadsheet></office:body></office:document-content>`,
+			),
+			filetype: archive.ODS,
+			text:     []string{"Travel\t120"},
+		},
+	}
+	for _, test := range tests {
+		document := a.upload(t, test.filename, test.content, archive.FolderRootID, "alice")
+		if document.Filetype != test.filetype {
+			t.Errorf("expected %s to be detected as %s, got %s", test.filename, test.filetype, document.Filetype)
+		}
+		for _, text := range test.text {
+			if !strings.Contains(document.Text, text) {
+				t.Errorf("expected the text of %s to contain %q, got %q", test.filename, text, document.Text)
+			}
+		}
+		if len(document.PreviewFilepaths) != 1 {
+			t.Errorf("expected a preview of %s, got %v", test.filename, document.PreviewFilepaths)
+		}
+	}
+}
+
+func TestOtherZipUpload(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	content := zipFile(t, "notes.txt", "not an office document")
+	err := a.UploadDocument("notes.zip", uint64(len(content)), archive.FolderRootID, "alice", bytes.NewReader(content))
+	if !errors.Is(err, archive.ErrUnsupportedFiletype) {
+		t.Errorf("expected zip archives other than office documents to be rejected, got %v", err)
+	}
+}
+
+func TestOfficeDocumentPartTooLarge(t *testing.T) {
+	storage := filesystem.NewDocumentStorage(configuration.Configuration{})
+	analyzer := archive.NewOfficeAnalyzer(storage, filesystem.NewDocumentPreviewStorage(configuration.Configuration{}))
+
+	// Compresses to a few hundred kilobytes but expands beyond the limit of a part
+	document := archive.Document{ID: "bomb", Owner: "alice", Filename: "bomb.docx", Filetype: archive.DOCX}
+	content := zipFile(t, "word/document.xml", "<w:document>"+strings.Repeat(" ", 101<<20)+"</w:document>")
+	if err := storage.Store(document.Filepath(), bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := analyzer.ExtractText(document)
+	if !errors.Is(err, archive.ErrUnsupportedFiletype) {
+		t.Errorf("expected the oversized part to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package archive
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPages = errors.New("invalid pages")
+
+// maxPages bounds the pages a user can select, ranges are expanded before the document is known.
+const maxPages = 10000
+
+// PageEditor changes the pages of PDF documents. Pages are zero-based indexes, the edited file is returned as a new PDF.
+type PageEditor interface {
+	PageCount(document Document) (int, error)
+	RotatePages(document Document, pages []int, quarterTurns int) ([]byte, error)
+	DeletePages(document Document, pages []int) ([]byte, error)
+	ExtractPages(document Document, pages []int) ([]byte, error)
+	MergeDocuments(documents []Document) ([]byte, error)
+}
+
+// ParsePages parses page numbers and ranges as shown to users, like "1,3-5", into zero-based page indexes.
+// The indexes are sorted and free of duplicates, no more than maxPages pages can be selected.
+func ParsePages(input string) ([]int, error) {
+	var pages []int
+	for part := range strings.SplitSeq(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil || from < 1 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPages, part)
+		}
+		to := from
+		if isRange {
+			to, err = strconv.Atoi(strings.TrimSpace(last))
+			if err != nil || to < from {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidPages, part)
+			}
+		}
+		if to > maxPages || len(pages)+to-from >= maxPages {
+			return nil, fmt.Errorf("%w: at most %d pages can be selected", ErrInvalidPages, maxPages)
+		}
+
+		for page := from; page <= to; page++ {
+			pages = append(pages, page-1)
+		}
+	}
+
+	if len(pages) == 0 {
+		return nil, ErrInvalidPages
+	}
+	return slices.Compact(slices.Sorted(slices.Values(pages))), nil
+}
+
+// RotateDocumentPages rotates pages by clockwise quarter turns, negative turns rotate counterclockwise.
+func (d *documents) RotateDocumentPages(documentID string, owner string, pages []int, quarterTurns int) error {
+	document, err := d.getEditablePDF(documentID, owner, pages)
+	if err != nil {
+		return err
+	}
+
+	data, err := d.pageEditor.RotatePages(document, pages, quarterTurns)
+	if err != nil {
+		return err
+	}
+
+	return d.replaceWithPDF(document, data)
+}
+
+// DeleteDocumentPages removes pages from a document, at least one page has to remain.
+func (d *documents) DeleteDocumentPages(documentID string, owner string, pages []int) error {
+	document, err := d.getEditablePDF(documentID, owner, pages)
+	if err != nil {
+		return err
+	}
+
+	pageCount, err := d.pageEditor.PageCount(document)
+	if err != nil {
+		return err
+	}
+	if len(pages) >= pageCount {
+		return fmt.Errorf("%w: a document cannot lose all of its pages", ErrInvalidPages)
+	}
+
+	data, err := d.pageEditor.DeletePages(document, pages)
+	if err != nil {
+		return err
+	}
+
+	return d.replaceWithPDF(document, data)
+}
+
+// SplitDocument splits a document before each of the given pages. The document keeps the pages
+// in front of the first split, every other part becomes a new document in the same folder.
+func (d *documents) SplitDocument(documentID string, owner string, pages []int) ([]Document, error) {
+	document, err := d.getEditablePDF(documentID, owner, pages)
+	if err != nil {
+		return nil, err
+	}
+	if slices.Contains(pages, 0) {
+		return nil, fmt.Errorf("%w: a document cannot be split before its first page", ErrInvalidPages)
+	}
+
+	pageCount, err := d.pageEditor.PageCount(document)
+	if err != nil {
+		return nil, err
+	}
+
+	boundaries := append(slices.Compact(slices.Sorted(slices.Values(pages))), pageCount)
+	var parts [][]byte
+	start := 0
+	for _, end := range boundaries {
+		var part []int
+		for page := start; page < end; page++ {
+			part = append(part, page)
+		}
+
+		data, err := d.pageEditor.ExtractPages(document, part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, data)
+		start = end
+	}
+
+	var created []Document
+	for i, data := range parts[1:] {
+		part, err := d.createPart(document, data, i+2)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, part)
+	}
+
+	return created, d.replaceWithPDF(document, parts[0])
+}
+
+// MergeDocuments appends the pages of the other documents to the document in the given order.
+// The other documents are moved to the trash afterwards.
+func (d *documents) MergeDocuments(documentID string, otherIDs []string, owner string) error {
+	if len(otherIDs) == 0 {
+		return ErrInvalidPages
+	}
+	// Pages would be appended twice and the merged document itself moved to the trash
+	if slices.Contains(otherIDs, documentID) {
+		return fmt.Errorf("%w: a document cannot be merged with itself", ErrInvalidPages)
+	}
+	if len(slices.Compact(slices.Sorted(slices.Values(otherIDs)))) != len(otherIDs) {
+		return fmt.Errorf("%w: a document can only be merged once", ErrInvalidPages)
+	}
+
+	document, err := d.getEditablePDF(documentID, owner, nil)
+	if err != nil {
+		return err
+	}
+
+	merged := []Document{document}
+	for _, otherID := range otherIDs {
+		other, err := d.getEditablePDF(otherID, owner, nil)
+		if err != nil {
+			return err
+		}
+		merged = append(merged, other)
+	}
+
+	data, err := d.pageEditor.MergeDocuments(merged)
+	if err != nil {
+		return err
+	}
+
+	err = d.replaceWithPDF(document, data)
+	if err != nil {
+		return err
+	}
+
+	for _, other := range merged[1:] {
+		err := d.TrashDocument(other.ID, owner)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMergeCandidates returns the other PDFs in the folder of the document that can be merged into it.
+// Users without access to the folder of a shared document get no candidates.
+func (d *documents) GetMergeCandidates(documentID string, user string) ([]Document, error) {
+	document, err := d.getWritableDocument(documentID, user)
+	if errors.Is(err, ErrNotAllowed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := d.GetDocumentsInFolder(document.FolderID, user)
+	if errors.Is(err, ErrNotAllowed) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(documents, func(candidate Document) bool {
+		return candidate.ID == document.ID || candidate.Owner != document.Owner || candidate.Filetype != PDF || candidate.IsTrashed()
+	}), nil
+}
+
+// getEditablePDF returns a PDF the user can change that is not trashed, the given pages must exist in it.
+func (d *documents) getEditablePDF(documentID string, user string, pages []int) (Document, error) {
+	document, err := d.getWritableDocument(documentID, user)
+	if err != nil {
+		return Document{}, err
+	}
+	if document.IsTrashed() {
+		return Document{}, ErrNotAllowed
+	}
+	if document.Filetype != PDF {
+		return Document{}, fmt.Errorf("%w: only the pages of PDFs can be edited", ErrUnsupportedFiletype)
+	}
+
+	// Every edit replaces the current file, parts and merged documents must not be left behind half done
+	err = d.legalHolds.checkDocument(document)
+	if err != nil {
+		return Document{}, err
+	}
+
+	if pages == nil {
+		return document, nil
+	}
+
+	pageCount, err := d.pageEditor.PageCount(document)
+	if err != nil {
+		return Document{}, err
+	}
+	if len(pages) == 0 || slices.ContainsFunc(pages, func(page int) bool { return page < 0 || page >= pageCount }) {
+		return Document{}, fmt.Errorf("%w: the document has %d pages", ErrInvalidPages, pageCount)
+	}
+
+	return document, nil
+}
+
+// replaceWithPDF makes the edited file the new version of the document.
+func (d *documents) replaceWithPDF(document Document, data []byte) error {
+	staging := path.Join(document.Owner, document.ID, "staging", document.Filename)
+	err := d.storage.Store(staging, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := d.storage.Delete(staging); err != nil {
+			slog.Error("failed to delete staged document file", "error", err.Error(), "documentID", document.ID)
+		}
+	}()
+
+	hash := sha256.Sum256(data)
+	return d.replaceHead(document, staging, document.Filename, PDF, uint64(len(data)), hex.EncodeToString(hash[:]))
+}
+
+// createPart stores a part split off a document as a new document with the metadata of the original.
+func (d *documents) createPart(document Document, data []byte, number int) (Document, error) {
+	part := newDocument(fmt.Sprintf("%s-%d.pdf", document.Name(), number), PDF, uint64(len(data)), document.Owner, document.FolderID)
+	part.Title = fmt.Sprintf("%s (%d)", document.Title, number)
+	hash := sha256.Sum256(data)
+	part.ContentHash = hex.EncodeToString(hash[:])
+	part.CustomFields = document.CustomFields
+	part.CorrespondentID = document.CorrespondentID
+	part.DocumentTypeID = document.DocumentTypeID
+	part.CreatedAt = time.Now()
+	part.UpdatedAt = time.Now()
+
+	err := d.storage.Store(part.Filepath(), bytes.NewReader(data))
+	if err != nil {
+		return Document{}, err
+	}
+
+	err = d.repository.Save(part)
+	if err != nil {
+		d.deleteFiles(part)
+		return Document{}, err
+	}
+
+	for _, tag := range document.Tags {
+		err := d.tagRepository.AssignToDocument(tag.ID, part.ID)
+		if err != nil {
+			return Document{}, err
+		}
+	}
+
+	err = d.messages.PublishDocumentUpserted(part)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return part, d.scheduleDocumentProcessing(part)
+}
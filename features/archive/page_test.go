@@ -0,0 +1,113 @@
+package archive_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestMergeDocuments(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	first := a.upload(t, "first.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	second := a.upload(t, "second.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+
+	if err := a.MergeDocuments(first.ID, []string{second.ID}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	merged := a.document(t, first.ID)
+	if len(merged.PreviewFilepaths) != len(first.PreviewFilepaths)+len(second.PreviewFilepaths) {
+		t.Errorf("expected the pages of both documents, got %d", len(merged.PreviewFilepaths))
+	}
+	if !a.document(t, second.ID).IsTrashed() {
+		t.Error("expected the merged document to be trashed")
+	}
+}
+
+func TestMergeDocumentsRejectsRepetitions(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	first := a.upload(t, "first.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	second := a.upload(t, "second.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+
+	for name, otherIDs := range map[string][]string{
+		"itself":     {first.ID},
+		"itself too": {second.ID, first.ID},
+		"twice":      {second.ID, second.ID},
+		"nothing":    nil,
+	} {
+		if err := a.MergeDocuments(first.ID, otherIDs, "alice"); !errors.Is(err, archive.ErrInvalidPages) {
+			t.Errorf("expected merging %s to be rejected, got %v", name, err)
+		}
+	}
+
+	if a.document(t, first.ID).Version != first.Version || a.document(t, second.ID).IsTrashed() {
+		t.Error("expected rejected merges to leave the documents as they were")
+	}
+}
+
+func TestParsePages(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int
+	}{
+		{"1", []int{0}},
+		{" 3, 1-2 ,", []int{0, 1, 2}},
+		{"2-4,3", []int{1, 2, 3}},
+		{"10000", []int{9999}},
+	}
+	for _, test := range tests {
+		pages, err := archive.ParsePages(test.input)
+		if err != nil || !slices.Equal(pages, test.expected) {
+			t.Errorf("expected %q to select %v, got %v: %v", test.input, test.expected, pages, err)
+		}
+	}
+
+	for _, input := range []string{"", " , ", "0", "-1", "3-2", "a", "1-b", "10001", "1-10001", "1-5000,5001-10001", "1-999999999999"} {
+		if pages, err := archive.ParsePages(input); !errors.Is(err, archive.ErrInvalidPages) {
+			t.Errorf("expected %q to be rejected, got %d pages", input, len(pages))
+		}
+	}
+}
+
+func TestSplitDocument(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	first := a.upload(t, "first.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	second := a.upload(t, "second.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	if err := a.MergeDocuments(first.ID, []string{second.ID}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+	merged := a.document(t, first.ID)
+	pageCount := len(merged.PreviewFilepaths)
+
+	for name, pages := range map[string][]int{
+		"before its first page": {0},
+		"after its last page":   {pageCount},
+	} {
+		if _, err := a.SplitDocument(first.ID, "alice", pages); !errors.Is(err, archive.ErrInvalidPages) {
+			t.Errorf("expected a split %s to be rejected, got %v", name, err)
+		}
+	}
+
+	split := len(first.PreviewFilepaths)
+	parts, err := a.SplitDocument(first.ID, "alice", []int{split})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	if len(parts) != 1 || parts[0].Title != merged.Title+" (2)" {
+		t.Fatalf("expected a second part, got %v", parts)
+	}
+	if pages := len(a.document(t, first.ID).PreviewFilepaths); pages != split {
+		t.Errorf("expected the document to keep the first %d pages, got %d", split, pages)
+	}
+	if pages := len(a.document(t, parts[0].ID).PreviewFilepaths); pages != pageCount-split {
+		t.Errorf("expected the part to hold the other %d pages, got %d", pageCount-split, pages)
+	}
+}
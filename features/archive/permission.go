@@ -0,0 +1,432 @@
+package archive
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+	"unterlagen/features/administration"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrGrantNotFound     = errors.New("grant not found")
+	ErrInvalidGrant      = errors.New("invalid grant")
+	ErrGranteeNotFound   = errors.New("user to share with not found")
+	ErrInvalidPermission = errors.New("invalid permission")
+)
+
+// Permission is the access of a user to a document or folder. Owners have full access,
+// other users get read or write access through grants.
+type Permission string
+
+const (
+	PermissionNone  Permission = ""
+	PermissionRead  Permission = "read"
+	PermissionWrite Permission = "write"
+	PermissionOwner Permission = "owner"
+)
+
+// GrantablePermissions are the permissions an owner can grant to other users.
+var GrantablePermissions = []Permission{PermissionRead, PermissionWrite}
+
+func (permission Permission) rank() int {
+	switch permission {
+	case PermissionRead:
+		return 1
+	case PermissionWrite:
+		return 2
+	case PermissionOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// Allows reports whether the permission includes the required one, write access includes read access.
+func (permission Permission) Allows(required Permission) bool {
+	return permission.rank() >= required.rank()
+}
+
+func (permission Permission) Label() string {
+	switch permission {
+	case PermissionRead:
+		return "Can view"
+	case PermissionWrite:
+		return "Can edit"
+	case PermissionOwner:
+		return "Owner"
+	default:
+		return "No access"
+	}
+}
+
+// Grant gives a user access to a document or a folder of another user. Grants on folders are
+// inherited by all subfolders and documents. Exactly one of DocumentID and FolderID is set.
+type Grant struct {
+	ID         string
+	DocumentID string
+	FolderID   string
+	Grantee    string
+	Permission Permission
+	CreatedAt  time.Time
+}
+
+type GrantRepository interface {
+	Save(grant Grant) error
+	FindByID(id string) (Grant, error)
+	FindAllByDocumentID(documentID string) ([]Grant, error)
+	FindAllByFolderID(folderID string) ([]Grant, error)
+	FindAllByGrantee(grantee string) ([]Grant, error)
+	DeleteByID(id string) error
+}
+
+// permissions decides who may access documents and folders. Documents always belong to the owner
+// of their folder, so the owner of a folder has full access to everything below it.
+type permissions struct {
+	repository         GrantRepository
+	documentRepository DocumentRepository
+	folderRepository   FolderRepository
+	userRepository     administration.UserRepository
+	groupRepository    administration.GroupRepository
+}
+
+// documentPermission returns the access of the user to a document, through ownership,
+// a grant on the document or a grant on one of its folders.
+func (p *permissions) documentPermission(document Document, user string) (Permission, error) {
+	if document.Owner == user {
+		return PermissionOwner, nil
+	}
+
+	isMember, err := p.isGroupMember(document.Owner, user)
+	if err != nil || isMember {
+		return PermissionWrite, err
+	}
+
+	grants, err := p.repository.FindAllByGrantee(user)
+	if err != nil {
+		return PermissionNone, err
+	}
+
+	permission := PermissionNone
+	hasFolderGrants := false
+	for _, grant := range grants {
+		if grant.DocumentID == document.ID && grant.Permission.rank() > permission.rank() {
+			permission = grant.Permission
+		}
+		hasFolderGrants = hasFolderGrants || grant.FolderID != ""
+	}
+	if !hasFolderGrants || document.FolderID == FolderRootID {
+		return permission, nil
+	}
+
+	hierarchy, err := p.folderRepository.GetHierarchy(document.FolderID)
+	if err != nil {
+		return PermissionNone, err
+	}
+
+	inherited := p.inheritedPermission(hierarchy, grants)
+	if inherited.rank() > permission.rank() {
+		permission = inherited
+	}
+	return permission, nil
+}
+
+// folderPermission returns the access of the user to the last folder of the hierarchy.
+func (p *permissions) folderPermission(hierarchy []Folder, user string) (Permission, error) {
+	if len(hierarchy) == 0 {
+		return PermissionNone, nil
+	}
+	if hierarchy[len(hierarchy)-1].Owner == user {
+		return PermissionOwner, nil
+	}
+
+	isMember, err := p.isGroupMember(hierarchy[len(hierarchy)-1].Owner, user)
+	if err != nil || isMember {
+		return PermissionWrite, err
+	}
+
+	grants, err := p.repository.FindAllByGrantee(user)
+	if err != nil {
+		return PermissionNone, err
+	}
+
+	return p.inheritedPermission(hierarchy, grants), nil
+}
+
+// isGroupMember reports whether the owner is a group the user is a member of. Members can change
+// everything their groups own, only the group itself is the owner.
+func (p *permissions) isGroupMember(owner string, user string) (bool, error) {
+	groups, err := p.groupRepository.FindAllByMember(user)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.ContainsFunc(groups, func(group administration.Group) bool { return group.Name == owner }), nil
+}
+
+// getGroupNames returns the names of the groups the user is a member of.
+func (p *permissions) getGroupNames(user string) ([]string, error) {
+	groups, err := p.groupRepository.FindAllByMember(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, group := range groups {
+		names = append(names, group.Name)
+	}
+	return names, nil
+}
+
+// inheritedPermission returns the highest permission the grants give on any folder of the hierarchy.
+// The root folder is shared by all users and can never be granted.
+func (p *permissions) inheritedPermission(hierarchy []Folder, grants []Grant) Permission {
+	permission := PermissionNone
+	for _, grant := range grants {
+		if grant.FolderID == "" || grant.FolderID == FolderRootID {
+			continue
+		}
+		if slices.ContainsFunc(hierarchy, func(folder Folder) bool { return folder.ID == grant.FolderID }) && grant.Permission.rank() > permission.rank() {
+			permission = grant.Permission
+		}
+	}
+	return permission
+}
+
+// ShareDocument grants another user access to a document of the owner. Sharing again with the same user changes the permission.
+func (p *permissions) ShareDocument(documentID string, grantee string, permission Permission, owner string) (Grant, error) {
+	document, err := p.documentRepository.FindByID(documentID)
+	if err != nil {
+		return Grant{}, err
+	}
+	if document.Owner != owner {
+		return Grant{}, ErrNotAllowed
+	}
+
+	existing, err := p.repository.FindAllByDocumentID(document.ID)
+	if err != nil {
+		return Grant{}, err
+	}
+
+	return p.grant(Grant{DocumentID: document.ID}, existing, grantee, permission, owner)
+}
+
+// ShareFolder grants another user access to a folder of the owner with all of its subfolders and documents.
+func (p *permissions) ShareFolder(folderID string, grantee string, permission Permission, owner string) (Grant, error) {
+	folder, err := p.getOwnedFolder(folderID, owner)
+	if err != nil {
+		return Grant{}, err
+	}
+
+	existing, err := p.repository.FindAllByFolderID(folder.ID)
+	if err != nil {
+		return Grant{}, err
+	}
+
+	return p.grant(Grant{FolderID: folder.ID}, existing, grantee, permission, owner)
+}
+
+func (p *permissions) grant(grant Grant, existing []Grant, grantee string, permission Permission, owner string) (Grant, error) {
+	if !slices.Contains(GrantablePermissions, permission) {
+		return Grant{}, ErrInvalidPermission
+	}
+	if grantee == owner {
+		return Grant{}, fmt.Errorf("%w: items cannot be shared with their owner", ErrInvalidGrant)
+	}
+
+	// Groups cannot sign in, their members get access through the folder of the group
+	account, err := p.userRepository.FindByUsername(grantee)
+	if err != nil || account.Role == administration.UserRoleGroup {
+		return Grant{}, fmt.Errorf("%w: %s", ErrGranteeNotFound, grantee)
+	}
+
+	grant.ID = common.GenerateID()
+	grant.CreatedAt = time.Now()
+	for _, other := range existing {
+		if other.Grantee == grantee {
+			grant.ID = other.ID
+			grant.CreatedAt = other.CreatedAt
+		}
+	}
+
+	grant.Grantee = grantee
+	grant.Permission = permission
+	return grant, p.repository.Save(grant)
+}
+
+// GetDocumentGrants returns the grants on a document of the owner.
+func (p *permissions) GetDocumentGrants(documentID string, owner string) ([]Grant, error) {
+	document, err := p.documentRepository.FindByID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.Owner != owner {
+		return nil, ErrNotAllowed
+	}
+
+	return p.repository.FindAllByDocumentID(document.ID)
+}
+
+// GetFolderGrants returns the grants on a folder of the owner.
+func (p *permissions) GetFolderGrants(folderID string, owner string) ([]Grant, error) {
+	folder, err := p.getOwnedFolder(folderID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.repository.FindAllByFolderID(folder.ID)
+}
+
+// RevokeGrant removes a grant. The owner of the shared item can revoke it, the grantee can give it up.
+func (p *permissions) RevokeGrant(grantID string, user string) error {
+	grant, err := p.repository.FindByID(grantID)
+	if err != nil {
+		return err
+	}
+
+	if grant.Grantee != user {
+		if grant.DocumentID != "" {
+			_, err = p.GetDocumentGrants(grant.DocumentID, user)
+		} else {
+			_, err = p.GetFolderGrants(grant.FolderID, user)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.repository.DeleteByID(grant.ID)
+}
+
+// GetSharedWithMe returns the folders and documents other users shared with the user directly.
+// Items in the trash are left out.
+func (p *permissions) GetSharedWithMe(user string) ([]Folder, []Document, error) {
+	grants, err := p.repository.FindAllByGrantee(user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var folders []Folder
+	var documentIDs []string
+	for _, grant := range grants {
+		if grant.DocumentID != "" {
+			documentIDs = append(documentIDs, grant.DocumentID)
+			continue
+		}
+
+		hierarchy, err := p.folderRepository.GetHierarchy(grant.FolderID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(hierarchy) > 0 && !hierarchy[len(hierarchy)-1].IsTrashed() {
+			folders = append(folders, hierarchy[len(hierarchy)-1])
+		}
+	}
+
+	var documents []Document
+	if len(documentIDs) > 0 {
+		documents, err = p.documentRepository.FindAllByIDIn(documentIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		documents = slices.DeleteFunc(documents, func(document Document) bool { return document.IsTrashed() })
+	}
+
+	return folders, documents, nil
+}
+
+// GetSharedDocumentIDs returns the ids of all documents of other users the user can read,
+// shared directly, through one of their folders or owned by a group of the user.
+func (p *permissions) GetSharedDocumentIDs(user string) ([]string, error) {
+	grants, err := p.repository.FindAllByGrantee(user)
+	if err != nil {
+		return nil, err
+	}
+
+	groupNames, err := p.getGroupNames(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, groupName := range groupNames {
+		documents, err := p.documentRepository.FindAllByOwner(groupName)
+		if err != nil {
+			return nil, err
+		}
+		for _, document := range documents {
+			ids = append(ids, document.ID)
+		}
+	}
+
+	for _, grant := range grants {
+		if grant.DocumentID != "" {
+			ids = append(ids, grant.DocumentID)
+			continue
+		}
+
+		err := p.collectDocumentIDs(grant.FolderID, &ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(ids))), nil
+}
+
+// collectDocumentIDs adds the ids of the documents in the folder and all of its subfolders.
+func (p *permissions) collectDocumentIDs(folderID string, ids *[]string) error {
+	documents, err := p.documentRepository.FindAllByFolderID(folderID)
+	if err != nil {
+		return err
+	}
+	for _, document := range documents {
+		*ids = append(*ids, document.ID)
+	}
+
+	children, err := p.folderRepository.FindAllByParentID(folderID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		err := p.collectDocumentIDs(child.ID, ids)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getOwnedFolder returns a folder of the owner that can be shared, the root folder cannot.
+func (p *permissions) getOwnedFolder(folderID string, owner string) (Folder, error) {
+	if folderID == FolderRootID {
+		return Folder{}, ErrNotAllowed
+	}
+
+	hierarchy, err := p.folderRepository.GetHierarchy(folderID)
+	if err != nil {
+		return Folder{}, err
+	}
+	if len(hierarchy) == 0 || hierarchy[len(hierarchy)-1].Owner != owner {
+		return Folder{}, ErrNotAllowed
+	}
+
+	return hierarchy[len(hierarchy)-1], nil
+}
+
+func newPermissions(
+	repository GrantRepository,
+	documentRepository DocumentRepository,
+	folderRepository FolderRepository,
+	userRepository administration.UserRepository,
+	groupRepository administration.GroupRepository,
+) *permissions {
+	return &permissions{
+		repository:         repository,
+		documentRepository: documentRepository,
+		folderRepository:   folderRepository,
+		userRepository:     userRepository,
+		groupRepository:    groupRepository,
+	}
+}
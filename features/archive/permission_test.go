@@ -0,0 +1,262 @@
+package archive_test
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+// sharing is an archive of alice with the folders Taxes and Taxes/2025, the receipt in Taxes/2025 and the invoice
+// in the root folder. Bob is a member of the family group, which owns the photo in its folder. Carol has no access.
+type sharing struct {
+	*testArchive
+	taxes       archive.Folder
+	taxes2025   archive.Folder
+	familyFiles archive.Folder
+	receipt     archive.Document
+	invoice     archive.Document
+	photo       archive.Document
+}
+
+func newSharing(t *testing.T) *sharing {
+	s := &sharing{testArchive: newTestArchive(t)}
+	s.createUsers(t, "alice", "bob", "carol")
+	s.familyFiles = s.createGroup(t, "family", "bob")
+	s.taxes = s.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	s.taxes2025 = s.createFolder(t, "2025", s.taxes.ID, "alice")
+	s.receipt = s.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), s.taxes2025.ID, "alice")
+	s.invoice = s.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	s.photo = s.upload(t, "photo.pdf", testFile(t, "mock_pdfs/invoice_0003.pdf"), s.familyFiles.ID, "bob")
+	return s
+}
+
+func (s *sharing) requireDocumentPermission(t *testing.T, documentID string, user string, expected archive.Permission) {
+	t.Helper()
+	permission, err := s.DocumentPermission(documentID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permission != expected {
+		t.Errorf("expected %s to have %q access to document %s, got %q", user, expected, documentID, permission)
+	}
+}
+
+func (s *sharing) requireFolderPermission(t *testing.T, folderID string, user string, expected archive.Permission) {
+	t.Helper()
+	permission, err := s.FolderPermission(folderID, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if permission != expected {
+		t.Errorf("expected %s to have %q access to folder %s, got %q", user, expected, folderID, permission)
+	}
+}
+
+func TestPermissionsWithoutGrants(t *testing.T) {
+	s := newSharing(t)
+
+	s.requireDocumentPermission(t, s.receipt.ID, "alice", archive.PermissionOwner)
+	s.requireFolderPermission(t, s.taxes2025.ID, "alice", archive.PermissionOwner)
+	s.requireDocumentPermission(t, s.receipt.ID, "carol", archive.PermissionNone)
+	s.requireFolderPermission(t, s.taxes.ID, "carol", archive.PermissionNone)
+
+	if _, err := s.GetDocument(s.receipt.ID, "carol"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected carol not to get the receipt, got %v", err)
+	}
+	if _, err := s.GetFolder(s.taxes.ID, "carol"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected carol not to get the folder, got %v", err)
+	}
+}
+
+func TestGroupPermissions(t *testing.T) {
+	s := newSharing(t)
+
+	// Members change everything their groups own, without being the owner
+	s.requireDocumentPermission(t, s.photo.ID, "bob", archive.PermissionWrite)
+	s.requireFolderPermission(t, s.familyFiles.ID, "bob", archive.PermissionWrite)
+	s.requireDocumentPermission(t, s.photo.ID, "carol", archive.PermissionNone)
+	s.requireFolderPermission(t, s.familyFiles.ID, "alice", archive.PermissionNone)
+
+	ids, err := s.GetSharedDocumentIDs("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []string{s.photo.ID}) {
+		t.Errorf("expected bob to find the photo of the group, got %v", ids)
+	}
+
+	// Groups cannot sign in, so nothing can be shared with them directly
+	if _, err := s.ShareDocument(s.receipt.ID, "family", archive.PermissionRead, "alice"); !errors.Is(err, archive.ErrGranteeNotFound) {
+		t.Errorf("expected sharing with a group to fail, got %v", err)
+	}
+}
+
+func TestFolderGrantInheritance(t *testing.T) {
+	s := newSharing(t)
+
+	grant, err := s.ShareFolder(s.taxes.ID, "carol", archive.PermissionRead, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.requireFolderPermission(t, s.taxes.ID, "carol", archive.PermissionRead)
+	s.requireFolderPermission(t, s.taxes2025.ID, "carol", archive.PermissionRead)
+	s.requireDocumentPermission(t, s.receipt.ID, "carol", archive.PermissionRead)
+	s.requireDocumentPermission(t, s.invoice.ID, "carol", archive.PermissionNone)
+
+	if _, err := s.GetDocument(s.receipt.ID, "carol"); err != nil {
+		t.Errorf("expected carol to get the receipt through the folder, got %v", err)
+	}
+	ids, err := s.GetSharedDocumentIDs("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []string{s.receipt.ID}) {
+		t.Errorf("expected carol to find the receipt in the shared folder, got %v", ids)
+	}
+
+	// Sharing again changes the permission of the grant
+	changed, err := s.ShareFolder(s.taxes.ID, "carol", archive.PermissionWrite, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed.ID != grant.ID {
+		t.Error("expected sharing again to change the existing grant")
+	}
+	s.requireDocumentPermission(t, s.receipt.ID, "carol", archive.PermissionWrite)
+
+	// The higher of the grants on the document and on its folders counts
+	if _, err := s.ShareFolder(s.taxes2025.ID, "bob", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ShareDocument(s.receipt.ID, "bob", archive.PermissionWrite, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	s.requireDocumentPermission(t, s.receipt.ID, "bob", archive.PermissionWrite)
+	s.requireFolderPermission(t, s.taxes.ID, "bob", archive.PermissionNone)
+}
+
+func TestRootFolderCannotBeShared(t *testing.T) {
+	s := newSharing(t)
+
+	if _, err := s.ShareFolder(archive.FolderRootID, "carol", archive.PermissionRead, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected sharing the root folder to fail, got %v", err)
+	}
+
+	// Even a grant on the root folder does not open the documents of all users in it
+	err := s.grants.Save(archive.Grant{ID: "root-grant", FolderID: archive.FolderRootID, Grantee: "carol", Permission: archive.PermissionWrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.requireDocumentPermission(t, s.invoice.ID, "carol", archive.PermissionNone)
+	s.requireDocumentPermission(t, s.receipt.ID, "carol", archive.PermissionNone)
+	s.requireFolderPermission(t, s.taxes.ID, "carol", archive.PermissionNone)
+
+	// Documents in the root folder are shared on their own
+	if _, err := s.ShareDocument(s.invoice.ID, "carol", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	s.requireDocumentPermission(t, s.invoice.ID, "carol", archive.PermissionRead)
+}
+
+func TestInvalidGrants(t *testing.T) {
+	s := newSharing(t)
+
+	cases := []struct {
+		name     string
+		share    func() (archive.Grant, error)
+		expected error
+	}{
+		{"with the owner", func() (archive.Grant, error) {
+			return s.ShareDocument(s.receipt.ID, "alice", archive.PermissionRead, "alice")
+		}, archive.ErrInvalidGrant},
+		{"with an unknown user", func() (archive.Grant, error) {
+			return s.ShareDocument(s.receipt.ID, "dave", archive.PermissionRead, "alice")
+		}, archive.ErrGranteeNotFound},
+		{"owner permission", func() (archive.Grant, error) {
+			return s.ShareDocument(s.receipt.ID, "carol", archive.PermissionOwner, "alice")
+		}, archive.ErrInvalidPermission},
+		{"document of another user", func() (archive.Grant, error) {
+			return s.ShareDocument(s.receipt.ID, "carol", archive.PermissionRead, "bob")
+		}, archive.ErrNotAllowed},
+		{"folder of another user", func() (archive.Grant, error) {
+			return s.ShareFolder(s.taxes.ID, "carol", archive.PermissionRead, "bob")
+		}, archive.ErrNotAllowed},
+	}
+	for _, c := range cases {
+		if _, err := c.share(); !errors.Is(err, c.expected) {
+			t.Errorf("sharing %s: expected %v, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestRevokeGrant(t *testing.T) {
+	s := newSharing(t)
+
+	folderGrant, err := s.ShareFolder(s.taxes.ID, "carol", archive.PermissionRead, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	documentGrant, err := s.ShareDocument(s.invoice.ID, "carol", archive.PermissionRead, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the owner and the grantee can revoke a grant
+	if err := s.RevokeGrant(folderGrant.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected bob not to revoke the grant of carol, got %v", err)
+	}
+
+	if err := s.RevokeGrant(folderGrant.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	s.requireDocumentPermission(t, s.receipt.ID, "carol", archive.PermissionNone)
+	s.requireFolderPermission(t, s.taxes2025.ID, "carol", archive.PermissionNone)
+
+	if err := s.RevokeGrant(documentGrant.ID, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	s.requireDocumentPermission(t, s.invoice.ID, "carol", archive.PermissionNone)
+
+	folders, documents, err := s.GetSharedWithMe("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 0 || len(documents) != 0 {
+		t.Errorf("expected nothing to be shared with carol anymore, got %v and %v", folders, documents)
+	}
+}
+
+func TestSharedWithMeLeavesOutTrash(t *testing.T) {
+	s := newSharing(t)
+
+	if _, err := s.ShareFolder(s.taxes2025.ID, "carol", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ShareDocument(s.invoice.ID, "carol", archive.PermissionRead, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	folders, documents, err := s.GetSharedWithMe("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 1 || folders[0].ID != s.taxes2025.ID || len(documents) != 1 || documents[0].ID != s.invoice.ID {
+		t.Errorf("expected the shared folder and document, got %v and %v", folders, documents)
+	}
+
+	s.taxes2025.TrashedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	if err := s.folders.Save(s.taxes2025); err != nil {
+		t.Fatal(err)
+	}
+	folders, _, err = s.GetSharedWithMe("carol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 0 {
+		t.Errorf("expected the trashed folder to be left out, got %v", folders)
+	}
+}
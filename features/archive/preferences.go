@@ -0,0 +1,132 @@
+package archive
+
+import "errors"
+
+var (
+	ErrPreferencesNotFound    = errors.New("preferences not found")
+	ErrInvalidDuplicatePolicy = errors.New("invalid duplicate policy")
+)
+
+type DuplicatePolicy string
+
+const (
+	// DuplicatePolicyWarn stores the duplicate anyway, it shows up in the duplicates report
+	DuplicatePolicyWarn DuplicatePolicy = "warn"
+	// DuplicatePolicyReject refuses the upload with ErrDuplicateDocument
+	DuplicatePolicyReject DuplicatePolicy = "reject"
+	// DuplicatePolicyLink discards the upload and returns a DuplicateLinkedError pointing to the document already in the archive
+	DuplicatePolicyLink DuplicatePolicy = "link"
+)
+
+func (policy DuplicatePolicy) IsValid() bool {
+	switch policy {
+	case DuplicatePolicyWarn, DuplicatePolicyReject, DuplicatePolicyLink:
+		return true
+	default:
+		return false
+	}
+}
+
+type ConsumeAction string
+
+const (
+	// ConsumeActionMove moves consumed files into the .consumed directory of the consume directory
+	ConsumeActionMove ConsumeAction = "move"
+	// ConsumeActionDelete deletes consumed files
+	ConsumeActionDelete ConsumeAction = "delete"
+)
+
+func (action ConsumeAction) IsValid() bool {
+	return action == ConsumeActionMove || action == ConsumeActionDelete
+}
+
+// Preferences are the archive settings of a single user.
+type Preferences struct {
+	Owner           string
+	DuplicatePolicy DuplicatePolicy
+	// ConsumeDirectory is watched for new files when set, relative paths are below the data directory
+	ConsumeDirectory string
+	ConsumeFolderID  string
+	ConsumeAction    ConsumeAction
+	// TrashRetention overrides how long trashed items of the user are kept
+	TrashRetention TrashRetention
+}
+
+func defaultPreferences(owner string) Preferences {
+	return Preferences{
+		Owner:           owner,
+		DuplicatePolicy: DuplicatePolicyWarn,
+		ConsumeFolderID: FolderRootID,
+		ConsumeAction:   ConsumeActionMove,
+		TrashRetention:  TrashRetentionDefault,
+	}
+}
+
+type PreferencesRepository interface {
+	Save(preferences Preferences) error
+	FindByOwner(owner string) (Preferences, error)
+	FindAll() ([]Preferences, error)
+}
+
+type preferences struct {
+	repository            PreferencesRepository
+	defaultTrashRetention TrashRetention
+}
+
+func (p *preferences) GetPreferences(owner string) (Preferences, error) {
+	preferences, err := p.repository.FindByOwner(owner)
+	if errors.Is(err, ErrPreferencesNotFound) {
+		return defaultPreferences(owner), nil
+	}
+	return preferences, err
+}
+
+func (p *preferences) UpdateDuplicatePolicy(owner string, policy DuplicatePolicy) error {
+	if !policy.IsValid() {
+		return ErrInvalidDuplicatePolicy
+	}
+
+	preferences, err := p.GetPreferences(owner)
+	if err != nil {
+		return err
+	}
+
+	preferences.DuplicatePolicy = policy
+	return p.repository.Save(preferences)
+}
+
+// GetTrashRetention returns how long trashed items of the owner are kept, the default unless the owner chose otherwise.
+func (p *preferences) GetTrashRetention(owner string) (TrashRetention, error) {
+	preferences, err := p.GetPreferences(owner)
+	if err != nil {
+		return 0, err
+	}
+
+	if preferences.TrashRetention == TrashRetentionDefault {
+		return p.defaultTrashRetention, nil
+	}
+	return preferences.TrashRetention, nil
+}
+
+func (p *preferences) UpdateTrashRetention(owner string, retention TrashRetention) error {
+	if !retention.IsValid() {
+		return ErrInvalidTrashRetention
+	}
+
+	preferences, err := p.GetPreferences(owner)
+	if err != nil {
+		return err
+	}
+
+	preferences.TrashRetention = retention
+	return p.repository.Save(preferences)
+}
+
+func newPreferences(repository PreferencesRepository, trashRetentionDays int) *preferences {
+	// Negative days of the configuration keep trashed items just like 0
+	defaultTrashRetention := TrashRetention(max(trashRetentionDays, 0))
+	return &preferences{
+		repository:            repository,
+		defaultTrashRetention: defaultTrashRetention,
+	}
+}
@@ -0,0 +1,440 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrRetentionPolicyNotFound = errors.New("retention policy not found")
+	ErrInvalidRetentionPolicy  = errors.New("invalid retention policy")
+)
+
+// RetentionBasis is the date the retention period of a document starts at.
+type RetentionBasis string
+
+const (
+	RetentionBasisUploaded RetentionBasis = "uploaded"
+	// RetentionBasisDocumentDate starts at the date in a custom date field of the document
+	RetentionBasisDocumentDate RetentionBasis = "document_date"
+)
+
+var RetentionBases = []RetentionBasis{RetentionBasisUploaded, RetentionBasisDocumentDate}
+
+func (basis RetentionBasis) IsValid() bool {
+	return slices.Contains(RetentionBases, basis)
+}
+
+func (basis RetentionBasis) Label() string {
+	switch basis {
+	case RetentionBasisUploaded:
+		return "Upload date"
+	case RetentionBasisDocumentDate:
+		return "Document date"
+	default:
+		return string(basis)
+	}
+}
+
+// RetentionAction is what happens with a document once its retention period is over.
+type RetentionAction string
+
+const (
+	RetentionActionTrash  RetentionAction = "trash"
+	RetentionActionNotify RetentionAction = "notify"
+)
+
+var RetentionActions = []RetentionAction{RetentionActionTrash, RetentionActionNotify}
+
+func (action RetentionAction) IsValid() bool {
+	return slices.Contains(RetentionActions, action)
+}
+
+func (action RetentionAction) Label() string {
+	switch action {
+	case RetentionActionTrash:
+		return "Move to trash"
+	case RetentionActionNotify:
+		return "Notify me"
+	default:
+		return string(action)
+	}
+}
+
+// RetentionPolicy keeps the documents of a folder with its subfolders, of a tag or of a document type
+// for a number of months. Exactly one of FolderID, TagID and DocumentTypeID is set.
+type RetentionPolicy struct {
+	ID             string
+	Name           string
+	FolderID       string
+	TagID          string
+	DocumentTypeID string
+	KeepMonths     int
+	Basis          RetentionBasis
+	// DateFieldID is the custom date field holding the document date, documents without it never expire
+	DateFieldID string
+	Action      RetentionAction
+	Owner       string
+	CreatedAt   time.Time
+}
+
+// KeepLabel describes the retention period, like "10 years" or "18 months".
+func (policy RetentionPolicy) KeepLabel() string {
+	if policy.KeepMonths%12 == 0 {
+		if policy.KeepMonths == 12 {
+			return "1 year"
+		}
+		return fmt.Sprintf("%d years", policy.KeepMonths/12)
+	}
+	if policy.KeepMonths == 1 {
+		return "1 month"
+	}
+	return fmt.Sprintf("%d months", policy.KeepMonths)
+}
+
+// ExpiresAt returns when the retention period of the document is over. Documents without
+// the date the period starts at do not expire.
+func (policy RetentionPolicy) ExpiresAt(document Document) (time.Time, bool) {
+	start := document.CreatedAt
+	if policy.Basis == RetentionBasisDocumentDate {
+		date, err := time.Parse(CustomFieldDateLayout, document.CustomFields[policy.DateFieldID])
+		if policy.DateFieldID == "" || err != nil {
+			return time.Time{}, false
+		}
+		start = date
+	}
+
+	if start.IsZero() {
+		return time.Time{}, false
+	}
+
+	return start.AddDate(0, policy.KeepMonths, 0), true
+}
+
+// RetentionExpiry is the end of the retention period of a document under the policy that keeps it the longest.
+type RetentionExpiry struct {
+	Document  Document
+	Policy    RetentionPolicy
+	ExpiresAt time.Time
+}
+
+// RetentionNotice tells the owner that a document of a notifying policy passed its retention period.
+type RetentionNotice struct {
+	PolicyID    string
+	DocumentID  string
+	Owner       string
+	ExpiredAt   time.Time
+	DismissedAt sql.NullTime
+	CreatedAt   time.Time
+}
+
+type RetentionPolicyRepository interface {
+	Save(policy RetentionPolicy) error
+	FindByID(id string) (RetentionPolicy, error)
+	FindAll() ([]RetentionPolicy, error)
+	FindAllByOwner(owner string) ([]RetentionPolicy, error)
+	DeleteByID(id string) error
+	// SaveNotice keeps existing notices as they are, so dismissed notices stay dismissed
+	SaveNotice(notice RetentionNotice) error
+	FindAllNoticesByOwner(owner string) ([]RetentionNotice, error)
+	DismissNotice(policyID string, documentID string) error
+}
+
+type retentionPolicies struct {
+	repository    RetentionPolicyRepository
+	documents     *documents
+	tags          *tags
+	documentTypes *documentTypes
+	customFields  *customFields
+}
+
+func (r *retentionPolicies) CreateRetentionPolicy(policy RetentionPolicy, owner string) (RetentionPolicy, error) {
+	policy.Name = strings.TrimSpace(policy.Name)
+	if policy.Name == "" || policy.KeepMonths <= 0 || !policy.Basis.IsValid() || !policy.Action.IsValid() {
+		return RetentionPolicy{}, ErrInvalidRetentionPolicy
+	}
+
+	targets := 0
+	for _, target := range []string{policy.FolderID, policy.TagID, policy.DocumentTypeID} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return RetentionPolicy{}, fmt.Errorf("%w: a policy applies to a folder, a tag or a document type", ErrInvalidRetentionPolicy)
+	}
+
+	var err error
+	switch {
+	case policy.FolderID != "":
+		_, err = r.documents.permissions.getOwnedFolder(policy.FolderID, owner)
+	case policy.TagID != "":
+		_, err = r.tags.GetTag(policy.TagID, owner)
+	default:
+		_, err = r.documentTypes.GetDocumentType(policy.DocumentTypeID, owner)
+	}
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+
+	if policy.Basis == RetentionBasisDocumentDate {
+		field, err := r.customFields.GetCustomField(policy.DateFieldID, owner)
+		if err != nil {
+			return RetentionPolicy{}, err
+		}
+		if field.Type != CustomFieldTypeDate {
+			return RetentionPolicy{}, fmt.Errorf("%w: the document date needs a date field", ErrInvalidRetentionPolicy)
+		}
+	} else {
+		policy.DateFieldID = ""
+	}
+
+	policy.ID = common.GenerateID()
+	policy.Owner = owner
+	policy.CreatedAt = time.Now()
+	return policy, r.repository.Save(policy)
+}
+
+func (r *retentionPolicies) GetRetentionPolicy(id string, owner string) (RetentionPolicy, error) {
+	policy, err := r.repository.FindByID(id)
+	if err != nil {
+		return RetentionPolicy{}, err
+	}
+
+	if policy.Owner != owner {
+		return RetentionPolicy{}, ErrNotAllowed
+	}
+
+	return policy, nil
+}
+
+func (r *retentionPolicies) GetRetentionPolicies(owner string) ([]RetentionPolicy, error) {
+	policies, err := r.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(policies, func(p1, p2 RetentionPolicy) int {
+		return strings.Compare(strings.ToLower(p1.Name), strings.ToLower(p2.Name))
+	})
+	return policies, nil
+}
+
+// DeleteRetentionPolicy removes the policy, its documents are kept as long as other policies require.
+func (r *retentionPolicies) DeleteRetentionPolicy(id string, owner string) error {
+	policy, err := r.GetRetentionPolicy(id, owner)
+	if err != nil {
+		return err
+	}
+
+	return r.repository.DeleteByID(policy.ID)
+}
+
+// GetRetentionPreview returns the documents of the owner whose retention period ends before the given time,
+// including those that already expired but are still kept because their policy only notifies.
+func (r *retentionPolicies) GetRetentionPreview(owner string, until time.Time) ([]RetentionExpiry, error) {
+	policies, err := r.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	expiries, err := r.expiries(policies)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(expiries, func(expiry RetentionExpiry) bool { return expiry.ExpiresAt.After(until) }), nil
+}
+
+// GetRetentionNotices returns the expired documents of notifying policies the owner has not dismissed yet.
+func (r *retentionPolicies) GetRetentionNotices(owner string) ([]RetentionExpiry, error) {
+	notices, err := r.repository.FindAllNoticesByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiries []RetentionExpiry
+	for _, notice := range notices {
+		if notice.DismissedAt.Valid {
+			continue
+		}
+
+		document, err := r.documents.repository.FindByID(notice.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		if document.IsTrashed() {
+			continue
+		}
+
+		policy, err := r.repository.FindByID(notice.PolicyID)
+		if err != nil {
+			return nil, err
+		}
+		expiries = append(expiries, RetentionExpiry{Document: document, Policy: policy, ExpiresAt: notice.ExpiredAt})
+	}
+	return expiries, nil
+}
+
+// DismissRetentionNotice hides the notice, it is not raised again for the document and policy.
+func (r *retentionPolicies) DismissRetentionNotice(policyID string, documentID string, owner string) error {
+	policy, err := r.GetRetentionPolicy(policyID, owner)
+	if err != nil {
+		return err
+	}
+
+	return r.repository.DismissNotice(policy.ID, documentID)
+}
+
+// ApplyRetentionPolicies handles the expired documents of the owner right away instead of waiting for the daily run.
+func (r *retentionPolicies) ApplyRetentionPolicies(owner string) error {
+	policies, err := r.repository.FindAllByOwner(owner)
+	if err != nil {
+		return err
+	}
+
+	return r.apply(policies)
+}
+
+func (r *retentionPolicies) applyDaily(ctx context.Context) {
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			policies, err := r.repository.FindAll()
+			if err != nil {
+				slog.Error("failed to find retention policies", "error", err.Error())
+				continue
+			}
+
+			err = r.apply(policies)
+			if err != nil {
+				slog.Error("failed to apply retention policies", "error", err.Error())
+			}
+		case <-ctx.Done():
+			slog.Info("retention policies stopped")
+			return
+		}
+	}
+}
+
+// apply trashes the expired documents of trashing policies and raises notices for those of notifying policies.
+func (r *retentionPolicies) apply(policies []RetentionPolicy) error {
+	expiries, err := r.expiries(policies)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, expiry := range expiries {
+		if expiry.ExpiresAt.After(now) {
+			continue
+		}
+
+		switch expiry.Policy.Action {
+		case RetentionActionTrash:
+			err = r.documents.TrashDocument(expiry.Document.ID, expiry.Document.Owner)
+			if errors.Is(err, ErrLegalHold) {
+				slog.Info("kept expired document", "documentID", expiry.Document.ID, "reason", err.Error())
+				continue
+			}
+			if err != nil {
+				slog.Error("failed to trash expired document", "error", err.Error(), "documentID", expiry.Document.ID)
+				continue
+			}
+			slog.Info("trashed expired document", "documentID", expiry.Document.ID, "policyID", expiry.Policy.ID)
+		case RetentionActionNotify:
+			err = r.repository.SaveNotice(RetentionNotice{
+				PolicyID:   expiry.Policy.ID,
+				DocumentID: expiry.Document.ID,
+				Owner:      expiry.Document.Owner,
+				ExpiredAt:  expiry.ExpiresAt,
+				CreatedAt:  now,
+			})
+			if err != nil {
+				slog.Error("failed to save retention notice", "error", err.Error(), "documentID", expiry.Document.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// expiries returns the end of the retention period of every document the policies apply to, sorted by it.
+// Documents under several policies are kept until the latest of their periods is over.
+func (r *retentionPolicies) expiries(policies []RetentionPolicy) ([]RetentionExpiry, error) {
+	latest := make(map[string]RetentionExpiry)
+	for _, policy := range policies {
+		documents, err := r.policyDocuments(policy)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, document := range documents {
+			if document.IsTrashed() || document.Owner != policy.Owner {
+				continue
+			}
+
+			expiresAt, ok := policy.ExpiresAt(document)
+			if !ok {
+				continue
+			}
+
+			existing, exists := latest[document.ID]
+			if !exists || expiresAt.After(existing.ExpiresAt) {
+				latest[document.ID] = RetentionExpiry{Document: document, Policy: policy, ExpiresAt: expiresAt}
+			}
+		}
+	}
+
+	expiries := slices.Collect(maps.Values(latest))
+	slices.SortFunc(expiries, func(e1, e2 RetentionExpiry) int {
+		return e1.ExpiresAt.Compare(e2.ExpiresAt)
+	})
+	return expiries, nil
+}
+
+func (r *retentionPolicies) policyDocuments(policy RetentionPolicy) ([]Document, error) {
+	switch {
+	case policy.FolderID != "":
+		var ids []string
+		err := r.documents.permissions.collectDocumentIDs(policy.FolderID, &ids)
+		if err != nil || len(ids) == 0 {
+			return nil, err
+		}
+		return r.documents.repository.FindAllByIDIn(ids)
+	case policy.TagID != "":
+		return r.documents.repository.FindAllByTagID(policy.TagID)
+	default:
+		return r.documents.repository.FindAllByOwnerAndFilter(policy.Owner, DocumentFilter{DocumentTypeID: policy.DocumentTypeID})
+	}
+}
+
+func newRetentionPolicies(
+	repository RetentionPolicyRepository,
+	documents *documents,
+	tags *tags,
+	documentTypes *documentTypes,
+	customFields *customFields,
+	jobScheduler *common.JobScheduler,
+) *retentionPolicies {
+	retentionPolicies := &retentionPolicies{
+		repository:    repository,
+		documents:     documents,
+		tags:          tags,
+		documentTypes: documentTypes,
+		customFields:  customFields,
+	}
+
+	jobScheduler.Schedule(retentionPolicies.applyDaily)
+	return retentionPolicies
+}
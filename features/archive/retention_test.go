@@ -0,0 +1,187 @@
+package archive_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+func TestRetentionPolicyExpiresAt(t *testing.T) {
+	uploaded := time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)
+	document := archive.Document{CreatedAt: uploaded, CustomFields: map[string]string{"date": "2015-06-30", "note": "soon"}}
+
+	tests := []struct {
+		policy    archive.RetentionPolicy
+		expiresAt time.Time
+		expires   bool
+	}{
+		{archive.RetentionPolicy{KeepMonths: 1, Basis: archive.RetentionBasisUploaded}, uploaded.AddDate(0, 1, 0), true},
+		{archive.RetentionPolicy{KeepMonths: 120, Basis: archive.RetentionBasisDocumentDate, DateFieldID: "date"}, time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC), true},
+		// Documents without a valid document date are kept
+		{archive.RetentionPolicy{KeepMonths: 120, Basis: archive.RetentionBasisDocumentDate, DateFieldID: "note"}, time.Time{}, false},
+		{archive.RetentionPolicy{KeepMonths: 120, Basis: archive.RetentionBasisDocumentDate, DateFieldID: "missing"}, time.Time{}, false},
+	}
+	for _, test := range tests {
+		expiresAt, expires := test.policy.ExpiresAt(document)
+		if expires != test.expires || !expiresAt.Equal(test.expiresAt) {
+			t.Errorf("expected %+v to expire at %v (%v), got %v (%v)", test.policy, test.expiresAt, test.expires, expiresAt, expires)
+		}
+	}
+}
+
+func TestCreateRetentionPolicy(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	foreign := a.createFolder(t, "Private", archive.FolderRootID, "bob")
+	note, err := a.CreateCustomField("Note", archive.CustomFieldTypeString, nil, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := archive.RetentionPolicy{Name: "Taxes", FolderID: taxes.ID, KeepMonths: 120, Basis: archive.RetentionBasisUploaded, Action: archive.RetentionActionTrash}
+	policies := map[string]struct {
+		change   func(policy *archive.RetentionPolicy)
+		expected error
+	}{
+		"without a name":         {func(policy *archive.RetentionPolicy) { policy.Name = " " }, archive.ErrInvalidRetentionPolicy},
+		"without a period":       {func(policy *archive.RetentionPolicy) { policy.KeepMonths = 0 }, archive.ErrInvalidRetentionPolicy},
+		"with an unknown action": {func(policy *archive.RetentionPolicy) { policy.Action = "shred" }, archive.ErrInvalidRetentionPolicy},
+		"with two targets":       {func(policy *archive.RetentionPolicy) { policy.TagID = tag.ID }, archive.ErrInvalidRetentionPolicy},
+		"without a target":       {func(policy *archive.RetentionPolicy) { policy.FolderID = "" }, archive.ErrInvalidRetentionPolicy},
+		"on a folder of bob":     {func(policy *archive.RetentionPolicy) { policy.FolderID = foreign.ID }, archive.ErrNotAllowed},
+		"dated by a text field": {func(policy *archive.RetentionPolicy) {
+			policy.Basis = archive.RetentionBasisDocumentDate
+			policy.DateFieldID = note.ID
+		}, archive.ErrInvalidRetentionPolicy},
+	}
+	for name, test := range policies {
+		policy := valid
+		test.change(&policy)
+		if _, err := a.CreateRetentionPolicy(policy, "alice"); !errors.Is(err, test.expected) {
+			t.Errorf("expected a policy %s to be rejected with %v, got %v", name, test.expected, err)
+		}
+	}
+
+	if _, err := a.CreateRetentionPolicy(valid, "alice"); err != nil {
+		t.Errorf("expected a valid policy, got %v", err)
+	}
+}
+
+func TestApplyRetentionPolicies(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	taxes := a.createFolder(t, "Taxes", archive.FolderRootID, "alice")
+	year := a.createFolder(t, "2010", taxes.ID, "alice")
+	expired := a.upload(t, "expired.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), year.ID, "alice")
+	recent := a.upload(t, "recent.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), year.ID, "alice")
+	kept := a.upload(t, "kept.pdf", testFile(t, "mock_pdfs/invoice_0003.pdf"), year.ID, "alice")
+	held := a.upload(t, "held.pdf", testFile(t, "mock_pdfs/contract_SA_0001.pdf"), year.ID, "alice")
+	undated := a.upload(t, "undated.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), year.ID, "alice")
+
+	date, err := a.CreateCustomField("Date", archive.CustomFieldTypeDate, nil, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for document, value := range map[string]string{expired.ID: "2010-03-31", recent.ID: "2024-12-31", kept.ID: "2010-03-31", held.ID: "2010-03-31"} {
+		if err := a.UpdateDocumentCustomFields(document, "alice", map[string]string{date.ID: value}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	contracts, err := a.CreateTag("Contracts", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AssignTag(kept.ID, contracts.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.PlaceDocumentLegalHold(held.ID, "Audit", "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The folder policy covers the subfolders, the longer period of the tag policy wins for the tagged document
+	policies := []archive.RetentionPolicy{
+		{Name: "Taxes", FolderID: taxes.ID, KeepMonths: 120, Basis: archive.RetentionBasisDocumentDate, DateFieldID: date.ID, Action: archive.RetentionActionTrash},
+		{Name: "Contracts", TagID: contracts.ID, KeepMonths: 360, Basis: archive.RetentionBasisDocumentDate, DateFieldID: date.ID, Action: archive.RetentionActionTrash},
+	}
+	for _, policy := range policies {
+		if _, err := a.CreateRetentionPolicy(policy, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	preview, err := a.GetRetentionPreview("alice", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview) != 2 || preview[0].ExpiresAt.Format(time.DateOnly) != "2020-03-31" {
+		t.Errorf("expected the expired and the held document in the preview, got %v", preview)
+	}
+
+	if err := a.ApplyRetentionPolicies("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if !a.document(t, expired.ID).IsTrashed() {
+		t.Error("expected the expired document to be trashed")
+	}
+	for _, document := range []archive.Document{recent, kept, held, undated} {
+		if a.document(t, document.ID).IsTrashed() {
+			t.Errorf("expected document %s to be kept", document.Filename)
+		}
+	}
+}
+
+func TestRetentionNotices(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	invoices, err := a.CreateDocumentType("Invoice", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AssignDocumentType(document.ID, invoices.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	uploaded := a.document(t, document.ID)
+	uploaded.CreatedAt = time.Now().AddDate(-2, 0, 0)
+	if err := a.documents.Save(uploaded); err != nil {
+		t.Fatal(err)
+	}
+	policy, err := a.CreateRetentionPolicy(archive.RetentionPolicy{
+		Name: "Invoices", DocumentTypeID: invoices.ID, KeepMonths: 12, Basis: archive.RetentionBasisUploaded, Action: archive.RetentionActionNotify,
+	}, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.ApplyRetentionPolicies("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if a.document(t, document.ID).IsTrashed() {
+		t.Error("expected notifying policies to keep the document")
+	}
+	notices, err := a.GetRetentionNotices("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notices) != 1 || notices[0].Document.ID != document.ID || notices[0].Policy.ID != policy.ID {
+		t.Fatalf("expected a notice for the expired document, got %v", notices)
+	}
+
+	// Dismissed notices are not raised again
+	if err := a.DismissRetentionNotice(policy.ID, document.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.ApplyRetentionPolicies("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if notices, err := a.GetRetentionNotices("alice"); err != nil || len(notices) != 0 {
+		t.Errorf("expected the dismissed notice to stay hidden, got %v: %v", notices, err)
+	}
+}
@@ -0,0 +1,185 @@
+package archive
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrTagNotFound     = errors.New("tag not found")
+	ErrTagExists       = errors.New("tag already exists")
+	ErrInvalidTagName  = errors.New("invalid tag name")
+	ErrInvalidTagColor = errors.New("invalid tag color")
+)
+
+const DefaultTagColor = "#6b7280"
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+// Tag labels documents across folders. A document can carry any number of tags of its owner.
+type Tag struct {
+	ID    string
+	Name  string
+	Color string
+	Owner string
+}
+
+type TagRepository interface {
+	Save(tag Tag) error
+	FindByID(id string) (Tag, error)
+	FindAllByOwner(owner string) ([]Tag, error)
+	DeleteByID(id string) error
+	AssignToDocument(tagID string, documentID string) error
+	RemoveFromDocument(tagID string, documentID string) error
+}
+
+type tags struct {
+	repository TagRepository
+	documents  *documents
+}
+
+func (t *tags) CreateTag(name string, color string, owner string) (Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Tag{}, ErrInvalidTagName
+	}
+
+	color = strings.ToLower(color)
+	if color == "" {
+		color = DefaultTagColor
+	}
+	if !tagColorPattern.MatchString(color) {
+		return Tag{}, ErrInvalidTagColor
+	}
+
+	existing, err := t.repository.FindAllByOwner(owner)
+	if err != nil {
+		return Tag{}, err
+	}
+	if slices.ContainsFunc(existing, func(tag Tag) bool { return strings.EqualFold(tag.Name, name) }) {
+		return Tag{}, ErrTagExists
+	}
+
+	tag := Tag{ID: common.GenerateID(), Name: name, Color: color, Owner: owner}
+	return tag, t.repository.Save(tag)
+}
+
+func (t *tags) GetTag(id string, owner string) (Tag, error) {
+	tag, err := t.repository.FindByID(id)
+	if err != nil {
+		return Tag{}, err
+	}
+
+	if tag.Owner != owner {
+		return Tag{}, ErrNotAllowed
+	}
+
+	return tag, nil
+}
+
+func (t *tags) GetTags(owner string) ([]Tag, error) {
+	tags, err := t.repository.FindAllByOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(tags, func(t1, t2 Tag) int {
+		return strings.Compare(strings.ToLower(t1.Name), strings.ToLower(t2.Name))
+	})
+	return tags, nil
+}
+
+// DeleteTag removes the tag from all of its documents before deleting it.
+func (t *tags) DeleteTag(id string, owner string) error {
+	tag, err := t.GetTag(id, owner)
+	if err != nil {
+		return err
+	}
+
+	documents, err := t.documents.repository.FindAllByTagID(tag.ID)
+	if err != nil {
+		return err
+	}
+
+	err = t.repository.DeleteByID(tag.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		err := t.publishTagsChanged(document.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *tags) GetDocumentsByTag(tagID string, owner string) ([]Document, error) {
+	tag, err := t.GetTag(tagID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.documents.repository.FindAllByTagID(tag.ID)
+}
+
+// AssignTag adds a tag to a document the user can change, the tag must belong to the owner of the document.
+func (t *tags) AssignTag(documentID string, tagID string, user string) error {
+	document, err := t.documents.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+
+	tag, err := t.GetTag(tagID, document.Owner)
+	if err != nil {
+		return err
+	}
+
+	err = t.repository.AssignToDocument(tag.ID, document.ID)
+	if err != nil {
+		return err
+	}
+
+	return t.publishTagsChanged(document.ID)
+}
+
+func (t *tags) RemoveTag(documentID string, tagID string, user string) error {
+	document, err := t.documents.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+
+	tag, err := t.GetTag(tagID, document.Owner)
+	if err != nil {
+		return err
+	}
+
+	err = t.repository.RemoveFromDocument(tag.ID, document.ID)
+	if err != nil {
+		return err
+	}
+
+	return t.publishTagsChanged(document.ID)
+}
+
+// publishTagsChanged reloads the document, so subscribers see its current tags.
+func (t *tags) publishTagsChanged(documentID string) error {
+	document, err := t.documents.repository.FindByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	return t.documents.messages.PublishDocumentUpserted(document)
+}
+
+func newTags(repository TagRepository, documents *documents) *tags {
+	return &tags{
+		repository: repository,
+		documents:  documents,
+	}
+}
@@ -0,0 +1,112 @@
+package archive_test
+
+import (
+	"errors"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+func TestCreateTag(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+
+	tag, err := a.CreateTag(" Taxes ", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.Name != "Taxes" || tag.Color != archive.DefaultTagColor {
+		t.Errorf("expected a trimmed name and the default color, got %+v", tag)
+	}
+
+	if _, err := a.CreateTag("taxes", "#ff0000", "alice"); !errors.Is(err, archive.ErrTagExists) {
+		t.Errorf("expected names to be unique regardless of case, got %v", err)
+	}
+	if _, err := a.CreateTag("Taxes", "#ff0000", "bob"); err != nil {
+		t.Errorf("expected other users to have a tag of the same name, got %v", err)
+	}
+	if _, err := a.CreateTag(" ", "#ff0000", "alice"); !errors.Is(err, archive.ErrInvalidTagName) {
+		t.Errorf("expected an empty name to be rejected, got %v", err)
+	}
+	if _, err := a.CreateTag("Travel", "red", "alice"); !errors.Is(err, archive.ErrInvalidTagColor) {
+		t.Errorf("expected a color other than hex to be rejected, got %v", err)
+	}
+}
+
+func TestAssignTag(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	other := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	taxes, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.AssignTag(document.ID, taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if tags := a.document(t, document.ID).Tags; len(tags) != 1 || tags[0].ID != taxes.ID {
+		t.Errorf("expected the document to carry the tag, got %v", tags)
+	}
+	documents, err := a.GetDocumentsByTag(taxes.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 1 || documents[0].ID != document.ID || documents[0].ID == other.ID {
+		t.Errorf("expected only the tagged document, got %v", documents)
+	}
+
+	if err := a.RemoveTag(document.ID, taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if tags := a.document(t, document.ID).Tags; len(tags) != 0 {
+		t.Errorf("expected the tag to be removed, got %v", tags)
+	}
+}
+
+func TestAssignTagOfOtherUser(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	private, err := a.CreateTag("Private", "", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taxes, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.AssignTag(document.ID, private.ID, "alice"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected tags of other users not to be assigned, got %v", err)
+	}
+	if err := a.AssignTag(document.ID, taxes.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected documents of other users not to be tagged, got %v", err)
+	}
+	if _, err := a.GetDocumentsByTag(taxes.ID, "bob"); !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected tags of other users not to be browsed, got %v", err)
+	}
+}
+
+func TestDeleteTag(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	document := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), archive.FolderRootID, "alice")
+	taxes, err := a.CreateTag("Taxes", "", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AssignTag(document.ID, taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.DeleteTag(taxes.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if tags := a.document(t, document.ID).Tags; len(tags) != 0 {
+		t.Errorf("expected the deleted tag to be gone from the document, got %v", tags)
+	}
+	if _, err := a.GetTag(taxes.ID, "alice"); !errors.Is(err, archive.ErrTagNotFound) {
+		t.Errorf("expected the tag to be deleted, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+	"unterlagen/features/common"
+)
+
+var (
+	ErrInvalidTrashRetention = errors.New("invalid trash retention")
+	ErrNotTrashed            = errors.New("not in the trash")
+)
+
+// TrashRetention is the number of days trashed documents and folders are kept before they are deleted.
+type TrashRetention int
+
+const (
+	// TrashRetentionDefault follows the retention of the configuration, users override it in their preferences
+	TrashRetentionDefault TrashRetention = -1
+	// TrashRetentionNever keeps trashed items until they are deleted by hand
+	TrashRetentionNever TrashRetention = 0
+)
+
+// TrashRetentionChoices are offered to users next to the default.
+var TrashRetentionChoices = []TrashRetention{7, 30, 90, 365, TrashRetentionNever}
+
+func (retention TrashRetention) IsValid() bool {
+	return retention == TrashRetentionDefault || slices.Contains(TrashRetentionChoices, retention)
+}
+
+// Expired tells whether an item trashed at the given time is to be deleted.
+func (retention TrashRetention) Expired(trashedAt time.Time) bool {
+	deletesAt, ok := retention.DeletesAt(trashedAt)
+	return ok && !time.Now().Before(deletesAt)
+}
+
+// DeletesAt returns when an item trashed at the given time is deleted, never if the retention keeps trashed items.
+func (retention TrashRetention) DeletesAt(trashedAt time.Time) (time.Time, bool) {
+	if retention <= TrashRetentionNever {
+		return time.Time{}, false
+	}
+	// Days are added to the date, a duration of many days would overflow
+	return trashedAt.AddDate(0, 0, int(retention)), true
+}
+
+func (retention TrashRetention) Label() string {
+	switch retention {
+	case TrashRetentionDefault:
+		return "Default"
+	case TrashRetentionNever:
+		return "Never delete automatically"
+	case 1:
+		return "1 day"
+	default:
+		return strconv.Itoa(int(retention)) + " days"
+	}
+}
+
+// Trash holds the trashed items of a user. Items trashed along with their folder are only listed through the folder.
+type Trash struct {
+	Folders   []Folder
+	Documents []Document
+	// Retention is the choice of the user, DefaultRetention applies as long as the user did not choose
+	Retention        TrashRetention
+	DefaultRetention TrashRetention
+	// retentions apply to the items of each owner, the items of groups follow the retention of the group
+	retentions map[string]TrashRetention
+}
+
+func (trash Trash) IsEmpty() bool {
+	return len(trash.Folders) == 0 && len(trash.Documents) == 0
+}
+
+// DeletesAt returns when an item of the owner trashed at the given time is deleted.
+func (trash Trash) DeletesAt(owner string, trashedAt time.Time) (time.Time, bool) {
+	return trash.retentions[owner].DeletesAt(trashedAt)
+}
+
+type trash struct {
+	documents   *documents
+	folders     *folders
+	preferences *preferences
+	permissions *permissions
+	legalHolds  *legalHolds
+}
+
+// GetTrash returns the trashed items of the user and of the groups the user is a member of.
+func (t *trash) GetTrash(user string) (Trash, error) {
+	owners, err := t.owners(user)
+	if err != nil {
+		return Trash{}, err
+	}
+
+	preferences, err := t.preferences.GetPreferences(user)
+	if err != nil {
+		return Trash{}, err
+	}
+
+	retentions := make(map[string]TrashRetention, len(owners))
+	for _, owner := range owners {
+		retentions[owner], err = t.preferences.GetTrashRetention(owner)
+		if err != nil {
+			return Trash{}, err
+		}
+	}
+
+	folders, documents, err := t.trashedItems(owners)
+	if err != nil {
+		return Trash{}, err
+	}
+
+	trashedFolderIDs := make(map[string]bool, len(folders))
+	for _, folder := range folders {
+		trashedFolderIDs[folder.ID] = true
+	}
+
+	result := Trash{
+		Retention:        preferences.TrashRetention,
+		DefaultRetention: t.preferences.defaultTrashRetention,
+		retentions:       retentions,
+	}
+	for _, folder := range folders {
+		if !trashedFolderIDs[folder.ParentID] {
+			result.Folders = append(result.Folders, folder)
+		}
+	}
+	for _, document := range documents {
+		if !trashedFolderIDs[document.FolderID] {
+			result.Documents = append(result.Documents, document)
+		}
+	}
+
+	slices.SortFunc(result.Folders, func(f1, f2 Folder) int {
+		return f2.TrashedAt.Time.Compare(f1.TrashedAt.Time)
+	})
+	slices.SortFunc(result.Documents, func(d1, d2 Document) int {
+		return d2.TrashedAt.Time.Compare(d1.TrashedAt.Time)
+	})
+	return result, nil
+}
+
+// DeleteDocumentPermanently deletes a trashed document the user can change right away.
+func (t *trash) DeleteDocumentPermanently(documentID string, user string) error {
+	document, err := t.documents.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+	if !document.IsTrashed() {
+		return ErrNotTrashed
+	}
+
+	err = t.legalHolds.checkDocument(document)
+	if err != nil {
+		return err
+	}
+
+	return t.documents.purgeDocument(document)
+}
+
+// DeleteFolderPermanently deletes a trashed folder the user can change with all of its subfolders and documents right away.
+func (t *trash) DeleteFolderPermanently(folderID string, user string) error {
+	folder, err := t.folders.getMutableFolder(folderID, user)
+	if err != nil {
+		return err
+	}
+	if !folder.IsTrashed() {
+		return ErrNotTrashed
+	}
+
+	err = t.legalHolds.checkFolder(folder)
+	if err != nil {
+		return err
+	}
+
+	return t.purgeFolder(folder)
+}
+
+// EmptyTrash deletes all trashed items of the user and of the groups the user is a member of right away.
+// Documents under legal hold stay in the trash, their number is returned.
+func (t *trash) EmptyTrash(user string) (int, error) {
+	owners, err := t.owners(user)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.purge(owners, func(owner string, trashedAt time.Time) bool { return true })
+}
+
+// emptyTrash deletes the trashed items whose retention passed every hour.
+func (t *trash) emptyTrash(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Hour)
+	for {
+		select {
+		case <-ticker.C:
+			retentions := make(map[string]TrashRetention)
+			_, err := t.purge(nil, func(owner string, trashedAt time.Time) bool {
+				retention, ok := retentions[owner]
+				if !ok {
+					var err error
+					retention, err = t.preferences.GetTrashRetention(owner)
+					if err != nil {
+						slog.Error("failed to get trash retention", "owner", owner, "error", err)
+						return false
+					}
+					retentions[owner] = retention
+				}
+				return retention.Expired(trashedAt)
+			})
+			if err != nil {
+				slog.Error("failed to empty trash", "error", err)
+			}
+		case <-ctx.Done():
+			slog.Info("documents trash emptying stopped")
+			return
+		}
+	}
+}
+
+// purge deletes the trashed items of the owners, of all owners if none are given, that are due.
+// Documents go first, folders are deleted from the bottom up once they are empty. Held documents are kept and counted.
+func (t *trash) purge(owners []string, due func(owner string, trashedAt time.Time) bool) (int, error) {
+	folders, documents, err := t.trashedItems(owners)
+	if err != nil {
+		return 0, err
+	}
+
+	kept := 0
+	for _, document := range documents {
+		if !due(document.Owner, document.TrashedAt.Time) {
+			continue
+		}
+
+		// Held documents stay in the trash until the hold is released
+		if err := t.legalHolds.checkDocument(document); err != nil {
+			slog.Info("kept trashed document", "documentID", document.ID, "reason", err.Error())
+			kept++
+			continue
+		}
+
+		err := t.documents.purgeDocument(document)
+		if err != nil {
+			slog.Error("failed to purge document", "documentID", document.ID, "error", err)
+		}
+	}
+
+	for {
+		deleted := 0
+		for _, folder := range folders {
+			if !due(folder.Owner, folder.TrashedAt.Time) {
+				continue
+			}
+
+			ok, err := t.folders.deleteIfEmpty(folder)
+			if err != nil {
+				slog.Error("failed to delete folder", "folderID", folder.ID, "error", err)
+				continue
+			}
+			if ok {
+				deleted++
+			}
+		}
+
+		if deleted == 0 {
+			return kept, nil
+		}
+
+		folders, _, err = t.trashedItems(owners)
+		if err != nil {
+			return kept, err
+		}
+	}
+}
+
+// purgeFolder deletes all documents below the folder before the folder itself, which removes its subfolders.
+func (t *trash) purgeFolder(folder Folder) error {
+	err := t.folders.walkSubtree(folder, func(_ Folder, documents []Document) error {
+		for _, document := range documents {
+			err := t.documents.purgeDocument(document)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.folders.repository.DeleteByID(folder.ID)
+}
+
+func (t *trash) trashedItems(owners []string) ([]Folder, []Document, error) {
+	folders, err := t.folders.repository.FindAllTrashed()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	documents, err := t.documents.repository.FindAllTrashed()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(owners) > 0 {
+		folders = slices.DeleteFunc(folders, func(folder Folder) bool { return !slices.Contains(owners, folder.Owner) })
+		documents = slices.DeleteFunc(documents, func(document Document) bool { return !slices.Contains(owners, document.Owner) })
+	}
+	return folders, documents, nil
+}
+
+func (t *trash) owners(user string) ([]string, error) {
+	groups, err := t.permissions.getGroupNames(user)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{user}, groups...), nil
+}
+
+func newTrash(documents *documents, folders *folders, preferences *preferences, permissions *permissions, legalHolds *legalHolds, jobScheduler *common.JobScheduler) *trash {
+	trash := &trash{
+		documents:   documents,
+		folders:     folders,
+		preferences: preferences,
+		permissions: permissions,
+		legalHolds:  legalHolds,
+	}
+
+	jobScheduler.Schedule(trash.emptyTrash)
+	return trash
+}
@@ -0,0 +1,111 @@
+package archive_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	"unterlagen/features/archive"
+)
+
+func TestTrashRetentionChoices(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+
+	for _, retention := range append([]archive.TrashRetention{archive.TrashRetentionDefault}, archive.TrashRetentionChoices...) {
+		if err := a.UpdateTrashRetention("alice", retention); err != nil {
+			t.Errorf("expected %s to be accepted, got %v", retention.Label(), err)
+		}
+	}
+	for _, retention := range []archive.TrashRetention{-5, 1, 31, 200000} {
+		if err := a.UpdateTrashRetention("alice", retention); !errors.Is(err, archive.ErrInvalidTrashRetention) {
+			t.Errorf("expected %s to be rejected, got %v", retention.Label(), err)
+		}
+	}
+}
+
+func TestTrashRetentionOfManyDays(t *testing.T) {
+	trashedAt := time.Now()
+
+	// As a duration, this many days overflows and would have expired everything at once
+	retention := archive.TrashRetention(200000)
+	deletesAt, ok := retention.DeletesAt(trashedAt)
+	if !ok || !deletesAt.After(trashedAt.AddDate(500, 0, 0)) {
+		t.Errorf("expected the item to be deleted in about 547 years, got %v", deletesAt)
+	}
+	if retention.Expired(trashedAt) {
+		t.Error("expected the item not to expire")
+	}
+}
+
+func TestTrashRetentionExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		retention archive.TrashRetention
+		trashedAt time.Time
+		expired   bool
+	}{
+		{7, now.AddDate(0, 0, -8), true},
+		{7, now.AddDate(0, 0, -7), true},
+		{7, now.AddDate(0, 0, -6), false},
+		{archive.TrashRetentionNever, now.AddDate(-10, 0, 0), false},
+	}
+	for _, test := range tests {
+		if expired := test.retention.Expired(test.trashedAt); expired != test.expired {
+			t.Errorf("expected an item trashed at %v under %s to be expired %v, got %v", test.trashedAt, test.retention.Label(), test.expired, expired)
+		}
+	}
+}
+
+func TestGetTrash(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	family := a.createGroup(t, "family", "alice")
+	old := a.createFolder(t, "Old", archive.FolderRootID, "alice")
+	inFolder := a.upload(t, "invoice.pdf", testFile(t, "mock_pdfs/invoice_0001.pdf"), old.ID, "alice")
+	own := a.upload(t, "receipt.pdf", testFile(t, "mock_pdfs/invoice_0002.pdf"), archive.FolderRootID, "alice")
+	shared := a.upload(t, "photo.pdf", testFile(t, "mock_pdfs/invoice_0003.pdf"), family.ID, "alice")
+	if err := a.UpdateTrashRetention("alice", 7); err != nil {
+		t.Fatal(err)
+	}
+	for _, document := range []archive.Document{own, shared} {
+		if err := a.TrashDocument(document.ID, "alice"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.TrashFolder(old.ID, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	trash, err := a.GetTrash("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trash.Folders) != 1 || len(trash.Documents) != 2 || slices.ContainsFunc(trash.Documents, func(document archive.Document) bool { return document.ID == inFolder.ID }) {
+		t.Errorf("expected documents trashed with their folder to be listed through it, got %v and %v", trash.Folders, trash.Documents)
+	}
+
+	// Items of the group follow the default retention of the group, not the choice of the member
+	for _, document := range trash.Documents {
+		deletesAt, ok := trash.DeletesAt(document.Owner, document.TrashedAt.Time)
+		days := 7
+		if document.Owner == "family" {
+			days = 30
+		}
+		if !ok || !deletesAt.Equal(document.TrashedAt.Time.AddDate(0, 0, days)) {
+			t.Errorf("expected %s to be deleted after %d days, got %v", document.Filename, days, deletesAt)
+		}
+	}
+
+	if kept, err := a.EmptyTrash("alice"); err != nil || kept != 0 {
+		t.Fatalf("expected the trash to be emptied, got %d kept: %v", kept, err)
+	}
+	for _, document := range []archive.Document{inFolder, own, shared} {
+		if _, err := a.documents.FindByID(document.ID); err == nil {
+			t.Errorf("expected %s to be deleted", document.Filename)
+		}
+	}
+	if trash, err := a.GetTrash("alice"); err != nil || !trash.IsEmpty() {
+		t.Errorf("expected an empty trash, got %v: %v", trash, err)
+	}
+}
@@ -0,0 +1,218 @@
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strconv"
+	"time"
+)
+
+var ErrVersionNotFound = errors.New("version not found")
+
+// DocumentVersion is a previous file of a document that was replaced by a newer upload.
+// The current file of a document is not a version, it stays at Document.Filepath().
+type DocumentVersion struct {
+	DocumentID  string
+	Number      int
+	Filename    string
+	Filetype    Filetype
+	Filesize    uint64
+	ContentHash string
+	Owner       string
+	CreatedAt   time.Time
+}
+
+func newDocumentVersion(document Document) DocumentVersion {
+	return DocumentVersion{
+		DocumentID:  document.ID,
+		Number:      document.Version,
+		Filename:    document.Filename,
+		Filetype:    document.Filetype,
+		Filesize:    document.Filesize,
+		ContentHash: document.ContentHash,
+		Owner:       document.Owner,
+		CreatedAt:   time.Now(),
+	}
+}
+
+func (version DocumentVersion) Filepath() string {
+	return path.Join(version.Owner, version.DocumentID, "versions", strconv.Itoa(version.Number), version.Filename)
+}
+
+type DocumentVersionRepository interface {
+	Save(version DocumentVersion) error
+	FindAllByDocumentID(documentID string) ([]DocumentVersion, error)
+	FindByDocumentIDAndNumber(documentID string, number int) (DocumentVersion, error)
+}
+
+func (d *documents) GetDocumentVersions(documentID string, owner string) ([]DocumentVersion, error) {
+	document, err := d.GetDocument(documentID, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.versionRepository.FindAllByDocumentID(document.ID)
+}
+
+func (d *documents) DownloadDocumentVersion(documentID string, number int, owner string, consumer func(version DocumentVersion, r io.Reader) error) error {
+	document, err := d.GetDocument(documentID, owner)
+	if err != nil {
+		return err
+	}
+
+	version, err := d.versionRepository.FindByDocumentIDAndNumber(document.ID, number)
+	if err != nil {
+		return err
+	}
+
+	return d.storage.Retrieve(version.Filepath(), func(r io.Reader) error {
+		return consumer(version, r)
+	})
+}
+
+// ReplaceDocumentFile uploads a new file for an existing document. The current file is kept as a version
+// and text, previews and summary are regenerated for the new file.
+func (d *documents) ReplaceDocumentFile(documentID string, user string, filename string, filesize uint64, r io.Reader) error {
+	document, err := d.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+
+	// The upload is staged first, so an unsupported file does not touch the current version
+	staging := path.Join(document.Owner, document.ID, "staging", filename)
+	hash := sha256.New()
+	err = d.storage.Store(staging, io.TeeReader(r, hash))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := d.storage.Delete(staging); err != nil {
+			slog.Error("failed to delete staged document file", "error", err.Error(), "documentID", document.ID)
+		}
+	}()
+
+	var filetype Filetype
+	err = d.storage.Retrieve(staging, func(r io.Reader) error {
+		filetype, err = d.determineFiletype(r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if filetype == MBOX {
+		return fmt.Errorf("%w: mailboxes cannot be a document version", ErrUnsupportedFiletype)
+	}
+
+	err = d.replaceHead(document, staging, filename, filetype, filesize, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("replaced document file", "documentID", document.ID, "filename", filename)
+	return nil
+}
+
+// RestoreDocumentVersion makes the file of an older version the current one.
+// The current file becomes a version itself, so restoring never loses anything.
+func (d *documents) RestoreDocumentVersion(documentID string, number int, user string) error {
+	document, err := d.getWritableDocument(documentID, user)
+	if err != nil {
+		return err
+	}
+
+	version, err := d.versionRepository.FindByDocumentIDAndNumber(document.ID, number)
+	if err != nil {
+		return err
+	}
+
+	err = d.replaceHead(document, version.Filepath(), version.Filename, version.Filetype, version.Filesize, version.ContentHash)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("restored document version", "documentID", document.ID, "version", number)
+	return nil
+}
+
+// replaceHead archives the current file as a version and copies the given file in its place.
+func (d *documents) replaceHead(document Document, source string, filename string, filetype Filetype, filesize uint64, contentHash string) error {
+	err := d.legalHolds.checkDocument(document)
+	if err != nil {
+		return err
+	}
+
+	version := newDocumentVersion(document)
+	err = d.copyFile(document.Filepath(), version.Filepath())
+	if err != nil {
+		return err
+	}
+
+	err = d.versionRepository.Save(version)
+	if err != nil {
+		return err
+	}
+
+	previousFilepath := document.Filepath()
+	document.Filename = filename
+	document.Filetype = filetype
+	document.Filesize = filesize
+	document.ContentHash = contentHash
+	document.Version++
+	err = d.copyFile(source, document.Filepath())
+	if err != nil {
+		return err
+	}
+
+	if previousFilepath != document.Filepath() {
+		if err := d.storage.Delete(previousFilepath); err != nil {
+			slog.Error("failed to delete replaced document file", "error", err.Error(), "documentID", document.ID)
+		}
+	}
+
+	// Email headers belong to the file, attachments stay with the document they were split into
+	document.Metadata = nil
+	if document.Filetype == EML {
+		err = d.storage.Retrieve(document.Filepath(), func(r io.Reader) error {
+			message, err := parseEmail(r)
+			document.Metadata = message.Headers
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, preview := range document.PreviewFilepaths {
+		if err := d.previewStorage.Delete(preview); err != nil {
+			slog.Error("failed to delete preview file", "error", err.Error(), "documentID", document.ID)
+		}
+	}
+
+	document.Text = ""
+	document.OCRPages = nil
+	document.PreviewFilepaths = nil
+	document.Summary = DocumentSummary{IsGenerating: true}
+	document.UpdatedAt = time.Now()
+	err = d.repository.Save(document)
+	if err != nil {
+		return err
+	}
+
+	err = d.messages.PublishDocumentUpserted(document)
+	if err != nil {
+		return err
+	}
+
+	return d.scheduleDocumentProcessing(document)
+}
+
+func (d *documents) copyFile(source string, destination string) error {
+	return d.storage.Retrieve(source, func(r io.Reader) error {
+		return d.storage.Store(destination, r)
+	})
+}
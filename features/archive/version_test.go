@@ -0,0 +1,124 @@
+package archive_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+// download returns the current file of a document.
+func (a *testArchive) download(t *testing.T, documentID string, user string) []byte {
+	t.Helper()
+	var content []byte
+	err := a.DownloadDocument(documentID, user, func(r io.Reader) error {
+		var err error
+		content, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func (a *testArchive) replace(t *testing.T, documentID string, filename string, content []byte, user string) {
+	t.Helper()
+	if err := a.ReplaceDocumentFile(documentID, user, filename, uint64(len(content)), bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+}
+
+func TestReplaceDocumentFile(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	first := testFile(t, "mock_pdfs/invoice_0001.pdf")
+	second := testFile(t, "mock_pdfs/invoice_0002.pdf")
+	document := a.upload(t, "invoice.pdf", first, archive.FolderRootID, "alice")
+
+	a.replace(t, document.ID, "corrected.pdf", second, "alice")
+
+	replaced := a.document(t, document.ID)
+	if replaced.Version != 2 || replaced.Filename != "corrected.pdf" || replaced.ContentHash == document.ContentHash {
+		t.Errorf("expected the second version to be current, got version %d of %s", replaced.Version, replaced.Filename)
+	}
+	if replaced.Text == "" || len(replaced.PreviewFilepaths) == 0 {
+		t.Error("expected the text and previews of the new file")
+	}
+	if !bytes.Equal(a.download(t, document.ID, "alice"), second) {
+		t.Error("expected the new file to be downloaded")
+	}
+
+	versions, err := a.GetDocumentVersions(document.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Number != 1 || versions[0].Filename != "invoice.pdf" {
+		t.Fatalf("expected the replaced file as the first version, got %v", versions)
+	}
+	err = a.DownloadDocumentVersion(document.ID, 1, "alice", func(version archive.DocumentVersion, r io.Reader) error {
+		content, err := io.ReadAll(r)
+		if !bytes.Equal(content, first) {
+			t.Error("expected the first file to be kept as the version")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreDocumentVersion(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice")
+	first := testFile(t, "mock_pdfs/invoice_0001.pdf")
+	second := testFile(t, "mock_pdfs/invoice_0002.pdf")
+	document := a.upload(t, "invoice.pdf", first, archive.FolderRootID, "alice")
+	a.replace(t, document.ID, "corrected.pdf", second, "alice")
+
+	if err := a.RestoreDocumentVersion(document.ID, 1, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	a.waitForTasks(t)
+
+	restored := a.document(t, document.ID)
+	if restored.Version != 3 || restored.Filename != "invoice.pdf" || !bytes.Equal(a.download(t, document.ID, "alice"), first) {
+		t.Errorf("expected the first file as the third version, got version %d of %s", restored.Version, restored.Filename)
+	}
+
+	// Restoring keeps the file it replaced
+	versions, err := a.GetDocumentVersions(document.ID, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Number != 2 || versions[0].Filename != "corrected.pdf" {
+		t.Errorf("expected both earlier files as versions, got %v", versions)
+	}
+
+	if err := a.RestoreDocumentVersion(document.ID, 7, "alice"); !errors.Is(err, archive.ErrVersionNotFound) {
+		t.Errorf("expected an unknown version not to be found, got %v", err)
+	}
+}
+
+func TestReplaceDocumentFileUnsupported(t *testing.T) {
+	a := newTestArchive(t)
+	a.createUsers(t, "alice", "bob")
+	content := testFile(t, "mock_pdfs/invoice_0001.pdf")
+	document := a.upload(t, "invoice.pdf", content, archive.FolderRootID, "alice")
+
+	unknown := []byte("\x00\x01 not a document")
+	err := a.ReplaceDocumentFile(document.ID, "alice", "data.bin", uint64(len(unknown)), bytes.NewReader(unknown))
+	if !errors.Is(err, archive.ErrUnsupportedFiletype) {
+		t.Errorf("expected an unsupported file to be rejected, got %v", err)
+	}
+	err = a.ReplaceDocumentFile(document.ID, "bob", "invoice.pdf", uint64(len(content)), bytes.NewReader(content))
+	if !errors.Is(err, archive.ErrNotAllowed) {
+		t.Errorf("expected other users not to replace the file, got %v", err)
+	}
+
+	if a.document(t, document.ID).Version != 1 || !bytes.Equal(a.download(t, document.ID, "alice"), content) {
+		t.Error("expected rejected files to leave the document as it was")
+	}
+}
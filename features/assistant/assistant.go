@@ -23,20 +23,29 @@ type Node struct {
 	Chunk      string
 	Embeddings Embeddings
 	DocumentID string
+	Owner      string
+}
+
+// SharedDocuments provides the documents of other owners a user can read, like those of the user's groups.
+type SharedDocuments interface {
+	GetSharedDocumentIDs(user string) ([]string, error)
 }
 
 type NodeRepository interface {
 	SaveAll(nodes []Node) error
-	FindSimilarByEmbedding(embeddings Embeddings) ([]Node, error)
+	// FindSimilarByEmbedding returns the nodes most similar to the embeddings among the nodes
+	// of the owner and of the shared documents.
+	FindSimilarByEmbedding(embeddings Embeddings, owner string, sharedDocumentIDs []string) ([]Node, error)
 	DeleteAllByDocumentID(documentID string) error
 }
 
 type Assistant struct {
-	nodeRepository NodeRepository
-	chatRepository ChatRepository
-	answerer       Answerer
-	embedder       Embedder
-	chunker        Chunker
+	nodeRepository  NodeRepository
+	chatRepository  ChatRepository
+	answerer        Answerer
+	embedder        Embedder
+	chunker         Chunker
+	sharedDocuments SharedDocuments
 }
 
 func (a *Assistant) StartChat(userID string) (Chat, error) {
@@ -65,7 +74,12 @@ func (a *Assistant) Ask(chatID string, question string, userID string) error {
 		return err
 	}
 
-	nodes, err := a.nodeRepository.FindSimilarByEmbedding(embedding)
+	sharedDocumentIDs, err := a.sharedDocuments.GetSharedDocumentIDs(userID)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := a.nodeRepository.FindSimilarByEmbedding(embedding, userID, sharedDocumentIDs)
 	if err != nil {
 		return err
 	}
@@ -98,6 +112,7 @@ func (a *Assistant) generateNodes(document archive.Document) error {
 			Chunk:      chunk,
 			Embeddings: embeddings,
 			DocumentID: document.ID,
+			Owner:      document.Owner,
 		})
 	}
 
@@ -114,14 +129,16 @@ func New(
 	answerer Answerer,
 	embedder Embedder,
 	chunker Chunker,
+	sharedDocuments SharedDocuments,
 	documentMessages archive.DocumentMessages,
 ) *Assistant {
 	assistant := &Assistant{
-		nodeRepository: nodeRepository,
-		chatRepository: chatRepository,
-		answerer:       answerer,
-		embedder:       embedder,
-		chunker:        chunker,
+		nodeRepository:  nodeRepository,
+		chatRepository:  chatRepository,
+		answerer:        answerer,
+		embedder:        embedder,
+		chunker:         chunker,
+		sharedDocuments: sharedDocuments,
 	}
 
 	err := documentMessages.SubscribeDocumentTextExtracted(assistant.generateNodes)
@@ -86,10 +86,14 @@ type TaskType string
 type TaskStatus string
 
 const (
-	TaskTypeExtractText       TaskType = "extract_text"
-	TaskTypeGeneratePreviews  TaskType = "generate_previews"
-	TaskTypeIndexDocument     TaskType = "index_document"
-	TaskTypeSummarizeDocument TaskType = "summarize_document"
+	TaskTypeExtractText        TaskType = "extract_text"
+	TaskTypeGeneratePreviews   TaskType = "generate_previews"
+	TaskTypeIndexDocument      TaskType = "index_document"
+	TaskTypeSummarizeDocument  TaskType = "summarize_document"
+	TaskTypeOCR                TaskType = "ocr"
+	TaskTypeComputeContentHash TaskType = "compute_content_hash"
+	TaskTypeClassifyDocument   TaskType = "classify_document"
+	TaskTypeBulkOperation      TaskType = "bulk_operation"
 )
 
 const (
@@ -3,31 +3,147 @@ package search
 import (
 	"log/slog"
 	"slices"
+	"strconv"
 	"strings"
 	"unterlagen/features/archive"
 	"unterlagen/features/common"
 )
 
+// Snippets are plain text with the matches enclosed in these markers, control characters do not occur in indexed text.
+const (
+	SnippetMatchStart = "\x02"
+	SnippetMatchEnd   = "\x03"
+)
+
 type SearchResult struct {
 	DocumentID string
 	Name       string
 	Rank       float64
-	Snippet    string
+	// Snippet is the text around the matches, enclosed in SnippetMatchStart and SnippetMatchEnd
+	Snippet string
+}
+
+// SnippetPart is a piece of a snippet, Match tells whether it matched the query.
+type SnippetPart struct {
+	Text  string
+	Match bool
+}
+
+// SnippetParts splits the snippet at its markers. Parts are plain text, so they are escaped like any other text when rendered.
+func (result SearchResult) SnippetParts() []SnippetPart {
+	var parts []SnippetPart
+	rest := result.Snippet
+	for rest != "" {
+		before, after, found := strings.Cut(rest, SnippetMatchStart)
+		if before != "" {
+			parts = append(parts, SnippetPart{Text: strings.ReplaceAll(before, SnippetMatchEnd, "")})
+		}
+		if !found {
+			break
+		}
+
+		match, after, _ := strings.Cut(after, SnippetMatchEnd)
+		if match != "" {
+			parts = append(parts, SnippetPart{Text: match, Match: true})
+		}
+		rest = after
+	}
+	return parts
+}
+
+// Filter narrows search results down to documents carrying all of the tags
+// and matching all of the custom field filters.
+type Filter struct {
+	TagIDs []string
+	Fields []FieldFilter
+}
+
+func (filter Filter) IsEmpty() bool {
+	return len(filter.TagIDs) == 0 && len(filter.Fields) == 0
+}
+
+// FieldFilter matches documents whose value of the field equals Value or lies between Min and Max.
+// Empty bounds are open, values are in the normalized form of archive.CustomField.
+type FieldFilter struct {
+	Field archive.CustomField
+	Value string
+	Min   string
+	Max   string
+}
+
+func (filter FieldFilter) IsEmpty() bool {
+	return filter.Value == "" && filter.Min == "" && filter.Max == ""
+}
+
+// Matches reports whether a document value passes the filter, numbers are compared numerically.
+func (filter FieldFilter) Matches(value string) bool {
+	if value == "" {
+		return false
+	}
+	if filter.Value != "" && value != filter.Value {
+		return false
+	}
+
+	compare := strings.Compare
+	if filter.Field.Type.IsNumeric() {
+		compare = func(a, b string) int {
+			x, _ := strconv.ParseFloat(a, 64)
+			y, _ := strconv.ParseFloat(b, 64)
+			if x < y {
+				return -1
+			} else if x > y {
+				return 1
+			}
+			return 0
+		}
+	}
+
+	if filter.Min != "" && compare(value, filter.Min) < 0 {
+		return false
+	}
+	if filter.Max != "" && compare(value, filter.Max) > 0 {
+		return false
+	}
+	return true
 }
 
 type SearchRepository interface {
 	IndexDocument(document archive.Document) error
-	SearchDocuments(query string, owner string, limit int) ([]SearchResult, error)
+	// SearchDocuments returns documents of the owner and the shared documents matching the query and the filter.
+	// An empty query matches all documents.
+	SearchDocuments(query string, filter Filter, owner string, sharedDocumentIDs []string, limit int) ([]SearchResult, error)
+}
+
+// SharedDocuments provides the documents of other users a user can read.
+type SharedDocuments interface {
+	GetSharedDocumentIDs(user string) ([]string, error)
 }
 
 type Search struct {
-	repository SearchRepository
+	repository      SearchRepository
+	sharedDocuments SharedDocuments
 }
 
-func (s *Search) SearchDocuments(query string, owner string, limit int) ([]SearchResult, error) {
-	// Sanitize and validate query
+func (s *Search) SearchDocuments(query string, filter Filter, owner string, limit int) ([]SearchResult, error) {
+	// Normalize field filters the same way values are stored, empty ones are dropped
+	var fields []FieldFilter
+	for _, field := range filter.Fields {
+		var err error
+		for _, bound := range []*string{&field.Value, &field.Min, &field.Max} {
+			*bound, err = field.Field.Normalize(*bound)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !field.IsEmpty() {
+			fields = append(fields, field)
+		}
+	}
+	filter.Fields = fields
+
+	// Sanitize and validate query, a filter alone lists all documents matching it
 	query = strings.TrimSpace(query)
-	if query == "" {
+	if query == "" && filter.IsEmpty() {
 		return []SearchResult{}, nil
 	}
 
@@ -36,7 +152,13 @@ func (s *Search) SearchDocuments(query string, owner string, limit int) ([]Searc
 		limit = 50
 	}
 
-	results, err := s.repository.SearchDocuments(query, owner, limit)
+	// Documents shared with the user are found along with the user's own
+	sharedDocumentIDs, err := s.sharedDocuments.GetSharedDocumentIDs(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.repository.SearchDocuments(query, filter, owner, sharedDocumentIDs, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +178,7 @@ func (s *Search) SearchDocuments(query string, owner string, limit int) ([]Searc
 	return results, err
 }
 
-func New(repository SearchRepository, documentMessages archive.DocumentMessages, taskScheduler *common.TaskScheduler) *Search {
+func New(repository SearchRepository, sharedDocuments SharedDocuments, documentMessages archive.DocumentMessages, taskScheduler *common.TaskScheduler) *Search {
 	taskProcessor := NewSearchTaskProcessor(repository)
 	taskScheduler.Register(taskProcessor)
 
@@ -67,5 +189,13 @@ func New(repository SearchRepository, documentMessages archive.DocumentMessages,
 		panic(err)
 	}
 
-	return &Search{repository: repository}
+	// Title and tag changes do not extract text again, the index is refreshed on every upsert
+	err = documentMessages.SubscribeDocumentUpserted(func(document archive.Document) error {
+		return taskScheduler.ScheduleTask(common.TaskTypeIndexDocument, document, 3)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return &Search{repository: repository, sharedDocuments: sharedDocuments}
 }
@@ -1,6 +1,9 @@
 package configuration
 
 import (
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -12,14 +15,31 @@ const (
 	Recursive ChunkerType       = "recursive"
 )
 
+const (
+	OCRNone    OCRProvider = "none"
+	OCRCommand OCRProvider = "command"
+)
+
+const (
+	StorageFilesystem StorageProvider = "filesystem"
+	StorageS3         StorageProvider = "s3"
+)
+
 type AssistantProvider string
+type StorageProvider string
 type ChunkerType string
+type OCRProvider string
 
 type Configuration struct {
 	Production bool
 	Server     ServerConfiguration
 	Assistant  AssistantConfiguration
 	Data       DataConfiguration
+	OCR        OCRConfiguration
+	Consume    ConsumeConfiguration
+	Mail       MailConfiguration
+	Trash      TrashConfiguration
+	Storage    StorageConfiguration
 }
 
 type AssistantConfiguration struct {
@@ -41,6 +61,53 @@ type AssistantChunkerConfiguration struct {
 	ChunkOverlap int
 }
 
+type OCRConfiguration struct {
+	Provider  OCRProvider
+	Command   string
+	Languages string
+	// Documents with less extracted characters per page than this are sent to OCR
+	MinCharactersPerPage int
+}
+
+type ConsumeConfiguration struct {
+	// Files in consume directories are uploaded once they did not change for this long
+	StableDuration time.Duration
+}
+
+type MailConfiguration struct {
+	// Mail accounts are checked for new messages this often
+	PollInterval time.Duration
+}
+
+type TrashConfiguration struct {
+	// Trashed documents and folders are deleted after this many days unless users chose otherwise, 0 keeps them
+	RetentionDays int
+}
+
+type StorageConfiguration struct {
+	Provider StorageProvider
+	S3       S3Configuration
+	// Documents and previews are encrypted with this base64 encoded 32 byte key, they are stored in plain without it
+	EncryptionKey string
+	// Keys that were replaced by EncryptionKey, they decrypt the files until the keys are rotated
+	PreviousEncryptionKeys []string
+}
+
+type S3Configuration struct {
+	// Endpoint of S3 compatible services like MinIO, empty for AWS
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is put in front of all keys, so the bucket can be shared
+	Prefix          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses the bucket in the path instead of the host name, most S3 compatible services need it
+	PathStyle bool
+	// Files larger than this are uploaded in parts of this size
+	PartSize int64
+}
+
 type DataConfiguration struct {
 	Directory string
 }
@@ -85,6 +152,36 @@ func Load() Configuration {
 		Data: DataConfiguration{
 			Directory: viper.GetString("data_directory"),
 		},
+		OCR: OCRConfiguration{
+			Provider:             OCRProvider(viper.GetString("ocr_provider")),
+			Command:              viper.GetString("ocr_command"),
+			Languages:            viper.GetString("ocr_languages"),
+			MinCharactersPerPage: viper.GetInt("ocr_min_characters_per_page"),
+		},
+		Consume: ConsumeConfiguration{
+			StableDuration: viper.GetDuration("consume_stable_duration"),
+		},
+		Mail: MailConfiguration{
+			PollInterval: viper.GetDuration("mail_poll_interval"),
+		},
+		Trash: TrashConfiguration{
+			RetentionDays: viper.GetInt("trash_retention_days"),
+		},
+		Storage: StorageConfiguration{
+			Provider: StorageProvider(viper.GetString("storage_provider")),
+			S3: S3Configuration{
+				Endpoint:        viper.GetString("storage_s3_endpoint"),
+				Region:          viper.GetString("storage_s3_region"),
+				Bucket:          viper.GetString("storage_s3_bucket"),
+				Prefix:          viper.GetString("storage_s3_prefix"),
+				AccessKeyID:     viper.GetString("storage_s3_access_key_id"),
+				SecretAccessKey: viper.GetString("storage_s3_secret_access_key"),
+				PathStyle:       viper.GetBool("storage_s3_path_style"),
+				PartSize:        viper.GetInt64("storage_s3_part_size"),
+			},
+			EncryptionKey:          viper.GetString("storage_encryption_key"),
+			PreviousEncryptionKeys: splitList(viper.GetString("storage_previous_encryption_keys")),
+		},
 	}
 
 	if config.Server.SessionKey == "" {
@@ -116,4 +213,39 @@ func setDefaults() {
 	viper.SetDefault("assistant_ollama_embedding_model", "embeddinggemma:300m")
 	viper.SetDefault("assistant_ollama_knowledge_base_model", "phi4:latest")
 	viper.SetDefault("assistant_ollama_summarization_model", "phi4:latest")
+
+	// OCR defaults
+	viper.SetDefault("ocr_provider", string(OCRNone))
+	viper.SetDefault("ocr_command", "tesseract")
+	viper.SetDefault("ocr_languages", "deu+eng")
+	viper.SetDefault("ocr_min_characters_per_page", 50)
+
+	// Consume defaults
+	viper.SetDefault("consume_stable_duration", "5s")
+
+	// Mail defaults
+	viper.SetDefault("mail_poll_interval", "5m")
+
+	// Trash defaults
+	viper.SetDefault("trash_retention_days", 30)
+
+	// Storage defaults
+	viper.SetDefault("storage_provider", string(StorageFilesystem))
+	viper.SetDefault("storage_s3_region", "us-east-1")
+	viper.SetDefault("storage_s3_path_style", false)
+	viper.SetDefault("storage_s3_part_size", 16*1024*1024)
+	viper.SetDefault("storage_encryption_key", "")
+	viper.SetDefault("storage_previous_encryption_keys", "")
+}
+
+// splitList splits a comma separated value, empty entries are dropped.
+func splitList(value string) []string {
+	var values []string
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry != "" {
+			values = append(values, entry)
+		}
+	}
+	return values
 }
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.BulkOperationRepository = &BulkOperationRepository{}
+
+type BulkOperationRepository struct {
+	operations map[string]archive.BulkOperation
+	mutex      sync.RWMutex
+}
+
+func (r *BulkOperationRepository) Save(operation archive.BulkOperation) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.operations[operation.ID] = operation
+	return nil
+}
+
+func (r *BulkOperationRepository) FindByID(id string) (archive.BulkOperation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	operation, exists := r.operations[id]
+	if !exists {
+		return archive.BulkOperation{}, archive.ErrBulkOperationNotFound
+	}
+	return operation, nil
+}
+
+func (r *BulkOperationRepository) FindAllByOwner(owner string) ([]archive.BulkOperation, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var operations []archive.BulkOperation
+	for _, operation := range r.operations {
+		if operation.Owner == owner {
+			operations = append(operations, operation)
+		}
+	}
+	return operations, nil
+}
+
+func (r *BulkOperationRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.operations, id)
+	return nil
+}
+
+func NewBulkOperationRepository() *BulkOperationRepository {
+	return &BulkOperationRepository{
+		operations: make(map[string]archive.BulkOperation),
+	}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.ClassificationRuleRepository = &ClassificationRuleRepository{}
+
+type ClassificationRuleRepository struct {
+	rules map[string]archive.ClassificationRule
+	mutex sync.RWMutex
+}
+
+func (r *ClassificationRuleRepository) Save(rule archive.ClassificationRule) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.rules[rule.ID] = rule
+	return nil
+}
+
+func (r *ClassificationRuleRepository) FindByID(id string) (archive.ClassificationRule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	rule, exists := r.rules[id]
+	if !exists {
+		return archive.ClassificationRule{}, archive.ErrClassificationRuleNotFound
+	}
+	return rule, nil
+}
+
+func (r *ClassificationRuleRepository) FindAllByOwner(owner string) ([]archive.ClassificationRule, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var rules []archive.ClassificationRule
+	for _, rule := range r.rules {
+		if rule.Owner == owner {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *ClassificationRuleRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.rules, id)
+	return nil
+}
+
+func NewClassificationRuleRepository() *ClassificationRuleRepository {
+	return &ClassificationRuleRepository{
+		rules: make(map[string]archive.ClassificationRule),
+	}
+}
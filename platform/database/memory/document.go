@@ -0,0 +1,168 @@
+package memory
+
+import (
+	"database/sql"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.DocumentRepository = &DocumentRepository{}
+
+// DocumentRepository keeps documents with their custom field values, which the database stores in a table of its own.
+// Tags and notes are looked up in their repositories when documents are found, like the database joins them.
+type DocumentRepository struct {
+	documents map[string]archive.Document
+	tags      *TagRepository
+	notes     *NoteRepository
+	mutex     sync.RWMutex
+}
+
+func (r *DocumentRepository) Save(document archive.Document) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.documents[document.ID] = document
+	return nil
+}
+
+func (r *DocumentRepository) FindByID(id string) (archive.Document, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	document, exists := r.documents[id]
+	if !exists {
+		return archive.Document{}, sql.ErrNoRows
+	}
+	return r.join(document), nil
+}
+
+func (r *DocumentRepository) FindAllByIDIn(ids []string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return slices.Contains(ids, document.ID) }), nil
+}
+
+func (r *DocumentRepository) FindAllByOwner(owner string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return document.Owner == owner }), nil
+}
+
+func (r *DocumentRepository) FindAllByFolderID(folderID string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return document.FolderID == folderID }), nil
+}
+
+func (r *DocumentRepository) FindAllByParentID(parentID string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return document.ParentID == parentID }), nil
+}
+
+func (r *DocumentRepository) FindAllByTagID(tagID string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return hasTag(document, tagID) }), nil
+}
+
+func (r *DocumentRepository) FindAllByCustomFieldID(fieldID string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool {
+		_, ok := document.CustomFields[fieldID]
+		return ok
+	}), nil
+}
+
+func (r *DocumentRepository) FindAllByOwnerAndFilter(owner string, filter archive.DocumentFilter) ([]archive.Document, error) {
+	documents := r.findAll(func(document archive.Document) bool {
+		return document.Owner == owner &&
+			(filter.TagID == "" || hasTag(document, filter.TagID)) &&
+			(filter.CorrespondentID == "" || document.CorrespondentID == filter.CorrespondentID) &&
+			(filter.DocumentTypeID == "" || document.DocumentTypeID == filter.DocumentTypeID)
+	})
+	slices.SortFunc(documents, func(d1, d2 archive.Document) int { return d2.CreatedAt.Compare(d1.CreatedAt) })
+	return documents, nil
+}
+
+func (r *DocumentRepository) CountAllByCorrespondent(owner string) (map[string]int, error) {
+	return r.countAll(owner, func(document archive.Document) string { return document.CorrespondentID }), nil
+}
+
+func (r *DocumentRepository) CountAllByDocumentType(owner string) (map[string]int, error) {
+	return r.countAll(owner, func(document archive.Document) string { return document.DocumentTypeID }), nil
+}
+
+func (r *DocumentRepository) FindAllByOwnerAndContentHash(owner string, contentHash string) ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool {
+		return document.Owner == owner && document.ContentHash == contentHash
+	}), nil
+}
+
+func (r *DocumentRepository) FindAllDuplicatesByOwner(owner string) ([]archive.Document, error) {
+	documents := r.findAll(func(document archive.Document) bool { return document.Owner == owner && document.ContentHash != "" })
+	counts := make(map[string]int)
+	for _, document := range documents {
+		counts[document.ContentHash]++
+	}
+
+	duplicates := slices.DeleteFunc(documents, func(document archive.Document) bool { return counts[document.ContentHash] < 2 })
+	slices.SortFunc(duplicates, func(d1, d2 archive.Document) int {
+		if compared := strings.Compare(d1.ContentHash, d2.ContentHash); compared != 0 {
+			return compared
+		}
+		return d1.CreatedAt.Compare(d2.CreatedAt)
+	})
+	return duplicates, nil
+}
+
+func (r *DocumentRepository) FindAllWithoutContentHash() ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return document.ContentHash == "" }), nil
+}
+
+func (r *DocumentRepository) FindAllTrashed() ([]archive.Document, error) {
+	return r.findAll(func(document archive.Document) bool { return document.IsTrashed() }), nil
+}
+
+func (r *DocumentRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.documents, id)
+	return nil
+}
+
+func (r *DocumentRepository) findAll(matches func(document archive.Document) bool) []archive.Document {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var documents []archive.Document
+	for _, document := range r.documents {
+		document = r.join(document)
+		if matches(document) {
+			documents = append(documents, document)
+		}
+	}
+	return documents
+}
+
+// join adds the current tags and notes, those the document was saved with are outdated.
+// Maps are copied, changes to a found document must not reach the stored one before it is saved.
+func (r *DocumentRepository) join(document archive.Document) archive.Document {
+	document.Metadata = maps.Clone(document.Metadata)
+	document.CustomFields = maps.Clone(document.CustomFields)
+	document.Tags = r.tags.findAllByDocumentID(document.ID)
+	document.Notes, _ = r.notes.FindAllByDocumentID(document.ID)
+	return document
+}
+
+// countAll counts the documents of an owner that are not trashed per value, documents without a value are left out.
+func (r *DocumentRepository) countAll(owner string, value func(document archive.Document) string) map[string]int {
+	counts := make(map[string]int)
+	for _, document := range r.findAll(func(document archive.Document) bool { return document.Owner == owner && !document.IsTrashed() }) {
+		if id := value(document); id != "" {
+			counts[id]++
+		}
+	}
+	return counts
+}
+
+func hasTag(document archive.Document, tagID string) bool {
+	return slices.ContainsFunc(document.Tags, func(tag archive.Tag) bool { return tag.ID == tagID })
+}
+
+func NewDocumentRepository(tags *TagRepository, notes *NoteRepository) *DocumentRepository {
+	return &DocumentRepository{
+		documents: make(map[string]archive.Document),
+		tags:      tags,
+		notes:     notes,
+	}
+}
@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.CustomFieldRepository = &CustomFieldRepository{}
+
+type CustomFieldRepository struct {
+	fields map[string]archive.CustomField
+	mutex  sync.RWMutex
+}
+
+func (r *CustomFieldRepository) Save(field archive.CustomField) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.fields[field.ID] = field
+	return nil
+}
+
+func (r *CustomFieldRepository) FindByID(id string) (archive.CustomField, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	field, exists := r.fields[id]
+	if !exists {
+		return archive.CustomField{}, archive.ErrCustomFieldNotFound
+	}
+	return field, nil
+}
+
+func (r *CustomFieldRepository) FindAllByOwner(owner string) ([]archive.CustomField, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var fields []archive.CustomField
+	for _, field := range r.fields {
+		if field.Owner == owner {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+func (r *CustomFieldRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.fields, id)
+	return nil
+}
+
+func NewCustomFieldRepository() *CustomFieldRepository {
+	return &CustomFieldRepository{
+		fields: make(map[string]archive.CustomField),
+	}
+}
@@ -31,6 +31,18 @@ func (r *FolderRepository) FindAllByParentID(parentID string) ([]archive.Folder,
 	return folders, nil
 }
 
+func (r *FolderRepository) FindAllByOwner(owner string) ([]archive.Folder, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var folders []archive.Folder
+	for _, folder := range r.folders {
+		if folder.Owner == owner {
+			folders = append(folders, folder)
+		}
+	}
+	return folders, nil
+}
+
 func (r *FolderRepository) GetHierarchy(folderID string) ([]archive.Folder, error) {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
@@ -56,6 +68,25 @@ func (r *FolderRepository) GetHierarchy(folderID string) ([]archive.Folder, erro
 	return hierarchy, nil
 }
 
+func (r *FolderRepository) FindAllTrashed() ([]archive.Folder, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var folders []archive.Folder
+	for _, folder := range r.folders {
+		if folder.IsTrashed() {
+			folders = append(folders, folder)
+		}
+	}
+	return folders, nil
+}
+
+func (r *FolderRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.folders, id)
+	return nil
+}
+
 func NewFolderRepository() *FolderRepository {
 	return &FolderRepository{
 		folders: make(map[string]archive.Folder),
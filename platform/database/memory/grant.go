@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.GrantRepository = &GrantRepository{}
+
+type GrantRepository struct {
+	grants map[string]archive.Grant
+	mutex  sync.RWMutex
+}
+
+func (r *GrantRepository) Save(grant archive.Grant) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.grants[grant.ID] = grant
+	return nil
+}
+
+func (r *GrantRepository) FindByID(id string) (archive.Grant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	grant, exists := r.grants[id]
+	if !exists {
+		return archive.Grant{}, archive.ErrGrantNotFound
+	}
+	return grant, nil
+}
+
+func (r *GrantRepository) FindAllByDocumentID(documentID string) ([]archive.Grant, error) {
+	return r.findAll(func(grant archive.Grant) bool { return grant.DocumentID == documentID }), nil
+}
+
+func (r *GrantRepository) FindAllByFolderID(folderID string) ([]archive.Grant, error) {
+	return r.findAll(func(grant archive.Grant) bool { return grant.FolderID == folderID }), nil
+}
+
+func (r *GrantRepository) FindAllByGrantee(grantee string) ([]archive.Grant, error) {
+	return r.findAll(func(grant archive.Grant) bool { return grant.Grantee == grantee }), nil
+}
+
+func (r *GrantRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.grants, id)
+	return nil
+}
+
+func (r *GrantRepository) findAll(matches func(grant archive.Grant) bool) []archive.Grant {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var grants []archive.Grant
+	for _, grant := range r.grants {
+		if matches(grant) {
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
+
+func NewGrantRepository() *GrantRepository {
+	return &GrantRepository{
+		grants: make(map[string]archive.Grant),
+	}
+}
@@ -0,0 +1,60 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"unterlagen/features/administration"
+)
+
+var _ administration.GroupRepository = &GroupRepository{}
+
+type GroupRepository struct {
+	groups map[string]administration.Group
+	mutex  sync.RWMutex
+}
+
+func (r *GroupRepository) Save(group administration.Group) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	group.Members = slices.Clone(group.Members)
+	r.groups[group.Name] = group
+	return nil
+}
+
+func (r *GroupRepository) FindByName(name string) (administration.Group, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	group, exists := r.groups[name]
+	if !exists {
+		return administration.Group{}, administration.ErrGroupNotFound
+	}
+	return group, nil
+}
+
+func (r *GroupRepository) FindAll() ([]administration.Group, error) {
+	return r.findAll(func(group administration.Group) bool { return true }), nil
+}
+
+func (r *GroupRepository) FindAllByMember(username string) ([]administration.Group, error) {
+	return r.findAll(func(group administration.Group) bool { return group.HasMember(username) }), nil
+}
+
+func (r *GroupRepository) findAll(matches func(group administration.Group) bool) []administration.Group {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []administration.Group
+	for _, group := range r.groups {
+		if matches(group) {
+			result = append(result, group)
+		}
+	}
+	slices.SortFunc(result, func(g1, g2 administration.Group) int { return strings.Compare(g1.Name, g2.Name) })
+	return result
+}
+
+func NewGroupRepository() *GroupRepository {
+	return &GroupRepository{
+		groups: make(map[string]administration.Group),
+	}
+}
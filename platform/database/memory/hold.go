@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.LegalHoldRepository = &LegalHoldRepository{}
+
+type LegalHoldRepository struct {
+	holds map[string]archive.LegalHold
+	mutex sync.RWMutex
+}
+
+func (r *LegalHoldRepository) Save(hold archive.LegalHold) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.holds[hold.ID] = hold
+	return nil
+}
+
+func (r *LegalHoldRepository) FindByID(id string) (archive.LegalHold, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	hold, exists := r.holds[id]
+	if !exists {
+		return archive.LegalHold{}, archive.ErrLegalHoldNotFound
+	}
+	return hold, nil
+}
+
+func (r *LegalHoldRepository) FindAll() ([]archive.LegalHold, error) {
+	return r.findAll(func(hold archive.LegalHold) bool { return true }), nil
+}
+
+func (r *LegalHoldRepository) FindAllByDocumentID(documentID string) ([]archive.LegalHold, error) {
+	return r.findAll(func(hold archive.LegalHold) bool { return hold.DocumentID == documentID }), nil
+}
+
+func (r *LegalHoldRepository) FindAllByFolderIDIn(folderIDs []string) ([]archive.LegalHold, error) {
+	return r.findAll(func(hold archive.LegalHold) bool {
+		return hold.FolderID != "" && slices.Contains(folderIDs, hold.FolderID)
+	}), nil
+}
+
+func (r *LegalHoldRepository) findAll(matches func(hold archive.LegalHold) bool) []archive.LegalHold {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []archive.LegalHold
+	for _, hold := range r.holds {
+		if matches(hold) {
+			result = append(result, hold)
+		}
+	}
+	return result
+}
+
+func (r *LegalHoldRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.holds, id)
+	return nil
+}
+
+func NewLegalHoldRepository() *LegalHoldRepository {
+	return &LegalHoldRepository{
+		holds: make(map[string]archive.LegalHold),
+	}
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.LabelRepository = &LabelRepository{}
+
+type LabelRepository struct {
+	labels      map[string]archive.Label
+	errNotFound error
+	mutex       sync.RWMutex
+}
+
+func (r *LabelRepository) Save(label archive.Label) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.labels[label.ID] = label
+	return nil
+}
+
+func (r *LabelRepository) FindByID(id string) (archive.Label, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	label, exists := r.labels[id]
+	if !exists {
+		return archive.Label{}, r.errNotFound
+	}
+	return label, nil
+}
+
+func (r *LabelRepository) FindAllByOwner(owner string) ([]archive.Label, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var labels []archive.Label
+	for _, label := range r.labels {
+		if label.Owner == owner {
+			labels = append(labels, label)
+		}
+	}
+	return labels, nil
+}
+
+func (r *LabelRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.labels, id)
+	return nil
+}
+
+func NewCorrespondentRepository() *LabelRepository {
+	return &LabelRepository{
+		labels:      make(map[string]archive.Label),
+		errNotFound: archive.ErrCorrespondentNotFound,
+	}
+}
+
+func NewDocumentTypeRepository() *LabelRepository {
+	return &LabelRepository{
+		labels:      make(map[string]archive.Label),
+		errNotFound: archive.ErrDocumentTypeNotFound,
+	}
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.ShareLinkRepository = &ShareLinkRepository{}
+
+type ShareLinkRepository struct {
+	links    map[string]archive.ShareLink
+	accesses []archive.ShareLinkAccess
+	mutex    sync.RWMutex
+}
+
+func (r *ShareLinkRepository) Save(link archive.ShareLink) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.links[link.ID] = link
+	return nil
+}
+
+func (r *ShareLinkRepository) FindByID(id string) (archive.ShareLink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	link, exists := r.links[id]
+	if !exists {
+		return archive.ShareLink{}, archive.ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+func (r *ShareLinkRepository) FindByToken(token string) (archive.ShareLink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, link := range r.links {
+		if link.Token == token {
+			return link, nil
+		}
+	}
+	return archive.ShareLink{}, archive.ErrShareLinkNotFound
+}
+
+func (r *ShareLinkRepository) FindAllByOwner(owner string) ([]archive.ShareLink, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var links []archive.ShareLink
+	for _, link := range r.links {
+		if link.Owner == owner {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (r *ShareLinkRepository) SaveAccess(access archive.ShareLinkAccess) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.accesses = append(r.accesses, access)
+	return nil
+}
+
+func (r *ShareLinkRepository) FindAllAccessesByLinkID(linkID string) ([]archive.ShareLinkAccess, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var accesses []archive.ShareLinkAccess
+	for _, access := range r.accesses {
+		if access.LinkID == linkID {
+			accesses = append(accesses, access)
+		}
+	}
+	return accesses, nil
+}
+
+func NewShareLinkRepository() *ShareLinkRepository {
+	return &ShareLinkRepository{
+		links: make(map[string]archive.ShareLink),
+	}
+}
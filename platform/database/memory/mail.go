@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.MailAccountRepository = &MailAccountRepository{}
+
+type seenUID struct {
+	accountID   string
+	uidValidity uint32
+	uid         uint32
+}
+
+type MailAccountRepository struct {
+	accounts map[string]archive.MailAccount
+	seenUIDs []seenUID
+	mutex    sync.RWMutex
+}
+
+func (r *MailAccountRepository) Save(account archive.MailAccount) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.accounts[account.ID] = account
+	return nil
+}
+
+func (r *MailAccountRepository) FindByID(id string) (archive.MailAccount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	account, exists := r.accounts[id]
+	if !exists {
+		return archive.MailAccount{}, archive.ErrMailAccountNotFound
+	}
+	return account, nil
+}
+
+func (r *MailAccountRepository) FindAll() ([]archive.MailAccount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var accounts []archive.MailAccount
+	for _, account := range r.accounts {
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+func (r *MailAccountRepository) FindAllByOwner(owner string) ([]archive.MailAccount, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var accounts []archive.MailAccount
+	for _, account := range r.accounts {
+		if account.Owner == owner {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (r *MailAccountRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.accounts, id)
+	r.seenUIDs = slices.DeleteFunc(r.seenUIDs, func(seen seenUID) bool { return seen.accountID == id })
+	return nil
+}
+
+func (r *MailAccountRepository) FindSeenUIDs(accountID string, uidValidity uint32) ([]uint32, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var uids []uint32
+	for _, seen := range r.seenUIDs {
+		if seen.accountID == accountID && seen.uidValidity == uidValidity {
+			uids = append(uids, seen.uid)
+		}
+	}
+	return uids, nil
+}
+
+func (r *MailAccountRepository) SaveSeenUID(accountID string, uidValidity uint32, uid uint32) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	seen := seenUID{accountID: accountID, uidValidity: uidValidity, uid: uid}
+	if !slices.Contains(r.seenUIDs, seen) {
+		r.seenUIDs = append(r.seenUIDs, seen)
+	}
+	return nil
+}
+
+func NewMailAccountRepository() *MailAccountRepository {
+	return &MailAccountRepository{
+		accounts: make(map[string]archive.MailAccount),
+	}
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.NoteRepository = &NoteRepository{}
+
+type NoteRepository struct {
+	notes map[string]archive.Note
+	mutex sync.RWMutex
+}
+
+func (r *NoteRepository) Save(note archive.Note) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.notes[note.ID] = note
+	return nil
+}
+
+func (r *NoteRepository) FindByID(id string) (archive.Note, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	note, exists := r.notes[id]
+	if !exists {
+		return archive.Note{}, archive.ErrNoteNotFound
+	}
+	return note, nil
+}
+
+func (r *NoteRepository) FindAllByDocumentID(documentID string) ([]archive.Note, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var notes []archive.Note
+	for _, note := range r.notes {
+		if note.DocumentID == documentID {
+			notes = append(notes, note)
+		}
+	}
+	slices.SortFunc(notes, func(n1, n2 archive.Note) int { return n1.CreatedAt.Compare(n2.CreatedAt) })
+	return notes, nil
+}
+
+func (r *NoteRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.notes, id)
+	return nil
+}
+
+func NewNoteRepository() *NoteRepository {
+	return &NoteRepository{
+		notes: make(map[string]archive.Note),
+	}
+}
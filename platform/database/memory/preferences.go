@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.PreferencesRepository = &PreferencesRepository{}
+
+type PreferencesRepository struct {
+	preferences map[string]archive.Preferences
+	mutex       sync.RWMutex
+}
+
+func (r *PreferencesRepository) Save(preferences archive.Preferences) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.preferences[preferences.Owner] = preferences
+	return nil
+}
+
+func (r *PreferencesRepository) FindByOwner(owner string) (archive.Preferences, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	preferences, exists := r.preferences[owner]
+	if !exists {
+		return archive.Preferences{}, archive.ErrPreferencesNotFound
+	}
+	return preferences, nil
+}
+
+func (r *PreferencesRepository) FindAll() ([]archive.Preferences, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []archive.Preferences
+	for _, preferences := range r.preferences {
+		result = append(result, preferences)
+	}
+	return result, nil
+}
+
+func NewPreferencesRepository() *PreferencesRepository {
+	return &PreferencesRepository{
+		preferences: make(map[string]archive.Preferences),
+	}
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"database/sql"
+	"slices"
+	"sync"
+	"time"
+	"unterlagen/features/archive"
+)
+
+var _ archive.RetentionPolicyRepository = &RetentionPolicyRepository{}
+
+type RetentionPolicyRepository struct {
+	policies map[string]archive.RetentionPolicy
+	notices  map[[2]string]archive.RetentionNotice
+	mutex    sync.RWMutex
+}
+
+func (r *RetentionPolicyRepository) Save(policy archive.RetentionPolicy) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.policies[policy.ID] = policy
+	return nil
+}
+
+func (r *RetentionPolicyRepository) FindByID(id string) (archive.RetentionPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	policy, exists := r.policies[id]
+	if !exists {
+		return archive.RetentionPolicy{}, archive.ErrRetentionPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (r *RetentionPolicyRepository) FindAll() ([]archive.RetentionPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []archive.RetentionPolicy
+	for _, policy := range r.policies {
+		result = append(result, policy)
+	}
+	return result, nil
+}
+
+func (r *RetentionPolicyRepository) FindAllByOwner(owner string) ([]archive.RetentionPolicy, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []archive.RetentionPolicy
+	for _, policy := range r.policies {
+		if policy.Owner == owner {
+			result = append(result, policy)
+		}
+	}
+	return result, nil
+}
+
+func (r *RetentionPolicyRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.policies, id)
+	for key := range r.notices {
+		if key[0] == id {
+			delete(r.notices, key)
+		}
+	}
+	return nil
+}
+
+func (r *RetentionPolicyRepository) SaveNotice(notice archive.RetentionNotice) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := [2]string{notice.PolicyID, notice.DocumentID}
+	if _, exists := r.notices[key]; !exists {
+		r.notices[key] = notice
+	}
+	return nil
+}
+
+func (r *RetentionPolicyRepository) FindAllNoticesByOwner(owner string) ([]archive.RetentionNotice, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []archive.RetentionNotice
+	for _, notice := range r.notices {
+		if notice.Owner == owner {
+			result = append(result, notice)
+		}
+	}
+	slices.SortFunc(result, func(n1, n2 archive.RetentionNotice) int {
+		return n1.ExpiredAt.Compare(n2.ExpiredAt)
+	})
+	return result, nil
+}
+
+func (r *RetentionPolicyRepository) DismissNotice(policyID string, documentID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := [2]string{policyID, documentID}
+	if notice, exists := r.notices[key]; exists {
+		notice.DismissedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		r.notices[key] = notice
+	}
+	return nil
+}
+
+func NewRetentionPolicyRepository() *RetentionPolicyRepository {
+	return &RetentionPolicyRepository{
+		policies: make(map[string]archive.RetentionPolicy),
+		notices:  make(map[[2]string]archive.RetentionNotice),
+	}
+}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"slices"
 	"strings"
 	"unterlagen/features/archive"
 	"unterlagen/features/search"
@@ -12,6 +13,10 @@ type IndexEntry struct {
 	DocumentID string
 	Name       string
 	Text       string
+	Notes      string
+	Owner      string
+	TagIDs     []string
+	Fields     map[string]string
 }
 
 type SearchRepository struct {
@@ -20,22 +25,51 @@ type SearchRepository struct {
 
 // IndexDocument implements search.SearchRepository.
 func (s *SearchRepository) IndexDocument(document archive.Document) error {
+	var tagIDs []string
+	for _, tag := range document.Tags {
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	var noteBodies []string
+	for _, note := range document.Notes {
+		noteBodies = append(noteBodies, note.Body)
+	}
+
+	// A document is indexed again whenever it changes
+	s.index = slices.DeleteFunc(s.index, func(entry IndexEntry) bool {
+		return entry.DocumentID == document.ID
+	})
 	s.index = append(s.index, IndexEntry{
 		DocumentID: document.ID,
 		Name:       document.Name(),
 		Text:       document.Text,
+		Notes:      strings.Join(noteBodies, " "),
+		Owner:      document.Owner,
+		TagIDs:     tagIDs,
+		Fields:     document.CustomFields,
 	})
 	return nil
 }
 
 // SearchDocuments implements search.SearchRepository.
-func (s *SearchRepository) SearchDocuments(query string, owner string, limit int) ([]search.SearchResult, error) {
+func (s *SearchRepository) SearchDocuments(query string, filter search.Filter, owner string, sharedDocumentIDs []string, limit int) ([]search.SearchResult, error) {
 	var results []search.SearchResult
 	queryLower := strings.ToLower(query)
 
 	for _, entry := range s.index {
+		matches := entry.Owner == owner || slices.Contains(sharedDocumentIDs, entry.DocumentID)
+		for _, tagID := range filter.TagIDs {
+			matches = matches && slices.Contains(entry.TagIDs, tagID)
+		}
+		for _, field := range filter.Fields {
+			matches = matches && field.Matches(entry.Fields[field.Field.ID])
+		}
+		if !matches {
+			continue
+		}
+
 		titleContains := strings.Contains(strings.ToLower(entry.Name), queryLower)
-		textContains := strings.Contains(strings.ToLower(entry.Text), queryLower)
+		textContains := strings.Contains(strings.ToLower(entry.Text), queryLower) || strings.Contains(strings.ToLower(entry.Notes), queryLower)
 		if titleContains || textContains {
 			rank := 0.0
 			if titleContains {
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.TagRepository = &TagRepository{}
+
+type TagRepository struct {
+	tags map[string]archive.Tag
+	// assignments maps a tag id to the ids of the documents carrying it
+	assignments map[string]map[string]bool
+	mutex       sync.RWMutex
+}
+
+func (r *TagRepository) Save(tag archive.Tag) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tags[tag.ID] = tag
+	return nil
+}
+
+func (r *TagRepository) FindByID(id string) (archive.Tag, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	tag, exists := r.tags[id]
+	if !exists {
+		return archive.Tag{}, archive.ErrTagNotFound
+	}
+	return tag, nil
+}
+
+func (r *TagRepository) FindAllByOwner(owner string) ([]archive.Tag, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var tags []archive.Tag
+	for _, tag := range r.tags {
+		if tag.Owner == owner {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *TagRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.tags, id)
+	delete(r.assignments, id)
+	return nil
+}
+
+func (r *TagRepository) AssignToDocument(tagID string, documentID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.assignments[tagID] == nil {
+		r.assignments[tagID] = make(map[string]bool)
+	}
+	r.assignments[tagID][documentID] = true
+	return nil
+}
+
+func (r *TagRepository) RemoveFromDocument(tagID string, documentID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.assignments[tagID], documentID)
+	return nil
+}
+
+// findAllByDocumentID returns the tags of a document by name, as the database does.
+func (r *TagRepository) findAllByDocumentID(documentID string) []archive.Tag {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var tags []archive.Tag
+	for id, documentIDs := range r.assignments {
+		if documentIDs[documentID] {
+			tags = append(tags, r.tags[id])
+		}
+	}
+	slices.SortFunc(tags, func(t1, t2 archive.Tag) int { return strings.Compare(t1.Name, t2.Name) })
+	return tags
+}
+
+func NewTagRepository() *TagRepository {
+	return &TagRepository{
+		tags:        make(map[string]archive.Tag),
+		assignments: make(map[string]map[string]bool),
+	}
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"database/sql"
+	"slices"
+	"sync"
+	"time"
+	"unterlagen/features/common"
+)
+
+var _ common.TaskRepository = &TaskRepository{}
+
+type TaskRepository struct {
+	tasks map[string]common.Task
+	mutex sync.RWMutex
+}
+
+func (r *TaskRepository) Save(task common.Task) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tasks[task.ID] = task
+	return nil
+}
+
+func (r *TaskRepository) FindByID(id string) (common.Task, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	task, exists := r.tasks[id]
+	if !exists {
+		return common.Task{}, sql.ErrNoRows
+	}
+	return task, nil
+}
+
+func (r *TaskRepository) FindPendingTasksOfAnyType(limit int, types []common.TaskType) ([]common.Task, error) {
+	now := time.Now()
+	tasks := r.findAll(func(task common.Task) bool {
+		return task.Status == common.TaskStatusPending && !task.NextRunAt.After(now) && slices.Contains(types, task.Type)
+	})
+	slices.Reverse(tasks)
+	return tasks[:min(limit, len(tasks))], nil
+}
+
+func (r *TaskRepository) FindAll() ([]common.Task, error) {
+	return r.findAll(func(task common.Task) bool { return true }), nil
+}
+
+func (r *TaskRepository) FindPaginated(limit, offset int) ([]common.Task, int, error) {
+	tasks := r.findAll(func(task common.Task) bool { return true })
+	offset = min(offset, len(tasks))
+	return tasks[offset:min(offset+limit, len(tasks))], len(tasks), nil
+}
+
+// findAll returns the matching tasks, the newest first.
+func (r *TaskRepository) findAll(matches func(task common.Task) bool) []common.Task {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var result []common.Task
+	for _, task := range r.tasks {
+		if matches(task) {
+			result = append(result, task)
+		}
+	}
+	slices.SortFunc(result, func(t1, t2 common.Task) int { return t2.CreatedAt.Compare(t1.CreatedAt) })
+	return result
+}
+
+func (r *TaskRepository) DeleteByID(id string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.tasks, id)
+	return nil
+}
+
+func (r *TaskRepository) DeleteCompleted() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for id, task := range r.tasks {
+		if task.Status == common.TaskStatusCompleted {
+			delete(r.tasks, id)
+		}
+	}
+	return nil
+}
+
+func NewTaskRepository() *TaskRepository {
+	return &TaskRepository{
+		tasks: make(map[string]common.Task),
+	}
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"slices"
+	"sync"
+	"unterlagen/features/archive"
+)
+
+var _ archive.DocumentVersionRepository = &DocumentVersionRepository{}
+
+type DocumentVersionRepository struct {
+	versions map[string][]archive.DocumentVersion
+	mutex    sync.RWMutex
+}
+
+func (r *DocumentVersionRepository) Save(version archive.DocumentVersion) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	versions := r.versions[version.DocumentID]
+	index := slices.IndexFunc(versions, func(existing archive.DocumentVersion) bool { return existing.Number == version.Number })
+	if index >= 0 {
+		version.Owner = versions[index].Owner
+		version.CreatedAt = versions[index].CreatedAt
+		versions[index] = version
+		return nil
+	}
+	r.versions[version.DocumentID] = append(versions, version)
+	return nil
+}
+
+func (r *DocumentVersionRepository) FindAllByDocumentID(documentID string) ([]archive.DocumentVersion, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	versions := slices.Clone(r.versions[documentID])
+	slices.SortFunc(versions, func(v1, v2 archive.DocumentVersion) int { return v2.Number - v1.Number })
+	return versions, nil
+}
+
+func (r *DocumentVersionRepository) FindByDocumentIDAndNumber(documentID string, number int) (archive.DocumentVersion, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, version := range r.versions[documentID] {
+		if version.Number == number {
+			return version, nil
+		}
+	}
+	return archive.DocumentVersion{}, archive.ErrVersionNotFound
+}
+
+func NewDocumentVersionRepository() *DocumentVersionRepository {
+	return &DocumentVersionRepository{
+		versions: make(map[string][]archive.DocumentVersion),
+	}
+}
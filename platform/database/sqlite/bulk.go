@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.BulkOperationRepository = &BulkOperationRepository{}
+
+type BulkOperationEntity struct {
+	ID               string    `db:"id"`
+	Action           string    `db:"action"`
+	DocumentIDs      []byte    `db:"document_ids"` // JSON stored as bytes
+	FolderID         string    `db:"folder_id"`
+	TagID            string    `db:"tag_id"`
+	CustomFieldID    string    `db:"custom_field_id"`
+	CustomFieldValue string    `db:"custom_field_value"`
+	Status           string    `db:"status"`
+	Processed        int       `db:"processed"`
+	Failed           int       `db:"failed"`
+	LastError        string    `db:"last_error"`
+	Owner            string    `db:"owner"`
+	CreatedAt        time.Time `db:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at"`
+}
+
+func (entity BulkOperationEntity) to() (archive.BulkOperation, error) {
+	var documentIDs []string
+	if len(entity.DocumentIDs) > 0 {
+		err := json.Unmarshal(entity.DocumentIDs, &documentIDs)
+		if err != nil {
+			return archive.BulkOperation{}, err
+		}
+	}
+
+	return archive.BulkOperation{
+		ID:               entity.ID,
+		Action:           archive.BulkAction(entity.Action),
+		DocumentIDs:      documentIDs,
+		FolderID:         entity.FolderID,
+		TagID:            entity.TagID,
+		CustomFieldID:    entity.CustomFieldID,
+		CustomFieldValue: entity.CustomFieldValue,
+		Status:           archive.BulkOperationStatus(entity.Status),
+		Processed:        entity.Processed,
+		Failed:           entity.Failed,
+		LastError:        entity.LastError,
+		Owner:            entity.Owner,
+		CreatedAt:        entity.CreatedAt,
+		UpdatedAt:        entity.UpdatedAt,
+	}, nil
+}
+
+type BulkOperationRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.BulkOperationRepository.
+func (r *BulkOperationRepository) Save(operation archive.BulkOperation) error {
+	documentIDs, err := json.Marshal(operation.DocumentIDs)
+	if err != nil {
+		return err
+	}
+
+	entity := BulkOperationEntity{
+		ID:               operation.ID,
+		Action:           string(operation.Action),
+		DocumentIDs:      documentIDs,
+		FolderID:         operation.FolderID,
+		TagID:            operation.TagID,
+		CustomFieldID:    operation.CustomFieldID,
+		CustomFieldValue: operation.CustomFieldValue,
+		Status:           string(operation.Status),
+		Processed:        operation.Processed,
+		Failed:           operation.Failed,
+		LastError:        operation.LastError,
+		Owner:            operation.Owner,
+		CreatedAt:        operation.CreatedAt,
+		UpdatedAt:        operation.UpdatedAt,
+	}
+
+	_, err = r.db.NamedExec(`
+		INSERT INTO bulk_operations (id, action, document_ids, folder_id, tag_id, custom_field_id, custom_field_value, status, processed, failed, last_error, owner, created_at, updated_at)
+		VALUES (:id, :action, :document_ids, :folder_id, :tag_id, :custom_field_id, :custom_field_value, :status, :processed, :failed, :last_error, :owner, :created_at, :updated_at)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			processed = excluded.processed,
+			failed = excluded.failed,
+			last_error = excluded.last_error,
+			updated_at = excluded.updated_at
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.BulkOperationRepository.
+func (r *BulkOperationRepository) FindByID(id string) (archive.BulkOperation, error) {
+	var entity BulkOperationEntity
+	err := r.db.Get(&entity, "SELECT * FROM bulk_operations WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.BulkOperation{}, archive.ErrBulkOperationNotFound
+	}
+	if err != nil {
+		return archive.BulkOperation{}, err
+	}
+
+	return entity.to()
+}
+
+// FindAllByOwner implements archive.BulkOperationRepository.
+func (r *BulkOperationRepository) FindAllByOwner(owner string) ([]archive.BulkOperation, error) {
+	var entities []BulkOperationEntity
+	err := r.db.Select(&entities, "SELECT * FROM bulk_operations WHERE owner = ?", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := make([]archive.BulkOperation, len(entities))
+	for i, entity := range entities {
+		operation, err := entity.to()
+		if err != nil {
+			return nil, err
+		}
+		operations[i] = operation
+	}
+	return operations, nil
+}
+
+// DeleteByID implements archive.BulkOperationRepository.
+func (r *BulkOperationRepository) DeleteByID(id string) error {
+	_, err := r.db.Exec("DELETE FROM bulk_operations WHERE id = ?", id)
+	return err
+}
+
+func NewBulkOperationRepository(db *sqlx.DB) *BulkOperationRepository {
+	return &BulkOperationRepository{db: db}
+}
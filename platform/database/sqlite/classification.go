@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.ClassificationRuleRepository = &ClassificationRuleRepository{}
+
+type ClassificationRuleEntity struct {
+	ID            string `db:"id"`
+	Name          string `db:"name"`
+	Position      int    `db:"position"`
+	Match         string `db:"match_type"`
+	Pattern       string `db:"pattern"`
+	FolderID      string `db:"folder_id"`
+	TagIDs        []byte `db:"tag_ids"` // JSON stored as bytes
+	TitleTemplate string `db:"title_template"`
+	CustomFields  []byte `db:"custom_fields"` // JSON stored as bytes
+	Owner         string `db:"owner"`
+}
+
+func (entity ClassificationRuleEntity) to() (archive.ClassificationRule, error) {
+	var tagIDs []string
+	if len(entity.TagIDs) > 0 {
+		err := json.Unmarshal(entity.TagIDs, &tagIDs)
+		if err != nil {
+			return archive.ClassificationRule{}, err
+		}
+	}
+
+	var customFields map[string]string
+	if len(entity.CustomFields) > 0 {
+		err := json.Unmarshal(entity.CustomFields, &customFields)
+		if err != nil {
+			return archive.ClassificationRule{}, err
+		}
+	}
+
+	return archive.ClassificationRule{
+		ID:            entity.ID,
+		Name:          entity.Name,
+		Position:      entity.Position,
+		Match:         archive.ClassificationMatch(entity.Match),
+		Pattern:       entity.Pattern,
+		FolderID:      entity.FolderID,
+		TagIDs:        tagIDs,
+		TitleTemplate: entity.TitleTemplate,
+		CustomFields:  customFields,
+		Owner:         entity.Owner,
+	}, nil
+}
+
+type ClassificationRuleRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.ClassificationRuleRepository.
+func (r *ClassificationRuleRepository) Save(rule archive.ClassificationRule) error {
+	tagIDs := rule.TagIDs
+	if tagIDs == nil {
+		tagIDs = []string{}
+	}
+	tagIDsData, err := json.Marshal(tagIDs)
+	if err != nil {
+		return err
+	}
+
+	customFields := rule.CustomFields
+	if customFields == nil {
+		customFields = map[string]string{}
+	}
+	customFieldsData, err := json.Marshal(customFields)
+	if err != nil {
+		return err
+	}
+
+	entity := ClassificationRuleEntity{
+		ID:            rule.ID,
+		Name:          rule.Name,
+		Position:      rule.Position,
+		Match:         string(rule.Match),
+		Pattern:       rule.Pattern,
+		FolderID:      rule.FolderID,
+		TagIDs:        tagIDsData,
+		TitleTemplate: rule.TitleTemplate,
+		CustomFields:  customFieldsData,
+		Owner:         rule.Owner,
+	}
+
+	_, err = r.db.NamedExec(`
+		INSERT INTO classification_rules (id, name, position, match_type, pattern, folder_id, tag_ids, title_template, custom_fields, owner)
+		VALUES (:id, :name, :position, :match_type, :pattern, :folder_id, :tag_ids, :title_template, :custom_fields, :owner)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			position = excluded.position,
+			match_type = excluded.match_type,
+			pattern = excluded.pattern,
+			folder_id = excluded.folder_id,
+			tag_ids = excluded.tag_ids,
+			title_template = excluded.title_template,
+			custom_fields = excluded.custom_fields
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.ClassificationRuleRepository.
+func (r *ClassificationRuleRepository) FindByID(id string) (archive.ClassificationRule, error) {
+	var entity ClassificationRuleEntity
+	err := r.db.Get(&entity, "SELECT * FROM classification_rules WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.ClassificationRule{}, archive.ErrClassificationRuleNotFound
+	}
+	if err != nil {
+		return archive.ClassificationRule{}, err
+	}
+
+	return entity.to()
+}
+
+// FindAllByOwner implements archive.ClassificationRuleRepository.
+func (r *ClassificationRuleRepository) FindAllByOwner(owner string) ([]archive.ClassificationRule, error) {
+	var entities []ClassificationRuleEntity
+	err := r.db.Select(&entities, "SELECT * FROM classification_rules WHERE owner = ? ORDER BY position", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []archive.ClassificationRule
+	for _, entity := range entities {
+		rule, err := entity.to()
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// DeleteByID implements archive.ClassificationRuleRepository.
+func (r *ClassificationRuleRepository) DeleteByID(id string) error {
+	_, err := r.db.Exec("DELETE FROM classification_rules WHERE id = ?", id)
+	return err
+}
+
+func NewClassificationRuleRepository(db *sqlx.DB) *ClassificationRuleRepository {
+	return &ClassificationRuleRepository{db: db}
+}
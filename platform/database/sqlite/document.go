@@ -14,18 +14,24 @@ var _ archive.DocumentRepository = &DocumentRepository{}
 
 // DocumentEntity represents a document in the database layer
 type DocumentEntity struct {
-	ID        string       `db:"id"`
-	Title     string       `db:"title"`
-	Filename  string       `db:"filename"`
-	Filetype  string       `db:"filetype"`
-	Filesize  uint64       `db:"filesize"`
-	Text      string       `db:"text"`
-	Summary   []byte       `db:"summary"` // JSON stored as bytes
-	FolderID  string       `db:"folder_id"`
-	Owner     string       `db:"owner"`
-	CreatedAt time.Time    `db:"created_at"`
-	UpdatedAt time.Time    `db:"updated_at"`
-	TrashedAt sql.NullTime `db:"trashed_at"`
+	ID            string         `db:"id"`
+	Title         string         `db:"title"`
+	Filename      string         `db:"filename"`
+	Filetype      string         `db:"filetype"`
+	Filesize      uint64         `db:"filesize"`
+	ContentHash   string         `db:"content_hash"`
+	Version       int            `db:"version"`
+	Text          string         `db:"text"`
+	Summary       []byte         `db:"summary"`  // JSON stored as bytes
+	Metadata      []byte         `db:"metadata"` // JSON stored as bytes
+	ParentID      sql.NullString `db:"parent_id"`
+	Correspondent sql.NullString `db:"correspondent_id"`
+	DocumentType  sql.NullString `db:"document_type_id"`
+	FolderID      string         `db:"folder_id"`
+	Owner         string         `db:"owner"`
+	CreatedAt     time.Time      `db:"created_at"`
+	UpdatedAt     time.Time      `db:"updated_at"`
+	TrashedAt     sql.NullTime   `db:"trashed_at"`
 }
 
 // to converts DocumentEntity to archive.Document
@@ -39,19 +45,34 @@ func (entity *DocumentEntity) to() (archive.Document, error) {
 		}
 	}
 
+	// Deserialize metadata
+	var metadata map[string]string
+	if len(entity.Metadata) > 0 {
+		err := json.Unmarshal(entity.Metadata, &metadata)
+		if err != nil {
+			return archive.Document{}, err
+		}
+	}
+
 	return archive.Document{
-		ID:        entity.ID,
-		Title:     entity.Title,
-		Filename:  entity.Filename,
-		Filetype:  archive.Filetype(entity.Filetype),
-		Filesize:  entity.Filesize,
-		Text:      entity.Text,
-		Summary:   summary,
-		FolderID:  entity.FolderID,
-		Owner:     entity.Owner,
-		CreatedAt: entity.CreatedAt,
-		UpdatedAt: entity.UpdatedAt,
-		TrashedAt: entity.TrashedAt,
+		ID:              entity.ID,
+		Title:           entity.Title,
+		Filename:        entity.Filename,
+		Filetype:        archive.Filetype(entity.Filetype),
+		Filesize:        entity.Filesize,
+		ContentHash:     entity.ContentHash,
+		Version:         entity.Version,
+		Text:            entity.Text,
+		Summary:         summary,
+		Metadata:        metadata,
+		ParentID:        entity.ParentID.String,
+		CorrespondentID: entity.Correspondent.String,
+		DocumentTypeID:  entity.DocumentType.String,
+		FolderID:        entity.FolderID,
+		Owner:           entity.Owner,
+		CreatedAt:       entity.CreatedAt,
+		UpdatedAt:       entity.UpdatedAt,
+		TrashedAt:       entity.TrashedAt,
 	}, nil
 }
 
@@ -62,19 +83,30 @@ func (entity *DocumentEntity) from(doc archive.Document) error {
 		return err
 	}
 
+	metadataData, err := json.Marshal(doc.Metadata)
+	if err != nil {
+		return err
+	}
+
 	*entity = DocumentEntity{
-		ID:        doc.ID,
-		Title:     doc.Title,
-		Filename:  doc.Filename,
-		Filetype:  string(doc.Filetype),
-		Filesize:  doc.Filesize,
-		Text:      doc.Text,
-		Summary:   summaryData,
-		FolderID:  doc.FolderID,
-		Owner:     doc.Owner,
-		CreatedAt: doc.CreatedAt,
-		UpdatedAt: doc.UpdatedAt,
-		TrashedAt: doc.TrashedAt,
+		ID:            doc.ID,
+		Title:         doc.Title,
+		Filename:      doc.Filename,
+		Filetype:      string(doc.Filetype),
+		Filesize:      doc.Filesize,
+		ContentHash:   doc.ContentHash,
+		Version:       doc.Version,
+		Text:          doc.Text,
+		Summary:       summaryData,
+		Metadata:      metadataData,
+		ParentID:      sql.NullString{String: doc.ParentID, Valid: doc.ParentID != ""},
+		Correspondent: sql.NullString{String: doc.CorrespondentID, Valid: doc.CorrespondentID != ""},
+		DocumentType:  sql.NullString{String: doc.DocumentTypeID, Valid: doc.DocumentTypeID != ""},
+		FolderID:      doc.FolderID,
+		Owner:         doc.Owner,
+		CreatedAt:     doc.CreatedAt,
+		UpdatedAt:     doc.UpdatedAt,
+		TrashedAt:     doc.TrashedAt,
 	}
 
 	return nil
@@ -110,25 +142,7 @@ func (d *DocumentRepository) FindAllByIDIn(ids []string) ([]archive.Document, er
 		return nil, err
 	}
 
-	// Convert entities to domain objects
-	var documents []archive.Document
-	for _, entity := range entities {
-		document, err := entity.to()
-		if err != nil {
-			return nil, err
-		}
-
-		// Load preview filepaths
-		previews, err := d.loadPreviewFilepaths(document.ID)
-		if err != nil {
-			return nil, err
-		}
-		document.PreviewFilepaths = previews
-
-		documents = append(documents, document)
-	}
-
-	return documents, nil
+	return d.mapToDocuments(entities)
 }
 
 // FindAllByOwner implements archive.DocumentRepository.
@@ -139,25 +153,7 @@ func (d *DocumentRepository) FindAllByOwner(owner string) ([]archive.Document, e
 		return nil, err
 	}
 
-	// Convert entities to domain objects
-	var documents []archive.Document
-	for _, entity := range entities {
-		document, err := entity.to()
-		if err != nil {
-			return nil, err
-		}
-
-		// Load preview filepaths
-		previews, err := d.loadPreviewFilepaths(document.ID)
-		if err != nil {
-			return nil, err
-		}
-		document.PreviewFilepaths = previews
-
-		documents = append(documents, document)
-	}
-
-	return documents, nil
+	return d.mapToDocuments(entities)
 }
 
 // DeleteByID implements archive.DocumentRepository.
@@ -174,54 +170,162 @@ func (d *DocumentRepository) FindAllByFolderID(folderID string) ([]archive.Docum
 		return nil, err
 	}
 
-	// Convert entities to domain objects
-	var documents []archive.Document
-	for _, entity := range entities {
-		document, err := entity.to()
-		if err != nil {
-			return nil, err
-		}
+	return d.mapToDocuments(entities)
+}
 
-		// Load preview filepaths
-		previews, err := d.loadPreviewFilepaths(document.ID)
-		if err != nil {
-			return nil, err
-		}
-		document.PreviewFilepaths = previews
+// FindAllByParentID implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllByParentID(parentID string) ([]archive.Document, error) {
+	var entities []DocumentEntity
+	err := d.Select(&entities, "SELECT * FROM documents WHERE parent_id = ?", parentID)
+	if err != nil {
+		return nil, err
+	}
 
-		documents = append(documents, document)
+	return d.mapToDocuments(entities)
+}
+
+// FindAllByTagID implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllByTagID(tagID string) ([]archive.Document, error) {
+	var entities []DocumentEntity
+	err := d.Select(&entities, `
+		SELECT documents.* FROM documents
+		JOIN documents_tags ON documents_tags.document_id = documents.id
+		WHERE documents_tags.tag_id = ?
+	`, tagID)
+	if err != nil {
+		return nil, err
 	}
 
-	return documents, nil
+	return d.mapToDocuments(entities)
 }
 
-// FindAllTrashed implements archive.DocumentRepository.
-func (d *DocumentRepository) FindAllTrashed() ([]archive.Document, error) {
+// FindAllByCustomFieldID implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllByCustomFieldID(fieldID string) ([]archive.Document, error) {
 	var entities []DocumentEntity
-	err := d.Select(&entities, "SELECT * FROM documents WHERE trashed_at IS NOT NULL")
+	err := d.Select(&entities, `
+		SELECT documents.* FROM documents
+		JOIN documents_custom_fields ON documents_custom_fields.document_id = documents.id
+		WHERE documents_custom_fields.field_id = ?
+	`, fieldID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert entities to domain objects
-	var documents []archive.Document
-	for _, entity := range entities {
-		document, err := entity.to()
-		if err != nil {
-			return nil, err
-		}
+	return d.mapToDocuments(entities)
+}
 
-		// Load preview filepaths
-		previews, err := d.loadPreviewFilepaths(document.ID)
-		if err != nil {
+// FindAllByOwnerAndFilter implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllByOwnerAndFilter(owner string, filter archive.DocumentFilter) ([]archive.Document, error) {
+	query := "SELECT * FROM documents WHERE owner = ?"
+	args := []any{owner}
+
+	if filter.TagID != "" {
+		query += " AND id IN (SELECT document_id FROM documents_tags WHERE tag_id = ?)"
+		args = append(args, filter.TagID)
+	}
+	if filter.CorrespondentID != "" {
+		query += " AND correspondent_id = ?"
+		args = append(args, filter.CorrespondentID)
+	}
+	if filter.DocumentTypeID != "" {
+		query += " AND document_type_id = ?"
+		args = append(args, filter.DocumentTypeID)
+	}
+
+	var entities []DocumentEntity
+	err := d.Select(&entities, query+" ORDER BY created_at DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.mapToDocuments(entities)
+}
+
+// CountAllByCorrespondent implements archive.DocumentRepository.
+func (d *DocumentRepository) CountAllByCorrespondent(owner string) (map[string]int, error) {
+	return d.countAllByColumn("correspondent_id", owner)
+}
+
+// CountAllByDocumentType implements archive.DocumentRepository.
+func (d *DocumentRepository) CountAllByDocumentType(owner string) (map[string]int, error) {
+	return d.countAllByColumn("document_type_id", owner)
+}
+
+// countAllByColumn counts the documents of an owner that are not trashed per value of the column
+func (d *DocumentRepository) countAllByColumn(column string, owner string) (map[string]int, error) {
+	rows, err := d.Query(`
+		SELECT `+column+`, COUNT(*) FROM documents
+		WHERE owner = ? AND `+column+` IS NOT NULL AND trashed_at IS NULL
+		GROUP BY `+column, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var count int
+		if err := rows.Scan(&id, &count); err != nil {
 			return nil, err
 		}
-		document.PreviewFilepaths = previews
+		counts[id] = count
+	}
 
-		documents = append(documents, document)
+	return counts, rows.Err()
+}
+
+// FindAllByOwnerAndContentHash implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllByOwnerAndContentHash(owner string, contentHash string) ([]archive.Document, error) {
+	var entities []DocumentEntity
+	err := d.Select(&entities, "SELECT * FROM documents WHERE owner = ? AND content_hash = ?", owner, contentHash)
+	if err != nil {
+		return nil, err
 	}
 
-	return documents, nil
+	return d.mapToDocuments(entities)
+}
+
+// FindAllDuplicatesByOwner implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllDuplicatesByOwner(owner string) ([]archive.Document, error) {
+	var entities []DocumentEntity
+	err := d.Select(&entities, `
+		SELECT * FROM documents
+		WHERE owner = ? AND content_hash IN (
+			SELECT content_hash FROM documents
+			WHERE owner = ? AND content_hash != ''
+			GROUP BY content_hash
+			HAVING COUNT(*) > 1
+		)
+		ORDER BY content_hash, created_at
+	`, owner, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.mapToDocuments(entities)
+}
+
+// FindAllWithoutContentHash implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllWithoutContentHash() ([]archive.Document, error) {
+	var entities []DocumentEntity
+	err := d.Select(&entities, "SELECT * FROM documents WHERE content_hash = ''")
+	if err != nil {
+		return nil, err
+	}
+
+	return d.mapToDocuments(entities)
+}
+
+// FindAllTrashed implements archive.DocumentRepository.
+func (d *DocumentRepository) FindAllTrashed() ([]archive.Document, error) {
+	var entities []DocumentEntity
+	err := d.Select(&entities, "SELECT * FROM documents WHERE trashed_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+
+	return d.mapToDocuments(entities)
 }
 
 // FindByID implements archive.DocumentRepository.
@@ -245,6 +349,34 @@ func (d *DocumentRepository) FindByID(id string) (archive.Document, error) {
 	}
 	document.PreviewFilepaths = previews
 
+	// Load OCR pages
+	ocrPages, err := d.loadOCRPages(id)
+	if err != nil {
+		return archive.Document{}, err
+	}
+	document.OCRPages = ocrPages
+
+	// Load tags
+	tags, err := d.loadTags(id)
+	if err != nil {
+		return archive.Document{}, err
+	}
+	document.Tags = tags
+
+	// Load notes
+	notes, err := d.loadNotes(id)
+	if err != nil {
+		return archive.Document{}, err
+	}
+	document.Notes = notes
+
+	// Load custom field values
+	customFields, err := d.loadCustomFields(id)
+	if err != nil {
+		return archive.Document{}, err
+	}
+	document.CustomFields = customFields
+
 	return document, nil
 }
 
@@ -265,15 +397,21 @@ func (d *DocumentRepository) Save(document archive.Document) error {
 
 	// Save document using NamedExec for cleaner code
 	_, err = tx.NamedExec(`
-		INSERT INTO documents (id, title, filename, filetype, filesize, text, summary, folder_id, owner, created_at, updated_at, trashed_at)
-		VALUES (:id, :title, :filename, :filetype, :filesize, :text, :summary, :folder_id, :owner, :created_at, :updated_at, :trashed_at)
+		INSERT INTO documents (id, title, filename, filetype, filesize, content_hash, version, text, summary, metadata, parent_id, correspondent_id, document_type_id, folder_id, owner, created_at, updated_at, trashed_at)
+		VALUES (:id, :title, :filename, :filetype, :filesize, :content_hash, :version, :text, :summary, :metadata, :parent_id, :correspondent_id, :document_type_id, :folder_id, :owner, :created_at, :updated_at, :trashed_at)
 		ON CONFLICT(id) DO UPDATE SET
 			title = excluded.title,
 			filename = excluded.filename,
 			filetype = excluded.filetype,
 			filesize = excluded.filesize,
+			content_hash = excluded.content_hash,
+			version = excluded.version,
 			text = excluded.text,
 			summary = excluded.summary,
+			metadata = excluded.metadata,
+			parent_id = excluded.parent_id,
+			correspondent_id = excluded.correspondent_id,
+			document_type_id = excluded.document_type_id,
 			folder_id = excluded.folder_id,
 			owner = excluded.owner,
 			updated_at = datetime(),
@@ -289,9 +427,66 @@ func (d *DocumentRepository) Save(document archive.Document) error {
 		return err
 	}
 
+	// Save OCR pages
+	err = d.saveOCRPages(tx, document.ID, document.OCRPages)
+	if err != nil {
+		return err
+	}
+
+	// Save custom field values
+	err = d.saveCustomFields(tx, document.ID, document.CustomFields)
+	if err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
+// mapToDocuments converts entities to domain objects including their previews, OCR pages, tags, notes and custom fields
+func (d *DocumentRepository) mapToDocuments(entities []DocumentEntity) ([]archive.Document, error) {
+	var documents []archive.Document
+	for _, entity := range entities {
+		document, err := entity.to()
+		if err != nil {
+			return nil, err
+		}
+
+		previews, err := d.loadPreviewFilepaths(document.ID)
+		if err != nil {
+			return nil, err
+		}
+		document.PreviewFilepaths = previews
+
+		ocrPages, err := d.loadOCRPages(document.ID)
+		if err != nil {
+			return nil, err
+		}
+		document.OCRPages = ocrPages
+
+		tags, err := d.loadTags(document.ID)
+		if err != nil {
+			return nil, err
+		}
+		document.Tags = tags
+
+		notes, err := d.loadNotes(document.ID)
+		if err != nil {
+			return nil, err
+		}
+		document.Notes = notes
+
+		customFields, err := d.loadCustomFields(document.ID)
+		if err != nil {
+			return nil, err
+		}
+		document.CustomFields = customFields
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
 func (d *DocumentRepository) loadPreviewFilepaths(documentID string) ([]string, error) {
 	rows, err := d.Query(`
 		SELECT filepath FROM documents_previews
@@ -330,6 +525,110 @@ func (d *DocumentRepository) savePreviewFilepaths(tx *sqlx.Tx, documentID string
 		}
 	}
 
+	// A replaced file may have less pages than the previous one
+	_, err := tx.Exec("DELETE FROM documents_previews WHERE document_id = ? AND page_number >= ?", documentID, len(filepaths))
+	return err
+}
+
+func (d *DocumentRepository) loadOCRPages(documentID string) ([]string, error) {
+	var pages []string
+	err := d.Select(&pages, `
+		SELECT text FROM documents_ocr_pages
+		WHERE document_id = ?
+		ORDER BY page_number ASC
+	`, documentID)
+	return pages, err
+}
+
+func (d *DocumentRepository) saveOCRPages(tx *sqlx.Tx, documentID string, pages []string) error {
+	// Pages of a previous OCR run are replaced as a whole, the page count may have changed
+	_, err := tx.Exec("DELETE FROM documents_ocr_pages WHERE document_id = ?", documentID)
+	if err != nil {
+		return err
+	}
+
+	for i, text := range pages {
+		_, err := tx.Exec(`
+			INSERT INTO documents_ocr_pages (document_id, page_number, text, created_at)
+			VALUES (?, ?, ?, datetime())
+		`, documentID, i, text)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *DocumentRepository) loadTags(documentID string) ([]archive.Tag, error) {
+	var entities []TagEntity
+	err := d.Select(&entities, `
+		SELECT tags.* FROM tags
+		JOIN documents_tags ON documents_tags.tag_id = tags.id
+		WHERE documents_tags.document_id = ?
+		ORDER BY tags.name ASC
+	`, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]archive.Tag, len(entities))
+	for i, entity := range entities {
+		tags[i] = entity.to()
+	}
+	return tags, nil
+}
+
+func (d *DocumentRepository) loadNotes(documentID string) ([]archive.Note, error) {
+	var entities []NoteEntity
+	err := d.Select(&entities, "SELECT * FROM notes WHERE document_id = ? ORDER BY created_at ASC", documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]archive.Note, len(entities))
+	for i, entity := range entities {
+		notes[i] = entity.to()
+	}
+	return notes, nil
+}
+
+func (d *DocumentRepository) loadCustomFields(documentID string) (map[string]string, error) {
+	rows, err := d.Query("SELECT field_id, value FROM documents_custom_fields WHERE document_id = ?", documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customFields := make(map[string]string)
+	for rows.Next() {
+		var fieldID, value string
+		if err := rows.Scan(&fieldID, &value); err != nil {
+			return nil, err
+		}
+		customFields[fieldID] = value
+	}
+
+	return customFields, rows.Err()
+}
+
+func (d *DocumentRepository) saveCustomFields(tx *sqlx.Tx, documentID string, customFields map[string]string) error {
+	// Unset fields have no row, so the values are replaced as a whole
+	_, err := tx.Exec("DELETE FROM documents_custom_fields WHERE document_id = ?", documentID)
+	if err != nil {
+		return err
+	}
+
+	for fieldID, value := range customFields {
+		_, err := tx.Exec(`
+			INSERT INTO documents_custom_fields (document_id, field_id, value)
+			VALUES (?, ?, ?)
+		`, documentID, fieldID, value)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.CustomFieldRepository = &CustomFieldRepository{}
+
+type CustomFieldEntity struct {
+	ID      string `db:"id"`
+	Name    string `db:"name"`
+	Type    string `db:"type"`
+	Options []byte `db:"options"` // JSON stored as bytes
+	Owner   string `db:"owner"`
+}
+
+func (entity CustomFieldEntity) to() (archive.CustomField, error) {
+	var options []string
+	if len(entity.Options) > 0 {
+		err := json.Unmarshal(entity.Options, &options)
+		if err != nil {
+			return archive.CustomField{}, err
+		}
+	}
+
+	return archive.CustomField{
+		ID:      entity.ID,
+		Name:    entity.Name,
+		Type:    archive.CustomFieldType(entity.Type),
+		Options: options,
+		Owner:   entity.Owner,
+	}, nil
+}
+
+type CustomFieldRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.CustomFieldRepository.
+func (r *CustomFieldRepository) Save(field archive.CustomField) error {
+	options := field.Options
+	if options == nil {
+		options = []string{}
+	}
+	optionsData, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+
+	entity := CustomFieldEntity{
+		ID:      field.ID,
+		Name:    field.Name,
+		Type:    string(field.Type),
+		Options: optionsData,
+		Owner:   field.Owner,
+	}
+
+	_, err = r.db.NamedExec(`
+		INSERT INTO custom_fields (id, name, type, options, owner)
+		VALUES (:id, :name, :type, :options, :owner)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			type = excluded.type,
+			options = excluded.options
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.CustomFieldRepository.
+func (r *CustomFieldRepository) FindByID(id string) (archive.CustomField, error) {
+	var entity CustomFieldEntity
+	err := r.db.Get(&entity, "SELECT * FROM custom_fields WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.CustomField{}, archive.ErrCustomFieldNotFound
+	}
+	if err != nil {
+		return archive.CustomField{}, err
+	}
+
+	return entity.to()
+}
+
+// FindAllByOwner implements archive.CustomFieldRepository.
+func (r *CustomFieldRepository) FindAllByOwner(owner string) ([]archive.CustomField, error) {
+	var entities []CustomFieldEntity
+	err := r.db.Select(&entities, "SELECT * FROM custom_fields WHERE owner = ?", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []archive.CustomField
+	for _, entity := range entities {
+		field, err := entity.to()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// DeleteByID implements archive.CustomFieldRepository.
+func (r *CustomFieldRepository) DeleteByID(id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM documents_custom_fields WHERE field_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM custom_fields WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func NewCustomFieldRepository(db *sqlx.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
@@ -17,10 +17,10 @@ type FolderRepository struct {
 // Save implements archive.FolderRepository.
 func (f *FolderRepository) Save(folder archive.Folder) error {
 	query := `
-        INSERT INTO folders (id, name, parent_id, owner, created_at, updated_at)
-        VALUES (:id, :name, :parent_id, :owner, datetime(), datetime())
+        INSERT INTO folders (id, name, parent_id, owner, created_at, updated_at, trashed_at)
+        VALUES (:id, :name, :parent_id, :owner, datetime(), datetime(), :trashed_at)
         ON CONFLICT (id) DO UPDATE
-        SET name = :name, parent_id = :parent_id, owner = :owner, updated_at = datetime()
+        SET name = :name, parent_id = :parent_id, owner = :owner, updated_at = datetime(), trashed_at = :trashed_at
     `
 
 	_, err := f.db.NamedExec(query, f.mapToEntity(folder))
@@ -30,7 +30,7 @@ func (f *FolderRepository) Save(folder archive.Folder) error {
 // FindAllByParentID implements archive.FolderRepository.
 func (f *FolderRepository) FindAllByParentID(parentID string) ([]archive.Folder, error) {
 	var entities []sqlFolderEntity
-	query := `SELECT id, name, parent_id, owner FROM folders WHERE parent_id = $1`
+	query := `SELECT id, name, parent_id, owner, trashed_at FROM folders WHERE parent_id = $1`
 
 	err := f.db.Select(&entities, query, parentID)
 	if err != nil {
@@ -40,19 +40,32 @@ func (f *FolderRepository) FindAllByParentID(parentID string) ([]archive.Folder,
 	return f.mapToFolders(entities), nil
 }
 
+// FindAllByOwner implements archive.FolderRepository.
+func (f *FolderRepository) FindAllByOwner(owner string) ([]archive.Folder, error) {
+	var entities []sqlFolderEntity
+	query := `SELECT id, name, parent_id, owner, trashed_at FROM folders WHERE owner = $1`
+
+	err := f.db.Select(&entities, query, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.mapToFolders(entities), nil
+}
+
 // GetHierarchy implements archive.FolderRepository.
 func (f *FolderRepository) GetHierarchy(folderID string) ([]archive.Folder, error) {
 	var entities []sqlFolderEntity
 
 	query := `
         WITH RECURSIVE folder_hierarchy AS (
-            SELECT id, name, parent_id, owner, created_at, updated_at FROM folders WHERE id = $1
+            SELECT id, name, parent_id, owner, created_at, updated_at, trashed_at, 0 AS depth FROM folders WHERE id = $1
             UNION ALL
-            SELECT f.id, f.name, f.parent_id, f.owner, f.created_at, f.updated_at FROM folders f
+            SELECT f.id, f.name, f.parent_id, f.owner, f.created_at, f.updated_at, f.trashed_at, fh.depth + 1 FROM folders f
             INNER JOIN folder_hierarchy fh ON f.id = fh.parent_id
         )
-        SELECT id, name, parent_id, owner, created_at, updated_at FROM folder_hierarchy
-        ORDER BY id ASC;
+        SELECT id, name, parent_id, owner, created_at, updated_at, trashed_at FROM folder_hierarchy
+        ORDER BY depth ASC;
     `
 
 	err := f.db.Select(&entities, query, folderID)
@@ -60,6 +73,25 @@ func (f *FolderRepository) GetHierarchy(folderID string) ([]archive.Folder, erro
 	return f.mapToFolders(entities), err
 }
 
+// FindAllTrashed implements archive.FolderRepository.
+func (f *FolderRepository) FindAllTrashed() ([]archive.Folder, error) {
+	var entities []sqlFolderEntity
+	query := `SELECT id, name, parent_id, owner, trashed_at FROM folders WHERE trashed_at IS NOT NULL`
+
+	err := f.db.Select(&entities, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.mapToFolders(entities), nil
+}
+
+// DeleteByID implements archive.FolderRepository.
+func (f *FolderRepository) DeleteByID(id string) error {
+	_, err := f.db.Exec(`DELETE FROM folders WHERE id = $1`, id)
+	return err
+}
+
 func (f *FolderRepository) mapToFolders(entities []sqlFolderEntity) []archive.Folder {
 	var folders []archive.Folder
 	for _, entity := range entities {
@@ -77,7 +109,8 @@ func (f *FolderRepository) mapToEntity(folder archive.Folder) sqlFolderEntity {
 			String: folder.ParentID,
 			Valid:  len(folder.ParentID) > 0,
 		},
-		Owner: folder.Owner,
+		Owner:     folder.Owner,
+		TrashedAt: folder.TrashedAt,
 	}
 }
 
@@ -92,13 +125,15 @@ type sqlFolderEntity struct {
 	Owner     string         `db:"owner"`
 	CreatedAt string         `db:"created_at"`
 	UpdatedAt string         `db:"updated_at"`
+	TrashedAt sql.NullTime   `db:"trashed_at"`
 }
 
 func (f *sqlFolderEntity) ToFolder() archive.Folder {
 	return archive.Folder{
-		ID:       f.ID,
-		Name:     f.Name,
-		ParentID: f.ParentID.String,
-		Owner:    f.Owner,
+		ID:        f.ID,
+		Name:      f.Name,
+		ParentID:  f.ParentID.String,
+		Owner:     f.Owner,
+		TrashedAt: f.TrashedAt,
 	}
 }
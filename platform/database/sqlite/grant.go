@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.GrantRepository = &GrantRepository{}
+
+type GrantEntity struct {
+	ID         string         `db:"id"`
+	DocumentID sql.NullString `db:"document_id"`
+	FolderID   sql.NullString `db:"folder_id"`
+	Grantee    string         `db:"grantee"`
+	Permission string         `db:"permission"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func (entity GrantEntity) to() archive.Grant {
+	return archive.Grant{
+		ID:         entity.ID,
+		DocumentID: entity.DocumentID.String,
+		FolderID:   entity.FolderID.String,
+		Grantee:    entity.Grantee,
+		Permission: archive.Permission(entity.Permission),
+		CreatedAt:  entity.CreatedAt,
+	}
+}
+
+type GrantRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.GrantRepository.
+func (r *GrantRepository) Save(grant archive.Grant) error {
+	entity := GrantEntity{
+		ID:         grant.ID,
+		DocumentID: sql.NullString{String: grant.DocumentID, Valid: grant.DocumentID != ""},
+		FolderID:   sql.NullString{String: grant.FolderID, Valid: grant.FolderID != ""},
+		Grantee:    grant.Grantee,
+		Permission: string(grant.Permission),
+		CreatedAt:  grant.CreatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO grants (id, document_id, folder_id, grantee, permission, created_at)
+		VALUES (:id, :document_id, :folder_id, :grantee, :permission, :created_at)
+		ON CONFLICT (id) DO UPDATE SET
+			permission = excluded.permission
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.GrantRepository.
+func (r *GrantRepository) FindByID(id string) (archive.Grant, error) {
+	var entity GrantEntity
+	err := r.db.Get(&entity, "SELECT * FROM grants WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.Grant{}, archive.ErrGrantNotFound
+	}
+	if err != nil {
+		return archive.Grant{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAllByDocumentID implements archive.GrantRepository.
+func (r *GrantRepository) FindAllByDocumentID(documentID string) ([]archive.Grant, error) {
+	return r.findAll("SELECT * FROM grants WHERE document_id = ? ORDER BY grantee ASC", documentID)
+}
+
+// FindAllByFolderID implements archive.GrantRepository.
+func (r *GrantRepository) FindAllByFolderID(folderID string) ([]archive.Grant, error) {
+	return r.findAll("SELECT * FROM grants WHERE folder_id = ? ORDER BY grantee ASC", folderID)
+}
+
+// FindAllByGrantee implements archive.GrantRepository.
+func (r *GrantRepository) FindAllByGrantee(grantee string) ([]archive.Grant, error) {
+	return r.findAll("SELECT * FROM grants WHERE grantee = ?", grantee)
+}
+
+// DeleteByID implements archive.GrantRepository.
+func (r *GrantRepository) DeleteByID(id string) error {
+	_, err := r.db.Exec("DELETE FROM grants WHERE id = ?", id)
+	return err
+}
+
+func (r *GrantRepository) findAll(query string, args ...any) ([]archive.Grant, error) {
+	var entities []GrantEntity
+	err := r.db.Select(&entities, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make([]archive.Grant, len(entities))
+	for i, entity := range entities {
+		grants[i] = entity.to()
+	}
+	return grants, nil
+}
+
+func NewGrantRepository(db *sqlx.DB) *GrantRepository {
+	return &GrantRepository{db: db}
+}
@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/administration"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ administration.GroupRepository = &GroupRepository{}
+
+type GroupEntity struct {
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type GroupRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements administration.GroupRepository.
+func (r *GroupRepository) Save(group administration.Group) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.NamedExec(`
+		INSERT INTO groups (name, created_at)
+		VALUES (:name, :created_at)
+		ON CONFLICT (name) DO NOTHING
+	`, GroupEntity{Name: group.Name, CreatedAt: group.CreatedAt})
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM group_members WHERE group_name = ?", group.Name)
+	if err != nil {
+		return err
+	}
+	for _, member := range group.Members {
+		_, err = tx.Exec("INSERT INTO group_members (group_name, member) VALUES (?, ?)", group.Name, member)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// FindByName implements administration.GroupRepository.
+func (r *GroupRepository) FindByName(name string) (administration.Group, error) {
+	var entity GroupEntity
+	err := r.db.Get(&entity, "SELECT * FROM groups WHERE name = ?", name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return administration.Group{}, administration.ErrGroupNotFound
+		}
+		return administration.Group{}, err
+	}
+	return r.to(entity)
+}
+
+// FindAll implements administration.GroupRepository.
+func (r *GroupRepository) FindAll() ([]administration.Group, error) {
+	var entities []GroupEntity
+	err := r.db.Select(&entities, "SELECT * FROM groups ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	return r.toAll(entities)
+}
+
+// FindAllByMember implements administration.GroupRepository.
+func (r *GroupRepository) FindAllByMember(username string) ([]administration.Group, error) {
+	var entities []GroupEntity
+	err := r.db.Select(&entities, `
+		SELECT groups.* FROM groups
+		JOIN group_members ON group_members.group_name = groups.name
+		WHERE group_members.member = ?
+		ORDER BY groups.name
+	`, username)
+	if err != nil {
+		return nil, err
+	}
+	return r.toAll(entities)
+}
+
+func (r *GroupRepository) to(entity GroupEntity) (administration.Group, error) {
+	var members []string
+	err := r.db.Select(&members, "SELECT member FROM group_members WHERE group_name = ? ORDER BY member", entity.Name)
+	if err != nil {
+		return administration.Group{}, err
+	}
+
+	return administration.Group{
+		Name:      entity.Name,
+		Members:   members,
+		CreatedAt: entity.CreatedAt,
+	}, nil
+}
+
+func (r *GroupRepository) toAll(entities []GroupEntity) ([]administration.Group, error) {
+	groups := make([]administration.Group, 0, len(entities))
+	for _, entity := range entities {
+		group, err := r.to(entity)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func NewGroupRepository(db *sqlx.DB) *GroupRepository {
+	return &GroupRepository{db: db}
+}
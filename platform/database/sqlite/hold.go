@@ -0,0 +1,117 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.LegalHoldRepository = &LegalHoldRepository{}
+
+type LegalHoldEntity struct {
+	ID         string         `db:"id"`
+	DocumentID sql.NullString `db:"document_id"`
+	FolderID   sql.NullString `db:"folder_id"`
+	Reason     string         `db:"reason"`
+	Actor      string         `db:"actor"`
+	CreatedAt  time.Time      `db:"created_at"`
+}
+
+func (entity LegalHoldEntity) to() archive.LegalHold {
+	return archive.LegalHold{
+		ID:         entity.ID,
+		DocumentID: entity.DocumentID.String,
+		FolderID:   entity.FolderID.String,
+		Reason:     entity.Reason,
+		Actor:      entity.Actor,
+		CreatedAt:  entity.CreatedAt,
+	}
+}
+
+type LegalHoldRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.LegalHoldRepository.
+func (r *LegalHoldRepository) Save(hold archive.LegalHold) error {
+	entity := LegalHoldEntity{
+		ID:         hold.ID,
+		DocumentID: sql.NullString{String: hold.DocumentID, Valid: hold.DocumentID != ""},
+		FolderID:   sql.NullString{String: hold.FolderID, Valid: hold.FolderID != ""},
+		Reason:     hold.Reason,
+		Actor:      hold.Actor,
+		CreatedAt:  hold.CreatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO legal_holds (id, document_id, folder_id, reason, actor, created_at)
+		VALUES (:id, :document_id, :folder_id, :reason, :actor, :created_at)
+		ON CONFLICT (id) DO UPDATE SET
+			reason = excluded.reason
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.LegalHoldRepository.
+func (r *LegalHoldRepository) FindByID(id string) (archive.LegalHold, error) {
+	var entity LegalHoldEntity
+	err := r.db.Get(&entity, "SELECT * FROM legal_holds WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.LegalHold{}, archive.ErrLegalHoldNotFound
+	}
+	if err != nil {
+		return archive.LegalHold{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAll implements archive.LegalHoldRepository.
+func (r *LegalHoldRepository) FindAll() ([]archive.LegalHold, error) {
+	return r.findAll("SELECT * FROM legal_holds")
+}
+
+// FindAllByDocumentID implements archive.LegalHoldRepository.
+func (r *LegalHoldRepository) FindAllByDocumentID(documentID string) ([]archive.LegalHold, error) {
+	return r.findAll("SELECT * FROM legal_holds WHERE document_id = ?", documentID)
+}
+
+// FindAllByFolderIDIn implements archive.LegalHoldRepository.
+func (r *LegalHoldRepository) FindAllByFolderIDIn(folderIDs []string) ([]archive.LegalHold, error) {
+	if len(folderIDs) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In("SELECT * FROM legal_holds WHERE folder_id IN (?)", folderIDs)
+	if err != nil {
+		return nil, err
+	}
+	return r.findAll(query, args...)
+}
+
+func (r *LegalHoldRepository) findAll(query string, args ...any) ([]archive.LegalHold, error) {
+	var entities []LegalHoldEntity
+	err := r.db.Select(&entities, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	holds := make([]archive.LegalHold, len(entities))
+	for i, entity := range entities {
+		holds[i] = entity.to()
+	}
+	return holds, nil
+}
+
+// DeleteByID implements archive.LegalHoldRepository.
+func (r *LegalHoldRepository) DeleteByID(id string) error {
+	_, err := r.db.Exec("DELETE FROM legal_holds WHERE id = ?", id)
+	return err
+}
+
+func NewLegalHoldRepository(db *sqlx.DB) *LegalHoldRepository {
+	return &LegalHoldRepository{db: db}
+}
@@ -0,0 +1,108 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.LabelRepository = &LabelRepository{}
+
+type LabelEntity struct {
+	ID    string `db:"id"`
+	Name  string `db:"name"`
+	Owner string `db:"owner"`
+}
+
+func (entity LabelEntity) to() archive.Label {
+	return archive.Label{
+		ID:    entity.ID,
+		Name:  entity.Name,
+		Owner: entity.Owner,
+	}
+}
+
+// LabelRepository stores one kind of labels in its own table, documents refer to them by a column of their own.
+type LabelRepository struct {
+	db          *sqlx.DB
+	table       string
+	column      string
+	errNotFound error
+}
+
+// Save implements archive.LabelRepository.
+func (r *LabelRepository) Save(label archive.Label) error {
+	entity := LabelEntity{
+		ID:    label.ID,
+		Name:  label.Name,
+		Owner: label.Owner,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO `+r.table+` (id, name, owner)
+		VALUES (:id, :name, :owner)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.LabelRepository.
+func (r *LabelRepository) FindByID(id string) (archive.Label, error) {
+	var entity LabelEntity
+	err := r.db.Get(&entity, "SELECT * FROM "+r.table+" WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.Label{}, r.errNotFound
+	}
+	if err != nil {
+		return archive.Label{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAllByOwner implements archive.LabelRepository.
+func (r *LabelRepository) FindAllByOwner(owner string) ([]archive.Label, error) {
+	var entities []LabelEntity
+	err := r.db.Select(&entities, "SELECT * FROM "+r.table+" WHERE owner = ?", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make([]archive.Label, len(entities))
+	for i, entity := range entities {
+		labels[i] = entity.to()
+	}
+	return labels, nil
+}
+
+// DeleteByID implements archive.LabelRepository.
+func (r *LabelRepository) DeleteByID(id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE documents SET "+r.column+" = NULL WHERE "+r.column+" = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM "+r.table+" WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func NewCorrespondentRepository(db *sqlx.DB) *LabelRepository {
+	return &LabelRepository{db: db, table: "correspondents", column: "correspondent_id", errNotFound: archive.ErrCorrespondentNotFound}
+}
+
+func NewDocumentTypeRepository(db *sqlx.DB) *LabelRepository {
+	return &LabelRepository{db: db, table: "document_types", column: "document_type_id", errNotFound: archive.ErrDocumentTypeNotFound}
+}
@@ -0,0 +1,170 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.ShareLinkRepository = &ShareLinkRepository{}
+
+type ShareLinkEntity struct {
+	ID           string         `db:"id"`
+	Token        string         `db:"token"`
+	DocumentID   sql.NullString `db:"document_id"`
+	FolderID     sql.NullString `db:"folder_id"`
+	Owner        string         `db:"owner"`
+	PasswordHash string         `db:"password_hash"`
+	ExpiresAt    sql.NullTime   `db:"expires_at"`
+	MaxDownloads int            `db:"max_downloads"`
+	Downloads    int            `db:"downloads"`
+	RevokedAt    sql.NullTime   `db:"revoked_at"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+func (entity ShareLinkEntity) to() archive.ShareLink {
+	return archive.ShareLink{
+		ID:           entity.ID,
+		Token:        entity.Token,
+		DocumentID:   entity.DocumentID.String,
+		FolderID:     entity.FolderID.String,
+		Owner:        entity.Owner,
+		PasswordHash: entity.PasswordHash,
+		ExpiresAt:    entity.ExpiresAt,
+		MaxDownloads: entity.MaxDownloads,
+		Downloads:    entity.Downloads,
+		RevokedAt:    entity.RevokedAt,
+		CreatedAt:    entity.CreatedAt,
+	}
+}
+
+type ShareLinkAccessEntity struct {
+	ID         string    `db:"id"`
+	LinkID     string    `db:"link_id"`
+	Action     string    `db:"action"`
+	DocumentID string    `db:"document_id"`
+	Address    string    `db:"address"`
+	UserAgent  string    `db:"user_agent"`
+	AccessedAt time.Time `db:"accessed_at"`
+}
+
+func (entity ShareLinkAccessEntity) to() archive.ShareLinkAccess {
+	return archive.ShareLinkAccess{
+		ID:         entity.ID,
+		LinkID:     entity.LinkID,
+		Action:     archive.ShareLinkAction(entity.Action),
+		DocumentID: entity.DocumentID,
+		Address:    entity.Address,
+		UserAgent:  entity.UserAgent,
+		AccessedAt: entity.AccessedAt,
+	}
+}
+
+type ShareLinkRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.ShareLinkRepository.
+func (r *ShareLinkRepository) Save(link archive.ShareLink) error {
+	entity := ShareLinkEntity{
+		ID:           link.ID,
+		Token:        link.Token,
+		DocumentID:   sql.NullString{String: link.DocumentID, Valid: link.DocumentID != ""},
+		FolderID:     sql.NullString{String: link.FolderID, Valid: link.FolderID != ""},
+		Owner:        link.Owner,
+		PasswordHash: link.PasswordHash,
+		ExpiresAt:    link.ExpiresAt,
+		MaxDownloads: link.MaxDownloads,
+		Downloads:    link.Downloads,
+		RevokedAt:    link.RevokedAt,
+		CreatedAt:    link.CreatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO share_links (id, token, document_id, folder_id, owner, password_hash, expires_at, max_downloads, downloads, revoked_at, created_at)
+		VALUES (:id, :token, :document_id, :folder_id, :owner, :password_hash, :expires_at, :max_downloads, :downloads, :revoked_at, :created_at)
+		ON CONFLICT (id) DO UPDATE SET
+			downloads = excluded.downloads,
+			revoked_at = excluded.revoked_at
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.ShareLinkRepository.
+func (r *ShareLinkRepository) FindByID(id string) (archive.ShareLink, error) {
+	return r.find("SELECT * FROM share_links WHERE id = ?", id)
+}
+
+// FindByToken implements archive.ShareLinkRepository.
+func (r *ShareLinkRepository) FindByToken(token string) (archive.ShareLink, error) {
+	return r.find("SELECT * FROM share_links WHERE token = ?", token)
+}
+
+func (r *ShareLinkRepository) find(query string, arg string) (archive.ShareLink, error) {
+	var entity ShareLinkEntity
+	err := r.db.Get(&entity, query, arg)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.ShareLink{}, archive.ErrShareLinkNotFound
+	}
+	if err != nil {
+		return archive.ShareLink{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAllByOwner implements archive.ShareLinkRepository.
+func (r *ShareLinkRepository) FindAllByOwner(owner string) ([]archive.ShareLink, error) {
+	var entities []ShareLinkEntity
+	err := r.db.Select(&entities, "SELECT * FROM share_links WHERE owner = ?", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]archive.ShareLink, len(entities))
+	for i, entity := range entities {
+		links[i] = entity.to()
+	}
+	return links, nil
+}
+
+// SaveAccess implements archive.ShareLinkRepository.
+func (r *ShareLinkRepository) SaveAccess(access archive.ShareLinkAccess) error {
+	entity := ShareLinkAccessEntity{
+		ID:         access.ID,
+		LinkID:     access.LinkID,
+		Action:     string(access.Action),
+		DocumentID: access.DocumentID,
+		Address:    access.Address,
+		UserAgent:  access.UserAgent,
+		AccessedAt: access.AccessedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO share_link_accesses (id, link_id, action, document_id, address, user_agent, accessed_at)
+		VALUES (:id, :link_id, :action, :document_id, :address, :user_agent, :accessed_at)
+	`, entity)
+	return err
+}
+
+// FindAllAccessesByLinkID implements archive.ShareLinkRepository.
+func (r *ShareLinkRepository) FindAllAccessesByLinkID(linkID string) ([]archive.ShareLinkAccess, error) {
+	var entities []ShareLinkAccessEntity
+	err := r.db.Select(&entities, "SELECT * FROM share_link_accesses WHERE link_id = ?", linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	accesses := make([]archive.ShareLinkAccess, len(entities))
+	for i, entity := range entities {
+		accesses[i] = entity.to()
+	}
+	return accesses, nil
+}
+
+func NewShareLinkRepository(db *sqlx.DB) *ShareLinkRepository {
+	return &ShareLinkRepository{db: db}
+}
@@ -0,0 +1,209 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"unterlagen/features/archive"
+	"unterlagen/platform/storage/encrypted"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.MailAccountRepository = &MailAccountRepository{}
+
+type MailAccountEntity struct {
+	ID               string       `db:"id"`
+	Name             string       `db:"name"`
+	Host             string       `db:"host"`
+	Port             int          `db:"port"`
+	Username         string       `db:"username"`
+	Password         string       `db:"password"`
+	TLS              bool         `db:"tls"`
+	Insecure         bool         `db:"insecure"`
+	Mailbox          string       `db:"mailbox"`
+	SenderFilter     string       `db:"sender_filter"`
+	SubjectFilter    string       `db:"subject_filter"`
+	FolderID         string       `db:"folder_id"`
+	ProcessedAction  string       `db:"processed_action"`
+	ProcessedMailbox string       `db:"processed_mailbox"`
+	Owner            string       `db:"owner"`
+	LastPolledAt     sql.NullTime `db:"last_polled_at"`
+	LastError        string       `db:"last_error"`
+}
+
+func (entity MailAccountEntity) to(keyring *encrypted.Keyring) (archive.MailAccount, error) {
+	password, err := encrypted.DecryptSecret(keyring, entity.Password)
+	if err != nil {
+		return archive.MailAccount{}, err
+	}
+
+	return archive.MailAccount{
+		ID:               entity.ID,
+		Name:             entity.Name,
+		Host:             entity.Host,
+		Port:             entity.Port,
+		Username:         entity.Username,
+		Password:         password,
+		TLS:              entity.TLS,
+		Insecure:         entity.Insecure,
+		Mailbox:          entity.Mailbox,
+		SenderFilter:     entity.SenderFilter,
+		SubjectFilter:    entity.SubjectFilter,
+		FolderID:         entity.FolderID,
+		ProcessedAction:  archive.MailProcessedAction(entity.ProcessedAction),
+		ProcessedMailbox: entity.ProcessedMailbox,
+		Owner:            entity.Owner,
+		LastPolledAt:     entity.LastPolledAt.Time,
+		LastError:        entity.LastError,
+	}, nil
+}
+
+// MailAccountRepository stores the passwords of mail accounts encrypted with the keyring of the document storage.
+// Without an encryption key they are stored in plaintext like documents, anybody reading the database can log in then.
+type MailAccountRepository struct {
+	db      *sqlx.DB
+	keyring *encrypted.Keyring
+}
+
+// Save implements archive.MailAccountRepository.
+func (r *MailAccountRepository) Save(account archive.MailAccount) error {
+	// Saving after every poll encrypts passwords stored before with the current key
+	password, err := encrypted.EncryptSecret(r.keyring, account.Password)
+	if err != nil {
+		return err
+	}
+
+	entity := MailAccountEntity{
+		ID:               account.ID,
+		Name:             account.Name,
+		Host:             account.Host,
+		Port:             account.Port,
+		Username:         account.Username,
+		Password:         password,
+		TLS:              account.TLS,
+		Insecure:         account.Insecure,
+		Mailbox:          account.Mailbox,
+		SenderFilter:     account.SenderFilter,
+		SubjectFilter:    account.SubjectFilter,
+		FolderID:         account.FolderID,
+		ProcessedAction:  string(account.ProcessedAction),
+		ProcessedMailbox: account.ProcessedMailbox,
+		Owner:            account.Owner,
+		LastPolledAt:     sql.NullTime{Time: account.LastPolledAt, Valid: !account.LastPolledAt.IsZero()},
+		LastError:        account.LastError,
+	}
+
+	_, err = r.db.NamedExec(`
+		INSERT INTO mail_accounts (id, name, host, port, username, password, tls, insecure, mailbox, sender_filter, subject_filter, folder_id, processed_action, processed_mailbox, owner, last_polled_at, last_error)
+		VALUES (:id, :name, :host, :port, :username, :password, :tls, :insecure, :mailbox, :sender_filter, :subject_filter, :folder_id, :processed_action, :processed_mailbox, :owner, :last_polled_at, :last_error)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			host = excluded.host,
+			port = excluded.port,
+			username = excluded.username,
+			password = excluded.password,
+			tls = excluded.tls,
+			insecure = excluded.insecure,
+			mailbox = excluded.mailbox,
+			sender_filter = excluded.sender_filter,
+			subject_filter = excluded.subject_filter,
+			folder_id = excluded.folder_id,
+			processed_action = excluded.processed_action,
+			processed_mailbox = excluded.processed_mailbox,
+			last_polled_at = excluded.last_polled_at,
+			last_error = excluded.last_error
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.MailAccountRepository.
+func (r *MailAccountRepository) FindByID(id string) (archive.MailAccount, error) {
+	var entity MailAccountEntity
+	err := r.db.Get(&entity, "SELECT * FROM mail_accounts WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.MailAccount{}, archive.ErrMailAccountNotFound
+	}
+	if err != nil {
+		return archive.MailAccount{}, err
+	}
+
+	return entity.to(r.keyring)
+}
+
+// FindAll implements archive.MailAccountRepository.
+func (r *MailAccountRepository) FindAll() ([]archive.MailAccount, error) {
+	var entities []MailAccountEntity
+	err := r.db.Select(&entities, "SELECT * FROM mail_accounts")
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]archive.MailAccount, len(entities))
+	for i, entity := range entities {
+		accounts[i], err = entity.to(r.keyring)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return accounts, nil
+}
+
+// FindAllByOwner implements archive.MailAccountRepository.
+func (r *MailAccountRepository) FindAllByOwner(owner string) ([]archive.MailAccount, error) {
+	var entities []MailAccountEntity
+	err := r.db.Select(&entities, "SELECT * FROM mail_accounts WHERE owner = ?", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]archive.MailAccount, len(entities))
+	for i, entity := range entities {
+		accounts[i], err = entity.to(r.keyring)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return accounts, nil
+}
+
+// DeleteByID implements archive.MailAccountRepository.
+func (r *MailAccountRepository) DeleteByID(id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM mail_seen_uids WHERE account_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM mail_accounts WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// FindSeenUIDs implements archive.MailAccountRepository.
+func (r *MailAccountRepository) FindSeenUIDs(accountID string, uidValidity uint32) ([]uint32, error) {
+	var uids []uint32
+	err := r.db.Select(&uids, "SELECT uid FROM mail_seen_uids WHERE account_id = ? AND uid_validity = ?", accountID, uidValidity)
+	return uids, err
+}
+
+// SaveSeenUID implements archive.MailAccountRepository.
+func (r *MailAccountRepository) SaveSeenUID(accountID string, uidValidity uint32, uid uint32) error {
+	_, err := r.db.Exec(`
+		INSERT INTO mail_seen_uids (account_id, uid_validity, uid)
+		VALUES (?, ?, ?)
+		ON CONFLICT (account_id, uid_validity, uid) DO NOTHING
+	`, accountID, uidValidity, uid)
+	return err
+}
+
+func NewMailAccountRepository(db *sqlx.DB, keyring *encrypted.Keyring) *MailAccountRepository {
+	return &MailAccountRepository{db: db, keyring: keyring}
+}
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Email headers and other document specific metadata
+ALTER TABLE documents ADD COLUMN metadata JSON DEFAULT '{}';
+
+-- Attachments of emails reference the email they were split off
+ALTER TABLE documents ADD COLUMN parent_id TEXT REFERENCES documents (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_documents_parent_id ON documents(parent_id);
+
+-- +goose Down
+DROP INDEX idx_documents_parent_id;
+
+ALTER TABLE documents DROP COLUMN parent_id;
+
+ALTER TABLE documents DROP COLUMN metadata;
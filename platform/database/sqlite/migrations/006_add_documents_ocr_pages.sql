@@ -0,0 +1,18 @@
+-- +goose Up
+-- Text recognized by OCR, one row per page of the document
+CREATE TABLE documents_ocr_pages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    document_id TEXT NOT NULL,
+    page_number INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    UNIQUE(document_id, page_number)
+);
+
+CREATE INDEX idx_documents_ocr_pages_document_id ON documents_ocr_pages(document_id);
+
+-- +goose Down
+DROP INDEX idx_documents_ocr_pages_document_id;
+
+DROP TABLE documents_ocr_pages;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- SHA-256 of the stored file, used to detect duplicate uploads per owner
+ALTER TABLE documents ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_documents_owner_content_hash ON documents(owner, content_hash);
+
+CREATE TABLE archive_preferences (
+    owner TEXT NOT NULL,
+    duplicate_policy TEXT NOT NULL,
+    PRIMARY KEY (owner),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE archive_preferences;
+
+DROP INDEX idx_documents_owner_content_hash;
+
+ALTER TABLE documents DROP COLUMN content_hash;
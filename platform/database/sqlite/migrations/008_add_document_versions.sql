@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE documents ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Files of a document that were replaced by a newer upload
+CREATE TABLE documents_versions (
+    document_id TEXT NOT NULL,
+    number INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    filetype TEXT NOT NULL,
+    filesize INTEGER NOT NULL,
+    content_hash TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (document_id, number),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE documents_versions;
+
+ALTER TABLE documents DROP COLUMN version;
@@ -0,0 +1,63 @@
+-- +goose Up
+CREATE TABLE tags (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (owner, name),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE TABLE documents_tags (
+    document_id TEXT NOT NULL,
+    tag_id TEXT NOT NULL,
+    PRIMARY KEY (document_id, tag_id),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_documents_tags_tag_id ON documents_tags(tag_id);
+
+-- Recreate FTS table with tag names for searching and tag ids for filtering
+DROP TABLE documents_fts;
+
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+    document_id UNINDEXED,
+    title,
+    filename,
+    text,
+    summary,
+    tags,
+    tag_ids,
+    owner UNINDEXED
+);
+
+-- Populate FTS table with existing documents (excluding trashed ones), there are no tags yet
+INSERT INTO documents_fts(document_id, title, filename, text, summary, tags, tag_ids, owner)
+SELECT id, title, filename, text, COALESCE(summary, '') as summary, '', '', owner
+FROM documents
+WHERE trashed_at IS NULL;
+
+-- +goose Down
+DROP TABLE documents_fts;
+
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+    document_id UNINDEXED,
+    title,
+    filename,
+    text,
+    summary,
+    owner UNINDEXED
+);
+
+INSERT INTO documents_fts(document_id, title, filename, text, summary, owner)
+SELECT id, title, filename, text, COALESCE(summary, '') as summary, owner
+FROM documents
+WHERE trashed_at IS NULL;
+
+DROP INDEX idx_documents_tags_tag_id;
+
+DROP TABLE documents_tags;
+
+DROP TABLE tags;
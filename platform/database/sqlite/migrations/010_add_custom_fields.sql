@@ -0,0 +1,86 @@
+-- +goose Up
+CREATE TABLE custom_fields (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    options JSON NOT NULL DEFAULT '[]',
+    owner TEXT NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (owner, name),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+-- Values are stored in their normalized text form, numbers are compared with CAST
+CREATE TABLE documents_custom_fields (
+    document_id TEXT NOT NULL,
+    field_id TEXT NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (document_id, field_id),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (field_id) REFERENCES custom_fields (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_documents_custom_fields_field_id ON documents_custom_fields(field_id, value);
+
+-- Recreate FTS table with custom field values
+DROP TABLE documents_fts;
+
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+    document_id UNINDEXED,
+    title,
+    filename,
+    text,
+    summary,
+    tags,
+    tag_ids,
+    fields,
+    owner UNINDEXED
+);
+
+-- Populate FTS table with existing documents (excluding trashed ones), tag ids are hex encoded like the search repository does
+INSERT INTO documents_fts(document_id, title, filename, text, summary, tags, tag_ids, fields, owner)
+SELECT
+    documents.id,
+    documents.title,
+    documents.filename,
+    documents.text,
+    COALESCE(documents.summary, '') as summary,
+    COALESCE((SELECT group_concat(tags.name, ' ') FROM documents_tags JOIN tags ON tags.id = documents_tags.tag_id WHERE documents_tags.document_id = documents.id), ''),
+    COALESCE((SELECT group_concat(lower(hex(documents_tags.tag_id)), ' ') FROM documents_tags WHERE documents_tags.document_id = documents.id), ''),
+    '',
+    documents.owner
+FROM documents
+WHERE documents.trashed_at IS NULL;
+
+-- +goose Down
+DROP TABLE documents_fts;
+
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+    document_id UNINDEXED,
+    title,
+    filename,
+    text,
+    summary,
+    tags,
+    tag_ids,
+    owner UNINDEXED
+);
+
+INSERT INTO documents_fts(document_id, title, filename, text, summary, tags, tag_ids, owner)
+SELECT
+    documents.id,
+    documents.title,
+    documents.filename,
+    documents.text,
+    COALESCE(documents.summary, '') as summary,
+    COALESCE((SELECT group_concat(tags.name, ' ') FROM documents_tags JOIN tags ON tags.id = documents_tags.tag_id WHERE documents_tags.document_id = documents.id), ''),
+    COALESCE((SELECT group_concat(lower(hex(documents_tags.tag_id)), ' ') FROM documents_tags WHERE documents_tags.document_id = documents.id), ''),
+    documents.owner
+FROM documents
+WHERE documents.trashed_at IS NULL;
+
+DROP INDEX idx_documents_custom_fields_field_id;
+
+DROP TABLE documents_custom_fields;
+
+DROP TABLE custom_fields;
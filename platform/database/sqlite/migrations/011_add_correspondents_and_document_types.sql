@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE correspondents (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (owner, name),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE TABLE document_types (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (owner, name),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+ALTER TABLE documents ADD COLUMN correspondent_id TEXT REFERENCES correspondents (id) ON DELETE SET NULL;
+ALTER TABLE documents ADD COLUMN document_type_id TEXT REFERENCES document_types (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_documents_correspondent_id ON documents(correspondent_id);
+CREATE INDEX idx_documents_document_type_id ON documents(document_type_id);
+
+-- +goose Down
+DROP INDEX idx_documents_document_type_id;
+DROP INDEX idx_documents_correspondent_id;
+
+ALTER TABLE documents DROP COLUMN document_type_id;
+ALTER TABLE documents DROP COLUMN correspondent_id;
+
+DROP TABLE document_types;
+
+DROP TABLE correspondents;
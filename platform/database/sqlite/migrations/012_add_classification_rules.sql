@@ -0,0 +1,23 @@
+-- +goose Up
+-- Actions reference tags, fields and folders loosely, missing ones are skipped when a rule is applied
+CREATE TABLE classification_rules (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    match_type TEXT NOT NULL,
+    pattern TEXT NOT NULL,
+    folder_id TEXT NOT NULL DEFAULT '',
+    tag_ids JSON NOT NULL DEFAULT '[]',
+    title_template TEXT NOT NULL DEFAULT '',
+    custom_fields JSON NOT NULL DEFAULT '{}',
+    owner TEXT NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_classification_rules_owner ON classification_rules(owner, position);
+
+-- +goose Down
+DROP INDEX idx_classification_rules_owner;
+
+DROP TABLE classification_rules;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE archive_preferences ADD COLUMN consume_directory TEXT NOT NULL DEFAULT '';
+ALTER TABLE archive_preferences ADD COLUMN consume_folder_id TEXT NOT NULL DEFAULT 'root';
+ALTER TABLE archive_preferences ADD COLUMN consume_action TEXT NOT NULL DEFAULT 'move';
+
+-- +goose Down
+ALTER TABLE archive_preferences DROP COLUMN consume_action;
+ALTER TABLE archive_preferences DROP COLUMN consume_folder_id;
+ALTER TABLE archive_preferences DROP COLUMN consume_directory;
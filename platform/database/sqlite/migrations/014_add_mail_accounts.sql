@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE mail_accounts (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    host TEXT NOT NULL,
+    port INTEGER NOT NULL,
+    username TEXT NOT NULL,
+    password TEXT NOT NULL,
+    tls BOOLEAN NOT NULL DEFAULT TRUE,
+    mailbox TEXT NOT NULL DEFAULT 'INBOX',
+    sender_filter TEXT NOT NULL DEFAULT '',
+    subject_filter TEXT NOT NULL DEFAULT '',
+    folder_id TEXT NOT NULL DEFAULT 'root',
+    processed_action TEXT NOT NULL DEFAULT 'seen',
+    processed_mailbox TEXT NOT NULL DEFAULT '',
+    owner TEXT NOT NULL,
+    last_polled_at TIMESTAMP,
+    last_error TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (id),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_mail_accounts_owner ON mail_accounts(owner);
+
+-- UIDs are only unique within a UID validity of a mailbox, a new validity starts over
+CREATE TABLE mail_seen_uids (
+    account_id TEXT NOT NULL,
+    uid_validity INTEGER NOT NULL,
+    uid INTEGER NOT NULL,
+    PRIMARY KEY (account_id, uid_validity, uid),
+    FOREIGN KEY (account_id) REFERENCES mail_accounts (id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE mail_seen_uids;
+
+DROP INDEX idx_mail_accounts_owner;
+
+DROP TABLE mail_accounts;
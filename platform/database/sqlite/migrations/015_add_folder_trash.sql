@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE folders ADD COLUMN trashed_at DATETIME;
+
+-- +goose Down
+ALTER TABLE folders DROP COLUMN trashed_at;
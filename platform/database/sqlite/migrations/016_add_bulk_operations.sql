@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE bulk_operations (
+    id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    document_ids TEXT NOT NULL DEFAULT '[]',
+    folder_id TEXT NOT NULL DEFAULT '',
+    tag_id TEXT NOT NULL DEFAULT '',
+    custom_field_id TEXT NOT NULL DEFAULT '',
+    custom_field_value TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    processed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    owner TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_bulk_operations_owner ON bulk_operations(owner);
+
+-- +goose Down
+DROP INDEX idx_bulk_operations_owner;
+
+DROP TABLE bulk_operations;
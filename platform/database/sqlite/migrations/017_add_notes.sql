@@ -0,0 +1,84 @@
+-- +goose Up
+-- Anchors are stored in fractions of the page size, page 0 means the note is not anchored to a page
+CREATE TABLE notes (
+    id TEXT NOT NULL,
+    document_id TEXT NOT NULL,
+    body TEXT NOT NULL,
+    author TEXT NOT NULL,
+    page INTEGER NOT NULL DEFAULT 0,
+    x REAL NOT NULL DEFAULT 0,
+    y REAL NOT NULL DEFAULT 0,
+    width REAL NOT NULL DEFAULT 0,
+    height REAL NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (author) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notes_document_id ON notes(document_id);
+
+-- Recreate FTS table with note bodies
+DROP TABLE documents_fts;
+
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+    document_id UNINDEXED,
+    title,
+    filename,
+    text,
+    summary,
+    tags,
+    tag_ids,
+    fields,
+    notes,
+    owner UNINDEXED
+);
+
+INSERT INTO documents_fts(document_id, title, filename, text, summary, tags, tag_ids, fields, notes, owner)
+SELECT
+    documents.id,
+    documents.title,
+    documents.filename,
+    documents.text,
+    COALESCE(documents.summary, '') as summary,
+    COALESCE((SELECT group_concat(tags.name, ' ') FROM documents_tags JOIN tags ON tags.id = documents_tags.tag_id WHERE documents_tags.document_id = documents.id), ''),
+    COALESCE((SELECT group_concat(lower(hex(documents_tags.tag_id)), ' ') FROM documents_tags WHERE documents_tags.document_id = documents.id), ''),
+    COALESCE((SELECT group_concat(documents_custom_fields.value, ' ') FROM documents_custom_fields WHERE documents_custom_fields.document_id = documents.id), ''),
+    '',
+    documents.owner
+FROM documents
+WHERE documents.trashed_at IS NULL;
+
+-- +goose Down
+DROP TABLE documents_fts;
+
+CREATE VIRTUAL TABLE documents_fts USING fts5(
+    document_id UNINDEXED,
+    title,
+    filename,
+    text,
+    summary,
+    tags,
+    tag_ids,
+    fields,
+    owner UNINDEXED
+);
+
+INSERT INTO documents_fts(document_id, title, filename, text, summary, tags, tag_ids, fields, owner)
+SELECT
+    documents.id,
+    documents.title,
+    documents.filename,
+    documents.text,
+    COALESCE(documents.summary, '') as summary,
+    COALESCE((SELECT group_concat(tags.name, ' ') FROM documents_tags JOIN tags ON tags.id = documents_tags.tag_id WHERE documents_tags.document_id = documents.id), ''),
+    COALESCE((SELECT group_concat(lower(hex(documents_tags.tag_id)), ' ') FROM documents_tags WHERE documents_tags.document_id = documents.id), ''),
+    COALESCE((SELECT group_concat(documents_custom_fields.value, ' ') FROM documents_custom_fields WHERE documents_custom_fields.document_id = documents.id), ''),
+    documents.owner
+FROM documents
+WHERE documents.trashed_at IS NULL;
+
+DROP INDEX idx_notes_document_id;
+
+DROP TABLE notes;
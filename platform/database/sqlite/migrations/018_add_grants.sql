@@ -0,0 +1,24 @@
+-- +goose Up
+-- A grant shares either a document or a folder with all of its subfolders and documents
+CREATE TABLE grants (
+    id TEXT NOT NULL,
+    document_id TEXT,
+    folder_id TEXT,
+    grantee TEXT NOT NULL,
+    permission TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (document_id, grantee),
+    UNIQUE (folder_id, grantee),
+    CHECK ((document_id IS NULL) != (folder_id IS NULL)),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE CASCADE,
+    FOREIGN KEY (grantee) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_grants_grantee ON grants(grantee);
+
+-- +goose Down
+DROP INDEX idx_grants_grantee;
+
+DROP TABLE grants;
@@ -0,0 +1,44 @@
+-- +goose Up
+CREATE TABLE share_links (
+    id TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    document_id TEXT,
+    folder_id TEXT,
+    owner TEXT NOT NULL,
+    password_hash TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMP,
+    max_downloads INTEGER NOT NULL DEFAULT 0,
+    downloads INTEGER NOT NULL DEFAULT 0,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    CHECK ((document_id IS NULL) != (folder_id IS NULL)),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE CASCADE,
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_share_links_owner ON share_links(owner);
+
+CREATE TABLE share_link_accesses (
+    id TEXT NOT NULL,
+    link_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    document_id TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    accessed_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (link_id) REFERENCES share_links (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_share_link_accesses_link_id ON share_link_accesses(link_id);
+
+-- +goose Down
+DROP INDEX idx_share_link_accesses_link_id;
+
+DROP TABLE share_link_accesses;
+
+DROP INDEX idx_share_links_owner;
+
+DROP TABLE share_links;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- Every group has an account in users with the same name, it owns the folders and documents of the group
+CREATE TABLE groups (
+    name TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (name),
+    FOREIGN KEY (name) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE TABLE group_members (
+    group_name TEXT NOT NULL,
+    member TEXT NOT NULL,
+    PRIMARY KEY (group_name, member),
+    FOREIGN KEY (group_name) REFERENCES groups (name) ON DELETE CASCADE,
+    FOREIGN KEY (member) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_members_member ON group_members(member);
+
+-- +goose Down
+DROP INDEX idx_group_members_member;
+
+DROP TABLE group_members;
+
+DROP TABLE groups;
@@ -0,0 +1,55 @@
+-- +goose Up
+-- Documents were stored without their upload date, retention periods need it to start at.
+-- The last update is the closest known date, documents never updated start now.
+UPDATE documents
+SET created_at = CASE WHEN updated_at LIKE '0001-01-01%' THEN datetime() ELSE updated_at END
+WHERE created_at LIKE '0001-01-01%';
+
+-- A retention policy applies to exactly one of a folder, a tag or a document type
+CREATE TABLE retention_policies (
+    id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    folder_id TEXT,
+    tag_id TEXT,
+    document_type_id TEXT,
+    keep_months INTEGER NOT NULL,
+    basis TEXT NOT NULL,
+    date_field_id TEXT,
+    action TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    CHECK ((folder_id IS NOT NULL) + (tag_id IS NOT NULL) + (document_type_id IS NOT NULL) = 1),
+    FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags (id) ON DELETE CASCADE,
+    FOREIGN KEY (document_type_id) REFERENCES document_types (id) ON DELETE CASCADE,
+    FOREIGN KEY (date_field_id) REFERENCES custom_fields (id) ON DELETE SET NULL,
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_retention_policies_owner ON retention_policies(owner);
+
+-- Notices of notifying policies are raised once per document, dismissed notices are kept so they are not raised again
+CREATE TABLE retention_notices (
+    policy_id TEXT NOT NULL,
+    document_id TEXT NOT NULL,
+    owner TEXT NOT NULL,
+    expired_at TIMESTAMP NOT NULL,
+    dismissed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (policy_id, document_id),
+    FOREIGN KEY (policy_id) REFERENCES retention_policies (id) ON DELETE CASCADE,
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (owner) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_retention_notices_owner ON retention_notices(owner);
+
+-- +goose Down
+DROP INDEX idx_retention_notices_owner;
+
+DROP TABLE retention_notices;
+
+DROP INDEX idx_retention_policies_owner;
+
+DROP TABLE retention_policies;
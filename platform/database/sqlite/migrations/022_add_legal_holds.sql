@@ -0,0 +1,24 @@
+-- +goose Up
+-- A legal hold freezes either a document or a folder with all of its subfolders and documents
+CREATE TABLE legal_holds (
+    id TEXT NOT NULL,
+    document_id TEXT,
+    folder_id TEXT,
+    reason TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (id),
+    CHECK ((document_id IS NULL) != (folder_id IS NULL)),
+    FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE CASCADE,
+    FOREIGN KEY (actor) REFERENCES users (username) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_legal_holds_document_id ON legal_holds(document_id);
+CREATE INDEX idx_legal_holds_folder_id ON legal_holds(folder_id);
+
+-- +goose Down
+DROP INDEX idx_legal_holds_folder_id;
+DROP INDEX idx_legal_holds_document_id;
+
+DROP TABLE legal_holds;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- -1 follows the trash retention of the configuration, 0 keeps trashed items until they are deleted by hand
+ALTER TABLE archive_preferences ADD COLUMN trash_retention_days INTEGER NOT NULL DEFAULT -1;
+
+-- +goose Down
+ALTER TABLE archive_preferences DROP COLUMN trash_retention_days;
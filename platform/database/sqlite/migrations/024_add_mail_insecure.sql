@@ -0,0 +1,7 @@
+-- +goose Up
+-- Existing accounts without TLS logged in without STARTTLS if the server did not offer it, they keep doing so
+ALTER TABLE mail_accounts ADD COLUMN insecure BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE mail_accounts SET insecure = TRUE WHERE tls = FALSE;
+
+-- +goose Down
+ALTER TABLE mail_accounts DROP COLUMN insecure;
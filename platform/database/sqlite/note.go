@@ -0,0 +1,118 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.NoteRepository = &NoteRepository{}
+
+type NoteEntity struct {
+	ID         string    `db:"id"`
+	DocumentID string    `db:"document_id"`
+	Body       string    `db:"body"`
+	Author     string    `db:"author"`
+	Page       int       `db:"page"`
+	X          float64   `db:"x"`
+	Y          float64   `db:"y"`
+	Width      float64   `db:"width"`
+	Height     float64   `db:"height"`
+	CreatedAt  time.Time `db:"created_at"`
+	UpdatedAt  time.Time `db:"updated_at"`
+}
+
+func (entity NoteEntity) to() archive.Note {
+	return archive.Note{
+		ID:         entity.ID,
+		DocumentID: entity.DocumentID,
+		Body:       entity.Body,
+		Author:     entity.Author,
+		Anchor: archive.NoteAnchor{
+			Page:   entity.Page,
+			X:      entity.X,
+			Y:      entity.Y,
+			Width:  entity.Width,
+			Height: entity.Height,
+		},
+		CreatedAt: entity.CreatedAt,
+		UpdatedAt: entity.UpdatedAt,
+	}
+}
+
+type NoteRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.NoteRepository.
+func (r *NoteRepository) Save(note archive.Note) error {
+	entity := NoteEntity{
+		ID:         note.ID,
+		DocumentID: note.DocumentID,
+		Body:       note.Body,
+		Author:     note.Author,
+		Page:       note.Anchor.Page,
+		X:          note.Anchor.X,
+		Y:          note.Anchor.Y,
+		Width:      note.Anchor.Width,
+		Height:     note.Anchor.Height,
+		CreatedAt:  note.CreatedAt,
+		UpdatedAt:  note.UpdatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO notes (id, document_id, body, author, page, x, y, width, height, created_at, updated_at)
+		VALUES (:id, :document_id, :body, :author, :page, :x, :y, :width, :height, :created_at, :updated_at)
+		ON CONFLICT (id) DO UPDATE SET
+			body = excluded.body,
+			page = excluded.page,
+			x = excluded.x,
+			y = excluded.y,
+			width = excluded.width,
+			height = excluded.height,
+			updated_at = excluded.updated_at
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.NoteRepository.
+func (r *NoteRepository) FindByID(id string) (archive.Note, error) {
+	var entity NoteEntity
+	err := r.db.Get(&entity, "SELECT * FROM notes WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.Note{}, archive.ErrNoteNotFound
+	}
+	if err != nil {
+		return archive.Note{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAllByDocumentID implements archive.NoteRepository.
+func (r *NoteRepository) FindAllByDocumentID(documentID string) ([]archive.Note, error) {
+	var entities []NoteEntity
+	err := r.db.Select(&entities, "SELECT * FROM notes WHERE document_id = ? ORDER BY created_at ASC", documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := make([]archive.Note, len(entities))
+	for i, entity := range entities {
+		notes[i] = entity.to()
+	}
+	return notes, nil
+}
+
+// DeleteByID implements archive.NoteRepository.
+func (r *NoteRepository) DeleteByID(id string) error {
+	_, err := r.db.Exec("DELETE FROM notes WHERE id = ?", id)
+	return err
+}
+
+func NewNoteRepository(db *sqlx.DB) *NoteRepository {
+	return &NoteRepository{db: db}
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.PreferencesRepository = &PreferencesRepository{}
+
+type PreferencesEntity struct {
+	Owner            string `db:"owner"`
+	DuplicatePolicy  string `db:"duplicate_policy"`
+	ConsumeDirectory string `db:"consume_directory"`
+	ConsumeFolderID  string `db:"consume_folder_id"`
+	ConsumeAction    string `db:"consume_action"`
+	TrashRetention   int    `db:"trash_retention_days"`
+}
+
+func (entity PreferencesEntity) to() archive.Preferences {
+	return archive.Preferences{
+		Owner:            entity.Owner,
+		DuplicatePolicy:  archive.DuplicatePolicy(entity.DuplicatePolicy),
+		ConsumeDirectory: entity.ConsumeDirectory,
+		ConsumeFolderID:  entity.ConsumeFolderID,
+		ConsumeAction:    archive.ConsumeAction(entity.ConsumeAction),
+		TrashRetention:   archive.TrashRetention(entity.TrashRetention),
+	}
+}
+
+type PreferencesRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.PreferencesRepository.
+func (p *PreferencesRepository) Save(preferences archive.Preferences) error {
+	entity := PreferencesEntity{
+		Owner:            preferences.Owner,
+		DuplicatePolicy:  string(preferences.DuplicatePolicy),
+		ConsumeDirectory: preferences.ConsumeDirectory,
+		ConsumeFolderID:  preferences.ConsumeFolderID,
+		ConsumeAction:    string(preferences.ConsumeAction),
+		TrashRetention:   int(preferences.TrashRetention),
+	}
+
+	_, err := p.db.NamedExec(`
+		INSERT INTO archive_preferences (owner, duplicate_policy, consume_directory, consume_folder_id, consume_action, trash_retention_days)
+		VALUES (:owner, :duplicate_policy, :consume_directory, :consume_folder_id, :consume_action, :trash_retention_days)
+		ON CONFLICT (owner) DO UPDATE SET
+			duplicate_policy = excluded.duplicate_policy,
+			consume_directory = excluded.consume_directory,
+			consume_folder_id = excluded.consume_folder_id,
+			consume_action = excluded.consume_action,
+			trash_retention_days = excluded.trash_retention_days
+	`, entity)
+	return err
+}
+
+// FindByOwner implements archive.PreferencesRepository.
+func (p *PreferencesRepository) FindByOwner(owner string) (archive.Preferences, error) {
+	var entity PreferencesEntity
+	err := p.db.Get(&entity, "SELECT * FROM archive_preferences WHERE owner = ?", owner)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.Preferences{}, archive.ErrPreferencesNotFound
+	}
+	if err != nil {
+		return archive.Preferences{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAll implements archive.PreferencesRepository.
+func (p *PreferencesRepository) FindAll() ([]archive.Preferences, error) {
+	var entities []PreferencesEntity
+	err := p.db.Select(&entities, "SELECT * FROM archive_preferences")
+	if err != nil {
+		return nil, err
+	}
+
+	preferences := make([]archive.Preferences, len(entities))
+	for i, entity := range entities {
+		preferences[i] = entity.to()
+	}
+	return preferences, nil
+}
+
+func NewPreferencesRepository(db *sqlx.DB) *PreferencesRepository {
+	return &PreferencesRepository{db: db}
+}
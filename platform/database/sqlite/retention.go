@@ -0,0 +1,183 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.RetentionPolicyRepository = &RetentionPolicyRepository{}
+
+type RetentionPolicyEntity struct {
+	ID             string         `db:"id"`
+	Name           string         `db:"name"`
+	FolderID       sql.NullString `db:"folder_id"`
+	TagID          sql.NullString `db:"tag_id"`
+	DocumentTypeID sql.NullString `db:"document_type_id"`
+	KeepMonths     int            `db:"keep_months"`
+	Basis          string         `db:"basis"`
+	DateFieldID    sql.NullString `db:"date_field_id"`
+	Action         string         `db:"action"`
+	Owner          string         `db:"owner"`
+	CreatedAt      time.Time      `db:"created_at"`
+}
+
+func (entity RetentionPolicyEntity) to() archive.RetentionPolicy {
+	return archive.RetentionPolicy{
+		ID:             entity.ID,
+		Name:           entity.Name,
+		FolderID:       entity.FolderID.String,
+		TagID:          entity.TagID.String,
+		DocumentTypeID: entity.DocumentTypeID.String,
+		KeepMonths:     entity.KeepMonths,
+		Basis:          archive.RetentionBasis(entity.Basis),
+		DateFieldID:    entity.DateFieldID.String,
+		Action:         archive.RetentionAction(entity.Action),
+		Owner:          entity.Owner,
+		CreatedAt:      entity.CreatedAt,
+	}
+}
+
+type RetentionNoticeEntity struct {
+	PolicyID    string       `db:"policy_id"`
+	DocumentID  string       `db:"document_id"`
+	Owner       string       `db:"owner"`
+	ExpiredAt   time.Time    `db:"expired_at"`
+	DismissedAt sql.NullTime `db:"dismissed_at"`
+	CreatedAt   time.Time    `db:"created_at"`
+}
+
+func (entity RetentionNoticeEntity) to() archive.RetentionNotice {
+	return archive.RetentionNotice{
+		PolicyID:    entity.PolicyID,
+		DocumentID:  entity.DocumentID,
+		Owner:       entity.Owner,
+		ExpiredAt:   entity.ExpiredAt,
+		DismissedAt: entity.DismissedAt,
+		CreatedAt:   entity.CreatedAt,
+	}
+}
+
+type RetentionPolicyRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) Save(policy archive.RetentionPolicy) error {
+	entity := RetentionPolicyEntity{
+		ID:             policy.ID,
+		Name:           policy.Name,
+		FolderID:       sql.NullString{String: policy.FolderID, Valid: policy.FolderID != ""},
+		TagID:          sql.NullString{String: policy.TagID, Valid: policy.TagID != ""},
+		DocumentTypeID: sql.NullString{String: policy.DocumentTypeID, Valid: policy.DocumentTypeID != ""},
+		KeepMonths:     policy.KeepMonths,
+		Basis:          string(policy.Basis),
+		DateFieldID:    sql.NullString{String: policy.DateFieldID, Valid: policy.DateFieldID != ""},
+		Action:         string(policy.Action),
+		Owner:          policy.Owner,
+		CreatedAt:      policy.CreatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO retention_policies (id, name, folder_id, tag_id, document_type_id, keep_months, basis, date_field_id, action, owner, created_at)
+		VALUES (:id, :name, :folder_id, :tag_id, :document_type_id, :keep_months, :basis, :date_field_id, :action, :owner, :created_at)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			keep_months = excluded.keep_months,
+			basis = excluded.basis,
+			date_field_id = excluded.date_field_id,
+			action = excluded.action
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) FindByID(id string) (archive.RetentionPolicy, error) {
+	var entity RetentionPolicyEntity
+	err := r.db.Get(&entity, "SELECT * FROM retention_policies WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.RetentionPolicy{}, archive.ErrRetentionPolicyNotFound
+	}
+	if err != nil {
+		return archive.RetentionPolicy{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAll implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) FindAll() ([]archive.RetentionPolicy, error) {
+	return r.findAll("SELECT * FROM retention_policies")
+}
+
+// FindAllByOwner implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) FindAllByOwner(owner string) ([]archive.RetentionPolicy, error) {
+	return r.findAll("SELECT * FROM retention_policies WHERE owner = ?", owner)
+}
+
+func (r *RetentionPolicyRepository) findAll(query string, args ...any) ([]archive.RetentionPolicy, error) {
+	var entities []RetentionPolicyEntity
+	err := r.db.Select(&entities, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]archive.RetentionPolicy, len(entities))
+	for i, entity := range entities {
+		policies[i] = entity.to()
+	}
+	return policies, nil
+}
+
+// DeleteByID implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) DeleteByID(id string) error {
+	_, err := r.db.Exec("DELETE FROM retention_policies WHERE id = ?", id)
+	return err
+}
+
+// SaveNotice implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) SaveNotice(notice archive.RetentionNotice) error {
+	entity := RetentionNoticeEntity{
+		PolicyID:    notice.PolicyID,
+		DocumentID:  notice.DocumentID,
+		Owner:       notice.Owner,
+		ExpiredAt:   notice.ExpiredAt,
+		DismissedAt: notice.DismissedAt,
+		CreatedAt:   notice.CreatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO retention_notices (policy_id, document_id, owner, expired_at, dismissed_at, created_at)
+		VALUES (:policy_id, :document_id, :owner, :expired_at, :dismissed_at, :created_at)
+		ON CONFLICT (policy_id, document_id) DO NOTHING
+	`, entity)
+	return err
+}
+
+// FindAllNoticesByOwner implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) FindAllNoticesByOwner(owner string) ([]archive.RetentionNotice, error) {
+	var entities []RetentionNoticeEntity
+	err := r.db.Select(&entities, "SELECT * FROM retention_notices WHERE owner = ? ORDER BY expired_at", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	notices := make([]archive.RetentionNotice, len(entities))
+	for i, entity := range entities {
+		notices[i] = entity.to()
+	}
+	return notices, nil
+}
+
+// DismissNotice implements archive.RetentionPolicyRepository.
+func (r *RetentionPolicyRepository) DismissNotice(policyID string, documentID string) error {
+	_, err := r.db.Exec("UPDATE retention_notices SET dismissed_at = ? WHERE policy_id = ? AND document_id = ?", time.Now(), policyID, documentID)
+	return err
+}
+
+func NewRetentionPolicyRepository(db *sqlx.DB) *RetentionPolicyRepository {
+	return &RetentionPolicyRepository{db: db}
+}
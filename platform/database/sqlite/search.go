@@ -1,8 +1,11 @@
 package sqlite
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 	"unterlagen/features/archive"
 	"unterlagen/features/search"
@@ -30,11 +33,30 @@ func (s *SearchRepository) IndexDocument(document archive.Document) error {
 	// Convert structured summary to searchable text
 	summaryText := s.summaryToText(document.Summary)
 
+	// Tag names are searchable, tag ids are only used for filtering
+	var tagNames, tagTokens []string
+	for _, tag := range document.Tags {
+		tagNames = append(tagNames, tag.Name)
+		tagTokens = append(tagTokens, s.tagToken(tag.ID))
+	}
+
+	// Custom field values like invoice numbers are searchable as text
+	var fieldValues []string
+	for _, value := range document.CustomFields {
+		fieldValues = append(fieldValues, value)
+	}
+
+	// Notes like "paid on 12.03." are searchable by their body
+	var noteBodies []string
+	for _, note := range document.Notes {
+		noteBodies = append(noteBodies, note.Body)
+	}
+
 	// Insert/update the document in FTS table
 	_, err = s.Exec(`
-		INSERT INTO documents_fts(document_id, title, filename, text, summary, owner)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, document.ID, document.Title, document.Filename, document.Text, summaryText, document.Owner)
+		INSERT INTO documents_fts(document_id, title, filename, text, summary, tags, tag_ids, fields, notes, owner)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, document.ID, document.Title, document.Filename, document.Text, summaryText, strings.Join(tagNames, " "), strings.Join(tagTokens, " "), strings.Join(fieldValues, " "), strings.Join(noteBodies, " "), document.Owner)
 	if err != nil {
 		return fmt.Errorf("failed to index document %s: %w", document.ID, err)
 	}
@@ -63,25 +85,66 @@ func (s *SearchRepository) summaryToText(summary archive.DocumentSummary) string
 }
 
 // SearchDocuments implements search.SearchRepository.
-func (s *SearchRepository) SearchDocuments(query string, owner string, limit int) ([]search.SearchResult, error) {
+func (s *SearchRepository) SearchDocuments(query string, filter search.Filter, owner string, sharedDocumentIDs []string, limit int) ([]search.SearchResult, error) {
 	// Simple approach: use FTS for text search and regular WHERE for owner filter
 	ftsQuery := s.buildFTSQuery(query)
+	for _, tagID := range filter.TagIDs {
+		tagFilter := fmt.Sprintf(`tag_ids : "%s"`, s.tagToken(tagID))
+		if ftsQuery == "" {
+			ftsQuery = tagFilter
+		} else {
+			ftsQuery = "(" + ftsQuery + ") AND " + tagFilter
+		}
+	}
 
+	sharedIDs, err := json.Marshal(sharedDocumentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a query or tags there is nothing to match, documents are only filtered by their fields
 	sqlQuery := `
 		SELECT
 			document_id,
 			COALESCE(title, filename) as name,
-			bm25(documents_fts) as rank,
-			snippet(documents_fts, 3, '<mark>', '</mark>', '...', 32) as snippet
+			0 as rank,
+			'' as snippet
 		FROM documents_fts
-		WHERE documents_fts MATCH ?
-		AND owner = ?
-		ORDER BY bm25(documents_fts)
-		LIMIT ?
+		WHERE (owner = ? OR document_id IN (SELECT value FROM json_each(?)))
 	`
+	args := []any{owner, string(sharedIDs)}
+	if ftsQuery != "" {
+		sqlQuery = `
+			SELECT
+				document_id,
+				COALESCE(title, filename) as name,
+				bm25(documents_fts) as rank,
+				snippet(documents_fts, 3, ?, ?, '...', 32) as snippet
+			FROM documents_fts
+			WHERE documents_fts MATCH ?
+			AND (owner = ? OR document_id IN (SELECT value FROM json_each(?)))
+		`
+		args = []any{search.SnippetMatchStart, search.SnippetMatchEnd, ftsQuery, owner, string(sharedIDs)}
+	}
+
+	// Field values are not part of the FTS table, they are filtered on the stored values
+	for _, field := range filter.Fields {
+		condition, conditionArgs, err := s.buildFieldCondition(field)
+		if err != nil {
+			return nil, err
+		}
+		sqlQuery += " AND " + condition
+		args = append(args, conditionArgs...)
+	}
+
+	if ftsQuery != "" {
+		sqlQuery += " ORDER BY bm25(documents_fts)"
+	}
+	sqlQuery += " LIMIT ?"
+	args = append(args, limit)
 
 	var results []search.SearchResult
-	err := s.Select(&results, sqlQuery, ftsQuery, owner, limit)
+	err = s.Select(&results, sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("search failed: %w", err)
 	}
@@ -89,6 +152,50 @@ func (s *SearchRepository) SearchDocuments(query string, owner string, limit int
 	return results, nil
 }
 
+// buildFieldCondition restricts the results to documents with a matching value of a custom field.
+// Numeric values are compared as numbers, all other values compare correctly as text.
+func (s *SearchRepository) buildFieldCondition(filter search.FieldFilter) (string, []any, error) {
+	condition := "document_id IN (SELECT document_id FROM documents_custom_fields WHERE field_id = ?"
+	args := []any{filter.Field.ID}
+
+	if filter.Value != "" {
+		condition += " AND value = ?"
+		args = append(args, filter.Value)
+	}
+
+	column := "value"
+	if filter.Field.Type.IsNumeric() {
+		column = "CAST(value AS REAL)"
+	}
+	for _, bound := range []struct {
+		operator string
+		value    string
+	}{{">=", filter.Min}, {"<=", filter.Max}} {
+		if bound.value == "" {
+			continue
+		}
+
+		var arg any = bound.value
+		if filter.Field.Type.IsNumeric() {
+			number, err := strconv.ParseFloat(bound.value, 64)
+			if err != nil {
+				return "", nil, err
+			}
+			arg = number
+		}
+		condition += fmt.Sprintf(" AND %s %s ?", column, bound.operator)
+		args = append(args, arg)
+	}
+
+	return condition + ")", args, nil
+}
+
+// tagToken encodes a tag id as a single FTS token. Generated ids may contain
+// separator characters like '-' that the tokenizer would split on.
+func (s *SearchRepository) tagToken(tagID string) string {
+	return hex.EncodeToString([]byte(tagID))
+}
+
 // buildFTSQuery converts a user query into a proper FTS5 query
 func (s *SearchRepository) buildFTSQuery(query string) string {
 	// Split query into terms and escape them
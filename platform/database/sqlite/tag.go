@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.TagRepository = &TagRepository{}
+
+type TagEntity struct {
+	ID    string `db:"id"`
+	Name  string `db:"name"`
+	Color string `db:"color"`
+	Owner string `db:"owner"`
+}
+
+func (entity TagEntity) to() archive.Tag {
+	return archive.Tag{
+		ID:    entity.ID,
+		Name:  entity.Name,
+		Color: entity.Color,
+		Owner: entity.Owner,
+	}
+}
+
+type TagRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.TagRepository.
+func (r *TagRepository) Save(tag archive.Tag) error {
+	entity := TagEntity{
+		ID:    tag.ID,
+		Name:  tag.Name,
+		Color: tag.Color,
+		Owner: tag.Owner,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO tags (id, name, color, owner)
+		VALUES (:id, :name, :color, :owner)
+		ON CONFLICT (id) DO UPDATE SET
+			name = excluded.name,
+			color = excluded.color
+	`, entity)
+	return err
+}
+
+// FindByID implements archive.TagRepository.
+func (r *TagRepository) FindByID(id string) (archive.Tag, error) {
+	var entity TagEntity
+	err := r.db.Get(&entity, "SELECT * FROM tags WHERE id = ?", id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.Tag{}, archive.ErrTagNotFound
+	}
+	if err != nil {
+		return archive.Tag{}, err
+	}
+
+	return entity.to(), nil
+}
+
+// FindAllByOwner implements archive.TagRepository.
+func (r *TagRepository) FindAllByOwner(owner string) ([]archive.Tag, error) {
+	var entities []TagEntity
+	err := r.db.Select(&entities, "SELECT * FROM tags WHERE owner = ?", owner)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]archive.Tag, len(entities))
+	for i, entity := range entities {
+		tags[i] = entity.to()
+	}
+	return tags, nil
+}
+
+// DeleteByID implements archive.TagRepository.
+func (r *TagRepository) DeleteByID(id string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM documents_tags WHERE tag_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec("DELETE FROM tags WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AssignToDocument implements archive.TagRepository.
+func (r *TagRepository) AssignToDocument(tagID string, documentID string) error {
+	_, err := r.db.Exec(`
+		INSERT INTO documents_tags (document_id, tag_id)
+		VALUES (?, ?)
+		ON CONFLICT (document_id, tag_id) DO NOTHING
+	`, documentID, tagID)
+	return err
+}
+
+// RemoveFromDocument implements archive.TagRepository.
+func (r *TagRepository) RemoveFromDocument(tagID string, documentID string) error {
+	_, err := r.db.Exec("DELETE FROM documents_tags WHERE document_id = ? AND tag_id = ?", documentID, tagID)
+	return err
+}
+
+func NewTagRepository(db *sqlx.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
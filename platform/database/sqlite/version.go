@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var _ archive.DocumentVersionRepository = &DocumentVersionRepository{}
+
+type DocumentVersionEntity struct {
+	DocumentID  string    `db:"document_id"`
+	Number      int       `db:"number"`
+	Filename    string    `db:"filename"`
+	Filetype    string    `db:"filetype"`
+	Filesize    uint64    `db:"filesize"`
+	ContentHash string    `db:"content_hash"`
+	Owner       string    `db:"owner"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+func (entity DocumentVersionEntity) to() archive.DocumentVersion {
+	return archive.DocumentVersion{
+		DocumentID:  entity.DocumentID,
+		Number:      entity.Number,
+		Filename:    entity.Filename,
+		Filetype:    archive.Filetype(entity.Filetype),
+		Filesize:    entity.Filesize,
+		ContentHash: entity.ContentHash,
+		Owner:       entity.Owner,
+		CreatedAt:   entity.CreatedAt,
+	}
+}
+
+type DocumentVersionRepository struct {
+	db *sqlx.DB
+}
+
+// Save implements archive.DocumentVersionRepository.
+func (r *DocumentVersionRepository) Save(version archive.DocumentVersion) error {
+	entity := DocumentVersionEntity{
+		DocumentID:  version.DocumentID,
+		Number:      version.Number,
+		Filename:    version.Filename,
+		Filetype:    string(version.Filetype),
+		Filesize:    version.Filesize,
+		ContentHash: version.ContentHash,
+		Owner:       version.Owner,
+		CreatedAt:   version.CreatedAt,
+	}
+
+	_, err := r.db.NamedExec(`
+		INSERT INTO documents_versions (document_id, number, filename, filetype, filesize, content_hash, owner, created_at)
+		VALUES (:document_id, :number, :filename, :filetype, :filesize, :content_hash, :owner, :created_at)
+		ON CONFLICT (document_id, number) DO UPDATE SET
+			filename = excluded.filename,
+			filetype = excluded.filetype,
+			filesize = excluded.filesize,
+			content_hash = excluded.content_hash
+	`, entity)
+	return err
+}
+
+// FindAllByDocumentID implements archive.DocumentVersionRepository.
+func (r *DocumentVersionRepository) FindAllByDocumentID(documentID string) ([]archive.DocumentVersion, error) {
+	var entities []DocumentVersionEntity
+	err := r.db.Select(&entities, "SELECT * FROM documents_versions WHERE document_id = ? ORDER BY number DESC", documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []archive.DocumentVersion
+	for _, entity := range entities {
+		versions = append(versions, entity.to())
+	}
+	return versions, nil
+}
+
+// FindByDocumentIDAndNumber implements archive.DocumentVersionRepository.
+func (r *DocumentVersionRepository) FindByDocumentIDAndNumber(documentID string, number int) (archive.DocumentVersion, error) {
+	var entity DocumentVersionEntity
+	err := r.db.Get(&entity, "SELECT * FROM documents_versions WHERE document_id = ? AND number = ?", documentID, number)
+	if errors.Is(err, sql.ErrNoRows) {
+		return archive.DocumentVersion{}, archive.ErrVersionNotFound
+	}
+	if err != nil {
+		return archive.DocumentVersion{}, err
+	}
+
+	return entity.to(), nil
+}
+
+func NewDocumentVersionRepository(db *sqlx.DB) *DocumentVersionRepository {
+	return &DocumentVersionRepository{db: db}
+}
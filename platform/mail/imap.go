@@ -0,0 +1,178 @@
+package mail
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+	"unterlagen/features/archive"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/emersion/go-imap/commands"
+)
+
+var _ archive.MailClient = &IMAPClient{}
+
+var ErrNoStartTLS = errors.New("server does not offer STARTTLS")
+
+// IMAPClient connects to IMAP servers, connections without implicit TLS are upgraded with STARTTLS.
+// Only insecure accounts log in without TLS if the server does not offer STARTTLS.
+type IMAPClient struct {
+	timeout time.Duration
+}
+
+// Open implements archive.MailClient.
+func (c *IMAPClient) Open(account archive.MailAccount) (archive.MailSession, error) {
+	address := net.JoinHostPort(account.Host, strconv.Itoa(account.Port))
+	tlsConfig := &tls.Config{ServerName: account.Host}
+
+	var imapClient *client.Client
+	var err error
+	if account.TLS {
+		imapClient, err = client.DialWithDialerTLS(&net.Dialer{Timeout: c.timeout}, address, tlsConfig)
+	} else {
+		imapClient, err = client.DialWithDialer(&net.Dialer{Timeout: c.timeout}, address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+	imapClient.Timeout = c.timeout
+
+	session, err := c.open(imapClient, account, tlsConfig)
+	if err != nil {
+		imapClient.Logout()
+		return nil, err
+	}
+	return session, nil
+}
+
+func (c *IMAPClient) open(imapClient *client.Client, account archive.MailAccount, tlsConfig *tls.Config) (*IMAPSession, error) {
+	if !account.TLS {
+		supported, err := imapClient.SupportStartTLS()
+		if err != nil {
+			return nil, err
+		}
+		if supported {
+			err := imapClient.StartTLS(tlsConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to start TLS: %w", err)
+			}
+		} else if !account.Insecure {
+			return nil, ErrNoStartTLS
+		}
+	}
+
+	err := imapClient.Login(account.Username, account.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+
+	status, err := imapClient.Select(account.Mailbox, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select %s: %w", account.Mailbox, err)
+	}
+
+	return &IMAPSession{client: imapClient, uidValidity: status.UidValidity}, nil
+}
+
+var _ archive.MailSession = &IMAPSession{}
+
+type IMAPSession struct {
+	client      *client.Client
+	uidValidity uint32
+}
+
+// UIDValidity implements archive.MailSession.
+func (s *IMAPSession) UIDValidity() uint32 {
+	return s.uidValidity
+}
+
+// UIDs implements archive.MailSession.
+func (s *IMAPSession) UIDs() ([]uint32, error) {
+	return s.client.UidSearch(imap.NewSearchCriteria())
+}
+
+// Fetch implements archive.MailSession.
+func (s *IMAPSession) Fetch(uid uint32) ([]byte, error) {
+	// BODY.PEEK leaves the seen flag alone, messages are only flagged once they were processed
+	section := &imap.BodySectionName{Peek: true}
+	messages := make(chan *imap.Message, 1)
+	err := s.client.UidFetch(uidSet(uid), []imap.FetchItem{section.FetchItem()}, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	message, ok := <-messages
+	if !ok || message == nil {
+		return nil, fmt.Errorf("message %d not found", uid)
+	}
+	body := message.GetBody(section)
+	if body == nil {
+		return nil, fmt.Errorf("message %d has no body", uid)
+	}
+	return io.ReadAll(body)
+}
+
+// MarkSeen implements archive.MailSession.
+func (s *IMAPSession) MarkSeen(uid uint32) error {
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	return s.client.UidStore(uidSet(uid), item, []any{imap.SeenFlag}, nil)
+}
+
+// Move implements archive.MailSession.
+func (s *IMAPSession) Move(uid uint32, mailbox string) error {
+	supported, err := s.client.Support("MOVE")
+	if err != nil {
+		return err
+	}
+	if supported {
+		err = s.client.UidMove(uidSet(uid), mailbox)
+		if err == nil {
+			return nil
+		}
+		slog.Debug("IMAP MOVE failed, falling back to copy and expunge", "error", err.Error())
+	}
+
+	// Servers without a working MOVE need the message copied, flagged as deleted and expunged
+	err = s.client.UidCopy(uidSet(uid), mailbox)
+	if err != nil {
+		return err
+	}
+	item := imap.FormatFlagsOp(imap.AddFlags, true)
+	err = s.client.UidStore(uidSet(uid), item, []any{imap.DeletedFlag}, nil)
+	if err != nil {
+		return err
+	}
+
+	// A plain EXPUNGE would also remove messages the user flagged as deleted, only UID EXPUNGE removes just this one.
+	// Without it the message stays flagged until the mail client of the user expunges the mailbox.
+	supported, err = s.client.Support("UIDPLUS")
+	if err != nil || !supported {
+		return err
+	}
+	status, err := s.client.Execute(&commands.Uid{Cmd: &imap.Command{Name: "EXPUNGE", Arguments: []any{uidSet(uid)}}}, nil)
+	if err != nil {
+		return err
+	}
+	return status.Err()
+}
+
+// Close implements archive.MailSession.
+func (s *IMAPSession) Close() error {
+	return s.client.Logout()
+}
+
+func uidSet(uid uint32) *imap.SeqSet {
+	set := new(imap.SeqSet)
+	set.AddNum(uid)
+	return set
+}
+
+func NewIMAPClient() *IMAPClient {
+	return &IMAPClient{timeout: 30 * time.Second}
+}
@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"unterlagen/features/archive"
+	"unterlagen/platform/mail/imaptest"
+
+	"github.com/emersion/go-imap"
+)
+
+const message = "From: billing@example.org\r\n" +
+	"To: me@example.org\r\n" +
+	"Subject: Invoice\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Your invoice"
+
+func TestIMAPSession(t *testing.T) {
+	server, err := imaptest.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	for range 3 {
+		if err := server.Deliver("INBOX", []byte(message)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	session, err := NewIMAPClient().Open(archive.MailAccount{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: imaptest.Username,
+		Password: imaptest.Password,
+		Insecure: true,
+		Mailbox:  "INBOX",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	uids, err := session.UIDs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uids) != 3 {
+		t.Fatalf("expected 3 messages, got %v", uids)
+	}
+
+	data, err := session.Fetch(uids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "Subject: Invoice") {
+		t.Errorf("unexpected message %q", data)
+	}
+
+	inbox, err := server.Mailbox("INBOX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(inbox.Messages[0].Flags, imap.SeenFlag) {
+		t.Error("fetching must not flag the message as seen")
+	}
+
+	if err := session.MarkSeen(uids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(inbox.Messages[0].Flags, imap.SeenFlag) {
+		t.Error("expected the message to be flagged as seen")
+	}
+
+	if _, err := server.Mailbox("Processed"); err != nil {
+		t.Fatal(err)
+	}
+	// Messages the user flagged as deleted must stay until the user expunges them
+	inbox.Messages[2].Flags = append(inbox.Messages[2].Flags, imap.DeletedFlag)
+	if err := session.Move(uids[1], "Processed"); err != nil {
+		t.Fatal(err)
+	}
+	processed, err := server.Mailbox("Processed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inbox.Messages) != 2 || len(processed.Messages) != 1 {
+		t.Errorf("expected the message to be moved, inbox has %d and processed %d messages", len(inbox.Messages), len(processed.Messages))
+	}
+	if len(inbox.Messages) == 2 && inbox.Messages[1].Uid != uids[2] {
+		t.Error("expected the message flagged by the user to stay")
+	}
+}
+
+func TestIMAPClientRequiresTLS(t *testing.T) {
+	server, err := imaptest.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	_, err = NewIMAPClient().Open(archive.MailAccount{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: imaptest.Username,
+		Password: imaptest.Password,
+		Mailbox:  "INBOX",
+	})
+	if !errors.Is(err, ErrNoStartTLS) {
+		t.Errorf("expected logging in without TLS to fail, got %v", err)
+	}
+}
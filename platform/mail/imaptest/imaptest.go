@@ -0,0 +1,146 @@
+// Package imaptest runs an in-process IMAP server to test mail polling against.
+package imaptest
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend/memory"
+	"github.com/emersion/go-imap/server"
+)
+
+const (
+	Username = "username"
+	Password = "password"
+)
+
+// Server is an IMAP server without TLS and MOVE that supports UIDPLUS, its user starts with an empty INBOX.
+type Server struct {
+	Host    string
+	Port    int
+	backend *memory.Backend
+	server  *server.Server
+}
+
+func Start() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	backend := memory.New()
+	inbox, err := mailbox(backend, "INBOX")
+	if err != nil {
+		return nil, err
+	}
+	inbox.Messages = nil
+
+	imapServer := server.New(backend)
+	imapServer.AllowInsecureAuth = true
+	imapServer.Enable(uidPlus{})
+	go imapServer.Serve(listener)
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		return nil, err
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{Host: host, Port: portNumber, backend: backend, server: imapServer}, nil
+}
+
+// Deliver appends a raw RFC 5322 message to a mailbox, the mailbox is created if needed.
+func (s *Server) Deliver(mailboxName string, message []byte) error {
+	box, err := s.Mailbox(mailboxName)
+	if err != nil {
+		return err
+	}
+	return box.CreateMessage(nil, time.Now(), bytes.NewReader(message))
+}
+
+// Mailbox returns a mailbox to inspect its messages and their flags, the mailbox is created if needed.
+func (s *Server) Mailbox(name string) (*memory.Mailbox, error) {
+	return mailbox(s.backend, name)
+}
+
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+func mailbox(backend *memory.Backend, name string) (*memory.Mailbox, error) {
+	user, err := backend.Login(nil, Username, Password)
+	if err != nil {
+		return nil, err
+	}
+
+	box, err := user.GetMailbox(name)
+	if err != nil {
+		err = user.CreateMailbox(name)
+		if err != nil {
+			return nil, err
+		}
+		box, err = user.GetMailbox(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return box.(*memory.Mailbox), nil
+}
+
+// uidPlus adds UID EXPUNGE, which the memory backend does not know.
+type uidPlus struct{}
+
+func (uidPlus) Capabilities(server.Conn) []string {
+	return []string{"UIDPLUS"}
+}
+
+func (uidPlus) Command(name string) server.HandlerFactory {
+	if name != "EXPUNGE" {
+		return nil
+	}
+	return func() server.Handler {
+		return &uidExpunge{}
+	}
+}
+
+type uidExpunge struct {
+	server.Expunge
+	uids *imap.SeqSet
+}
+
+func (cmd *uidExpunge) Parse(fields []any) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	set, err := imap.ParseString(fields[0])
+	if err != nil {
+		return err
+	}
+	cmd.uids, err = imap.ParseSeqSet(set)
+	return err
+}
+
+// UidHandle expunges the messages flagged as deleted whose UIDs are in the set.
+func (cmd *uidExpunge) UidHandle(conn server.Conn) error {
+	box, ok := conn.Context().Mailbox.(*memory.Mailbox)
+	if !ok {
+		return errors.New("no mailbox selected")
+	}
+	if cmd.uids == nil {
+		return errors.New("missing UIDs")
+	}
+
+	box.Messages = slices.DeleteFunc(box.Messages, func(message *memory.Message) bool {
+		return cmd.uids.Contains(message.Uid) && slices.Contains(message.Flags, imap.DeletedFlag)
+	})
+	return nil
+}
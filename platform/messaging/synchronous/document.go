@@ -11,6 +11,7 @@ type DocumentMessages struct {
 	documentTextExtractedSubscribers []func(document archive.Document) error
 	documentUploadedSubscribers      []func(document archive.Document) error
 	documentDeletedSubscribers       []func(document archive.Document) error
+	documentMovedSubscribers         []func(document archive.Document, previousFolderID string) error
 }
 
 func (d *DocumentMessages) PublishDocumentTextExtracted(document archive.Document) error {
@@ -33,6 +34,16 @@ func (d *DocumentMessages) PublishDocumentDeleted(document archive.Document) err
 	return nil
 }
 
+func (d *DocumentMessages) PublishDocumentMoved(document archive.Document, previousFolderID string) error {
+	for _, subscriber := range d.documentMovedSubscribers {
+		err := subscriber(document, previousFolderID)
+		if err != nil {
+			slog.Error("failed to process document moved event", slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
+
 func (d *DocumentMessages) PublishDocumentUpserted(document archive.Document) error {
 	for _, subscriber := range d.documentUploadedSubscribers {
 		err := subscriber(document)
@@ -58,10 +69,16 @@ func (d *DocumentMessages) SubscribeDocumentUpserted(subscriber func(document ar
 	return nil
 }
 
+func (d *DocumentMessages) SubscribeDocumentMoved(subscriber func(document archive.Document, previousFolderID string) error) error {
+	d.documentMovedSubscribers = append(d.documentMovedSubscribers, subscriber)
+	return nil
+}
+
 func NewDocumentMessages() *DocumentMessages {
 	return &DocumentMessages{
 		documentTextExtractedSubscribers: []func(document archive.Document) error{},
 		documentUploadedSubscribers:      []func(document archive.Document) error{},
 		documentDeletedSubscribers:       []func(document archive.Document) error{},
+		documentMovedSubscribers:         []func(document archive.Document, previousFolderID string) error{},
 	}
 }
@@ -0,0 +1,33 @@
+package synchronous
+
+import (
+	"log/slog"
+	"unterlagen/features/administration"
+)
+
+var _ administration.GroupMessages = &GroupMessages{}
+
+type GroupMessages struct {
+	groupCreatedSubscribers []func(group administration.Group) error
+}
+
+func (g *GroupMessages) PublishGroupCreated(group administration.Group) error {
+	for _, subscriber := range g.groupCreatedSubscribers {
+		err := subscriber(group)
+		if err != nil {
+			slog.Error("failed to process group created event", slog.String("error", err.Error()))
+		}
+	}
+	return nil
+}
+
+func (g *GroupMessages) SubscribeGroupCreated(subscriber func(group administration.Group) error) error {
+	g.groupCreatedSubscribers = append(g.groupCreatedSubscribers, subscriber)
+	return nil
+}
+
+func NewGroupMessages() *GroupMessages {
+	return &GroupMessages{
+		groupCreatedSubscribers: []func(group administration.Group) error{},
+	}
+}
@@ -0,0 +1,51 @@
+package ocr
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"unterlagen/features/archive"
+	"unterlagen/platform/configuration"
+)
+
+var _ archive.OCREngine = &CommandEngine{}
+
+// CommandEngine runs an external OCR binary with a tesseract compatible command line:
+// the image is passed via stdin and the recognized text is read from stdout.
+type CommandEngine struct {
+	command   string
+	languages string
+}
+
+// RecognizeText implements archive.OCREngine.
+func (e *CommandEngine) RecognizeText(image io.Reader) (string, error) {
+	args := []string{"stdin", "stdout"}
+	if e.languages != "" {
+		args = append(args, "-l", e.languages)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(e.command, args...)
+	cmd.Stdin = image
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s failed: %w: %s", e.command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func NewCommandEngine(config configuration.Configuration) *CommandEngine {
+	command, err := exec.LookPath(config.OCR.Command)
+	if err != nil {
+		panic(fmt.Errorf("ocr command %q not found: %w", config.OCR.Command, err))
+	}
+
+	return &CommandEngine{
+		command:   command,
+		languages: config.OCR.Languages,
+	}
+}
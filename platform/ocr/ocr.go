@@ -0,0 +1,18 @@
+package ocr
+
+import (
+	"log/slog"
+	"unterlagen/features/archive"
+	"unterlagen/platform/configuration"
+)
+
+// GetEngine returns nil when OCR is disabled, documents without text then stay without text.
+func GetEngine(config configuration.Configuration) archive.OCREngine {
+	if config.OCR.Provider == configuration.OCRCommand {
+		slog.Info("Command chosen as OCR engine", "command", config.OCR.Command)
+		return NewCommandEngine(config)
+	}
+
+	slog.Info("OCR disabled")
+	return nil
+}
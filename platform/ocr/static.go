@@ -0,0 +1,23 @@
+package ocr
+
+import (
+	"io"
+	"unterlagen/features/archive"
+)
+
+var _ archive.OCREngine = &StaticEngine{}
+
+// StaticEngine recognizes the same text on every page and stands in for a real engine in tests
+type StaticEngine struct {
+	text string
+}
+
+// RecognizeText implements archive.OCREngine.
+func (e *StaticEngine) RecognizeText(image io.Reader) (string, error) {
+	_, err := io.Copy(io.Discard, image)
+	return e.text, err
+}
+
+func NewStaticEngine(text string) *StaticEngine {
+	return &StaticEngine{text: text}
+}
@@ -0,0 +1,106 @@
+package encrypted
+
+import (
+	"bufio"
+	"io"
+	"unterlagen/features/archive"
+)
+
+var _ archive.DocumentStorage = &DocumentStorage{}
+var _ archive.DocumentPreviewStorage = &DocumentPreviewStorage{}
+
+// DocumentStorage encrypts documents before they reach the storage it wraps and decrypts them on retrieval,
+// so consumers like the PDF analyzer never see encrypted content.
+type DocumentStorage struct {
+	storage archive.DocumentStorage
+	keyring *Keyring
+}
+
+// Retrieve implements archive.DocumentStorage.
+func (storage *DocumentStorage) Retrieve(filepath string, consumer archive.DocumentConsumer) error {
+	return storage.storage.Retrieve(filepath, decrypting(storage.keyring, consumer))
+}
+
+// Store implements archive.DocumentStorage.
+func (storage *DocumentStorage) Store(filepath string, r io.Reader) error {
+	encrypted, err := newEncryptingReader(r, storage.keyring)
+	if err != nil {
+		return err
+	}
+	return storage.storage.Store(filepath, encrypted)
+}
+
+// Delete implements archive.DocumentStorage.
+func (storage *DocumentStorage) Delete(filepath string) error {
+	return storage.storage.Delete(filepath)
+}
+
+// Size implements archive.DocumentStorage and returns the size of the decrypted document.
+func (storage *DocumentStorage) Size(filepath string) (int64, error) {
+	encrypted := false
+	err := storage.storage.Retrieve(filepath, func(r io.Reader) error {
+		encrypted = isEncrypted(bufio.NewReaderSize(r, len(magic)))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := storage.storage.Size(filepath)
+	if err != nil || !encrypted {
+		// Files stored before encryption was turned on are in plain
+		return size, err
+	}
+	return plaintextSize(size), nil
+}
+
+// DocumentPreviewStorage encrypts previews the same way as DocumentStorage does documents.
+type DocumentPreviewStorage struct {
+	storage archive.DocumentPreviewStorage
+	keyring *Keyring
+}
+
+// Retrieve implements archive.DocumentPreviewStorage.
+func (storage *DocumentPreviewStorage) Retrieve(filepath string, consumer func(r io.Reader) error) error {
+	return storage.storage.Retrieve(filepath, decrypting(storage.keyring, consumer))
+}
+
+// Store implements archive.DocumentPreviewStorage.
+func (storage *DocumentPreviewStorage) Store(filepath string, r io.Reader) error {
+	encrypted, err := newEncryptingReader(r, storage.keyring)
+	if err != nil {
+		return err
+	}
+	return storage.storage.Store(filepath, encrypted)
+}
+
+// Delete implements archive.DocumentPreviewStorage.
+func (storage *DocumentPreviewStorage) Delete(filepath string) error {
+	return storage.storage.Delete(filepath)
+}
+
+func decrypting(keyring *Keyring, consumer func(r io.Reader) error) func(r io.Reader) error {
+	return func(r io.Reader) error {
+		decrypted, err := newDecryptingReader(r, keyring)
+		if err != nil {
+			return err
+		}
+		return consumer(decrypted)
+	}
+}
+
+// NewDocumentStorage encrypts the documents of the storage when an encryption key is configured.
+func NewDocumentStorage(storage archive.DocumentStorage, keyring *Keyring) archive.DocumentStorage {
+	if keyring == nil {
+		return storage
+	}
+	return &DocumentStorage{storage: storage, keyring: keyring}
+}
+
+// NewDocumentPreviewStorage encrypts the previews of the storage when an encryption key is configured.
+func NewDocumentPreviewStorage(storage archive.DocumentPreviewStorage, keyring *Keyring) archive.DocumentPreviewStorage {
+	if keyring == nil {
+		return storage
+	}
+	return &DocumentPreviewStorage{storage: storage, keyring: keyring}
+}
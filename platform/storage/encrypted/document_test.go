@@ -0,0 +1,98 @@
+package encrypted
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
+	"testing"
+	"unterlagen/features/archive"
+)
+
+// memoryStorage keeps files in memory, like the storages the encryption wraps keep them on disk or in buckets.
+type memoryStorage struct {
+	files map[string][]byte
+}
+
+func newMemoryStorage() *memoryStorage {
+	return &memoryStorage{files: make(map[string][]byte)}
+}
+
+func (storage *memoryStorage) Retrieve(filepath string, consumer archive.DocumentConsumer) error {
+	data, ok := storage.files[filepath]
+	if !ok {
+		return fs.ErrNotExist
+	}
+	return consumer(bytes.NewReader(data))
+}
+
+func (storage *memoryStorage) Store(filepath string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	storage.files[filepath] = data
+	return nil
+}
+
+func (storage *memoryStorage) Delete(filepath string) error {
+	delete(storage.files, filepath)
+	return nil
+}
+
+func (storage *memoryStorage) Size(filepath string) (int64, error) {
+	data, ok := storage.files[filepath]
+	if !ok {
+		return 0, fs.ErrNotExist
+	}
+	return int64(len(data)), nil
+}
+
+func (storage *memoryStorage) Walk(visit func(filepath string, r io.Reader) error) error {
+	for _, filepath := range slices.Sorted(maps.Keys(storage.files)) {
+		if err := visit(filepath, bytes.NewReader(storage.files[filepath])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func retrieve(t *testing.T, storage archive.DocumentStorage, filepath string) []byte {
+	var data []byte
+	err := storage.Retrieve(filepath, func(r io.Reader) error {
+		var err error
+		data, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDocumentStorage(t *testing.T) {
+	inner := newMemoryStorage()
+	storage := NewDocumentStorage(inner, newTestKeyring(t, newKey(t)))
+
+	if err := storage.Store("owner/document/invoice.pdf", bytes.NewReader([]byte("invoice"))); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(inner.files["owner/document/invoice.pdf"], []byte("invoice")) {
+		t.Error("expected the document to be stored encrypted")
+	}
+	if data := retrieve(t, storage, "owner/document/invoice.pdf"); string(data) != "invoice" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	inner.files["owner/legacy/scan.pdf"] = []byte("stored before encryption")
+	for filepath, expected := range map[string]int64{"owner/document/invoice.pdf": 7, "owner/legacy/scan.pdf": 24} {
+		size, err := storage.Size(filepath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != expected {
+			t.Errorf("expected %s to have %d bytes, got %d", filepath, expected, size)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"unterlagen/platform/configuration"
+)
+
+var ErrUnknownKey = errors.New("file was encrypted with an unknown master key")
+
+const (
+	keySize   = 32
+	keyIDSize = 8
+)
+
+type masterKey struct {
+	id   [keyIDSize]byte
+	aead cipher.AEAD
+}
+
+// Keyring holds the master key new files are encrypted with and previous master keys that still decrypt
+// files until the key rotation wrapped their data keys with the current one.
+type Keyring struct {
+	current  masterKey
+	previous []masterKey
+}
+
+func (keyring *Keyring) find(id [keyIDSize]byte) (masterKey, error) {
+	if keyring.current.id == id {
+		return keyring.current, nil
+	}
+	for _, key := range keyring.previous {
+		if key.id == id {
+			return key, nil
+		}
+	}
+	return masterKey{}, ErrUnknownKey
+}
+
+// NewKeyring decodes base64 encoded master keys of 32 bytes.
+func NewKeyring(current string, previous []string) (*Keyring, error) {
+	currentKey, err := newMasterKey(current)
+	if err != nil {
+		return nil, err
+	}
+
+	keyring := &Keyring{current: currentKey}
+	for _, encoded := range previous {
+		key, err := newMasterKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		keyring.previous = append(keyring.previous, key)
+	}
+	return keyring, nil
+}
+
+func newMasterKey(encoded string) (masterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return masterKey{}, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if len(key) != keySize {
+		return masterKey{}, fmt.Errorf("invalid encryption key: expected %d bytes, got %d", keySize, len(key))
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return masterKey{}, err
+	}
+
+	// The id tells which master key wrapped the data key of a file without revealing the key
+	sum := sha256.Sum256(key)
+	var id [keyIDSize]byte
+	copy(id[:], sum[:keyIDSize])
+	return masterKey{id: id, aead: aead}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GetKeyring returns nil when no encryption key is configured, files are stored unencrypted then.
+func GetKeyring(config configuration.Configuration) *Keyring {
+	if config.Storage.EncryptionKey == "" {
+		return nil
+	}
+
+	keyring, err := NewKeyring(config.Storage.EncryptionKey, config.Storage.PreviousEncryptionKeys)
+	if err != nil {
+		panic(err)
+	}
+	return keyring
+}
@@ -0,0 +1,98 @@
+package encrypted
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// WalkableStorage lists all of its files, which lets the key rotation reach files no document refers to anymore.
+type WalkableStorage interface {
+	Walk(visit func(filepath string, r io.Reader) error) error
+	Store(filepath string, r io.Reader) error
+}
+
+// Rotate wraps the data keys of all files encrypted with a previous master key with the current one
+// and encrypts files stored before encryption was turned on. The content of encrypted files is not touched,
+// so files are rotated without decrypting them. It returns the number of rewritten files.
+func Rotate(keyring *Keyring, storages ...WalkableStorage) (int, error) {
+	rotated := 0
+	for _, storage := range storages {
+		err := storage.Walk(func(filepath string, r io.Reader) error {
+			ok, err := rotate(keyring, storage, filepath, r)
+			if err != nil {
+				return err
+			}
+			if ok {
+				slog.Info("rotated file", "path", filepath)
+				rotated++
+			}
+			return nil
+		})
+		if err != nil {
+			return rotated, err
+		}
+	}
+	return rotated, nil
+}
+
+func rotate(keyring *Keyring, storage WalkableStorage, filepath string, r io.Reader) (bool, error) {
+	buffered := bufio.NewReaderSize(r, headerSize)
+
+	var rotated io.Reader
+	if isEncrypted(buffered) {
+		data, err := buffered.Peek(headerSize)
+		if err != nil {
+			return false, ErrCorrupted
+		}
+
+		h := parseHeader(data)
+		if h.keyID == keyring.current.id {
+			return false, nil
+		}
+
+		dataKey, err := unwrapKey(keyring, h)
+		if err != nil {
+			return false, err
+		}
+
+		h, err = wrapKey(keyring.current, dataKey)
+		if err != nil {
+			return false, err
+		}
+
+		_, err = buffered.Discard(headerSize)
+		if err != nil {
+			return false, err
+		}
+		rotated = io.MultiReader(bytes.NewReader(h.bytes()), buffered)
+	} else {
+		encrypted, err := newEncryptingReader(buffered, keyring)
+		if err != nil {
+			return false, err
+		}
+		rotated = encrypted
+	}
+
+	// The file is written to a temporary file first as the storage replaces the file that is still being read
+	temp, err := os.CreateTemp("", "unterlagen-rotate-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	_, err = io.Copy(temp, rotated)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = temp.Seek(0, io.SeekStart)
+	if err != nil {
+		return false, err
+	}
+
+	return true, storage.Store(filepath, temp)
+}
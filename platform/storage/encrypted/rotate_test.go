@@ -0,0 +1,74 @@
+package encrypted
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	previous, current := newKey(t), newKey(t)
+	documents, previews := newMemoryStorage(), newMemoryStorage()
+
+	// Files of a legacy plaintext archive, one encrypted with the previous key and one already with the current key
+	documents.files["owner/legacy/scan.pdf"] = []byte("stored before encryption")
+	previews.files["owner/legacy/previews/page0.jpeg"] = []byte{}
+	if err := NewDocumentStorage(documents, newTestKeyring(t, previous)).Store("owner/old/invoice.pdf", bytes.NewReader([]byte("invoice"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewDocumentStorage(documents, newTestKeyring(t, current)).Store("owner/new/receipt.pdf", bytes.NewReader([]byte("receipt"))); err != nil {
+		t.Fatal(err)
+	}
+	unchanged := bytes.Clone(documents.files["owner/new/receipt.pdf"])
+
+	rotated, err := Rotate(newTestKeyring(t, current, previous), documents, previews)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated != 3 {
+		t.Errorf("expected 3 rotated files, got %d", rotated)
+	}
+	if !bytes.Equal(documents.files["owner/new/receipt.pdf"], unchanged) {
+		t.Error("expected files of the current key to stay as they are")
+	}
+
+	// The previous key is not needed anymore after the rotation
+	keyring := newTestKeyring(t, current)
+	for filepath, expected := range map[string]string{
+		"owner/legacy/scan.pdf": "stored before encryption",
+		"owner/old/invoice.pdf": "invoice",
+		"owner/new/receipt.pdf": "receipt",
+	} {
+		if !bytes.HasPrefix(documents.files[filepath], []byte(magic)) {
+			t.Errorf("expected %s to be encrypted", filepath)
+		}
+		if data := retrieve(t, NewDocumentStorage(documents, keyring), filepath); string(data) != expected {
+			t.Errorf("expected %s to contain %q, got %q", filepath, expected, data)
+		}
+	}
+	if data := retrieve(t, NewDocumentStorage(previews, keyring), "owner/legacy/previews/page0.jpeg"); len(data) != 0 {
+		t.Errorf("expected the empty preview to stay empty, got %q", data)
+	}
+
+	rotated, err = Rotate(keyring, documents, previews)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated != 0 {
+		t.Errorf("expected nothing to rotate again, got %d files", rotated)
+	}
+}
+
+func TestRotateUnknownKey(t *testing.T) {
+	documents := newMemoryStorage()
+	if err := NewDocumentStorage(documents, newTestKeyring(t, newKey(t))).Store("owner/document/invoice.pdf", bytes.NewReader([]byte("invoice"))); err != nil {
+		t.Fatal(err)
+	}
+	stored := bytes.Clone(documents.files["owner/document/invoice.pdf"])
+
+	if _, err := Rotate(newTestKeyring(t, newKey(t)), documents); err == nil {
+		t.Error("expected files of an unknown key to fail the rotation")
+	}
+	if !bytes.Equal(documents.files["owner/document/invoice.pdf"], stored) {
+		t.Error("expected the file of an unknown key to stay as it is")
+	}
+}
@@ -0,0 +1,55 @@
+package encrypted
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"strings"
+)
+
+// secretPrefix marks secrets encrypted by EncryptSecret, other secrets were stored before encryption was turned on.
+const secretPrefix = "encrypted:"
+
+// EncryptSecret encrypts a short secret like a password to store it in the database. Without a keyring the secret
+// is returned as it is, like files are stored unencrypted then.
+func EncryptSecret(keyring *Keyring, secret string) (string, error) {
+	if keyring == nil || secret == "" {
+		return secret, nil
+	}
+
+	reader, err := newEncryptingReader(strings.NewReader(secret), keyring)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptSecret returns the plaintext of a secret of EncryptSecret, secrets stored before encryption was turned on
+// are returned as they are.
+func DecryptSecret(keyring *Keyring, secret string) (string, error) {
+	encoded, ok := strings.CutPrefix(secret, secretPrefix)
+	if !ok {
+		return secret, nil
+	}
+	if keyring == nil {
+		return "", ErrUnknownKey
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCorrupted
+	}
+	reader, err := newDecryptingReader(bytes.NewReader(data), keyring)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
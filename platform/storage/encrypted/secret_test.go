@@ -0,0 +1,83 @@
+package encrypted
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	previous := newKey(t)
+	keyring := newTestKeyring(t, newKey(t), previous)
+
+	secret, err := EncryptSecret(keyring, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, secretPrefix) || strings.Contains(secret, "password") {
+		t.Errorf("expected the secret to be encrypted, got %q", secret)
+	}
+	other, err := EncryptSecret(keyring, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Error("expected every encryption to use another data key")
+	}
+
+	plaintext, err := DecryptSecret(keyring, secret)
+	if err != nil || plaintext != "password" {
+		t.Errorf("expected the password, got %q: %v", plaintext, err)
+	}
+
+	// Secrets of a previous key stay readable
+	old, err := EncryptSecret(newTestKeyring(t, previous), "old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err = DecryptSecret(keyring, old)
+	if err != nil || plaintext != "old" {
+		t.Errorf("expected the secret of the previous key, got %q: %v", plaintext, err)
+	}
+}
+
+func TestLegacySecret(t *testing.T) {
+	keyring := newTestKeyring(t, newKey(t))
+
+	plaintext, err := DecryptSecret(keyring, "password")
+	if err != nil || plaintext != "password" {
+		t.Errorf("expected the secret stored before encryption, got %q: %v", plaintext, err)
+	}
+
+	// Without a key secrets are kept as they are
+	secret, err := EncryptSecret(nil, "password")
+	if err != nil || secret != "password" {
+		t.Errorf("expected the secret to be kept without a key, got %q: %v", secret, err)
+	}
+	empty, err := EncryptSecret(keyring, "")
+	if err != nil || empty != "" {
+		t.Errorf("expected an empty secret to stay empty, got %q: %v", empty, err)
+	}
+}
+
+func TestSecretWithoutKey(t *testing.T) {
+	keyring := newTestKeyring(t, newKey(t))
+	secret, err := EncryptSecret(keyring, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := DecryptSecret(nil, secret); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected no key to fail, got %v", err)
+	}
+	if _, err := DecryptSecret(newTestKeyring(t, newKey(t)), secret); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected an unknown key to fail, got %v", err)
+	}
+	if _, err := DecryptSecret(keyring, secretPrefix+"not base64"); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("expected a damaged secret to fail, got %v", err)
+	}
+	tampered := secret[:len(secret)-4] + "AAA="
+	if _, err := DecryptSecret(keyring, tampered); !errors.Is(err, ErrCorrupted) {
+		t.Error("expected a tampered secret to fail")
+	}
+}
@@ -0,0 +1,224 @@
+package encrypted
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var ErrCorrupted = errors.New("encrypted file is corrupted")
+
+// An encrypted file starts with a header holding the data key of the file wrapped by a master key:
+//
+//	magic (8) | master key id (8) | nonce (12) | wrapped data key (32 + 16)
+//
+// The content follows in chunks of chunkSize bytes, each sealed on its own with the data key so files are
+// encrypted and decrypted while streaming. The nonce of a chunk is its number and a flag marking the final chunk,
+// which is always shorter than chunkSize, so reordered, dropped or truncated chunks fail to open.
+const (
+	chunkSize  = 64 * 1024
+	nonceSize  = 12
+	tagSize    = 16
+	headerSize = len(magic) + keyIDSize + nonceSize + keySize + tagSize
+)
+
+const magic = "\x00UNTENC\x01"
+
+type header struct {
+	keyID      [keyIDSize]byte
+	nonce      [nonceSize]byte
+	wrappedKey [keySize + tagSize]byte
+}
+
+func (h header) bytes() []byte {
+	data := make([]byte, 0, headerSize)
+	data = append(data, magic...)
+	data = append(data, h.keyID[:]...)
+	data = append(data, h.nonce[:]...)
+	return append(data, h.wrappedKey[:]...)
+}
+
+// additionalData binds the wrapped data key to the master key it was wrapped with.
+func (h header) additionalData() []byte {
+	return append([]byte(magic), h.keyID[:]...)
+}
+
+func parseHeader(data []byte) header {
+	var h header
+	data = data[len(magic):]
+	copy(h.keyID[:], data[:keyIDSize])
+	copy(h.nonce[:], data[keyIDSize:keyIDSize+nonceSize])
+	copy(h.wrappedKey[:], data[keyIDSize+nonceSize:])
+	return h
+}
+
+func wrapKey(key masterKey, dataKey []byte) (header, error) {
+	h := header{keyID: key.id}
+	if _, err := rand.Read(h.nonce[:]); err != nil {
+		return header{}, err
+	}
+	copy(h.wrappedKey[:], key.aead.Seal(nil, h.nonce[:], dataKey, h.additionalData()))
+	return h, nil
+}
+
+func unwrapKey(keyring *Keyring, h header) ([]byte, error) {
+	key, err := keyring.find(h.keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := key.aead.Open(nil, h.nonce[:], h.wrappedKey[:], h.additionalData())
+	if err != nil {
+		return nil, ErrCorrupted
+	}
+	return dataKey, nil
+}
+
+func chunkNonce(number uint64, final bool) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce, number)
+	if final {
+		nonce[nonceSize-1] = 1
+	}
+	return nonce
+}
+
+// encryptingReader reads the plaintext from source and returns the encrypted file.
+type encryptingReader struct {
+	source  io.Reader
+	aead    cipher.AEAD
+	pending bytes.Buffer
+	chunk   []byte
+	number  uint64
+	done    bool
+}
+
+func newEncryptingReader(source io.Reader, keyring *Keyring) (*encryptingReader, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	h, err := wrapKey(keyring.current, dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &encryptingReader{
+		source: source,
+		aead:   aead,
+		chunk:  make([]byte, chunkSize),
+	}
+	reader.pending.Write(h.bytes())
+	return reader, nil
+}
+
+func (reader *encryptingReader) Read(p []byte) (int, error) {
+	for reader.pending.Len() == 0 {
+		if reader.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(reader.source, reader.chunk)
+		final := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !final {
+			return 0, err
+		}
+
+		reader.pending.Write(reader.aead.Seal(nil, chunkNonce(reader.number, final), reader.chunk[:n], nil))
+		reader.number++
+		reader.done = final
+	}
+
+	return reader.pending.Read(p)
+}
+
+// decryptingReader reads an encrypted file from source and returns the plaintext.
+type decryptingReader struct {
+	source  io.Reader
+	aead    cipher.AEAD
+	pending bytes.Buffer
+	chunk   []byte
+	number  uint64
+	done    bool
+}
+
+// newDecryptingReader returns the plaintext of source. Files stored before encryption was turned on
+// have no header, they are returned as they are.
+func newDecryptingReader(source io.Reader, keyring *Keyring) (io.Reader, error) {
+	buffered := bufio.NewReaderSize(source, headerSize)
+	if !isEncrypted(buffered) {
+		return buffered, nil
+	}
+
+	data := make([]byte, headerSize)
+	if _, err := io.ReadFull(buffered, data); err != nil {
+		return nil, ErrCorrupted
+	}
+
+	dataKey, err := unwrapKey(keyring, parseHeader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptingReader{
+		source: buffered,
+		aead:   aead,
+		chunk:  make([]byte, chunkSize+tagSize),
+	}, nil
+}
+
+func isEncrypted(reader *bufio.Reader) bool {
+	prefix, _ := reader.Peek(len(magic))
+	return string(prefix) == magic
+}
+
+func (reader *decryptingReader) Read(p []byte) (int, error) {
+	for reader.pending.Len() == 0 {
+		if reader.done {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(reader.source, reader.chunk)
+		if errors.Is(err, io.EOF) {
+			// The final chunk is missing
+			return 0, ErrCorrupted
+		}
+		final := errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !final {
+			return 0, err
+		}
+
+		plaintext, err := reader.aead.Open(nil, chunkNonce(reader.number, final), reader.chunk[:n], nil)
+		if err != nil {
+			return 0, ErrCorrupted
+		}
+
+		reader.pending.Write(plaintext)
+		reader.number++
+		reader.done = final
+	}
+
+	return reader.pending.Read(p)
+}
+
+// plaintextSize returns the size of the content of an encrypted file of the given size.
+func plaintextSize(size int64) int64 {
+	content := size - int64(headerSize)
+	chunks := (content + chunkSize + tagSize - 1) / (chunkSize + tagSize)
+	return content - chunks*tagSize
+}
@@ -0,0 +1,154 @@
+package encrypted
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+)
+
+func newKey(t *testing.T) string {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func newTestKeyring(t *testing.T, current string, previous ...string) *Keyring {
+	keyring, err := NewKeyring(current, previous)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func encrypt(t *testing.T, keyring *Keyring, plaintext []byte) []byte {
+	reader, err := newEncryptingReader(bytes.NewReader(plaintext), keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func decrypt(keyring *Keyring, data []byte) ([]byte, error) {
+	reader, err := newDecryptingReader(bytes.NewReader(data), keyring)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestRoundTrip(t *testing.T) {
+	keyring := newTestKeyring(t, newKey(t))
+
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+
+		data := encrypt(t, keyring, plaintext)
+		if !bytes.HasPrefix(data, []byte(magic)) {
+			t.Errorf("expected %d bytes to be stored with a header", size)
+		}
+		// Short plaintexts occur in any ciphertext by chance
+		if size >= 16 && bytes.Contains(data, plaintext) {
+			t.Errorf("expected %d bytes to be encrypted", size)
+		}
+		if got := plaintextSize(int64(len(data))); got != int64(size) {
+			t.Errorf("expected a plaintext size of %d, got %d", size, got)
+		}
+
+		decrypted, err := decrypt(keyring, data)
+		if err != nil {
+			t.Fatalf("failed to decrypt %d bytes: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("content of %d bytes differs after decryption", size)
+		}
+	}
+}
+
+func TestLegacyPlaintext(t *testing.T) {
+	keyring := newTestKeyring(t, newKey(t))
+
+	for _, plaintext := range [][]byte{{}, []byte("%PDF-1.7 stored before encryption")} {
+		decrypted, err := decrypt(keyring, plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("expected %q to be returned as it is, got %q", plaintext, decrypted)
+		}
+	}
+}
+
+func TestCorruptedChunks(t *testing.T) {
+	keyring := newTestKeyring(t, newKey(t))
+	plaintext := make([]byte, 2*chunkSize+100)
+	rand.Read(plaintext)
+	data := encrypt(t, keyring, plaintext)
+
+	sealedChunk := chunkSize + tagSize
+	first := data[headerSize : headerSize+sealedChunk]
+	second := data[headerSize+sealedChunk : headerSize+2*sealedChunk]
+	final := data[headerSize+2*sealedChunk:]
+
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{data[:headerSize]}, parts...), nil)
+	}
+	tampered := bytes.Clone(data)
+	tampered[headerSize+10] ^= 1
+	tamperedKey := bytes.Clone(data)
+	tamperedKey[headerSize-1] ^= 1
+
+	cases := map[string][]byte{
+		"truncated chunk":      data[:len(data)-1],
+		"missing final chunk":  join(first, second),
+		"dropped chunk":        join(first, final),
+		"reordered chunks":     join(second, first, final),
+		"tampered chunk":       tampered,
+		"tampered wrapped key": tamperedKey,
+		"truncated header":     data[:headerSize-1],
+		"trailing data":        append(bytes.Clone(data), 0),
+	}
+	for name, corrupted := range cases {
+		if _, err := decrypt(keyring, corrupted); !errors.Is(err, ErrCorrupted) {
+			t.Errorf("%s: expected ErrCorrupted, got %v", name, err)
+		}
+	}
+}
+
+func TestUnknownKey(t *testing.T) {
+	data := encrypt(t, newTestKeyring(t, newKey(t)), []byte("invoice"))
+
+	if _, err := decrypt(newTestKeyring(t, newKey(t)), data); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestPreviousKey(t *testing.T) {
+	previous := newKey(t)
+	data := encrypt(t, newTestKeyring(t, previous), []byte("invoice"))
+
+	decrypted, err := decrypt(newTestKeyring(t, newKey(t), previous), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decrypted) != "invoice" {
+		t.Errorf("unexpected content %q", decrypted)
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	for _, key := range []string{"", "not base64", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := NewKeyring(key, nil); err == nil {
+			t.Errorf("expected %q to be rejected", key)
+		}
+	}
+}
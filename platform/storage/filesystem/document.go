@@ -2,6 +2,7 @@ package filesystem
 
 import (
 	"io"
+	"os"
 	"path/filepath"
 	fp "path/filepath"
 	"unterlagen/features/archive"
@@ -76,6 +77,11 @@ func (storage *DocumentStorage) Size(filepath string) (int64, error) {
 	return fileInfo.Size(), nil
 }
 
+// Walk visits all stored documents.
+func (storage *DocumentStorage) Walk(visit func(filepath string, r io.Reader) error) error {
+	return walk(storage.fs, visit)
+}
+
 func NewDocumentStorage(configuration configuration.Configuration) *DocumentStorage {
 	var fs afero.Fs
 	if configuration.Production {
@@ -148,6 +154,11 @@ func (storage *DocumentPreviewStorage) Store(filepath string, r io.Reader) error
 	return nil
 }
 
+// Walk visits all stored previews.
+func (storage *DocumentPreviewStorage) Walk(visit func(filepath string, r io.Reader) error) error {
+	return walk(storage.fs, visit)
+}
+
 func NewDocumentPreviewStorage(configuration configuration.Configuration) *DocumentPreviewStorage {
 	var fs afero.Fs
 	if configuration.Production {
@@ -163,3 +174,18 @@ func NewDocumentPreviewStorage(configuration configuration.Configuration) *Docum
 		fs: fs,
 	}
 }
+
+func walk(fs afero.Fs, visit func(filepath string, r io.Reader) error) error {
+	return afero.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		file, err := fs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return visit(path, file)
+	})
+}
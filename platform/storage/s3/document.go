@@ -0,0 +1,290 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"path"
+	"strings"
+	"unterlagen/features/archive"
+	"unterlagen/platform/configuration"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+var _ archive.DocumentStorage = &DocumentStorage{}
+var _ archive.DocumentPreviewStorage = &DocumentPreviewStorage{}
+
+// minPartSize is the smallest part S3 accepts in a multipart upload, only the last part may be smaller.
+const minPartSize = 5 * 1024 * 1024
+
+type DocumentStorage struct {
+	bucket *bucket
+}
+
+// Retrieve implements archive.DocumentStorage.
+func (storage *DocumentStorage) Retrieve(filepath string, consumer archive.DocumentConsumer) error {
+	return storage.bucket.retrieve(filepath, consumer)
+}
+
+// Store implements archive.DocumentStorage.
+func (storage *DocumentStorage) Store(filepath string, r io.Reader) error {
+	return storage.bucket.store(filepath, r)
+}
+
+// Delete implements archive.DocumentStorage.
+func (storage *DocumentStorage) Delete(filepath string) error {
+	return storage.bucket.delete(filepath)
+}
+
+// Size implements archive.DocumentStorage.
+func (storage *DocumentStorage) Size(filepath string) (int64, error) {
+	return storage.bucket.size(filepath)
+}
+
+// Walk visits all stored documents.
+func (storage *DocumentStorage) Walk(visit func(filepath string, r io.Reader) error) error {
+	return storage.bucket.walk(visit)
+}
+
+func NewDocumentStorage(configuration configuration.Configuration) *DocumentStorage {
+	return &DocumentStorage{
+		bucket: newBucket(configuration.Storage.S3),
+	}
+}
+
+type DocumentPreviewStorage struct {
+	bucket *bucket
+}
+
+// Delete implements archive.DocumentPreviewStorage.
+func (storage *DocumentPreviewStorage) Delete(preview string) error {
+	return storage.bucket.delete(preview)
+}
+
+// Retrieve implements archive.DocumentPreviewStorage.
+func (storage *DocumentPreviewStorage) Retrieve(filepath string, consumer func(r io.Reader) error) error {
+	return storage.bucket.retrieve(filepath, consumer)
+}
+
+// Store implements archive.DocumentPreviewStorage.
+func (storage *DocumentPreviewStorage) Store(filepath string, r io.Reader) error {
+	return storage.bucket.store(filepath, r)
+}
+
+// Walk visits all stored previews.
+func (storage *DocumentPreviewStorage) Walk(visit func(filepath string, r io.Reader) error) error {
+	return storage.bucket.walk(visit)
+}
+
+func NewDocumentPreviewStorage(configuration configuration.Configuration) *DocumentPreviewStorage {
+	return &DocumentPreviewStorage{
+		bucket: newBucket(configuration.Storage.S3),
+	}
+}
+
+// bucket keeps documents and previews under the same prefix, just like the filesystem keeps them in the same directory.
+type bucket struct {
+	client   *awss3.Client
+	name     string
+	prefix   string
+	partSize int64
+}
+
+func (b *bucket) key(filepath string) string {
+	return path.Join(b.prefix, strings.TrimPrefix(filepath, "/"))
+}
+
+func (b *bucket) retrieve(filepath string, consumer func(r io.Reader) error) error {
+	key := b.key(filepath)
+	object, err := b.client.GetObject(context.Background(), &awss3.GetObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return notExist(key, err)
+	}
+	defer object.Body.Close()
+	return consumer(object.Body)
+}
+
+// store uploads files up to the part size at once, larger files are uploaded in parts so only one part is held in memory.
+// The first part grows with the file, small files like previews do not take up a whole part.
+func (b *bucket) store(filepath string, r io.Reader) error {
+	key := b.key(filepath)
+	var first bytes.Buffer
+	n, err := first.ReadFrom(io.LimitReader(r, b.partSize))
+	if err != nil {
+		return err
+	}
+	if n < b.partSize {
+		_, err = b.client.PutObject(context.Background(), &awss3.PutObjectInput{
+			Bucket:        aws.String(b.name),
+			Key:           aws.String(key),
+			Body:          bytes.NewReader(first.Bytes()),
+			ContentLength: aws.Int64(n),
+		})
+		return err
+	}
+	part := first.Bytes()
+
+	upload, err := b.client.CreateMultipartUpload(context.Background(), &awss3.CreateMultipartUploadInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+
+	parts, err := b.uploadParts(key, upload.UploadId, part, r)
+	if err != nil {
+		_, abortErr := b.client.AbortMultipartUpload(context.Background(), &awss3.AbortMultipartUploadInput{
+			Bucket:   aws.String(b.name),
+			Key:      aws.String(key),
+			UploadId: upload.UploadId,
+		})
+		if abortErr != nil {
+			slog.Error("failed to abort multipart upload", "key", key, "error", abortErr)
+		}
+		return err
+	}
+
+	_, err = b.client.CompleteMultipartUpload(context.Background(), &awss3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(b.name),
+		Key:             aws.String(key),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	return err
+}
+
+// uploadParts uploads the first part that was already read and the rest of the reader.
+func (b *bucket) uploadParts(key string, uploadID *string, first []byte, r io.Reader) ([]types.CompletedPart, error) {
+	var parts []types.CompletedPart
+	part := first
+	for number := int32(1); len(part) > 0; number++ {
+		uploaded, err := b.client.UploadPart(context.Background(), &awss3.UploadPartInput{
+			Bucket:        aws.String(b.name),
+			Key:           aws.String(key),
+			UploadId:      uploadID,
+			PartNumber:    aws.Int32(number),
+			Body:          bytes.NewReader(part),
+			ContentLength: aws.Int64(int64(len(part))),
+		})
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, types.CompletedPart{ETag: uploaded.ETag, PartNumber: aws.Int32(number)})
+
+		n, err := io.ReadFull(r, first)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+		part = first[:n]
+	}
+	return parts, nil
+}
+
+func (b *bucket) delete(filepath string) error {
+	_, err := b.client.DeleteObject(context.Background(), &awss3.DeleteObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(b.key(filepath)),
+	})
+	return err
+}
+
+func (b *bucket) size(filepath string) (int64, error) {
+	key := b.key(filepath)
+	head, err := b.client.HeadObject(context.Background(), &awss3.HeadObjectInput{
+		Bucket: aws.String(b.name),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return 0, notExist(key, err)
+	}
+	return aws.ToInt64(head.ContentLength), nil
+}
+
+func (b *bucket) walk(visit func(filepath string, r io.Reader) error) error {
+	prefix := ""
+	if b.prefix != "" {
+		prefix = b.prefix + "/"
+	}
+
+	pages := awss3.NewListObjectsV2Paginator(b.client, &awss3.ListObjectsV2Input{
+		Bucket: aws.String(b.name),
+		Prefix: aws.String(prefix),
+	})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(context.Background())
+		if err != nil {
+			return err
+		}
+
+		for _, object := range page.Contents {
+			filepath := strings.TrimPrefix(aws.ToString(object.Key), prefix)
+			err := b.retrieve(filepath, func(r io.Reader) error {
+				return visit(filepath, r)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// notExist reports missing objects like the filesystem does, so callers can check for fs.ErrNotExist.
+func notExist(key string, err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound") {
+		return fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+	}
+	return err
+}
+
+func newBucket(config configuration.S3Configuration) *bucket {
+	if config.Bucket == "" {
+		panic("S3 bucket is not configured")
+	}
+	if config.PartSize < minPartSize {
+		panic(fmt.Sprintf("S3 part size must be at least %d bytes", minPartSize))
+	}
+
+	options := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(config.Region)}
+	if config.AccessKeyID != "" {
+		// Without keys the credentials are taken from the environment, like the role of an instance
+		options = append(options, awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(config.AccessKeyID, config.SecretAccessKey, "")))
+	}
+
+	awsConfig, err := awsconfig.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		panic(err)
+	}
+
+	client := awss3.NewFromConfig(awsConfig, func(o *awss3.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+		o.UsePathStyle = config.PathStyle
+		// S3 compatible services often do not know the checksums newer AWS SDKs send by default
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+
+	return &bucket{
+		client:   client,
+		name:     config.Bucket,
+		prefix:   strings.Trim(config.Prefix, "/"),
+		partSize: config.PartSize,
+	}
+}
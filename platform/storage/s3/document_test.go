@@ -0,0 +1,164 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/fs"
+	"runtime"
+	"slices"
+	"testing"
+	"unterlagen/platform/configuration"
+	"unterlagen/platform/storage/s3/s3test"
+)
+
+func start(t *testing.T) (*s3test.Server, configuration.Configuration) {
+	server, err := s3test.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(server.Close)
+
+	config := configuration.Configuration{
+		Storage: configuration.StorageConfiguration{
+			Provider: configuration.StorageS3,
+			S3: configuration.S3Configuration{
+				Endpoint:        server.Endpoint,
+				Region:          "us-east-1",
+				Bucket:          s3test.Bucket,
+				Prefix:          "archive",
+				AccessKeyID:     s3test.AccessKeyID,
+				SecretAccessKey: s3test.SecretAccessKey,
+				PathStyle:       true,
+				PartSize:        minPartSize,
+			},
+		},
+	}
+	return server, config
+}
+
+func retrieve(t *testing.T, storage *DocumentStorage, filepath string) []byte {
+	var data []byte
+	err := storage.Retrieve(filepath, func(r io.Reader) error {
+		var err error
+		data, err = io.ReadAll(r)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestDocumentStorage(t *testing.T) {
+	server, config := start(t)
+	storage := NewDocumentStorage(config)
+
+	if err := storage.Store("owner/document/invoice.pdf", bytes.NewReader([]byte("invoice"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if data := retrieve(t, storage, "owner/document/invoice.pdf"); string(data) != "invoice" {
+		t.Errorf("unexpected content %q", data)
+	}
+	if data, err := server.Object("archive/owner/document/invoice.pdf"); err != nil || string(data) != "invoice" {
+		t.Errorf("expected the document below the prefix, got %q, %v", data, err)
+	}
+
+	size, err := storage.Size("owner/document/invoice.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != int64(len("invoice")) {
+		t.Errorf("expected size %d, got %d", len("invoice"), size)
+	}
+
+	if err := storage.Delete("owner/document/invoice.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	err = storage.Retrieve("owner/document/invoice.pdf", func(r io.Reader) error { return nil })
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a deleted document to not exist, got %v", err)
+	}
+	if _, err := storage.Size("owner/document/invoice.pdf"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected no size of a deleted document, got %v", err)
+	}
+}
+
+func TestDocumentStorageMultipartUpload(t *testing.T) {
+	_, config := start(t)
+	storage := NewDocumentStorage(config)
+
+	for _, size := range []int{minPartSize, 2*minPartSize + 1000} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		if err := storage.Store("owner/document/scan.pdf", bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+
+		if stored := retrieve(t, storage, "owner/document/scan.pdf"); !bytes.Equal(stored, data) {
+			t.Errorf("content of %d bytes differs after upload", size)
+		}
+
+		stored, err := storage.Size("owner/document/scan.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored != int64(size) {
+			t.Errorf("expected size %d, got %d", size, stored)
+		}
+	}
+}
+
+func TestDocumentStorageSmallUpload(t *testing.T) {
+	_, config := start(t)
+	storage := NewDocumentStorage(config)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for range 10 {
+		if err := storage.Store("owner/document/preview.jpeg", bytes.NewReader([]byte("preview"))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+
+	// Allocating a whole part for each of them would be ten times the part size
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > minPartSize {
+		t.Errorf("expected small uploads not to allocate whole parts, allocated %d bytes", allocated)
+	}
+}
+
+func TestDocumentStorageWalk(t *testing.T) {
+	_, config := start(t)
+	storage := NewDocumentStorage(config)
+	previewStorage := NewDocumentPreviewStorage(config)
+
+	if err := storage.Store("owner/document/invoice.pdf", bytes.NewReader([]byte("invoice"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := previewStorage.Store("owner/document/previews/page0.jpeg", bytes.NewReader([]byte("preview"))); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	err := storage.Walk(func(filepath string, r io.Reader) error {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath+"="+string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(paths)
+	expected := []string{"owner/document/invoice.pdf=invoice", "owner/document/previews/page0.jpeg=preview"}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+}
@@ -0,0 +1,47 @@
+// Package s3test runs an in-process S3 server to test the S3 storage against.
+package s3test
+
+import (
+	"io"
+	"net/http/httptest"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+const (
+	Bucket          = "unterlagen"
+	AccessKeyID     = "access-key-id"
+	SecretAccessKey = "secret-access-key"
+)
+
+// Server keeps objects in memory, it starts with an empty bucket.
+type Server struct {
+	Endpoint string
+	backend  *s3mem.Backend
+	server   *httptest.Server
+}
+
+func Start() (*Server, error) {
+	backend := s3mem.New()
+	if err := backend.CreateBucket(Bucket); err != nil {
+		return nil, err
+	}
+
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	return &Server{Endpoint: server.URL, backend: backend, server: server}, nil
+}
+
+// Object returns the stored content of a key.
+func (s *Server) Object(key string) ([]byte, error) {
+	object, err := s.backend.GetObject(Bucket, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer object.Contents.Close()
+	return io.ReadAll(object.Contents)
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
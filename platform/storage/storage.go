@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"unterlagen/features/archive"
+	"unterlagen/platform/configuration"
+	"unterlagen/platform/storage/encrypted"
+	"unterlagen/platform/storage/filesystem"
+	"unterlagen/platform/storage/s3"
+)
+
+var ErrNoEncryptionKey = errors.New("no encryption key configured")
+
+type documentStorage interface {
+	archive.DocumentStorage
+	Walk(visit func(filepath string, r io.Reader) error) error
+}
+
+type documentPreviewStorage interface {
+	archive.DocumentPreviewStorage
+	Walk(visit func(filepath string, r io.Reader) error) error
+}
+
+// GetDocumentStorage returns the configured storage, encrypted when an encryption key is configured.
+func GetDocumentStorage(config configuration.Configuration) archive.DocumentStorage {
+	return encrypted.NewDocumentStorage(newDocumentStorage(config), encrypted.GetKeyring(config))
+}
+
+// GetDocumentPreviewStorage returns the configured storage for previews, encrypted when an encryption key is configured.
+func GetDocumentPreviewStorage(config configuration.Configuration) archive.DocumentPreviewStorage {
+	return encrypted.NewDocumentPreviewStorage(newDocumentPreviewStorage(config), encrypted.GetKeyring(config))
+}
+
+// RotateKey encrypts all stored documents and previews with the current encryption key.
+func RotateKey(config configuration.Configuration) (int, error) {
+	keyring := encrypted.GetKeyring(config)
+	if keyring == nil {
+		return 0, ErrNoEncryptionKey
+	}
+	return encrypted.Rotate(keyring, newDocumentStorage(config), newDocumentPreviewStorage(config))
+}
+
+func newDocumentStorage(config configuration.Configuration) documentStorage {
+	switch config.Storage.Provider {
+	case configuration.StorageFilesystem:
+		slog.Info("Filesystem chosen as document storage", "directory", config.Data.Directory)
+		return filesystem.NewDocumentStorage(config)
+	case configuration.StorageS3:
+		slog.Info("S3 chosen as document storage", "bucket", config.Storage.S3.Bucket, "endpoint", config.Storage.S3.Endpoint)
+		return s3.NewDocumentStorage(config)
+	default:
+		panic("unknown storage provider " + string(config.Storage.Provider))
+	}
+}
+
+func newDocumentPreviewStorage(config configuration.Configuration) documentPreviewStorage {
+	switch config.Storage.Provider {
+	case configuration.StorageFilesystem:
+		return filesystem.NewDocumentPreviewStorage(config)
+	case configuration.StorageS3:
+		return s3.NewDocumentPreviewStorage(config)
+	default:
+		panic("unknown storage provider " + string(config.Storage.Provider))
+	}
+}
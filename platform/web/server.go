@@ -9,9 +9,12 @@ import (
 	"io"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
+	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 	"unterlagen/features/administration"
 	"unterlagen/features/archive"
@@ -170,8 +173,13 @@ func (server *Server) getArchive(w http.ResponseWriter, r *http.Request) {
 	if len(folderIDs) > 0 {
 		folderID = folderIDs[0]
 	}
+	filter := archive.DocumentFilter{
+		TagID:           r.URL.Query().Get("tagID"),
+		CorrespondentID: r.URL.Query().Get("correspondentID"),
+		DocumentTypeID:  r.URL.Query().Get("documentTypeID"),
+	}
 
-	// Verify user owns the folder they're trying to access
+	// Verify user can read the folder they're trying to access
 	_, err := server.archive.GetFolder(folderID, user)
 	if err != nil {
 		slog.Error("failed to get folder", slog.String("folderID", folderID), slog.String("user", user), slog.String("error", err.Error()))
@@ -185,20 +193,53 @@ func (server *Server) getArchive(w http.ResponseWriter, r *http.Request) {
 		showTrashed = true
 	}
 
-	documents, err := server.archive.GetDocumentsInFolder(folderID, user)
+	tags, err := server.archive.GetTags(user)
+	if err != nil {
+		slog.Error("failed to get tags", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	correspondents, err := server.archive.GetCorrespondents(user)
 	if err != nil {
-		slog.Error("failed to get documents in folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		slog.Error("failed to get correspondents", slog.String("user", user), slog.String("error", err.Error()))
 		templates.ErrorServer("").Render(r.Context(), w)
 		return
 	}
 
-	folders, err := server.archive.GetFolderChildren(folderID, user)
+	documentTypes, err := server.archive.GetDocumentTypes(user)
 	if err != nil {
-		slog.Error("failed to get children of folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		slog.Error("failed to get document types", slog.String("user", user), slog.String("error", err.Error()))
 		templates.ErrorServer("").Render(r.Context(), w)
 		return
 	}
 
+	var documents []archive.Document
+	var folders []archive.Folder
+	if !filter.IsEmpty() {
+		// Filtered documents are listed across all folders
+		documents, err = server.archive.FilterDocuments(filter, user)
+		if err != nil {
+			slog.Error("failed to filter documents", slog.String("user", user), slog.String("error", err.Error()))
+			templates.ErrorServer("").Render(r.Context(), w)
+			return
+		}
+	} else {
+		documents, err = server.archive.GetDocumentsInFolder(folderID, user)
+		if err != nil {
+			slog.Error("failed to get documents in folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+			templates.ErrorServer("").Render(r.Context(), w)
+			return
+		}
+
+		folders, err = server.archive.GetFolderChildren(folderID, user)
+		if err != nil {
+			slog.Error("failed to get children of folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+			templates.ErrorServer("").Render(r.Context(), w)
+			return
+		}
+	}
+
 	hierarchy, err := server.archive.GetFolderHierarchy(folderID, user)
 	if err != nil {
 		slog.Error("failed to get hierarchy of folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
@@ -206,8 +247,187 @@ func (server *Server) getArchive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	allFolders, err := server.archive.GetFolders(user)
+	if err != nil {
+		slog.Error("failed to get folders", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	customFields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	bulkOperations, err := server.archive.GetBulkOperations(user)
+	if err != nil {
+		slog.Error("failed to get bulk operations", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	// Only the owner of a folder sees whom it is shared with
+	var grants []archive.Grant
+	if folder := hierarchy[len(hierarchy)-1]; folder.ID != archive.FolderRootID && folder.Owner == user {
+		grants, err = server.archive.GetFolderGrants(folder.ID, user)
+		if err != nil {
+			slog.Error("failed to get folder grants", slog.String("folderID", folderID), slog.String("error", err.Error()))
+			templates.ErrorServer("").Render(r.Context(), w)
+			return
+		}
+	}
+
+	holds, err := server.archive.GetFolderLegalHolds(folderID, user)
+	if err != nil {
+		slog.Error("failed to get folder legal holds", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
 	notifications := server.buildNotifications(r, w)
-	templates.Archive(folderID, documents, folders, hierarchy, notifications, server.isAdmin(r), showTrashed).Render(r.Context(), w)
+	notices, err := server.archive.GetRetentionNotices(user)
+	if err != nil {
+		slog.Error("failed to get retention notices", slog.String("user", user), slog.String("error", err.Error()))
+	} else if len(notices) > 0 {
+		notifications = append(notifications, templates.Notification{
+			Type:    templates.NotificationWarning,
+			Message: fmt.Sprintf("%d documents passed their retention period, review them under Retention", len(notices)),
+		})
+	}
+	templates.Archive(folderID, documents, folders, allFolders, hierarchy, tags, correspondents, documentTypes, customFields, bulkOperations, grants, holds, user, filter, notifications, server.isAdmin(r), showTrashed).Render(r.Context(), w)
+}
+
+func (server *Server) handleRenameFolder(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+	redirect := fmt.Sprintf("/archive?folderID=%s", folderID)
+
+	err := server.archive.RenameFolder(folderID, r.PostFormValue("name"), user)
+	if err != nil {
+		slog.Error("failed to rename folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidFolderName) {
+			session.AddFlash("Name is required", "error")
+		} else {
+			session.AddFlash("Failed to rename folder", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder renamed successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleMoveFolder(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+	redirect := fmt.Sprintf("/archive?folderID=%s", folderID)
+
+	err := server.archive.MoveFolder(folderID, r.PostFormValue("parentID"), user)
+	if err != nil {
+		slog.Error("failed to move folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrFolderCycle) {
+			session.AddFlash("A folder cannot be moved into one of its own subfolders", "error")
+		} else {
+			session.AddFlash("Failed to move folder", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder moved successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleTrashFolder(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+
+	folder, err := server.archive.GetFolder(folderID, user)
+	if err == nil {
+		err = server.archive.TrashFolder(folderID, user)
+	}
+	if err != nil {
+		slog.Error("failed to trash folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		session.AddFlash(legalHoldErrorMessage(err, "Failed to move folder to trash"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder moved to trash", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folder.ParentID), http.StatusFound)
+}
+
+func (server *Server) handleRestoreFolder(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+	redirect := fmt.Sprintf("/archive?folderID=%s", folderID)
+
+	err := server.archive.RestoreFolder(folderID, user)
+	if err != nil {
+		slog.Error("failed to restore folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to restore folder", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder restored successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleCreateTag(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+
+	_, err := server.archive.CreateTag(r.PostFormValue("name"), r.PostFormValue("color"), user)
+	if err != nil {
+		slog.Error("failed to create tag", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrTagExists) {
+			session.AddFlash("A tag with this name already exists", "error")
+		} else {
+			session.AddFlash("Failed to create tag", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Tag created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive", http.StatusFound)
+}
+
+func (server *Server) handleDeleteTag(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	tagID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteTag(tagID, user)
+	if err != nil {
+		slog.Error("failed to delete tag", slog.String("tagID", tagID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete tag", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Tag deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive", http.StatusFound)
 }
 
 func (server *Server) handleCreateFolder(w http.ResponseWriter, r *http.Request) {
@@ -225,7 +445,7 @@ func (server *Server) handleCreateFolder(w http.ResponseWriter, r *http.Request)
 		parentFolderID = archive.FolderRootID
 	}
 
-	// Verify user owns the parent folder
+	// Verify user can access the parent folder
 	_, err := server.archive.GetFolder(parentFolderID, username)
 	if err != nil {
 		slog.Error("failed to verify parent folder ownership", slog.String("error", err.Error()))
@@ -264,220 +484,2016 @@ func (server *Server) handleSynchronize(w http.ResponseWriter, r *http.Request)
 	session.AddFlash("Archive synchronization started", "success")
 	session.Save(r, w)
 
-	// Redirect back to the archive page with the current folder
-	http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+	// Redirect back to the archive page with the current folder
+	http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+}
+
+func (server *Server) handleUploadDocument(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	username := server.getAuthenticatedUser(r)
+	folderID := r.PostFormValue("folderID")
+	if folderID == "" {
+		folderID = archive.FolderRootID
+	}
+
+	// Verify user can access the target folder, uploading checks for write access
+	_, err := server.archive.GetFolder(folderID, username)
+	if err != nil {
+		slog.Error("failed to verify folder ownership for upload", slog.String("error", err.Error()))
+		session.AddFlash("You don't have permission to upload to this folder", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive", http.StatusFound)
+		return
+	}
+
+	err = r.ParseMultipartForm(32 << 20) // 32 MB max memory
+	if err != nil {
+		session.AddFlash("Failed to parse uploaded files", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+		return
+	}
+
+	files := r.MultipartForm.File["documents"]
+	if len(files) == 0 {
+		session.AddFlash("No files selected", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+		return
+	}
+
+	uploadedCount := 0
+	duplicateCount := 0
+	var linked []archive.Document
+	for _, fileHeader := range files {
+		file, err := fileHeader.Open()
+		if err != nil {
+			slog.Error("failed to open uploaded file", slog.String("filename", fileHeader.Filename), slog.String("error", err.Error()))
+			continue
+		}
+
+		err = server.archive.UploadDocument(fileHeader.Filename, uint64(fileHeader.Size), folderID, username, file)
+		file.Close()
+
+		if errors.Is(err, archive.ErrDuplicateDocument) {
+			duplicateCount++
+			continue
+		}
+		if document, ok := archive.LinkedDuplicate(err); ok {
+			// The existing document may be in a folder of the owner the user cannot see
+			if _, err := server.archive.GetDocument(document.ID, username); err != nil {
+				document = archive.Document{Title: fileHeader.Filename}
+			}
+			linked = append(linked, document)
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to upload document", slog.String("filename", fileHeader.Filename), slog.String("error", err.Error()))
+			continue
+		}
+
+		uploadedCount++
+	}
+
+	if duplicateCount > 0 {
+		session.AddFlash(fmt.Sprintf("%d of %d documents are already in the archive and were rejected", duplicateCount, len(files)), "warning")
+	}
+
+	// Linked uploads were not stored, the user is pointed to the documents already in the archive
+	if len(linked) == 1 && len(files) == 1 && linked[0].ID != "" {
+		session.AddFlash(fmt.Sprintf("This document is already in the archive as \"%s\", showing it instead", linked[0].Title), "info")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/documents/"+linked[0].ID, http.StatusFound)
+		return
+	}
+	for _, document := range linked {
+		session.AddFlash(fmt.Sprintf("\"%s\" is already in the archive and was linked instead of uploaded again", document.Title), "info")
+	}
+
+	if uploadedCount == 0 && duplicateCount+len(linked) < len(files) {
+		session.AddFlash("Failed to upload any documents", "error")
+	} else if uploadedCount == len(files) {
+		if len(files) == 1 {
+			session.AddFlash("Document uploaded successfully", "success")
+		} else {
+			session.AddFlash(fmt.Sprintf("%d documents uploaded successfully", uploadedCount), "success")
+		}
+	} else if uploadedCount > 0 {
+		session.AddFlash(fmt.Sprintf("%d of %d documents uploaded successfully", uploadedCount, len(files)), "warning")
+	}
+
+	session.Save(r, w)
+	http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+}
+
+func (server *Server) getDuplicates(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	groups, err := server.archive.GetDuplicateDocuments(user)
+	if err != nil {
+		slog.Error("failed to get duplicate documents", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	preferences, err := server.archive.GetPreferences(user)
+	if err != nil {
+		slog.Error("failed to get archive preferences", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.Duplicates(groups, preferences, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleUpdateDuplicatePolicy(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	policy := archive.DuplicatePolicy(r.PostFormValue("policy"))
+
+	err := server.archive.UpdateDuplicatePolicy(user, policy)
+	if err != nil {
+		slog.Error("failed to update duplicate policy", slog.String("user", user), slog.String("error", err.Error()))
+		session.AddFlash("Failed to update duplicate handling", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/duplicates", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Duplicate handling updated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/duplicates", http.StatusFound)
+}
+
+func (server *Server) getConsumeSettings(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	preferences, err := server.archive.GetPreferences(user)
+	if err != nil {
+		slog.Error("failed to get archive preferences", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	folders, err := server.archive.GetFolders(user)
+	if err != nil {
+		slog.Error("failed to get folders", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.ConsumeSettings(preferences, server.archive.ConsumeDirectoryPath(preferences), folders, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleUpdateConsumeSettings(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	directory := strings.TrimSpace(r.PostFormValue("directory"))
+
+	// Only administrators may point the archive at arbitrary paths of the server
+	if filepath.IsAbs(directory) && !server.isAdmin(r) {
+		session.AddFlash("Only administrators may use absolute paths", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/consume", http.StatusFound)
+		return
+	}
+
+	err := server.archive.UpdateConsumeSettings(user, directory, r.PostFormValue("folderID"), archive.ConsumeAction(r.PostFormValue("action")))
+	if err != nil {
+		slog.Error("failed to update consume settings", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidConsumeDirectory) {
+			session.AddFlash("The consume directory must stay within your consume directory and may not contain the archive", "error")
+		} else if errors.Is(err, archive.ErrInvalidConsumeAction) {
+			session.AddFlash("Unknown action for consumed files", "error")
+		} else if errors.Is(err, archive.ErrNotAllowed) {
+			session.AddFlash("You may not add documents to this folder", "error")
+		} else {
+			session.AddFlash("Failed to update consume settings", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/consume", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Consume settings updated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/consume", http.StatusFound)
+}
+
+func (server *Server) getMailAccounts(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	accounts, err := server.archive.GetMailAccounts(user)
+	if err != nil {
+		slog.Error("failed to get mail accounts", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	folders, err := server.archive.GetFolders(user)
+	if err != nil {
+		slog.Error("failed to get folders", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.MailAccounts(accounts, folders, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateMailAccount(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+
+	port := 0
+	if value := r.PostFormValue("port"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			session.AddFlash("The port must be a number", "error")
+			session.Save(r, w)
+			http.Redirect(w, r, "/archive/mail", http.StatusFound)
+			return
+		}
+		port = parsed
+	}
+
+	account := archive.MailAccount{
+		Name:             r.PostFormValue("name"),
+		Host:             r.PostFormValue("host"),
+		Port:             port,
+		Username:         r.PostFormValue("username"),
+		Password:         r.PostFormValue("password"),
+		TLS:              r.PostFormValue("tls") == "true",
+		Insecure:         r.PostFormValue("insecure") == "true",
+		Mailbox:          r.PostFormValue("mailbox"),
+		SenderFilter:     strings.TrimSpace(r.PostFormValue("senderFilter")),
+		SubjectFilter:    strings.TrimSpace(r.PostFormValue("subjectFilter")),
+		FolderID:         r.PostFormValue("folderID"),
+		ProcessedAction:  archive.MailProcessedAction(r.PostFormValue("processedAction")),
+		ProcessedMailbox: r.PostFormValue("processedMailbox"),
+	}
+
+	_, err := server.archive.CreateMailAccount(account, user)
+	if err != nil {
+		slog.Error("failed to create mail account", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidMailAccount) {
+			session.AddFlash(err.Error(), "error")
+		} else if errors.Is(err, archive.ErrMailAccountExists) {
+			session.AddFlash("A mail account with this name already exists", "error")
+		} else if errors.Is(err, archive.ErrNotAllowed) {
+			session.AddFlash("You may not add documents to this folder", "error")
+		} else {
+			session.AddFlash("Failed to create mail account", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/mail", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Mail account created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/mail", http.StatusFound)
+}
+
+func (server *Server) handleDeleteMailAccount(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	accountID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteMailAccount(accountID, user)
+	if err != nil {
+		slog.Error("failed to delete mail account", slog.String("accountID", accountID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete mail account", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/mail", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Mail account deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/mail", http.StatusFound)
+}
+
+func (server *Server) handlePollMailAccount(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	accountID := chi.URLParam(r, "id")
+
+	err := server.archive.PollMailAccount(accountID, user)
+	if err != nil {
+		slog.Error("failed to poll mail account", slog.String("accountID", accountID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to check mail account: "+err.Error(), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/mail", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Mail account checked successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/mail", http.StatusFound)
+}
+
+func (server *Server) getRetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	policies, err := server.archive.GetRetentionPolicies(user)
+	if err != nil {
+		slog.Error("failed to get retention policies", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notices, err := server.archive.GetRetentionNotices(user)
+	if err != nil {
+		slog.Error("failed to get retention notices", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	previewUntil := time.Now().AddDate(0, 1, 0)
+	preview, err := server.archive.GetRetentionPreview(user, previewUntil)
+	if err != nil {
+		slog.Error("failed to get retention preview", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	folders, err := server.archive.GetFolders(user)
+	if err != nil {
+		slog.Error("failed to get folders", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	tags, err := server.archive.GetTags(user)
+	if err != nil {
+		slog.Error("failed to get tags", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	documentTypes, err := server.archive.GetDocumentTypes(user)
+	if err != nil {
+		slog.Error("failed to get document types", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	customFields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.RetentionPolicies(policies, notices, preview, previewUntil, folders, tags, documentTypes, customFields, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+
+	keep, err := strconv.Atoi(r.PostFormValue("keep"))
+	if err != nil || keep <= 0 {
+		session.AddFlash("Please enter how long documents are kept", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/retention", http.StatusFound)
+		return
+	}
+	if r.PostFormValue("unit") != "months" {
+		keep *= 12
+	}
+
+	policy := archive.RetentionPolicy{
+		Name:        r.PostFormValue("name"),
+		KeepMonths:  keep,
+		Basis:       archive.RetentionBasis(r.PostFormValue("basis")),
+		DateFieldID: r.PostFormValue("dateFieldID"),
+		Action:      archive.RetentionAction(r.PostFormValue("action")),
+	}
+	kind, id, _ := strings.Cut(r.PostFormValue("target"), ":")
+	switch kind {
+	case "folder":
+		policy.FolderID = id
+	case "tag":
+		policy.TagID = id
+	case "documentType":
+		policy.DocumentTypeID = id
+	}
+
+	_, err = server.archive.CreateRetentionPolicy(policy, user)
+	if err != nil {
+		slog.Error("failed to create retention policy", slog.String("user", user), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, archive.ErrInvalidRetentionPolicy):
+			session.AddFlash("Please check the retention policy: "+err.Error(), "error")
+		case errors.Is(err, archive.ErrCustomFieldNotFound):
+			session.AddFlash("Please choose the field holding the document date", "error")
+		default:
+			session.AddFlash("Failed to create retention policy", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/retention", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Retention policy created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/retention", http.StatusFound)
+}
+
+func (server *Server) handleDeleteRetentionPolicy(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+	policyID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteRetentionPolicy(policyID, user)
+	if err != nil {
+		slog.Error("failed to delete retention policy", slog.String("policyID", policyID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete retention policy", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/retention", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Retention policy deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/retention", http.StatusFound)
+}
+
+func (server *Server) handleApplyRetentionPolicies(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+
+	err := server.archive.ApplyRetentionPolicies(user)
+	if err != nil {
+		slog.Error("failed to apply retention policies", slog.String("user", user), slog.String("error", err.Error()))
+		session.AddFlash("Failed to apply retention policies", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/retention", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Retention policies applied successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/retention", http.StatusFound)
+}
+
+func (server *Server) handleDismissRetentionNotice(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+	policyID := chi.URLParam(r, "id")
+
+	err := server.archive.DismissRetentionNotice(policyID, chi.URLParam(r, "documentID"), user)
+	if err != nil {
+		slog.Error("failed to dismiss retention notice", slog.String("policyID", policyID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to dismiss notice", "error")
+		session.Save(r, w)
+	}
+
+	http.Redirect(w, r, "/archive/retention", http.StatusFound)
+}
+
+func (server *Server) getTrash(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	trash, err := server.archive.GetTrash(user)
+	if err != nil {
+		slog.Error("failed to get trash", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.Trash(trash, user, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleUpdateTrashRetention(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+
+	retention, err := strconv.Atoi(r.PostFormValue("retention"))
+	if err == nil {
+		err = server.archive.UpdateTrashRetention(user, archive.TrashRetention(retention))
+	}
+	if err != nil {
+		slog.Error("failed to update trash retention", slog.String("user", user), slog.String("error", err.Error()))
+		session.AddFlash("Failed to update trash retention", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/trash", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Trash retention updated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/trash", http.StatusFound)
+}
+
+func (server *Server) handleEmptyTrash(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+
+	kept, err := server.archive.EmptyTrash(user)
+	if err != nil {
+		slog.Error("failed to empty trash", slog.String("user", user), slog.String("error", err.Error()))
+		session.AddFlash("Failed to empty trash", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/trash", http.StatusFound)
+		return
+	}
+
+	if kept > 0 {
+		session.AddFlash(fmt.Sprintf("Trash emptied, %d documents under legal hold were kept", kept), "warning")
+	} else {
+		session.AddFlash("Trash emptied successfully", "success")
+	}
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/trash", http.StatusFound)
+}
+
+func (server *Server) handleDeleteDocumentPermanently(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+
+	err := server.archive.DeleteDocumentPermanently(documentID, user)
+	if err != nil {
+		slog.Error("failed to delete document permanently", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash(legalHoldErrorMessage(err, "Failed to delete document"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/trash", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document deleted permanently", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/trash", http.StatusFound)
+}
+
+func (server *Server) handleDeleteFolderPermanently(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+
+	err := server.archive.DeleteFolderPermanently(folderID, user)
+	if err != nil {
+		slog.Error("failed to delete folder permanently", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		session.AddFlash(legalHoldErrorMessage(err, "Failed to delete folder"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/trash", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder deleted permanently", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/trash", http.StatusFound)
+}
+
+func (server *Server) getCustomFields(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	fields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.CustomFields(fields, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateCustomField(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	fieldType := archive.CustomFieldType(r.PostFormValue("type"))
+	options := strings.Split(r.PostFormValue("options"), ",")
+
+	_, err := server.archive.CreateCustomField(r.PostFormValue("name"), fieldType, options, user)
+	if err != nil {
+		slog.Error("failed to create custom field", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrCustomFieldExists) {
+			session.AddFlash("A field with this name already exists", "error")
+		} else {
+			session.AddFlash("Failed to create field", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/fields", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Field created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/fields", http.StatusFound)
+}
+
+func (server *Server) handleDeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	fieldID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteCustomField(fieldID, user)
+	if err != nil {
+		slog.Error("failed to delete custom field", slog.String("fieldID", fieldID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete field", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/fields", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Field deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/fields", http.StatusFound)
+}
+
+func (server *Server) getCorrespondents(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	correspondents, err := server.archive.GetCorrespondents(user)
+	if err != nil {
+		slog.Error("failed to get correspondents", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.Correspondents(correspondents, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateCorrespondent(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+
+	_, err := server.archive.CreateCorrespondent(r.PostFormValue("name"), user)
+	if err != nil {
+		slog.Error("failed to create correspondent", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrCorrespondentExists) {
+			session.AddFlash("A correspondent with this name already exists", "error")
+		} else {
+			session.AddFlash("Failed to create correspondent", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/correspondents", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Correspondent created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/correspondents", http.StatusFound)
+}
+
+func (server *Server) handleDeleteCorrespondent(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	correspondentID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteCorrespondent(correspondentID, user)
+	if err != nil {
+		slog.Error("failed to delete correspondent", slog.String("correspondentID", correspondentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete correspondent", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/correspondents", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Correspondent deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/correspondents", http.StatusFound)
+}
+
+func (server *Server) getDocumentTypes(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	documentTypes, err := server.archive.GetDocumentTypes(user)
+	if err != nil {
+		slog.Error("failed to get document types", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.DocumentTypes(documentTypes, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateDocumentType(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+
+	_, err := server.archive.CreateDocumentType(r.PostFormValue("name"), user)
+	if err != nil {
+		slog.Error("failed to create document type", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrDocumentTypeExists) {
+			session.AddFlash("A document type with this name already exists", "error")
+		} else {
+			session.AddFlash("Failed to create document type", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/document-types", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document type created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/document-types", http.StatusFound)
+}
+
+func (server *Server) handleDeleteDocumentType(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	documentTypeID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteDocumentType(documentTypeID, user)
+	if err != nil {
+		slog.Error("failed to delete document type", slog.String("documentTypeID", documentTypeID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete document type", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/document-types", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document type deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/document-types", http.StatusFound)
+}
+
+func (server *Server) getClassificationRules(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	rules, err := server.archive.GetClassificationRules(user)
+	if err != nil {
+		slog.Error("failed to get classification rules", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	folders, err := server.archive.GetFolders(user)
+	if err != nil {
+		slog.Error("failed to get folders", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	tags, err := server.archive.GetTags(user)
+	if err != nil {
+		slog.Error("failed to get tags", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	fields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.ClassificationRules(rules, folders, tags, fields, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateClassificationRule(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+
+	fields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	r.ParseForm()
+	customFields := make(map[string]string)
+	for _, field := range fields {
+		value := r.PostForm.Get(templates.CustomFieldInputName(field, ""))
+		if value != "" {
+			customFields[field.ID] = value
+		}
+	}
+
+	rule := archive.ClassificationRule{
+		Name:          r.PostForm.Get("name"),
+		Match:         archive.ClassificationMatch(r.PostForm.Get("match")),
+		Pattern:       r.PostForm.Get("pattern"),
+		FolderID:      r.PostForm.Get("folderID"),
+		TagIDs:        r.PostForm["tagID"],
+		TitleTemplate: r.PostForm.Get("titleTemplate"),
+		CustomFields:  customFields,
+	}
+
+	_, err = server.archive.CreateClassificationRule(rule, user)
+	if err != nil {
+		slog.Error("failed to create classification rule", slog.String("user", user), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidClassificationRule) || errors.Is(err, archive.ErrInvalidCustomFieldValue) {
+			session.AddFlash(err.Error(), "error")
+		} else {
+			session.AddFlash("Failed to create rule", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/rules", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Rule created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/rules", http.StatusFound)
+}
+
+func (server *Server) handleMoveClassificationRule(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	ruleID := chi.URLParam(r, "id")
+
+	offset, err := strconv.Atoi(r.PostFormValue("offset"))
+	if err == nil {
+		err = server.archive.MoveClassificationRule(ruleID, user, offset)
+	}
+	if err != nil {
+		slog.Error("failed to move classification rule", slog.String("ruleID", ruleID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to move rule", "error")
+		session.Save(r, w)
+	}
+
+	http.Redirect(w, r, "/archive/rules", http.StatusFound)
+}
+
+func (server *Server) handleDeleteClassificationRule(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	user := server.getAuthenticatedUser(r)
+	ruleID := chi.URLParam(r, "id")
+
+	err := server.archive.DeleteClassificationRule(ruleID, user)
+	if err != nil {
+		slog.Error("failed to delete classification rule", slog.String("ruleID", ruleID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete rule", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/rules", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Rule deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/rules", http.StatusFound)
+}
+
+func (server *Server) getClassificationRuleDryRun(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	ruleID := chi.URLParam(r, "id")
+
+	documents, err := server.archive.DryRunClassificationRule(ruleID, user)
+	if err != nil {
+		slog.Error("failed to dry run classification rule", slog.String("ruleID", ruleID), slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	templates.ClassificationRuleMatches(documents).Render(r.Context(), w)
+}
+
+func (server *Server) getDocumentDetails(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	if documentID == "" {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	document, err := server.archive.GetDocument(documentID, user)
+	if err != nil {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	attachments, err := server.archive.GetDocumentAttachments(documentID, user)
+	if err != nil {
+		slog.Error("failed to get document attachments", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	duplicates, err := server.archive.GetDocumentDuplicates(documentID, user)
+	if err != nil {
+		slog.Error("failed to get document duplicates", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	versions, err := server.archive.GetDocumentVersions(documentID, user)
+	if err != nil {
+		slog.Error("failed to get document versions", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	// Shared documents are classified with the vocabulary of their owner
+	tags, err := server.archive.GetTags(document.Owner)
+	if err != nil {
+		slog.Error("failed to get tags", slog.String("user", document.Owner), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	fields, err := server.archive.GetCustomFields(document.Owner)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", document.Owner), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	correspondents, err := server.archive.GetCorrespondents(document.Owner)
+	if err != nil {
+		slog.Error("failed to get correspondents", slog.String("user", document.Owner), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	documentTypes, err := server.archive.GetDocumentTypes(document.Owner)
+	if err != nil {
+		slog.Error("failed to get document types", slog.String("user", document.Owner), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	var grants []archive.Grant
+	if document.Owner == user {
+		grants, err = server.archive.GetDocumentGrants(documentID, user)
+		if err != nil {
+			slog.Error("failed to get document grants", slog.String("documentID", documentID), slog.String("error", err.Error()))
+			templates.ErrorServer("").Render(r.Context(), w)
+			return
+		}
+	}
+
+	mergeCandidates, err := server.archive.GetMergeCandidates(documentID, user)
+	if err != nil {
+		slog.Error("failed to get merge candidates", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	holds, err := server.archive.GetDocumentLegalHolds(documentID, user)
+	if err != nil {
+		slog.Error("failed to get document legal holds", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.DocumentDetails(document, attachments, duplicates, versions, tags, fields, correspondents, documentTypes, mergeCandidates, grants, holds, user, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) downloadDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	if documentID == "" {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	document, err := server.archive.GetDocument(documentID, user)
+	if err != nil {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", document.Filename))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", document.Filesize))
+	err = server.archive.DownloadDocument(documentID, user, func(r io.Reader) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+
+	if err != nil {
+		slog.Error("failed to download document",
+			slog.String("documentID", documentID),
+			slog.String("user", user),
+			slog.String("error", err.Error()))
+	}
+}
+
+func (server *Server) exportAllDocuments(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	filename := fmt.Sprintf("documents-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Type", "application/zip")
+
+	err := server.archive.ExportAllDocuments(user, w)
+	if err != nil {
+		slog.Error("failed to export all documents",
+			slog.String("user", user),
+			slog.String("error", err.Error()))
+	}
+}
+
+func (server *Server) handleDeleteDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	if documentID == "" {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	session := server.getSession(r)
+	err := server.archive.TrashDocument(documentID, user)
+	if err != nil {
+		slog.Error("failed to delete document", slog.String("error", err.Error()))
+		session.AddFlash(legalHoldErrorMessage(err, "Failed to delete document"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document trashed successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+}
+
+func (server *Server) handleRestoreDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	if documentID == "" {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	session := server.getSession(r)
+	err := server.archive.RestoreDocument(documentID, user)
+	if err != nil {
+		slog.Error("failed to restore document", slog.String("error", err.Error()))
+		session.AddFlash("Failed to restore document", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document restored successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+}
+
+func (server *Server) handleMoveDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive?folderID=%s", r.PostFormValue("currentFolderID"))
+
+	err := server.archive.MoveDocument(documentID, r.PostFormValue("folderID"), user)
+	if err != nil {
+		slog.Error("failed to move document", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to move document", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document moved successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleCopyDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive?folderID=%s", r.PostFormValue("currentFolderID"))
+
+	_, err := server.archive.CopyDocument(documentID, r.PostFormValue("folderID"), user)
+	if err != nil {
+		slog.Error("failed to copy document", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to copy document", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document copied successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleBulkOperation(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	session := server.getSession(r)
+	r.ParseForm()
+	redirect := fmt.Sprintf("/archive?folderID=%s", r.PostFormValue("currentFolderID"))
+
+	operation := archive.BulkOperation{
+		Action:           archive.BulkAction(r.PostFormValue("action")),
+		DocumentIDs:      r.PostForm["documentIDs"],
+		FolderID:         r.PostFormValue("folderID"),
+		TagID:            r.PostFormValue("tagID"),
+		CustomFieldID:    r.PostFormValue("customFieldID"),
+		CustomFieldValue: r.PostFormValue("customFieldValue"),
+	}
+
+	operation, err := server.archive.RunBulkOperation(operation, user)
+	if err != nil {
+		slog.Error("failed to run bulk operation", slog.String("action", r.PostFormValue("action")), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, archive.ErrInvalidBulkOperation):
+			session.AddFlash("Select documents and an action", "error")
+		case errors.Is(err, archive.ErrFolderTrashed):
+			session.AddFlash("Documents cannot be moved into a folder in the trash", "error")
+		case errors.Is(err, archive.ErrInvalidCustomFieldValue):
+			session.AddFlash("Invalid value for the custom field", "error")
+		default:
+			session.AddFlash("Failed to apply action to documents", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	switch {
+	case operation.IsRunning():
+		session.AddFlash(fmt.Sprintf("Applying action to %d documents in the background", operation.Total()), "info")
+	case operation.Failed > 0:
+		session.AddFlash(fmt.Sprintf("Action applied to %d of %d documents", operation.Total()-operation.Failed, operation.Total()), "error")
+	default:
+		session.AddFlash(fmt.Sprintf("Action applied to %d documents", operation.Total()), "success")
+	}
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) getBulkOperation(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	operationID := chi.URLParam(r, "id")
+
+	operation, err := server.archive.GetBulkOperation(operationID, user)
+	if err != nil {
+		slog.Error("failed to get bulk operation", slog.String("operationID", operationID), slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	templates.BulkOperationProgress(operation).Render(r.Context(), w)
+}
+
+func (server *Server) handleDismissBulkOperation(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	operationID := chi.URLParam(r, "id")
+
+	err := server.archive.DismissBulkOperation(operationID, user)
+	if err != nil {
+		slog.Error("failed to dismiss bulk operation", slog.String("operationID", operationID), slog.String("error", err.Error()))
+	}
+
+	http.Redirect(w, r, r.Referer(), http.StatusFound)
+}
+
+func (server *Server) downloadBulkDocuments(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	r.ParseForm()
+	documentIDs := r.PostForm["documentIDs"]
+
+	filename := fmt.Sprintf("documents-%s.zip", time.Now().Format("2006-01-02"))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+	w.Header().Set("Content-Type", "application/zip")
+
+	err := server.archive.DownloadDocuments(documentIDs, user, w)
+	if err != nil {
+		slog.Error("failed to download documents",
+			slog.String("user", user),
+			slog.String("error", err.Error()))
+	}
+}
+
+func (server *Server) handleUpdateDocumentTitle(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	if documentID == "" {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	newTitle := r.FormValue("title")
+	if newTitle == "" {
+		session := server.getSession(r)
+		session.AddFlash("Title cannot be empty", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+		return
+	}
+
+	err := server.archive.UpdateDocumentTitle(documentID, user, newTitle)
+	if err != nil {
+		slog.Error("failed to update document title", slog.String("error", err.Error()))
+		session := server.getSession(r)
+		session.AddFlash("Failed to update document title", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+		return
+	}
+
+	// Add success flash message and redirect back to the document page
+	session := server.getSession(r)
+	session.AddFlash("Document title updated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+}
+
+func (server *Server) handleUpdateDocumentCustomFields(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	fields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	r.ParseForm()
+	values := make(map[string]string)
+	for _, field := range fields {
+		name := templates.CustomFieldInputName(field, "")
+		if r.PostForm.Has(name) {
+			values[field.ID] = r.PostForm.Get(name)
+		}
+	}
+
+	err = server.archive.UpdateDocumentCustomFields(documentID, user, values)
+	if err != nil {
+		slog.Error("failed to update document custom fields", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidCustomFieldValue) {
+			session.AddFlash(err.Error(), "error")
+		} else {
+			session.AddFlash("Failed to update custom fields", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Custom fields updated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleUploadDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	file, fileHeader, err := r.FormFile("document")
+	if err != nil {
+		session.AddFlash("No file selected", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+	defer file.Close()
+
+	err = server.archive.ReplaceDocumentFile(documentID, user, fileHeader.Filename, uint64(fileHeader.Size), file)
+	if err != nil {
+		slog.Error("failed to upload document version", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash(legalHoldErrorMessage(err, "Failed to upload new version"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("New version uploaded successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) downloadDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	number, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err != nil {
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	err = server.archive.DownloadDocumentVersion(documentID, number, user, func(version archive.DocumentVersion, r io.Reader) error {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", version.Filename))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", version.Filesize))
+		_, err := io.Copy(w, r)
+		return err
+	})
+	if err != nil {
+		slog.Error("failed to download document version",
+			slog.String("documentID", documentID),
+			slog.Int("version", number),
+			slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+	}
+}
+
+func (server *Server) handleRestoreDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	number, err := strconv.Atoi(chi.URLParam(r, "number"))
+	if err == nil {
+		err = server.archive.RestoreDocumentVersion(documentID, number, user)
+	}
+	if err != nil {
+		slog.Error("failed to restore document version", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash(legalHoldErrorMessage(err, "Failed to restore version"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash(fmt.Sprintf("Version %d restored successfully", number), "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleRotateDocumentPages(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	pages, err := archive.ParsePages(r.PostFormValue("pages"))
+	if err == nil {
+		var turns int
+		turns, err = strconv.Atoi(r.PostFormValue("turns"))
+		if err == nil {
+			err = server.archive.RotateDocumentPages(documentID, user, pages, turns)
+		}
+	}
+	if err != nil {
+		slog.Error("failed to rotate document pages", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidPages) {
+			session.AddFlash(err.Error(), "error")
+		} else {
+			session.AddFlash(legalHoldErrorMessage(err, "Failed to rotate pages"), "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Pages rotated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleDeleteDocumentPages(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	pages, err := archive.ParsePages(r.PostFormValue("pages"))
+	if err == nil {
+		err = server.archive.DeleteDocumentPages(documentID, user, pages)
+	}
+	if err != nil {
+		slog.Error("failed to delete document pages", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidPages) {
+			session.AddFlash(err.Error(), "error")
+		} else {
+			session.AddFlash(legalHoldErrorMessage(err, "Failed to delete pages"), "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Pages deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleSplitDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	var parts []archive.Document
+	pages, err := archive.ParsePages(r.PostFormValue("pages"))
+	if err == nil {
+		parts, err = server.archive.SplitDocument(documentID, user, pages)
+	}
+	if err != nil {
+		slog.Error("failed to split document", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidPages) {
+			session.AddFlash(err.Error(), "error")
+		} else {
+			session.AddFlash(legalHoldErrorMessage(err, "Failed to split document"), "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash(fmt.Sprintf("Document split into %d documents", len(parts)+1), "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleMergeDocuments(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	r.ParseForm()
+	err := server.archive.MergeDocuments(documentID, r.PostForm["documentIDs"], user)
+	if err != nil {
+		slog.Error("failed to merge documents", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidPages) {
+			session.AddFlash("Select the documents to merge", "error")
+		} else {
+			session.AddFlash(legalHoldErrorMessage(err, "Failed to merge documents"), "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Documents merged successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) getDocumentNotes(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+
+	document, err := server.archive.GetDocument(documentID, user)
+	if err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	notes, err := server.archive.GetNotes(documentID, user)
+	if err != nil {
+		slog.Error("failed to get notes", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		http.Error(w, "Failed to get notes", http.StatusInternalServerError)
+		return
+	}
+
+	templates.DocumentNotes(document, notes, user).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateNote(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	// The anchor is optional, the preview fills it in when an area is marked on a page
+	var anchor archive.NoteAnchor
+	var err error
+	if page := r.PostFormValue("page"); page != "" {
+		anchor.Page, err = strconv.Atoi(page)
+	}
+	for _, coordinate := range []struct {
+		name  string
+		value *float64
+	}{{"x", &anchor.X}, {"y", &anchor.Y}, {"width", &anchor.Width}, {"height", &anchor.Height}} {
+		if err != nil || r.PostFormValue(coordinate.name) == "" {
+			continue
+		}
+		*coordinate.value, err = strconv.ParseFloat(r.PostFormValue(coordinate.name), 64)
+	}
+	if err != nil {
+		err = archive.ErrInvalidNoteAnchor
+	} else {
+		_, err = server.archive.CreateNote(documentID, user, r.PostFormValue("body"), anchor)
+	}
+	if err != nil {
+		slog.Error("failed to create note", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, archive.ErrInvalidNote):
+			session.AddFlash("Note must not be empty", "error")
+		case errors.Is(err, archive.ErrInvalidNoteAnchor):
+			session.AddFlash("The marked area is not on a page of the document", "error")
+		default:
+			session.AddFlash("Failed to add note", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Note added successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleUpdateNote(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	err := server.archive.UpdateNote(documentID, chi.URLParam(r, "noteID"), user, r.PostFormValue("body"))
+	if err != nil {
+		slog.Error("failed to update note", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		switch {
+		case errors.Is(err, archive.ErrInvalidNote):
+			session.AddFlash("Note must not be empty", "error")
+		case errors.Is(err, archive.ErrNotAllowed):
+			session.AddFlash("Only the author can edit a note", "error")
+		default:
+			session.AddFlash("Failed to update note", "error")
+		}
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Note updated successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleDeleteNote(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	err := server.archive.DeleteNote(documentID, chi.URLParam(r, "noteID"), user)
+	if err != nil {
+		slog.Error("failed to delete note", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to delete note", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Note deleted successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) getSharedWithMe(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	folders, documents, err := server.archive.GetSharedWithMe(user)
+	if err != nil {
+		slog.Error("failed to get shared items", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.SharedWithMe(folders, documents, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleShareDocument(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	_, err := server.archive.ShareDocument(documentID, r.PostFormValue("grantee"), archive.Permission(r.PostFormValue("permission")), user)
+	if err != nil {
+		slog.Error("failed to share document", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		server.addShareErrorFlash(session, err)
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document shared successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleShareFolder(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive?folderID=%s", folderID)
+
+	_, err := server.archive.ShareFolder(folderID, r.PostFormValue("grantee"), archive.Permission(r.PostFormValue("permission")), user)
+	if err != nil {
+		slog.Error("failed to share folder", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		server.addShareErrorFlash(session, err)
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder shared successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) addShareErrorFlash(session *sessions.Session, err error) {
+	switch {
+	case errors.Is(err, archive.ErrGranteeNotFound):
+		session.AddFlash("There is no user with this name", "error")
+	case errors.Is(err, archive.ErrInvalidPermission):
+		session.AddFlash("Choose whether the user can view or edit", "error")
+	case errors.Is(err, archive.ErrInvalidGrant):
+		session.AddFlash("You cannot share with yourself", "error")
+	default:
+		session.AddFlash("Failed to share", "error")
+	}
+}
+
+func (server *Server) handleRevokeGrant(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	grantID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+
+	// The form tells which item was shared, so the user returns to it
+	redirect := "/archive/shared"
+	if documentID := r.PostFormValue("documentID"); documentID != "" {
+		redirect = fmt.Sprintf("/archive/documents/%s", documentID)
+	} else if folderID := r.PostFormValue("folderID"); folderID != "" {
+		redirect = fmt.Sprintf("/archive?folderID=%s", folderID)
+	}
+
+	err := server.archive.RevokeGrant(grantID, user)
+	if err != nil {
+		slog.Error("failed to revoke grant", slog.String("grantID", grantID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to revoke access", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Access revoked successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handlePlaceDocumentLegalHold(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	_, err := server.archive.PlaceDocumentLegalHold(documentID, r.PostFormValue("reason"), user)
+	if err != nil {
+		slog.Error("failed to place legal hold", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash(placeLegalHoldErrorMessage(err), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Document placed under legal hold", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handlePlaceFolderLegalHold(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive?folderID=%s", folderID)
+
+	_, err := server.archive.PlaceFolderLegalHold(folderID, r.PostFormValue("reason"), user)
+	if err != nil {
+		slog.Error("failed to place legal hold", slog.String("folderID", folderID), slog.String("error", err.Error()))
+		session.AddFlash(placeLegalHoldErrorMessage(err), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Folder placed under legal hold", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func placeLegalHoldErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, archive.ErrInvalidLegalHold):
+		return "Please enter the reason for the legal hold"
+	case errors.Is(err, archive.ErrNotAllowed):
+		return "You are not allowed to place a legal hold here"
+	default:
+		return "Failed to place legal hold"
+	}
+}
+
+func (server *Server) handleReleaseLegalHold(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	holdID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+
+	// The form tells which item was held, so the user returns to it
+	redirect := "/archive"
+	if documentID := r.PostFormValue("documentID"); documentID != "" {
+		redirect = fmt.Sprintf("/archive/documents/%s", documentID)
+	} else if folderID := r.PostFormValue("folderID"); folderID != "" {
+		redirect = fmt.Sprintf("/archive?folderID=%s", folderID)
+	}
+
+	err := server.archive.ReleaseLegalHold(holdID, user)
+	if err != nil {
+		slog.Error("failed to release legal hold", slog.String("holdID", holdID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to release legal hold", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, redirect, http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Legal hold released", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+// legalHoldErrorMessage tells the user about a legal hold blocking the change, the error carries its reason.
+func legalHoldErrorMessage(err error, fallback string) string {
+	if errors.Is(err, archive.ErrLegalHold) {
+		return "Not possible while " + err.Error()
+	}
+	return fallback
+}
+
+func (server *Server) getShareLinks(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+
+	links, err := server.archive.GetShareLinks(user)
+	if err != nil {
+		slog.Error("failed to get share links", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	accesses := make(map[string][]archive.ShareLinkAccess)
+	for _, link := range links {
+		accesses[link.ID], err = server.archive.GetShareLinkAccesses(link.ID, user)
+		if err != nil {
+			slog.Error("failed to get share link accesses", slog.String("linkID", link.ID), slog.String("error", err.Error()))
+			templates.ErrorServer("").Render(r.Context(), w)
+			return
+		}
+	}
+
+	origin := "http://" + r.Host
+	if r.TLS != nil {
+		origin = "https://" + r.Host
+	}
+
+	notifications := server.buildNotifications(r, w)
+	templates.ShareLinks(links, accesses, origin, notifications, server.isAdmin(r)).Render(r.Context(), w)
+}
+
+func (server *Server) handleCreateDocumentShareLink(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+
+	options, err := parseShareLinkOptions(r)
+	if err == nil {
+		_, err = server.archive.CreateDocumentShareLink(documentID, user, options)
+	}
+	server.finishCreateShareLink(w, r, err, fmt.Sprintf("/archive/documents/%s", documentID))
 }
 
-func (server *Server) handleUploadDocument(w http.ResponseWriter, r *http.Request) {
-	session := server.getSession(r)
-	username := server.getAuthenticatedUser(r)
-	folderID := r.PostFormValue("folderID")
-	if folderID == "" {
-		folderID = archive.FolderRootID
-	}
+func (server *Server) handleCreateFolderShareLink(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	folderID := chi.URLParam(r, "id")
 
-	// Verify user owns the target folder
-	_, err := server.archive.GetFolder(folderID, username)
-	if err != nil {
-		slog.Error("failed to verify folder ownership for upload", slog.String("error", err.Error()))
-		session.AddFlash("You don't have permission to upload to this folder", "error")
-		session.Save(r, w)
-		http.Redirect(w, r, "/archive", http.StatusFound)
-		return
+	options, err := parseShareLinkOptions(r)
+	if err == nil {
+		_, err = server.archive.CreateFolderShareLink(folderID, user, options)
 	}
+	server.finishCreateShareLink(w, r, err, fmt.Sprintf("/archive?folderID=%s", folderID))
+}
 
-	err = r.ParseMultipartForm(32 << 20) // 32 MB max memory
+// finishCreateShareLink shows the new link in the list of share links or returns to the shared item on failure.
+func (server *Server) finishCreateShareLink(w http.ResponseWriter, r *http.Request, err error, redirect string) {
+	session := server.getSession(r)
 	if err != nil {
-		session.AddFlash("Failed to parse uploaded files", "error")
-		session.Save(r, w)
-		http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
-		return
-	}
-
-	files := r.MultipartForm.File["documents"]
-	if len(files) == 0 {
-		session.AddFlash("No files selected", "error")
+		slog.Error("failed to create share link", slog.String("error", err.Error()))
+		if errors.Is(err, archive.ErrInvalidShareLink) {
+			session.AddFlash("Expiry and download limit must not be negative", "error")
+		} else {
+			session.AddFlash("Failed to create share link", "error")
+		}
 		session.Save(r, w)
-		http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+		http.Redirect(w, r, redirect, http.StatusFound)
 		return
 	}
 
-	uploadedCount := 0
-	for _, fileHeader := range files {
-		file, err := fileHeader.Open()
-		if err != nil {
-			slog.Error("failed to open uploaded file", slog.String("filename", fileHeader.Filename), slog.String("error", err.Error()))
-			continue
-		}
+	session.AddFlash("Share link created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/links", http.StatusFound)
+}
 
-		err = server.archive.UploadDocument(fileHeader.Filename, uint64(fileHeader.Size), folderID, username, file)
-		file.Close()
+func parseShareLinkOptions(r *http.Request) (archive.ShareLinkOptions, error) {
+	options := archive.ShareLinkOptions{Password: r.PostFormValue("password")}
 
+	if value := r.PostFormValue("expiresInDays"); value != "" {
+		days, err := strconv.Atoi(value)
 		if err != nil {
-			slog.Error("failed to upload document", slog.String("filename", fileHeader.Filename), slog.String("error", err.Error()))
-			continue
+			return archive.ShareLinkOptions{}, archive.ErrInvalidShareLink
 		}
-
-		uploadedCount++
+		options.ExpiresIn = time.Duration(days) * 24 * time.Hour
 	}
 
-	if uploadedCount == 0 {
-		session.AddFlash("Failed to upload any documents", "error")
-	} else if uploadedCount == len(files) {
-		if len(files) == 1 {
-			session.AddFlash("Document uploaded successfully", "success")
-		} else {
-			session.AddFlash(fmt.Sprintf("%d documents uploaded successfully", uploadedCount), "success")
+	if value := r.PostFormValue("maxDownloads"); value != "" {
+		maxDownloads, err := strconv.Atoi(value)
+		if err != nil {
+			return archive.ShareLinkOptions{}, archive.ErrInvalidShareLink
 		}
-	} else {
-		session.AddFlash(fmt.Sprintf("%d of %d documents uploaded successfully", uploadedCount, len(files)), "warning")
+		options.MaxDownloads = maxDownloads
 	}
 
-	session.Save(r, w)
-	http.Redirect(w, r, fmt.Sprintf("/archive?folderID=%s", folderID), http.StatusFound)
+	return options, nil
 }
 
-func (server *Server) getDocumentDetails(w http.ResponseWriter, r *http.Request) {
+func (server *Server) handleRevokeShareLink(w http.ResponseWriter, r *http.Request) {
 	user := server.getAuthenticatedUser(r)
-	documentID := chi.URLParam(r, "id")
-	if documentID == "" {
-		templates.ErrorServer("").Render(r.Context(), w)
+	linkID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+
+	err := server.archive.RevokeShareLink(linkID, user)
+	if err != nil {
+		slog.Error("failed to revoke share link", slog.String("linkID", linkID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to revoke share link", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/archive/links", http.StatusFound)
 		return
 	}
 
-	document, err := server.archive.GetDocument(documentID, user)
+	session.AddFlash("Share link revoked successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/archive/links", http.StatusFound)
+}
+
+// getPublicShareLink shows the document or folder of a share link to visitors without an account.
+func (server *Server) getPublicShareLink(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	link, err := server.archive.OpenShareLink(token, server.isShareLinkUnlocked(r, token), shareLinkVisitor(r))
+	if errors.Is(err, archive.ErrShareLinkLocked) {
+		notifications := server.buildNotifications(r, w)
+		templates.PublicShareLinkPassword(token, notifications).Render(r.Context(), w)
+		return
+	}
 	if err != nil {
-		templates.ErrorServer("").Render(r.Context(), w)
+		server.renderShareLinkUnavailable(w, r, err)
 		return
 	}
 
 	notifications := server.buildNotifications(r, w)
-	templates.DocumentDetails(document, notifications, server.isAdmin(r)).Render(r.Context(), w)
-}
+	if link.DocumentID != "" {
+		document, err := server.archive.GetShareLinkDocument(link, link.DocumentID)
+		if err != nil {
+			server.renderShareLinkUnavailable(w, r, err)
+			return
+		}
 
-func (server *Server) downloadDocument(w http.ResponseWriter, r *http.Request) {
-	user := server.getAuthenticatedUser(r)
-	documentID := chi.URLParam(r, "id")
-	if documentID == "" {
-		templates.ErrorServer("").Render(r.Context(), w)
+		templates.PublicShareLinkDocument(token, document, notifications).Render(r.Context(), w)
 		return
 	}
 
-	document, err := server.archive.GetDocument(documentID, user)
+	folder, folders, documents, err := server.archive.GetShareLinkFolder(link, r.URL.Query().Get("folderID"))
 	if err != nil {
-		templates.ErrorServer("").Render(r.Context(), w)
+		server.renderShareLinkUnavailable(w, r, err)
 		return
 	}
 
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", document.Filename))
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", document.Filesize))
-	err = server.archive.DownloadDocument(documentID, user, func(r io.Reader) error {
-		_, err := io.Copy(w, r)
+	templates.PublicShareLinkFolder(token, link, folder, folders, documents, notifications).Render(r.Context(), w)
+}
+
+func (server *Server) handleUnlockShareLink(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	session := server.getSession(r)
+
+	_, err := server.archive.UnlockShareLink(token, r.PostFormValue("password"), shareLinkVisitor(r))
+	if errors.Is(err, archive.ErrShareLinkPassword) {
+		session.AddFlash("That didn't work. Please try again.", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/s/"+token, http.StatusFound)
+		return
+	}
+	if err != nil {
+		server.renderShareLinkUnavailable(w, r, err)
+		return
+	}
+
+	// The session cookie is signed, visitors cannot unlock links without the password
+	session.Values[shareLinkSessionKey(token)] = true
+	session.Save(r, w)
+	http.Redirect(w, r, "/s/"+token, http.StatusFound)
+}
+
+func (server *Server) downloadPublicShareLinkDocument(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+	documentID := chi.URLParam(r, "documentID")
+
+	err := server.archive.DownloadShareLinkDocument(token, documentID, server.isShareLinkUnlocked(r, token), shareLinkVisitor(r), func(document archive.Document, reader io.Reader) error {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", document.Filename))
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", document.Filesize))
+		_, err := io.Copy(w, reader)
 		return err
 	})
-
+	if errors.Is(err, archive.ErrShareLinkLocked) {
+		http.Redirect(w, r, "/s/"+token, http.StatusFound)
+		return
+	}
 	if err != nil {
-		slog.Error("failed to download document",
-			slog.String("documentID", documentID),
-			slog.String("user", user),
-			slog.String("error", err.Error()))
+		server.renderShareLinkUnavailable(w, r, err)
 	}
 }
 
-func (server *Server) exportAllDocuments(w http.ResponseWriter, r *http.Request) {
-	user := server.getAuthenticatedUser(r)
+func (server *Server) renderShareLinkUnavailable(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Info("share link unavailable", slog.String("error", err.Error()))
+	w.WriteHeader(http.StatusNotFound)
+	templates.PublicShareLinkUnavailable().Render(r.Context(), w)
+}
 
-	filename := fmt.Sprintf("documents-%s.zip", time.Now().Format("2006-01-02"))
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
-	w.Header().Set("Content-Type", "application/zip")
+func (server *Server) isShareLinkUnlocked(r *http.Request, token string) bool {
+	unlocked, _ := server.getSession(r).Values[shareLinkSessionKey(token)].(bool)
+	return unlocked
+}
 
-	err := server.archive.ExportAllDocuments(user, w)
+func shareLinkSessionKey(token string) string {
+	return "shareLink:" + token
+}
+
+func shareLinkVisitor(r *http.Request) archive.ShareLinkVisitor {
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		slog.Error("failed to export all documents",
-			slog.String("user", user),
-			slog.String("error", err.Error()))
+		address = r.RemoteAddr
 	}
+	return archive.ShareLinkVisitor{Address: address, UserAgent: r.UserAgent()}
 }
 
-func (server *Server) handleDeleteDocument(w http.ResponseWriter, r *http.Request) {
+func (server *Server) handleAssignTag(w http.ResponseWriter, r *http.Request) {
 	user := server.getAuthenticatedUser(r)
 	documentID := chi.URLParam(r, "id")
-	if documentID == "" {
-		templates.ErrorServer("").Render(r.Context(), w)
-		return
-	}
-
 	session := server.getSession(r)
-	err := server.archive.TrashDocument(documentID, user)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	err := server.archive.AssignTag(documentID, r.PostFormValue("tagID"), user)
 	if err != nil {
-		slog.Error("failed to delete document", slog.String("error", err.Error()))
-		session.AddFlash("Failed to delete document", "error")
+		slog.Error("failed to assign tag", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to add tag", "error")
 		session.Save(r, w)
-		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
-		return
 	}
 
-	session.AddFlash("Document trashed successfully", "success")
-	session.Save(r, w)
-	http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+	http.Redirect(w, r, redirect, http.StatusFound)
 }
 
-func (server *Server) handleRestoreDocument(w http.ResponseWriter, r *http.Request) {
+func (server *Server) handleAssignCorrespondent(w http.ResponseWriter, r *http.Request) {
 	user := server.getAuthenticatedUser(r)
 	documentID := chi.URLParam(r, "id")
-	if documentID == "" {
-		templates.ErrorServer("").Render(r.Context(), w)
-		return
-	}
-
 	session := server.getSession(r)
-	err := server.archive.RestoreDocument(documentID, user)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	err := server.archive.AssignCorrespondent(documentID, r.PostFormValue("correspondentID"), user)
 	if err != nil {
-		slog.Error("failed to restore document", slog.String("error", err.Error()))
-		session.AddFlash("Failed to restore document", "error")
+		slog.Error("failed to assign correspondent", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to update correspondent", "error")
 		session.Save(r, w)
-		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
-		return
 	}
 
-	session.AddFlash("Document restored successfully", "success")
-	session.Save(r, w)
-	http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+	http.Redirect(w, r, redirect, http.StatusFound)
 }
 
-func (server *Server) handleUpdateDocumentTitle(w http.ResponseWriter, r *http.Request) {
+func (server *Server) handleAssignDocumentType(w http.ResponseWriter, r *http.Request) {
 	user := server.getAuthenticatedUser(r)
 	documentID := chi.URLParam(r, "id")
-	if documentID == "" {
-		templates.ErrorServer("").Render(r.Context(), w)
-		return
-	}
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
 
-	newTitle := r.FormValue("title")
-	if newTitle == "" {
-		session := server.getSession(r)
-		session.AddFlash("Title cannot be empty", "error")
+	err := server.archive.AssignDocumentType(documentID, r.PostFormValue("documentTypeID"), user)
+	if err != nil {
+		slog.Error("failed to assign document type", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to update document type", "error")
 		session.Save(r, w)
-		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
-		return
 	}
 
-	err := server.archive.UpdateDocumentTitle(documentID, user, newTitle)
+	http.Redirect(w, r, redirect, http.StatusFound)
+}
+
+func (server *Server) handleRemoveTag(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
+	documentID := chi.URLParam(r, "id")
+	session := server.getSession(r)
+	redirect := fmt.Sprintf("/archive/documents/%s", documentID)
+
+	err := server.archive.RemoveTag(documentID, chi.URLParam(r, "tagID"), user)
 	if err != nil {
-		slog.Error("failed to update document title", slog.String("error", err.Error()))
-		session := server.getSession(r)
-		session.AddFlash("Failed to update document title", "error")
+		slog.Error("failed to remove tag", slog.String("documentID", documentID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to remove tag", "error")
 		session.Save(r, w)
-		http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
-		return
 	}
 
-	// Add success flash message and redirect back to the document page
-	session := server.getSession(r)
-	session.AddFlash("Document title updated successfully", "success")
-	session.Save(r, w)
-	http.Redirect(w, r, fmt.Sprintf("/archive/documents/%s", documentID), http.StatusFound)
+	http.Redirect(w, r, redirect, http.StatusFound)
 }
 
 func (server *Server) getDocumentPreview(w http.ResponseWriter, r *http.Request) {
@@ -552,6 +2568,7 @@ func (server *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (server *Server) getSearch(w http.ResponseWriter, r *http.Request) {
+	user := server.getAuthenticatedUser(r)
 	notifications := server.buildNotifications(r, w)
 	isAdmin := server.isAdmin(r)
 
@@ -560,19 +2577,51 @@ func (server *Server) getSearch(w http.ResponseWriter, r *http.Request) {
 	// Start with empty results
 	var results []search.SearchResult
 
-	templates.Search(notifications, page, isAdmin, results).Render(r.Context(), w)
+	tags, err := server.archive.GetTags(user)
+	if err != nil {
+		slog.Error("failed to get tags", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	fields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		slog.Error("failed to get custom fields", slog.String("user", user), slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	templates.Search(notifications, page, isAdmin, results, tags, fields).Render(r.Context(), w)
 }
 
 func (server *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	user := server.getAuthenticatedUser(r)
 	query := r.URL.Query().Get("q")
+	filter := search.Filter{TagIDs: r.URL.Query()["tagID"]}
+
+	fields, err := server.archive.GetCustomFields(user)
+	if err != nil {
+		templates.EmptySearchResults().Render(r.Context(), w)
+		return
+	}
+	for _, field := range fields {
+		fieldFilter := search.FieldFilter{
+			Field: field,
+			Value: r.URL.Query().Get(templates.CustomFieldInputName(field, "")),
+			Min:   r.URL.Query().Get(templates.CustomFieldInputName(field, "min")),
+			Max:   r.URL.Query().Get(templates.CustomFieldInputName(field, "max")),
+		}
+		if !fieldFilter.IsEmpty() {
+			filter.Fields = append(filter.Fields, fieldFilter)
+		}
+	}
 
-	if query == "" {
+	if query == "" && filter.IsEmpty() {
 		templates.EmptySearchResults().Render(r.Context(), w)
 		return
 	}
 
-	hits, err := server.search.SearchDocuments(query, user, 20)
+	hits, err := server.search.SearchDocuments(query, filter, user, 20)
 	if err != nil {
 		templates.EmptySearchResults().Render(r.Context(), w)
 		return
@@ -615,6 +2664,20 @@ func (server *Server) admin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	groups, err := server.administration.GetGroups()
+	if err != nil {
+		slog.Error("failed to get groups", slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
+	holds, err := server.archive.GetLegalHolds()
+	if err != nil {
+		slog.Error("failed to get legal holds", slog.String("error", err.Error()))
+		templates.ErrorServer("").Render(r.Context(), w)
+		return
+	}
+
 	// Get page for tasks pagination
 	page := 1
 	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
@@ -647,7 +2710,7 @@ func (server *Server) admin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	runtimeInfo := server.administration.GetRuntimeInfo()
-	templates.Administration(notifications, currentTab, settings, users, properties, runtimeInfo).Render(r.Context(), w)
+	templates.Administration(notifications, currentTab, settings, users, groups, holds, properties, runtimeInfo).Render(r.Context(), w)
 }
 
 func (server *Server) handleUpdateSettings(w http.ResponseWriter, r *http.Request) {
@@ -699,6 +2762,87 @@ func (server *Server) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/admin?tab=users", http.StatusFound)
 }
 
+func (server *Server) handleCreateGroup(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	err := server.administration.CreateGroup(r.FormValue("name"))
+	if err != nil {
+		slog.Error("failed to create group", slog.String("error", err.Error()))
+		session.AddFlash(groupErrorMessage(err, "Failed to create group"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/admin?tab=groups", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Group created successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/admin?tab=groups", http.StatusFound)
+}
+
+func (server *Server) handleAddGroupMember(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	err := server.administration.AddGroupMember(r.FormValue("group"), r.FormValue("username"))
+	if err != nil {
+		slog.Error("failed to add group member", slog.String("error", err.Error()))
+		session.AddFlash(groupErrorMessage(err, "Failed to add member"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/admin?tab=groups", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Member added successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/admin?tab=groups", http.StatusFound)
+}
+
+func (server *Server) handleRemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	err := server.administration.RemoveGroupMember(r.FormValue("group"), r.FormValue("username"))
+	if err != nil {
+		slog.Error("failed to remove group member", slog.String("error", err.Error()))
+		session.AddFlash(groupErrorMessage(err, "Failed to remove member"), "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/admin?tab=groups", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Member removed successfully", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/admin?tab=groups", http.StatusFound)
+}
+
+func groupErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, administration.ErrInvalidGroupName):
+		return "Please enter a group name"
+	case errors.Is(err, administration.ErrGroupExists):
+		return "A group or user with this name already exists"
+	case errors.Is(err, administration.ErrGroupNotFound):
+		return "Group not found"
+	case errors.Is(err, administration.ErrMemberNotFound):
+		return "User not found"
+	default:
+		return fallback
+	}
+}
+
+func (server *Server) handleReleaseAnyLegalHold(w http.ResponseWriter, r *http.Request) {
+	session := server.getSession(r)
+	holdID := chi.URLParam(r, "id")
+
+	err := server.archive.ReleaseAnyLegalHold(holdID)
+	if err != nil {
+		slog.Error("failed to release legal hold", slog.String("holdID", holdID), slog.String("error", err.Error()))
+		session.AddFlash("Failed to release legal hold", "error")
+		session.Save(r, w)
+		http.Redirect(w, r, "/admin?tab=holds", http.StatusFound)
+		return
+	}
+
+	session.AddFlash("Legal hold released", "success")
+	session.Save(r, w)
+	http.Redirect(w, r, "/admin?tab=holds", http.StatusFound)
+}
+
 func (server *Server) handleForceGC(w http.ResponseWriter, r *http.Request) {
 	runtime.GC()
 
@@ -883,6 +3027,11 @@ func NewServer(
 		router.Get("/login", server.login)
 		router.Post("/login", server.handleLogin)
 
+		// Share links are opened by visitors without an account
+		router.Get("/s/{token}", server.getPublicShareLink)
+		router.Post("/s/{token}", server.handleUnlockShareLink)
+		router.Get("/s/{token}/documents/{documentID}/download", server.downloadPublicShareLinkDocument)
+
 		router.Group(func(router chi.Router) {
 			router.Use(server.requireLogin)
 			router.Get("/", server.home)
@@ -890,7 +3039,57 @@ func NewServer(
 			router.Post("/logout", server.handleLogout)
 			router.Get("/archive", server.getArchive)
 			router.Get("/archive/export", server.exportAllDocuments)
+			router.Post("/archive/bulk", server.handleBulkOperation)
+			router.Post("/archive/bulk/download", server.downloadBulkDocuments)
+			router.Get("/archive/bulk/{id}", server.getBulkOperation)
+			router.Post("/archive/bulk/{id}/dismiss", server.handleDismissBulkOperation)
+			router.Get("/archive/shared", server.getSharedWithMe)
+			router.Post("/archive/shares/{id}/revoke", server.handleRevokeGrant)
+			router.Get("/archive/links", server.getShareLinks)
+			router.Post("/archive/links/{id}/revoke", server.handleRevokeShareLink)
+			router.Post("/archive/holds/{id}/release", server.handleReleaseLegalHold)
+			router.Get("/archive/duplicates", server.getDuplicates)
+			router.Post("/archive/duplicates/policy", server.handleUpdateDuplicatePolicy)
 			router.Post("/archive/folders", server.handleCreateFolder)
+			router.Post("/archive/folders/{id}/rename", server.handleRenameFolder)
+			router.Post("/archive/folders/{id}/move", server.handleMoveFolder)
+			router.Post("/archive/folders/{id}/trash", server.handleTrashFolder)
+			router.Post("/archive/folders/{id}/restore", server.handleRestoreFolder)
+			router.Post("/archive/folders/{id}/shares", server.handleShareFolder)
+			router.Post("/archive/folders/{id}/links", server.handleCreateFolderShareLink)
+			router.Post("/archive/folders/{id}/holds", server.handlePlaceFolderLegalHold)
+			router.Post("/archive/tags", server.handleCreateTag)
+			router.Get("/archive/correspondents", server.getCorrespondents)
+			router.Post("/archive/correspondents", server.handleCreateCorrespondent)
+			router.Post("/archive/correspondents/{id}/delete", server.handleDeleteCorrespondent)
+			router.Get("/archive/document-types", server.getDocumentTypes)
+			router.Post("/archive/document-types", server.handleCreateDocumentType)
+			router.Post("/archive/document-types/{id}/delete", server.handleDeleteDocumentType)
+			router.Get("/archive/consume", server.getConsumeSettings)
+			router.Post("/archive/consume", server.handleUpdateConsumeSettings)
+			router.Get("/archive/retention", server.getRetentionPolicies)
+			router.Post("/archive/retention", server.handleCreateRetentionPolicy)
+			router.Post("/archive/retention/apply", server.handleApplyRetentionPolicies)
+			router.Post("/archive/retention/{id}/delete", server.handleDeleteRetentionPolicy)
+			router.Post("/archive/retention/{id}/notices/{documentID}/dismiss", server.handleDismissRetentionNotice)
+			router.Get("/archive/trash", server.getTrash)
+			router.Post("/archive/trash/retention", server.handleUpdateTrashRetention)
+			router.Post("/archive/trash/empty", server.handleEmptyTrash)
+			router.Post("/archive/trash/documents/{id}/delete", server.handleDeleteDocumentPermanently)
+			router.Post("/archive/trash/folders/{id}/delete", server.handleDeleteFolderPermanently)
+			router.Get("/archive/mail", server.getMailAccounts)
+			router.Post("/archive/mail", server.handleCreateMailAccount)
+			router.Post("/archive/mail/{id}/delete", server.handleDeleteMailAccount)
+			router.Post("/archive/mail/{id}/poll", server.handlePollMailAccount)
+			router.Get("/archive/rules", server.getClassificationRules)
+			router.Post("/archive/rules", server.handleCreateClassificationRule)
+			router.Get("/archive/rules/{id}/dry-run", server.getClassificationRuleDryRun)
+			router.Post("/archive/rules/{id}/move", server.handleMoveClassificationRule)
+			router.Post("/archive/rules/{id}/delete", server.handleDeleteClassificationRule)
+			router.Get("/archive/fields", server.getCustomFields)
+			router.Post("/archive/fields", server.handleCreateCustomField)
+			router.Post("/archive/fields/{id}/delete", server.handleDeleteCustomField)
+			router.Post("/archive/tags/{id}/delete", server.handleDeleteTag)
 			router.Post("/archive/synchronize", server.handleSynchronize)
 			router.Post("/archive/documents", server.handleUploadDocument)
 			router.Get("/archive/documents/{id}", server.getDocumentDetails)
@@ -899,7 +3098,28 @@ func NewServer(
 			router.Get("/archive/documents/{id}/preview-component/{page}", server.getDocumentPreviewComponent)
 			router.Post("/archive/documents/{id}/delete", server.handleDeleteDocument)
 			router.Post("/archive/documents/{id}/restore", server.handleRestoreDocument)
+			router.Post("/archive/documents/{id}/move", server.handleMoveDocument)
+			router.Post("/archive/documents/{id}/copy", server.handleCopyDocument)
+			router.Post("/archive/documents/{id}/shares", server.handleShareDocument)
+			router.Post("/archive/documents/{id}/links", server.handleCreateDocumentShareLink)
+			router.Post("/archive/documents/{id}/holds", server.handlePlaceDocumentLegalHold)
 			router.Post("/archive/documents/{id}/update-title", server.handleUpdateDocumentTitle)
+			router.Post("/archive/documents/{id}/fields", server.handleUpdateDocumentCustomFields)
+			router.Post("/archive/documents/{id}/versions", server.handleUploadDocumentVersion)
+			router.Get("/archive/documents/{id}/versions/{number}/download", server.downloadDocumentVersion)
+			router.Post("/archive/documents/{id}/versions/{number}/restore", server.handleRestoreDocumentVersion)
+			router.Post("/archive/documents/{id}/pages/rotate", server.handleRotateDocumentPages)
+			router.Post("/archive/documents/{id}/pages/delete", server.handleDeleteDocumentPages)
+			router.Post("/archive/documents/{id}/pages/split", server.handleSplitDocument)
+			router.Post("/archive/documents/{id}/merge", server.handleMergeDocuments)
+			router.Get("/archive/documents/{id}/notes", server.getDocumentNotes)
+			router.Post("/archive/documents/{id}/notes", server.handleCreateNote)
+			router.Post("/archive/documents/{id}/notes/{noteID}/update", server.handleUpdateNote)
+			router.Post("/archive/documents/{id}/notes/{noteID}/delete", server.handleDeleteNote)
+			router.Post("/archive/documents/{id}/tags", server.handleAssignTag)
+			router.Post("/archive/documents/{id}/correspondent", server.handleAssignCorrespondent)
+			router.Post("/archive/documents/{id}/document-type", server.handleAssignDocumentType)
+			router.Post("/archive/documents/{id}/tags/{tagID}/remove", server.handleRemoveTag)
 			router.Get("/search", server.getSearch)
 			router.Get("/search/execute", server.handleSearch)
 
@@ -908,6 +3128,10 @@ func NewServer(
 				router.Get("/admin", server.admin)
 				router.Post("/admin/settings", server.handleUpdateSettings)
 				router.Post("/admin/users", server.handleCreateUser)
+				router.Post("/admin/groups", server.handleCreateGroup)
+				router.Post("/admin/groups/members", server.handleAddGroupMember)
+				router.Post("/admin/groups/members/remove", server.handleRemoveGroupMember)
+				router.Post("/admin/holds/{id}/release", server.handleReleaseAnyLegalHold)
 				router.Post("/admin/runtime/gc", server.handleForceGC)
 				router.Post("/admin/tasks/clear-completed", server.handleClearCompletedTasks)
 			})
@@ -0,0 +1,26 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	var html bytes.Buffer
+	body := "Paid on **12.03.**\n\n<script>alert(1)</script>\n\n[receipt](javascript:alert(1)) <img src=x onerror=alert(1)>"
+	if err := Markdown(body).Render(context.Background(), &html); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := html.String()
+	if !strings.Contains(rendered, "<strong>12.03.</strong>") {
+		t.Errorf("expected the markdown to be rendered, got %s", rendered)
+	}
+	for _, unsafe := range []string{"<script", "javascript:", "<img", "onerror"} {
+		if strings.Contains(rendered, unsafe) {
+			t.Errorf("expected %s to be left out, got %s", unsafe, rendered)
+		}
+	}
+}
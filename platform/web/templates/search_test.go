@@ -0,0 +1,30 @@
+package templates
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"unterlagen/features/search"
+)
+
+func TestSearchResultItemEscapesSnippet(t *testing.T) {
+	result := search.SearchResult{
+		DocumentID: "document",
+		Name:       "Invoice",
+		Snippet:    `<img src=x onerror=alert(1)> paid ` + search.SnippetMatchStart + `<b>invoice</b>` + search.SnippetMatchEnd + ` due`,
+	}
+
+	var html bytes.Buffer
+	if err := SearchResultItem(result).Render(context.Background(), &html); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := html.String()
+	if strings.Contains(rendered, "<img") || strings.Contains(rendered, "<b>") {
+		t.Errorf("expected the snippet to be escaped, got %s", rendered)
+	}
+	if !strings.Contains(rendered, "&lt;img src=x onerror=alert(1)&gt; paid <mark>&lt;b&gt;invoice&lt;/b&gt;</mark> due") {
+		t.Errorf("expected the match to be highlighted, got %s", rendered)
+	}
+}